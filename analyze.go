@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	// Number of rows shown in each suggestion table
+	suggestTopCount = 5
+	// Minimum share of the bundle a group must hold to be recommended for exclusion
+	suggestMinShare = 0.05
+)
+
+// Supported -format values for the analyze report
+var analyzeFormats = []string{"text", "csv"}
+
+// Supported -sort keys for the top files of the analyze report
+var analyzeSorts = []string{"size", "symbols", "tokens", "loc"}
+
+// sortFiles orders files by the -sort key, largest first. Files with equal
+// keys keep their order.
+func sortFiles(files []FileInfo, by string) error {
+	var key func(FileInfo) int64
+	switch by {
+	case "", "size":
+		key = func(file FileInfo) int64 { return file.size }
+	case "symbols":
+		key = func(file FileInfo) int64 { return int64(file.symbols) }
+	case "tokens":
+		key = func(file FileInfo) int64 { return int64(file.tokens) }
+	case "loc":
+		key = func(file FileInfo) int64 { return int64(file.lines.code) }
+	default:
+		return fmt.Errorf("unknown sort %q (use %s)", by, strings.Join(analyzeSorts, ", "))
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return key(files[i]) > key(files[j])
+	})
+	return nil
+}
+
+// topFilesTitle returns the title of the top files table for the -sort key
+func topFilesTitle(by string, count int) string {
+	switch by {
+	case "symbols", "tokens":
+		return fmt.Sprintf("Top %d files by %s:", count, by)
+	case "loc":
+		return fmt.Sprintf("Top %d files by lines of code:", count)
+	default:
+		return fmt.Sprintf("Top %d largest files:", count)
+	}
+}
+
+// checkAnalyzeFormat rejects the analyze options the CSV report can't hold
+func checkAnalyzeFormat(opts analyzeOptions) error {
+	if opts.format != "csv" {
+		return nil
+	}
+	var conflicts []string
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{"-complexity", opts.complexity},
+		{"-list", opts.list},
+		{"-loc", opts.loc},
+		{"-models", opts.models},
+		{"-suggest", opts.suggest},
+		{"-target", opts.target != ""},
+	} {
+		if option.set {
+			conflicts = append(conflicts, option.name)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("-format csv can't be combined with %s", strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// analyzeCSV renders one row per file of the result file, in bundle order
+// unless -sort is given, for spreadsheets and CI artifacts
+func analyzeCSV(files []FileInfo) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"path", "size", "symbols", "tokens", "language"})
+	for _, file := range files {
+		_ = w.Write([]string{
+			file.path,
+			fmt.Sprint(file.size),
+			fmt.Sprint(file.symbols),
+			fmt.Sprint(file.tokens),
+			file.language,
+		})
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+// tokenGroup aggregates the files sharing a directory and/or extension
+type tokenGroup struct {
+	dir    string // directory with a trailing slash, empty for the whole bundle
+	ext    string // extension including the dot, empty for all extensions
+	files  int
+	tokens int
+}
+
+// label describes the group the way an exclusion would be phrased
+func (g tokenGroup) label() string {
+	switch {
+	case g.ext != "" && g.dir != "":
+		return fmt.Sprintf("*%s under %s", g.ext, g.dir)
+	case g.ext != "":
+		return fmt.Sprintf("*%s files", g.ext)
+	default:
+		return g.dir
+	}
+}
+
+// formatTokens renders a token count compactly (e.g. 312, 2.4k, 45k, 1.2M)
+func formatTokens(tokens int) string {
+	switch {
+	case tokens >= 1000000:
+		return fmt.Sprintf("%.1fM", float64(tokens)/1000000)
+	case tokens >= 10000:
+		return fmt.Sprintf("%dk", (tokens+500)/1000)
+	case tokens >= 1000:
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
+
+// parentDirs returns every ancestor directory of a slash-separated path,
+// each with a trailing slash
+func parentDirs(filePath string) []string {
+	var dirs []string
+	dir := path.Dir(filePath)
+	for dir != "." && dir != "/" {
+		dirs = append(dirs, dir+"/")
+		dir = path.Dir(dir)
+	}
+	return dirs
+}
+
+// collectTokenGroups groups files by directory, by extension and by both
+func collectTokenGroups(files []FileInfo) map[string]*tokenGroup {
+	groups := make(map[string]*tokenGroup)
+	addTo := func(dir, ext string, file FileInfo) {
+		key := dir + "\x00" + ext
+		group, ok := groups[key]
+		if !ok {
+			group = &tokenGroup{dir: dir, ext: ext}
+			groups[key] = group
+		}
+		group.files++
+		group.tokens += file.tokens
+	}
+
+	for _, file := range files {
+		ext := strings.ToLower(path.Ext(file.path))
+		if ext != "" {
+			addTo("", ext, file)
+		}
+		for _, dir := range parentDirs(file.path) {
+			addTo(dir, "", file)
+			if ext != "" {
+				addTo(dir, ext, file)
+			}
+		}
+	}
+	return groups
+}
+
+// sortedGroups returns the groups matching keep, ordered by tokens descending
+func sortedGroups(groups map[string]*tokenGroup, keep func(*tokenGroup) bool) []*tokenGroup {
+	var result []*tokenGroup
+	for _, group := range groups {
+		if keep(group) {
+			result = append(result, group)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].tokens != result[j].tokens {
+			return result[i].tokens > result[j].tokens
+		}
+		return result[i].label() < result[j].label()
+	})
+	return result
+}
+
+// mainLanguageExts returns the source extensions of the languages that make
+// up the bundle: the one holding the most source tokens and any other holding
+// at least detectMinShare of them
+func mainLanguageExts(files []FileInfo) map[string]bool {
+	stackTokens := make([]int, len(stacks))
+	sourceTokens := 0
+	for _, file := range files {
+		ext := strings.ToLower(path.Ext(file.path))
+		for i, s := range stacks {
+			if contains(s.sources, ext) {
+				stackTokens[i] += file.tokens
+				sourceTokens += file.tokens
+				break
+			}
+		}
+	}
+
+	exts := make(map[string]bool)
+	if sourceTokens == 0 {
+		return exts
+	}
+	top := 0
+	for i, tokens := range stackTokens {
+		if tokens > stackTokens[top] {
+			top = i
+		}
+	}
+	for i, s := range stacks {
+		if i == top || float64(stackTokens[i])/float64(sourceTokens) >= detectMinShare {
+			for _, ext := range s.sources {
+				exts[ext] = true
+			}
+		}
+	}
+	return exts
+}
+
+// suggestExclusions picks the groups worth excluding, leaving out the main
+// languages and skipping groups that cover exactly the same files as a more
+// specific suggestion already made
+func suggestExclusions(groups map[string]*tokenGroup, totalTokens int, mainExts map[string]bool) []*tokenGroup {
+	candidates := sortedGroups(groups, func(g *tokenGroup) bool {
+		share := float64(g.tokens) / float64(totalTokens)
+		return share >= suggestMinShare && share < 1 && !mainExts[g.ext]
+	})
+
+	// Prefer the most specific description among groups with identical savings
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.tokens != b.tokens {
+			return a.tokens > b.tokens
+		}
+		if a.files != b.files {
+			return a.files > b.files
+		}
+		return strings.Count(a.dir, "/") > strings.Count(b.dir, "/")
+	})
+
+	var suggestions []*tokenGroup
+	seen := make(map[[2]int]bool)
+	for _, group := range candidates {
+		key := [2]int{group.files, group.tokens}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		suggestions = append(suggestions, group)
+		if len(suggestions) == suggestTopCount {
+			break
+		}
+	}
+	return suggestions
+}
+
+// writeSuggestions prints the token hotspots of the bundle and recommends
+// exclusions that would save the most tokens
+func writeSuggestions(out io.Writer, files []FileInfo) {
+	totalTokens := 0
+	for _, file := range files {
+		totalTokens += file.tokens
+	}
+	if totalTokens == 0 {
+		return
+	}
+
+	groups := collectTokenGroups(files)
+	writeGroupTable := func(title string, rows []*tokenGroup) {
+		if len(rows) == 0 {
+			return
+		}
+		fmt.Fprintf(out, "%s:\n", title)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Path\tFiles\tTokens\tShare")
+		fmt.Fprintln(w, "────\t─────\t──────\t─────")
+		for i, group := range rows {
+			if i >= suggestTopCount {
+				break
+			}
+			fmt.Fprintf(w, "%s\t%d\t~%s\t%.1f%%\n", group.label(), group.files, formatTokens(group.tokens),
+				float64(group.tokens)*100/float64(totalTokens))
+		}
+		w.Flush()
+		fmt.Fprintln(out, "")
+	}
+
+	writeGroupTable("Top token-consuming directories", sortedGroups(groups, func(g *tokenGroup) bool {
+		return g.ext == ""
+	}))
+	writeGroupTable("Top token-consuming extensions", sortedGroups(groups, func(g *tokenGroup) bool {
+		return g.dir == ""
+	}))
+
+	suggestions := suggestExclusions(groups, totalTokens, mainLanguageExts(files))
+	fmt.Fprintln(out, "Suggestions:")
+	if len(suggestions) == 0 {
+		fmt.Fprintln(out, "  No single directory or extension dominates the bundle.")
+		fmt.Fprintln(out, "")
+		return
+	}
+	for _, group := range suggestions {
+		noun := "files"
+		if group.files == 1 {
+			noun = "file"
+		}
+		fmt.Fprintf(out, "  excluding %s saves ~%s tokens (%.1f%% of bundle, %d %s)\n",
+			group.label(), formatTokens(group.tokens), float64(group.tokens)*100/float64(totalTokens), group.files, noun)
+	}
+	fmt.Fprintln(out, "")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestResult creates a result file with one section per path, each
+// holding content of the given size
+func writeTestResult(t *testing.T, sizes map[string]int) {
+	t.Helper()
+
+	var out strings.Builder
+	for path, size := range sizes {
+		out.WriteString("#FILE " + path + "\n#TYPE txt\n#START\n```txt\n")
+		out.WriteString(strings.Repeat("x", size) + "\n")
+		out.WriteString("```\n#END\n\n")
+	}
+	if err := os.WriteFile(resultName, []byte(out.String()), 0644); err != nil {
+		t.Fatalf("Failed to create test result file: %v", err)
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	tests := []struct {
+		tokens   int
+		expected string
+	}{
+		{312, "312"},
+		{2400, "2.4k"},
+		{45200, "45k"},
+		{1250000, "1.2M"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, formatTokens(tc.tokens), "formatTokens(%d) returned unexpected result", tc.tokens)
+	}
+}
+
+func TestSuggestExclusions(t *testing.T) {
+	files := []FileInfo{
+		{path: "testdata/a.json", tokens: 40000},
+		{path: "testdata/b.json", tokens: 5000},
+		{path: "src/main.go", tokens: 10000},
+		{path: "src/util.go", tokens: 2000},
+		{path: "README.md", tokens: 500},
+	}
+
+	groups := collectTokenGroups(files)
+	suggestions := suggestExclusions(groups, 57500, mainLanguageExts(files))
+	require.NotEmpty(t, suggestions)
+
+	// testdata/ and *.json under testdata/ and *.json cover the same files;
+	// only the most specific one should be reported
+	assert.Equal(t, "*.json under testdata/", suggestions[0].label())
+	assert.Equal(t, 45000, suggestions[0].tokens)
+	for _, group := range suggestions[1:] {
+		assert.NotEqual(t, 45000, group.tokens, "Duplicate suggestion for the same files: %s", group.label())
+		assert.NotEqual(t, ".go", group.ext, "The main language was suggested: %s", group.label())
+	}
+}
+
+func TestMainLanguageExts(t *testing.T) {
+	// Go holds most source tokens, PHP more than a quarter of them and
+	// Python too little to count
+	files := []FileInfo{
+		{path: "main.go", tokens: 5000},
+		{path: "web/index.php", tokens: 3000},
+		{path: "tools/gen.py", tokens: 500},
+		{path: "data.json", tokens: 90000},
+	}
+	exts := mainLanguageExts(files)
+	assert.True(t, exts[".go"])
+	assert.True(t, exts[".php"])
+	assert.False(t, exts[".py"])
+	assert.False(t, exts[".json"])
+
+	assert.Empty(t, mainLanguageExts([]FileInfo{{path: "README.md", tokens: 100}}))
+}
+
+func TestAnalyzeSuggest(t *testing.T) {
+	writeTestResult(t, map[string]int{
+		"testdata/fixture.json": 8000,
+		"src/main.go":           1000,
+		"src/util.go":           800,
+	})
+	defer os.Remove(resultName)
+
+	result, err := analyzeResultFileInternal(analyzeOptions{topCount: 5, suggest: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "Top token-consuming directories:")
+	assert.Contains(t, result, "Top token-consuming extensions:")
+	assert.Contains(t, result, "excluding *.json under testdata/ saves ~2.0k tokens (81.6% of bundle, 1 file)")
+	assert.NotContains(t, result, "excluding *.go", "The main language should not be suggested")
+
+	result, err = analyzeResultFileInternal(analyzeOptions{topCount: 5})
+	require.NoError(t, err)
+	assert.NotContains(t, result, "Suggestions:", "Suggestions should be opt-in")
+}
+
+func TestAnalyzeCSV(t *testing.T) {
+	content := "#FILE src/main.go\n#TYPE go\n#START\n```go\npackage main\n```\n#END\n\n" +
+		"#FILE docs/a,b.md\n#TYPE md\n#START\n```markdown\n# Title\n```\n#END\n\n"
+	require.NoError(t, os.WriteFile(resultName, []byte(content), 0644))
+	defer os.Remove(resultName)
+
+	result, err := analyzeResultFileInternal(analyzeOptions{topCount: 20, format: "csv"})
+	require.NoError(t, err)
+	assert.Equal(t, "path,size,symbols,tokens,language\n"+
+		"src/main.go,13,11,4,go\n"+
+		"\"docs/a,b.md\",8,6,2,markdown\n", result)
+
+	_, err = analyzeResultFileInternal(analyzeOptions{topCount: 20, format: "csv", loc: true, suggest: true})
+	assert.EqualError(t, err, "-format csv can't be combined with -loc, -suggest")
+}
+
+func TestAnalyzeSort(t *testing.T) {
+	// Bytes, symbols, tokens and code lines rank the files differently
+	content := testBundle("spaces.txt", strings.Repeat("a                   \n", 20)) +
+		testBundle("symbols.js", strings.Repeat("{}", 120)) +
+		testBundle("lines.go", strings.Repeat("x\n", 60))
+	require.NoError(t, os.WriteFile(resultName, []byte(content), 0644))
+	defer os.Remove(resultName)
+
+	top := func(by string) string {
+		t.Helper()
+		result, err := analyzeResultFileInternal(analyzeOptions{topCount: 1, sort: by})
+		require.NoError(t, err)
+		return result
+	}
+	assert.Regexp(t, `Top 1 largest files:\nFile +Size \(KB\) +Symbols\n.*\nspaces\.txt `, top(""))
+	assert.Regexp(t, `Top 1 files by symbols:\n.*\n.*\nsymbols\.js `, top("symbols"))
+	assert.Regexp(t, `Top 1 files by tokens:\nFile +Size \(KB\) +Symbols +Tokens\n.*\nspaces\.txt +0\.41 +20 +~106\n`, top("tokens"))
+	assert.Regexp(t, `Top 1 files by lines of code:\nFile +Size \(KB\) +Symbols +Code lines\n.*\nlines\.go +0\.12 +60 +60\n`, top("loc"))
+
+	result, err := analyzeResultFileInternal(analyzeOptions{topCount: 1, sort: "symbols", format: "csv"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result, "path,size,symbols,tokens,language\nsymbols.js,"), "-sort orders the CSV rows")
+
+	_, err = analyzeResultFileInternal(analyzeOptions{topCount: 1, sort: "name"})
+	assert.EqualError(t, err, `unknown sort "name" (use size, symbols, tokens, loc)`)
+}
+
+func TestAnalyzeCommandFormat(t *testing.T) {
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"analyze", "-format", "xml"}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Unknown format \"xml\" (use text or csv)\n", output)
+
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"analyze", "-format", "csv", "-list"}))
+	output = CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: -format csv can't be combined with -list\n", output)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+
+	"skukozh/bundle"
+)
+
+// fileAnnotation renders the size annotation of a file section
+func fileAnnotation(lines, tokens int) string {
+	unit := "lines"
+	if lines == 1 {
+		unit = "line"
+	}
+	return fmt.Sprintf(" (%d %s, ~%s tokens)", lines, unit, formatTokens(tokens))
+}
+
+// parseFileHeader returns the path of a #FILE line's value, dropping the
+// size annotation if present
+func parseFileHeader(value string) string {
+	return bundle.HeaderPath(value)
+}
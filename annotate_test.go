@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileAnnotation(t *testing.T) {
+	assert.Equal(t, " (312 lines, ~2.4k tokens)", fileAnnotation(312, 2400))
+	assert.Equal(t, " (1 line, ~3 tokens)", fileAnnotation(1, 3))
+
+	tests := []struct {
+		value string
+		path  string
+	}{
+		{"main.go", "main.go"},
+		{"main.go (312 lines, ~2.4k tokens)\n", "main.go"},
+		{"docs/big file.md (1 line, ~15k tokens)", "docs/big file.md"},
+		{"notes (draft).md", "notes (draft).md"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.path, parseFileHeader(tc.value), tc.value)
+	}
+}
+
+func TestGenerateAnnotated(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	require.NoError(t, os.WriteFile(fileListName, []byte("file1.go\nfile5.txt"), 0644))
+	defer os.Remove(fileListName)
+
+	result, err := generateContentFileInternal(testDir, genOptions{annotate: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#FILE file1.go (3 lines, ~7 tokens)\n")
+	assert.Contains(t, result, "#FILE file5.txt (2 lines, ~9 tokens)\n")
+
+	// Annotated bundles read back with plain paths
+	var paths []string
+	for _, section := range parseBundleSections(result) {
+		paths = append(paths, section.path)
+	}
+	assert.Equal(t, []string{"file1.go", "file5.txt"}, paths)
+
+	_, err = generateContentFileInternal(testDir, genOptions{annotate: true, format: "jsonl"})
+	assert.EqualError(t, err, "-format jsonl can't be combined with -annotate")
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"skukozh/bundle"
+)
+
+// Name of the API reference written by the docs command next to the result
+const apiDocsName = "skukozh_docs.txt"
+
+// docstringStart matches the opening quotes of a Python docstring, with an
+// optional string prefix such as r or u
+var docstringStart = regexp.MustCompile(`^[rRuU]?("""|''')`)
+
+// apiDocs reduces a source file to its doc comments and signatures. Go
+// files keep the package doc and the exported declarations without function
+// bodies; other languages with -outline support keep the comments above
+// each declaration and, in Python, the docstrings below it. The second
+// return value is false for files without docs support.
+func apiDocs(file string, content []byte) ([]byte, bool) {
+	language := fenceLanguage(file, content)
+	if language == "go" {
+		if isGoTestFile(file) {
+			return nil, false
+		}
+		return apiDocsGo(file, content)
+	}
+
+	patterns, ok := outlinePatterns[language]
+	if !ok {
+		return nil, false
+	}
+
+	lines := strings.Split(string(content), "\n")
+	var blocks []string
+	if language == "python" {
+		if start, ok := firstCodeLine(lines); ok && docstringStart.MatchString(strings.TrimSpace(lines[start])) {
+			blocks = append(blocks, strings.Join(lines[start:docstringEnd(lines, start)+1], "\n"))
+		}
+	}
+	for i, line := range lines {
+		if !matchesAny(patterns, line) {
+			continue
+		}
+		block := append([]string{}, lines[docStart(lines, i):i]...)
+		signature := strings.TrimRight(line, " \t\r")
+		if strings.HasSuffix(signature, "{") {
+			signature += " ... }"
+		}
+		block = append(block, signature)
+		if language == "python" {
+			if start, ok := firstCodeLine(lines[i+1:]); ok && docstringStart.MatchString(strings.TrimSpace(lines[i+1+start])) {
+				start += i + 1
+				block = append(block, lines[start:docstringEnd(lines, start)+1]...)
+			}
+		}
+		blocks = append(blocks, strings.Join(block, "\n"))
+	}
+	if len(blocks) == 0 {
+		return nil, true
+	}
+	return []byte(strings.Join(blocks, "\n\n") + "\n"), true
+}
+
+// matchesAny reports whether line matches one of patterns
+func matchesAny(patterns []*regexp.Regexp, line string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstCodeLine returns the index of the first line that isn't blank or a
+// comment
+func firstCodeLine(lines []string) (int, bool) {
+	for i, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// docstringEnd returns the index of the line closing the docstring opened
+// at line start, or the last line when it's never closed
+func docstringEnd(lines []string, start int) int {
+	trimmed := strings.TrimSpace(lines[start])
+	quotes := docstringStart.FindStringSubmatch(trimmed)[1]
+	if strings.Contains(trimmed[strings.Index(trimmed, quotes)+3:], quotes) {
+		return start
+	}
+	for i := start + 1; i < len(lines); i++ {
+		if strings.Contains(lines[i], quotes) {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
+
+// apiDocsGo keeps the package clause and its doc, and the exported
+// declarations with their doc comments, dropping imports and function bodies
+func apiDocsGo(file string, content []byte) ([]byte, bool) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, content, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+	comments := ast.NewCommentMap(fset, parsed, parsed.Comments)
+
+	var decls []ast.Decl
+	for _, decl := range parsed.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !isExportedFunc(d) {
+				continue
+			}
+			d.Body = nil
+		case *ast.GenDecl:
+			switch d.Tok {
+			case token.IMPORT:
+				continue
+			case token.TYPE:
+				if !keepExportedTypes(d) {
+					continue
+				}
+			case token.CONST, token.VAR:
+				if !keepExportedSpecs(d) {
+					continue
+				}
+			}
+		}
+		decls = append(decls, decl)
+	}
+	parsed.Decls = decls
+
+	// Drop the comments that belonged to removed declarations and bodies
+	parsed.Comments = comments.Filter(parsed).Comments()
+
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, parsed); err != nil {
+		return nil, false
+	}
+	return out.Bytes(), true
+}
+
+// generateAPIDocsInternal builds the API reference of the listed files,
+// one section per file with docs support and declarations, and returns it
+// with the number of files it covers
+func generateAPIDocsInternal(baseDir string) (string, int, error) {
+	listed, err := readFileList(fileListName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var out strings.Builder
+	count := 0
+	for _, entry := range listed {
+		file := entryPath(entry.Path)
+		content, err := os.ReadFile(filepath.Join(baseDir, file))
+		if err != nil {
+			return "", 0, err
+		}
+		docs, ok := apiDocs(file, content)
+		if !ok || len(docs) == 0 {
+			continue
+		}
+		language := fenceLanguage(file, content)
+		fileType := strings.TrimPrefix(filepath.Ext(file), ".")
+		if fileType == "" {
+			fileType = language
+		}
+		err = bundle.WriteSection(&out, bundle.Section{
+			Path:     file,
+			Type:     fileType,
+			Language: language,
+			Content:  strings.TrimRight(string(docs), "\n"),
+		})
+		if err != nil {
+			return "", 0, err
+		}
+		count++
+	}
+	return out.String(), count, nil
+}
+
+// generateAPIDocs writes the API reference of the listed files next to the
+// result and returns the exit code
+func generateAPIDocs(baseDir string) int {
+	docs, count, err := generateAPIDocsInternal(baseDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	outputName := besideResult(apiDocsName)
+	if err := writeFileAtomic(outputName, []byte(docs), 0644); err != nil {
+		fmt.Printf("Error writing API reference: %v\n", err)
+		return 1
+	}
+	statusf("API reference of %d files saved to %s\n", count, outputName)
+	return 0
+}
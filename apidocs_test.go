@@ -0,0 +1,152 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIDocsGo(t *testing.T) {
+	source := `// Package shop sells things.
+package shop
+
+import "fmt"
+
+// Cart holds the items of an order.
+type Cart struct {
+	// Items are the things in the cart
+	Items []string
+}
+
+type cache struct{}
+
+// Add puts an item in the cart.
+func (c *Cart) Add(item string) {
+	// Log it
+	fmt.Println(item)
+	c.Items = append(c.Items, item)
+}
+
+func (c *cache) Get() {}
+
+func helper() {}
+
+// Max is the most items a cart holds.
+const Max = 10
+`
+	docs, ok := apiDocs("shop.go", []byte(source))
+	require.True(t, ok)
+	assert.Equal(t, `// Package shop sells things.
+package shop
+
+// Cart holds the items of an order.
+type Cart struct {
+	// Items are the things in the cart
+	Items []string
+}
+
+// Add puts an item in the cart.
+func (c *Cart) Add(item string)
+
+// Max is the most items a cart holds.
+const Max = 10
+`, string(docs))
+
+	_, ok = apiDocs("shop_test.go", []byte(source))
+	assert.False(t, ok, "test files aren't API")
+	_, ok = apiDocs("broken.go", []byte("package shop\nfunc {"))
+	assert.False(t, ok)
+}
+
+func TestAPIDocsPatterns(t *testing.T) {
+	python := `"""Billing helpers."""
+import os
+
+
+# Charges a card
+def charge(card, amount):
+    """Charge the card.
+
+    Returns the receipt.
+    """
+    return os.getenv("GATEWAY")
+
+
+class Invoice:
+    '''An invoice.'''
+
+    def total(self):
+        return sum(self.lines)
+`
+	docs, ok := apiDocs("billing.py", []byte(python))
+	require.True(t, ok)
+	assert.Equal(t, `"""Billing helpers."""
+
+# Charges a card
+def charge(card, amount):
+    """Charge the card.
+
+    Returns the receipt.
+    """
+
+class Invoice:
+    '''An invoice.'''
+
+    def total(self):
+`, string(docs))
+
+	typescript := `import { round } from "./math";
+
+/**
+ * Formats a price.
+ * @param cents amount in cents
+ */
+export function format(cents: number): string {
+  return round(cents / 100);
+}
+
+const rate = 1.2;
+`
+	docs, ok = apiDocs("price.ts", []byte(typescript))
+	require.True(t, ok)
+	assert.Equal(t, `/**
+ * Formats a price.
+ * @param cents amount in cents
+ */
+export function format(cents: number): string { ... }
+`, string(docs))
+
+	docs, ok = apiDocs("util.py", []byte("X = 1\n"))
+	assert.True(t, ok)
+	assert.Empty(t, docs)
+
+	_, ok = apiDocs("README.md", []byte("# Shop\n"))
+	assert.False(t, ok)
+}
+
+func TestDocsCommand(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"shop.go":               "package shop\n\n// Open starts the shop.\nfunc Open() error {\n\treturn nil\n}\n",
+		"shop_test.go":          "package shop\n\nfunc TestOpen(t *testing.T) {}\n",
+		"README.md":             "# Shop\n",
+		"skukozh_file_list.txt": "shop.go\nshop_test.go\nREADME.md\n",
+	})
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "docs", testDir)
+	})
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "API reference of 1 files saved to skukozh_docs.txt")
+	assert.Equal(t, "#FILE shop.go\n#TYPE go\n#START\n```go\npackage shop\n\n// Open starts the shop.\nfunc Open() error\n```\n#END\n\n",
+		ReadTestFile(t, filepath.Join(testDir, apiDocsName)))
+
+	output = CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, t.TempDir(), "docs", testDir)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, "Error:")
+}
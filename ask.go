@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"skukozh/bundle"
+)
+
+// Environment variable with an API key used instead of the provider's own
+const apiKeyEnv = "SKUKOZH_API_KEY"
+
+// A model taking longer than this to answer fails ask
+const askTimeout = 10 * time.Minute
+
+// Estimated tokens kept free for the request wrapping and estimation errors
+const askTokenMargin = 256
+
+// askOptions holds the settings of the ask command
+type askOptions struct {
+	provider      string // provider name, see llmProviders
+	model         string // model name, the provider's default when empty
+	baseURL       string // API base URL, the provider's default when empty
+	maxTokens     int    // tokens reserved for the answer
+	contextTokens int    // context size of the model in tokens, 0 for the model's own
+	prompt        string // prompt template of the library the question is rendered with
+}
+
+// askOptionsFromFlags builds ask options from the provided FlagSet
+func askOptionsFromFlags(fs *flag.FlagSet) askOptions {
+	maxTokens, _ := strconv.Atoi(fs.Lookup("max-tokens").Value.String())
+	contextTokens, _ := strconv.Atoi(fs.Lookup("context-tokens").Value.String())
+	return askOptions{
+		provider:      fs.Lookup("provider").Value.String(),
+		model:         fs.Lookup("model").Value.String(),
+		baseURL:       strings.TrimRight(fs.Lookup("base-url").Value.String(), "/"),
+		maxTokens:     maxTokens,
+		contextTokens: contextTokens,
+		prompt:        fs.Lookup("prompt").Value.String(),
+	}
+}
+
+// llmProvider describes a chat API the bundle can be sent to
+type llmProvider struct {
+	baseURL       string // default API base URL
+	keyEnv        string // environment variable holding the API key, empty when none is needed
+	model         string // default model, empty when -model is required
+	contextTokens int    // context size assumed without -context-tokens
+	// contextLength, when set, asks the server for the context size of the model instead
+	contextLength func(client *http.Client, opts askOptions) (int, error)
+	// send posts the prompt and returns the text of the answer
+	send func(client *http.Client, opts askOptions, key, prompt string) (string, error)
+}
+
+// Providers accepted by -provider. openai also covers the many servers
+// speaking the same API, selected with -base-url.
+var llmProviders = map[string]llmProvider{
+	"openai": {
+		baseURL:       "https://api.openai.com/v1",
+		keyEnv:        "OPENAI_API_KEY",
+		model:         "gpt-4o",
+		contextTokens: 128000,
+		send:          sendOpenAI,
+	},
+	"anthropic": {
+		baseURL:       "https://api.anthropic.com",
+		keyEnv:        "ANTHROPIC_API_KEY",
+		model:         "claude-sonnet-4-0",
+		contextTokens: 200000,
+		send:          sendAnthropic,
+	},
+	"ollama": {
+		baseURL:       "http://localhost:11434",
+		contextLength: ollamaContextLength,
+		send:          sendOllama,
+	},
+}
+
+// providerNames lists the providers accepted by -provider
+func providerNames() string {
+	var names []string
+	for name := range llmProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// validate checks the options and fills in the provider's defaults
+func (o *askOptions) validate() (llmProvider, error) {
+	provider, ok := llmProviders[o.provider]
+	if !ok {
+		return provider, fmt.Errorf("unknown provider %q (use %s)", o.provider, providerNames())
+	}
+	if o.model == "" {
+		o.model = provider.model
+	}
+	if o.model == "" {
+		return provider, fmt.Errorf("-model is required with -provider %s", o.provider)
+	}
+	if o.baseURL == "" {
+		o.baseURL = provider.baseURL
+	}
+	if o.maxTokens <= 0 {
+		return provider, fmt.Errorf("-max-tokens must be positive")
+	}
+	if o.contextTokens < 0 || o.contextTokens > 0 && o.contextTokens <= o.maxTokens {
+		return provider, fmt.Errorf("-context-tokens must be larger than -max-tokens")
+	}
+	return provider, nil
+}
+
+// resolveContext sets the context size of the model when -context-tokens
+// isn't given, asking the server where the provider supports it
+func (o *askOptions) resolveContext(client *http.Client, provider llmProvider) error {
+	if o.contextTokens > 0 {
+		return nil
+	}
+	if provider.contextLength == nil {
+		o.contextTokens = provider.contextTokens
+		return nil
+	}
+	tokens, err := provider.contextLength(client, *o)
+	if err != nil {
+		return fmt.Errorf("looking up the context size of %s: %w (set it with -context-tokens)", o.model, err)
+	}
+	if tokens <= o.maxTokens {
+		return fmt.Errorf("%s has a context of %d tokens, not more than -max-tokens", o.model, tokens)
+	}
+	o.contextTokens = tokens
+	return nil
+}
+
+// apiKey returns the key from SKUKOZH_API_KEY or the provider's variable.
+// Only the provider's own endpoint insists on one; servers behind -base-url
+// often run without.
+func apiKey(provider llmProvider, opts askOptions) (string, error) {
+	if key := os.Getenv(apiKeyEnv); key != "" {
+		return key, nil
+	}
+	if provider.keyEnv == "" {
+		return "", nil
+	}
+	if key := os.Getenv(provider.keyEnv); key != "" {
+		return key, nil
+	}
+	if opts.baseURL == provider.baseURL {
+		return "", fmt.Errorf("no API key: set %s or %s", provider.keyEnv, apiKeyEnv)
+	}
+	return "", nil
+}
+
+// trimBundle drops the largest file sections of a bundle until its token
+// estimate is within budget, keeping the text before the first and after
+// the last section. The head of a trimmed bundle is rewritten by
+// trimmedHead, so it no longer lists the dropped files. It returns the
+// trimmed bundle and the dropped paths, largest first.
+func trimBundle(content string, budget int) (string, []string) {
+	if estimateTokens(content) <= budget {
+		return content, nil
+	}
+	parsed := bundle.Parse(content)
+	if len(parsed) == 0 {
+		return content, nil
+	}
+
+	type section struct {
+		text, path string
+		tokens     int
+	}
+	head := content[:parsed[0].Offset]
+	tail := content[parsed[len(parsed)-1].End:]
+	var sections []section
+	for i, s := range parsed {
+		// A section runs to the next one, taking the blank line after #END
+		end := s.End
+		if i+1 < len(parsed) {
+			end = parsed[i+1].Offset
+		}
+		text := content[s.Offset:end]
+		sections = append(sections, section{text: text, path: s.Path, tokens: estimateTokens(text)})
+	}
+
+	// Drop the largest sections first, so as many files as possible stay
+	order := make([]int, len(sections))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sections[order[a]].tokens > sections[order[b]].tokens
+	})
+	body := estimateTokens(content) - estimateTokens(head)
+	dropped := make(map[int]bool)
+	var paths []string
+	for _, i := range order {
+		if body+estimateTokens(trimmedHead(head, len(sections)-len(paths), paths)) <= budget {
+			break
+		}
+		dropped[i] = true
+		paths = append(paths, sections[i].path)
+		body -= sections[i].tokens
+	}
+
+	var trimmed strings.Builder
+	trimmed.WriteString(trimmedHead(head, len(sections)-len(paths), paths))
+	for i, s := range sections {
+		if !dropped[i] {
+			trimmed.WriteString(s.text)
+		}
+	}
+	trimmed.WriteString(tail)
+	return trimmed.String(), paths
+}
+
+// askPrompt builds the message sent to the model: the bundle, trimmed to
+// the context left after the question and the answer, then the question
+func askPrompt(bundle, question string, opts askOptions) (string, error) {
+	budget := opts.contextTokens - opts.maxTokens - estimateTokens(question) - askTokenMargin
+	trimmed, dropped := trimBundle(bundle, budget)
+	if estimateTokens(trimmed) > budget {
+		return "", fmt.Errorf("the bundle doesn't fit a %d token context even without its files; raise -context-tokens or lower -max-tokens", opts.contextTokens)
+	}
+	if len(dropped) > 0 {
+		statusf("Left out %d files to fit ~%s tokens: %s\n", len(dropped), formatTokens(budget), strings.Join(dropped, ", "))
+	}
+	return strings.TrimRight(trimmed, "\n") + "\n\n" + question, nil
+}
+
+// postJSON sends a JSON request and decodes the JSON response into out.
+// Error responses are reported with the message of their error object, or
+// the error string Ollama sends.
+func postJSON(client *http.Client, url string, headers map[string]string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != nil {
+			var message string
+			var object struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(apiErr.Error, &object) == nil && object.Message != "" {
+				return fmt.Errorf("%s: %s", resp.Status, object.Message)
+			}
+			if json.Unmarshal(apiErr.Error, &message) == nil && message != "" {
+				return fmt.Errorf("%s: %s", resp.Status, message)
+			}
+		}
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", url, err)
+	}
+	return nil
+}
+
+// sendOpenAI sends the prompt to an OpenAI-compatible chat completions API
+func sendOpenAI(client *http.Client, opts askOptions, key, prompt string) (string, error) {
+	headers := map[string]string{}
+	if key != "" {
+		headers["Authorization"] = "Bearer " + key
+	}
+	body := map[string]any{
+		"model":      opts.model,
+		"max_tokens": opts.maxTokens,
+		"messages":   []map[string]string{{"role": "user", "content": prompt}},
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := postJSON(client, opts.baseURL+"/chat/completions", headers, body, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("the response has no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// sendAnthropic sends the prompt to the Anthropic Messages API
+func sendAnthropic(client *http.Client, opts askOptions, key, prompt string) (string, error) {
+	headers := map[string]string{"anthropic-version": "2023-06-01"}
+	if key != "" {
+		headers["x-api-key"] = key
+	}
+	body := map[string]any{
+		"model":      opts.model,
+		"max_tokens": opts.maxTokens,
+		"messages":   []map[string]string{{"role": "user", "content": prompt}},
+	}
+	var resp struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := postJSON(client, opts.baseURL+"/v1/messages", headers, body, &resp); err != nil {
+		return "", err
+	}
+	var answer strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			answer.WriteString(block.Text)
+		}
+	}
+	return answer.String(), nil
+}
+
+// sendOllama sends the prompt to the chat API of an Ollama server. Ollama
+// loads models with a small context unless told otherwise, so the request
+// asks for the context the bundle was trimmed to.
+func sendOllama(client *http.Client, opts askOptions, key, prompt string) (string, error) {
+	headers := map[string]string{}
+	if key != "" {
+		headers["Authorization"] = "Bearer " + key
+	}
+	body := map[string]any{
+		"model":    opts.model,
+		"stream":   false,
+		"messages": []map[string]string{{"role": "user", "content": prompt}},
+		"options":  map[string]int{"num_ctx": opts.contextTokens, "num_predict": opts.maxTokens},
+	}
+	var resp struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}
+	if err := postJSON(client, opts.baseURL+"/api/chat", headers, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
+
+// ollamaContextLength reads the context length of a model from the
+// <architecture>.context_length entry of its model info
+func ollamaContextLength(client *http.Client, opts askOptions) (int, error) {
+	var resp struct {
+		ModelInfo map[string]any `json:"model_info"`
+	}
+	if err := postJSON(client, opts.baseURL+"/api/show", nil, map[string]string{"model": opts.model}, &resp); err != nil {
+		return 0, err
+	}
+	for key, value := range resp.ModelInfo {
+		if length, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") && length > 0 {
+			return int(length), nil
+		}
+	}
+	return 0, fmt.Errorf("the model info has no context length")
+}
+
+// askBundle sends the result file and a question, rendered with the
+// -prompt template if one is given, to a model, prints the answer and
+// returns the exit code
+func askBundle(question string, opts askOptions) int {
+	provider, err := opts.validate()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if opts.prompt != "" {
+		if question, err = renderPrompt(opts.prompt, question); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+	key, err := apiKey(provider, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	bundle, err := readResultFile()
+	if err != nil {
+		fmt.Printf("Error reading result file: %v\n", err)
+		return 1
+	}
+
+	client := &http.Client{Timeout: askTimeout}
+	if err := opts.resolveContext(client, provider); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	prompt, err := askPrompt(string(bundle), question, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	answer, err := provider.send(client, opts, key, prompt)
+	if err != nil {
+		fmt.Printf("Error: %s: %v\n", opts.model, err)
+		return 1
+	}
+	fmt.Println(strings.TrimRight(answer, "\n"))
+	return 0
+}
+
+// trimmedHead rewrites the text before the first section of a bundle that
+// kept only kept of its files: the #SKUKOZH header counts the kept files,
+// the table of contents, whose offsets no longer hold, is left out, and a
+// #TRIMMED note names the dropped paths
+func trimmedHead(head string, kept int, dropped []string) string {
+	if len(dropped) == 0 {
+		return head
+	}
+	if fields := parseMetaHeader(head); fields != nil {
+		for i := range fields {
+			if fields[i].key == "files" {
+				fields[i].value = strconv.Itoa(kept)
+			}
+		}
+		_, rest, _ := strings.Cut(head, "\n")
+		head = renderMetaHeader(fields) + rest
+	}
+	if start := strings.Index(head, "#TOC\n"); start >= 0 && (start == 0 || head[start-1] == '\n') {
+		if end := strings.Index(head[start:], "#END TOC\n"); end >= 0 {
+			end += start + len("#END TOC\n")
+			if strings.HasPrefix(head[end:], "\n") {
+				end++
+			}
+			head = head[:start] + head[end:]
+		}
+	}
+
+	var note strings.Builder
+	note.WriteString("#TRIMMED\n")
+	fmt.Fprintf(&note, "Left out %d files to fit the context:\n", len(dropped))
+	for _, path := range dropped {
+		note.WriteString(path + "\n")
+	}
+	note.WriteString("#END TRIMMED\n\n")
+	return head + note.String()
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBundle renders a bundle with one section per path and content
+func testBundle(files ...string) string {
+	var b strings.Builder
+	b.WriteString("Review this code.\n\n")
+	for i := 0; i < len(files); i += 2 {
+		b.WriteString("#FILE " + files[i] + "\n#TYPE go\n#START\n```go\n" + files[i+1] + "\n```\n#END\n")
+	}
+	return b.String()
+}
+
+func TestTrimBundle(t *testing.T) {
+	bundle := testBundle("small.go", "package a", "large.go", strings.Repeat("x", 4000), "medium.go", strings.Repeat("y", 400)) + "Answer briefly.\n"
+
+	trimmed, dropped := trimBundle(bundle, estimateTokens(bundle))
+	assert.Equal(t, bundle, trimmed)
+	assert.Empty(t, dropped)
+
+	trimmed, dropped = trimBundle(bundle, 200)
+	assert.Equal(t, []string{"large.go"}, dropped)
+	assert.NotContains(t, trimmed, "#FILE large.go")
+	assert.Contains(t, trimmed, "#FILE medium.go")
+	assert.True(t, strings.HasPrefix(trimmed, "Review this code.\n\n#TRIMMED\nLeft out 1 files to fit the context:\nlarge.go\n#END TRIMMED\n\n#FILE small.go"))
+	assert.True(t, strings.HasSuffix(trimmed, "#END\nAnswer briefly.\n"), "the closing instruction is kept")
+
+	trimmed, dropped = trimBundle(bundle, 50)
+	assert.Equal(t, []string{"large.go", "medium.go"}, dropped)
+	assert.LessOrEqual(t, estimateTokens(trimmed), 50)
+}
+
+func TestTrimBundleRewritesHead(t *testing.T) {
+	body := testBundle("small.go", "package a", "large.go", strings.Repeat("x", 4000))
+	entries := []tocEntry{{path: "small.go"}, {path: "large.go", offset: strings.Index(body, "#FILE large.go")}}
+	bundle := addTOC("#SKUKOZH version=dev root=app files=2\n"+body, len("#SKUKOZH version=dev root=app files=2\n"), entries)
+
+	trimmed, dropped := trimBundle(bundle, 200)
+	assert.Equal(t, []string{"large.go"}, dropped)
+	assert.Equal(t, []metaField{{"version", "dev"}, {"root", "app"}, {"files", "1"}}, parseMetaHeader(trimmed))
+	assert.NotContains(t, trimmed, "#TOC")
+	assert.Contains(t, trimmed, "files=1\nReview this code.\n\n#TRIMMED\nLeft out 1 files to fit the context:\nlarge.go\n#END TRIMMED\n\n#FILE small.go")
+	assert.LessOrEqual(t, estimateTokens(trimmed), 200)
+}
+
+func TestAskPromptTooSmallContext(t *testing.T) {
+	opts := askOptions{maxTokens: 100, contextTokens: 300}
+	_, err := askPrompt(strings.Repeat("preamble ", 200)+testBundle("a.go", "package a"), "Why?", opts)
+	assert.EqualError(t, err, "the bundle doesn't fit a 300 token context even without its files; raise -context-tokens or lower -max-tokens")
+}
+
+func TestAskOptionsValidate(t *testing.T) {
+	opts := askOptions{provider: "anthropic", maxTokens: 4096, contextTokens: 200000}
+	provider, err := opts.validate()
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet-4-0", opts.model)
+	assert.Equal(t, "https://api.anthropic.com", opts.baseURL)
+	assert.Equal(t, "ANTHROPIC_API_KEY", provider.keyEnv)
+
+	opts = askOptions{provider: "gemini", maxTokens: 1, contextTokens: 2}
+	_, err = opts.validate()
+	assert.EqualError(t, err, `unknown provider "gemini" (use anthropic, ollama, openai)`)
+
+	opts = askOptions{provider: "openai", maxTokens: 8000, contextTokens: 8000}
+	_, err = opts.validate()
+	assert.EqualError(t, err, "-context-tokens must be larger than -max-tokens")
+
+	opts = askOptions{provider: "ollama", maxTokens: 4096}
+	_, err = opts.validate()
+	assert.EqualError(t, err, "-model is required with -provider ollama")
+}
+
+func TestResolveContext(t *testing.T) {
+	opts := askOptions{provider: "anthropic", maxTokens: 4096}
+	provider, err := opts.validate()
+	require.NoError(t, err)
+	require.NoError(t, opts.resolveContext(nil, provider))
+	assert.Equal(t, 200000, opts.contextTokens)
+
+	// An explicit -context-tokens wins
+	opts = askOptions{provider: "openai", maxTokens: 4096, contextTokens: 32768}
+	provider, err = opts.validate()
+	require.NoError(t, err)
+	require.NoError(t, opts.resolveContext(nil, provider))
+	assert.Equal(t, 32768, opts.contextTokens)
+}
+
+func TestAPIKey(t *testing.T) {
+	t.Setenv(apiKeyEnv, "")
+	t.Setenv("OPENAI_API_KEY", "")
+	provider := llmProviders["openai"]
+
+	_, err := apiKey(provider, askOptions{baseURL: provider.baseURL})
+	assert.EqualError(t, err, "no API key: set OPENAI_API_KEY or SKUKOZH_API_KEY")
+
+	// Local servers usually need no key
+	key, err := apiKey(provider, askOptions{baseURL: "http://localhost:8000/v1"})
+	require.NoError(t, err)
+	assert.Empty(t, key)
+
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	key, _ = apiKey(provider, askOptions{baseURL: provider.baseURL})
+	assert.Equal(t, "sk-openai", key)
+	t.Setenv(apiKeyEnv, "sk-override")
+	key, _ = apiKey(provider, askOptions{baseURL: provider.baseURL})
+	assert.Equal(t, "sk-override", key)
+}
+
+func TestAskOpenAICompatible(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, resultName), []byte(testBundle("main.go", "package main")), 0644))
+	t.Setenv(apiKeyEnv, "sk-test")
+
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "It prints nothing.\n"}}]}`))
+	}))
+	defer server.Close()
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "ask", "-base-url", server.URL + "/v1/", "-model", "qwen2.5-coder", "What", "does", "it", "print?"}))
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Equal(t, "It prints nothing.\n", output)
+
+	assert.Equal(t, "qwen2.5-coder", request["model"])
+	assert.Equal(t, float64(4096), request["max_tokens"])
+	messages := request["messages"].([]any)
+	require.Len(t, messages, 1)
+	content := messages[0].(map[string]any)["content"].(string)
+	assert.True(t, strings.HasPrefix(content, "Review this code.\n\n#FILE main.go\n"))
+	assert.True(t, strings.HasSuffix(content, "#END\n\nWhat does it print?"), "the question follows the bundle")
+}
+
+func TestAskAnthropic(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, resultName), []byte(testBundle("main.go", "package main")), 0644))
+	t.Setenv(apiKeyEnv, "")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "sk-ant", r.Header.Get("x-api-key"))
+		assert.Equal(t, "2023-06-01", r.Header.Get("anthropic-version"))
+		w.Write([]byte(`{"content": [{"type": "text", "text": "Package main "}, {"type": "text", "text": "is empty."}]}`))
+	}))
+	defer server.Close()
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "ask", "-provider", "anthropic", "-base-url", server.URL, "Summarize")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Equal(t, "Package main is empty.\n", output)
+}
+
+func TestAskAPIError(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, resultName), []byte(testBundle("main.go", "package main")), 0644))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"type": "invalid_request_error", "message": "Incorrect API key provided"}}`))
+	}))
+	defer server.Close()
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "ask", "-base-url", server.URL, "Why?")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: gpt-4o: 401 Unauthorized: Incorrect API key provided\n", output)
+}
+
+func TestAskOllama(t *testing.T) {
+	testDir := t.TempDir()
+	bundle := testBundle("main.go", "package main", "big.go", strings.Repeat("z", 40000))
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, resultName), []byte(bundle), 0644))
+	t.Setenv(apiKeyEnv, "")
+
+	var chat map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/show":
+			var show map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&show))
+			if show["model"] != "llama3.1" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"model 'missing' not found"}`))
+				return
+			}
+			w.Write([]byte(`{"model_info": {"general.architecture": "llama", "llama.context_length": 8192}}`))
+		case "/api/chat":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&chat))
+			w.Write([]byte(`{"model": "llama3.1", "message": {"role": "assistant", "content": "Local answer."}, "done": true}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "ask", "-provider", "ollama", "-base-url", server.URL, "-model", "llama3.1", "-max-tokens", "1024", "Explain")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Equal(t, "Left out 1 files to fit ~6.9k tokens: big.go\nLocal answer.\n", output)
+
+	// The context read from the model info sizes both the bundle and the request
+	assert.Equal(t, false, chat["stream"])
+	assert.Equal(t, map[string]any{"num_ctx": float64(8192), "num_predict": float64(1024)}, chat["options"])
+	content := chat["messages"].([]any)[0].(map[string]any)["content"].(string)
+	assert.NotContains(t, content, "#FILE big.go")
+
+	output = CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "ask", "-provider", "ollama", "-base-url", server.URL, "-model", "missing", "Explain")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: looking up the context size of missing: 404 Not Found: model 'missing' not found (set it with -context-tokens)\n", output)
+}
+
+func TestAskWithoutResultFile(t *testing.T) {
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, t.TempDir(), "ask", "-base-url", "http://localhost:1", "Why?")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, "Error reading result file:")
+}
+
+// runCommandIn runs a command with -chdir set to dir and returns its exit code
+func runCommandIn(t *testing.T, dir string, args ...string) int {
+	t.Helper()
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse(append([]string{"-chdir", dir}, args...)))
+	return runWithFlags(flagSet)
+}
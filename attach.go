@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"skukozh/bundle"
+)
+
+// Timeout of fetching an -attach URL
+const attachTimeout = 30 * time.Second
+
+// Environment variable holding a bearer token sent when fetching -attach
+// URLs, e.g. for a private issue tracker
+const attachTokenEnv = "SKUKOZH_ATTACH_TOKEN"
+
+// attachment is a context document appended to the bundle with -attach
+type attachment struct {
+	source  string // path or URL as given
+	content []byte
+}
+
+// isAttachURL reports whether an -attach source is fetched over HTTP
+func isAttachURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// loadAttachments reads the -attach documents in order: files relative to
+// the current directory, and http(s) URLs
+func loadAttachments(sources []string) ([]attachment, error) {
+	var attachments []attachment
+	for _, source := range sources {
+		var content []byte
+		var err error
+		if isAttachURL(source) {
+			content, err = fetchAttachment(source)
+		} else {
+			content, err = os.ReadFile(source)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't attach %s: %w", source, err)
+		}
+		attachments = append(attachments, attachment{source: source, content: content})
+	}
+	return attachments, nil
+}
+
+// fetchAttachment downloads an -attach URL, sending the token of
+// SKUKOZH_ATTACH_TOKEN if set
+func fetchAttachment(source string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv(attachTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: attachTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// attachmentName returns the name an attachment is listed under: the path
+// as given, or the URL without credentials
+func attachmentName(source string) string {
+	if u, err := url.Parse(source); err == nil && isAttachURL(source) {
+		return u.Redacted()
+	}
+	return source
+}
+
+// renderAttachments formats the attachments placed after the file
+// sections, each fenced between "#ATTACHMENT <source>" and "#END
+// ATTACHMENT" lines like a file section, so the documents can't be mistaken
+// for code
+func renderAttachments(attachments []attachment) string {
+	var out strings.Builder
+	for _, a := range attachments {
+		language := fenceLanguage(a.source, a.content)
+		if u, err := url.Parse(a.source); err == nil && isAttachURL(a.source) {
+			language = fenceLanguage(u.Path, a.content)
+		}
+		content := strings.TrimRight(string(a.content), "\n")
+		fence := bundle.Fence(content)
+		fmt.Fprintf(&out, "#ATTACHMENT %s\n#START\n%s%s\n%s\n%s\n#END ATTACHMENT\n\n", attachmentName(a.source), fence, language, content, fence)
+	}
+	return out.String()
+}
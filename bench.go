@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// How often bench samples the heap for its peak
+const benchSampleInterval = 5 * time.Millisecond
+
+// Metric whose peak bench reports: the memory of live and unswept heap objects
+const benchHeapMetric = "/memory/classes/heap/objects:bytes"
+
+// benchReport holds the measurements of one bench run
+type benchReport struct {
+	root     string
+	files    int
+	walk     time.Duration
+	bytes    int64
+	read     time.Duration
+	tokens   int
+	tokenize time.Duration
+	peakHeap uint64
+}
+
+// heapSampler records the peak heap size while a benchmark runs
+type heapSampler struct {
+	mu     sync.Mutex
+	peak   uint64
+	sample []metrics.Sample
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// startHeapSampler starts sampling the heap in the background
+func startHeapSampler() *heapSampler {
+	s := &heapSampler{
+		sample: []metrics.Sample{{Name: benchHeapMetric}},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	s.record()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(benchSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.record()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// record reads the heap size and keeps it if it's the largest so far
+func (s *heapSampler) record() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics.Read(s.sample)
+	if s.sample[0].Value.Kind() == metrics.KindUint64 {
+		s.peak = max(s.peak, s.sample[0].Value.Uint64())
+	}
+}
+
+// finish stops sampling and returns the peak heap size
+func (s *heapSampler) finish() uint64 {
+	s.record()
+	close(s.stop)
+	<-s.done
+	return s.peak
+}
+
+// benchmark walks root with the find options, reads the files found and
+// estimates their tokens, timing each step. The contents stay in memory
+// until the tokens are counted, as they do while gen builds the result.
+func benchmark(root string, supportedExts []string, opts findOptions) (benchReport, error) {
+	report := benchReport{root: root}
+	runtime.GC()
+	sampler := startHeapSampler()
+
+	start := time.Now()
+	files, err := findFilesWithOptions(root, supportedExts, opts)
+	report.walk = time.Since(start)
+	if err != nil {
+		sampler.finish()
+		return report, err
+	}
+	report.files = len(files)
+	sampler.record()
+
+	start = time.Now()
+	contents := make([][]byte, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			sampler.finish()
+			return report, err
+		}
+		contents = append(contents, content)
+		report.bytes += int64(len(content))
+	}
+	report.read = time.Since(start)
+	sampler.record()
+
+	start = time.Now()
+	for _, content := range contents {
+		report.tokens += estimateTokens(string(content))
+	}
+	report.tokenize = time.Since(start)
+
+	report.peakHeap = sampler.finish()
+	return report, nil
+}
+
+// roundDuration rounds d for display, to microseconds from a millisecond and
+// to milliseconds from a second
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond)
+	default:
+		return d
+	}
+}
+
+// perSecond returns the rate of n per d, or 0 for a run too fast to time
+func perSecond(n float64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return n / d.Seconds()
+}
+
+// write prints the report with the versions it was measured with, so runs
+// of different releases can be compared
+func (r benchReport) write(out io.Writer) {
+	const mb = 1024 * 1024
+	fmt.Fprintln(out, "\nBenchmark Report")
+	fmt.Fprintln(out, "================")
+	fmt.Fprintf(out, "Directory: %s\n", r.root)
+	fmt.Fprintf(out, "Versions:  skukozh %s, %s, %s/%s, %d CPUs\n\n", buildVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+	fmt.Fprintf(out, "Walk:      %v (%d files, %.0f files/s)\n", roundDuration(r.walk), r.files, perSecond(float64(r.files), r.walk))
+	fmt.Fprintf(out, "Read:      %v (%.2f MB, %.1f MB/s)\n", roundDuration(r.read), float64(r.bytes)/mb, perSecond(float64(r.bytes)/mb, r.read))
+	fmt.Fprintf(out, "Tokenize:  %v (~%s tokens, %s tokens/s)\n", roundDuration(r.tokenize), formatTokens(r.tokens), formatTokens(int(perSecond(float64(r.tokens), r.tokenize))))
+	fmt.Fprintf(out, "Total:     %v\n", roundDuration(r.walk+r.read+r.tokenize))
+	fmt.Fprintf(out, "Peak heap: %.2f MB\n\n", float64(r.peakHeap)/mb)
+}
+
+// runBenchmark handles "bench" and returns the exit code. The files are
+// selected with the find flags, so the walk matches the one of find.
+func runBenchmark(root string, supportedExts, excludedExts []string, fs *flag.FlagSet) int {
+	defer applyFindFlags(fs)()
+
+	opts, err := findOptionsFromFlags(fs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	opts.excludedExts = excludedExts
+
+	report, err := benchmark(root, supportedExts, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	report.write(os.Stdout)
+	return 0
+}
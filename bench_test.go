@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmark(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":      "package main\n\nfunc main() {}\n",
+		"util.go":      "package main\n",
+		"README.md":    "# App\n",
+		"web/index.js": "export {}\n",
+		".env.example": "PORT=8080\n",
+	})
+
+	fs := DefaultFlags()
+	require.NoError(t, fs.Parse([]string{"bench", "-ext", "go,md", dir}))
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(fs)
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "Benchmark Report")
+	assert.Contains(t, output, "Directory: "+dir)
+	assert.Regexp(t, `Walk: +\S+ \(3 files, \d+ files/s\)`, output, "the find flags select the files")
+	assert.Regexp(t, `Read: +\S+ \(0\.00 MB, [\d.]+ MB/s\)`, output)
+	assert.Regexp(t, `Tokenize: +\S+ \(~14 tokens, `, output)
+	assert.Regexp(t, `Peak heap: [\d.]+ MB`, output)
+
+	// -hidden walks the hidden files too, without a .gitignore to read
+	fs = DefaultFlags()
+	require.NoError(t, fs.Parse([]string{"bench", "-hidden", dir}))
+	output = CaptureOutput(t, func() {
+		exitCode = runWithFlags(fs)
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Regexp(t, `Walk: +\S+ \(5 files, `, output)
+
+	report, err := benchmark(dir, []string{".go"}, findOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.files)
+	assert.Equal(t, int64(42), report.bytes)
+	assert.NotZero(t, report.peakHeap)
+
+	var out bytes.Buffer
+	benchReport{root: dir}.write(&out)
+	assert.Contains(t, out.String(), "Walk:      0s (0 files, 0 files/s)", "runs too fast to time report no rate")
+	assert.Zero(t, perSecond(10, 0))
+	assert.Equal(t, 20.0, perSecond(10, 500*time.Millisecond))
+
+	CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, dir, "bench")
+	})
+	assert.Equal(t, 1, exitCode)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// blameLine is the origin of a line of a file according to git blame
+type blameLine struct {
+	commit string
+	author string
+	time   time.Time
+	text   string
+}
+
+// gitBlame returns the blame of every line of a file. The second return
+// value is false when the file isn't tracked by git.
+func gitBlame(baseDir, file string) ([]blameLine, bool) {
+	out, err := runGit(baseDir, "blame", "--line-porcelain", "--", file)
+	if err != nil || out == "" {
+		return nil, false
+	}
+	return parseBlame(out), true
+}
+
+// parseBlame parses the output of git blame --line-porcelain
+func parseBlame(out string) []blameLine {
+	var lines []blameLine
+	var current blameLine
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			current.text = line[1:]
+			lines = append(lines, current)
+			current = blameLine{}
+		case strings.HasPrefix(line, "author "):
+			current.author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			if seconds, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64); err == nil {
+				current.time = time.Unix(seconds, 0)
+			}
+		case current.commit == "":
+			if fields := strings.Fields(line); len(fields) >= 3 {
+				current.commit = fields[0]
+			}
+		}
+	}
+	return lines
+}
+
+// formatAge renders a duration compactly, e.g. 5d, 3w, 4mo or 2y
+func formatAge(age time.Duration) string {
+	days := int(age.Hours() / 24)
+	switch {
+	case days < 14:
+		return fmt.Sprintf("%dd", max(days, 0))
+	case days < 60:
+		return fmt.Sprintf("%dw", days/7)
+	case days < 730:
+		return fmt.Sprintf("%dmo", days/30)
+	default:
+		return fmt.Sprintf("%dy", days/365)
+	}
+}
+
+// blameHeader renders the #BLAME line for the emitted lines of a file.
+// Emitted lines are matched in order against the blamed lines, so blank
+// lines, ranges and truncation leave the rest aligned; lines that don't
+// match, like the truncation marker, aren't annotated. Runs of lines from
+// the same commit share one entry, numbered by emitted line.
+func blameHeader(emitted []string, blamed []blameLine, now time.Time) string {
+	type run struct {
+		start, end int
+		line       blameLine
+	}
+	var runs []run
+
+	next := 0
+	for i, text := range emitted {
+		text = strings.TrimRight(text, "\r")
+		match := -1
+		for j := next; j < len(blamed); j++ {
+			if strings.TrimRight(blamed[j].text, "\r") == text {
+				match = j
+				break
+			}
+		}
+		if match == -1 {
+			continue
+		}
+		next = match + 1
+
+		line := blamed[match]
+		if last := len(runs) - 1; last >= 0 && runs[last].end == i && runs[last].line.commit == line.commit {
+			runs[last].end = i + 1
+			continue
+		}
+		runs = append(runs, run{start: i + 1, end: i + 1, line: line})
+	}
+	if len(runs) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(runs))
+	for _, r := range runs {
+		lines := strconv.Itoa(r.start)
+		if r.end > r.start {
+			lines += "-" + strconv.Itoa(r.end)
+		}
+		origin := "uncommitted"
+		if !strings.HasPrefix(r.line.commit, "0000000") {
+			origin = fmt.Sprintf("%s (%s)", r.line.author, formatAge(now.Sub(r.line.time)))
+		}
+		parts = append(parts, lines+" "+origin)
+	}
+	return "#BLAME " + strings.Join(parts, "; ") + "\n"
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAge(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{0, "0d"},
+		{-time.Hour, "0d"},
+		{5 * day, "5d"},
+		{20 * day, "2w"},
+		{120 * day, "4mo"},
+		{800 * day, "2y"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, formatAge(tc.age), "age %v", tc.age)
+	}
+}
+
+func TestParseBlame(t *testing.T) {
+	out := "1111111111111111111111111111111111111111 1 1 2\n" +
+		"author Alice\n" +
+		"author-time 1700000000\n" +
+		"summary first\n" +
+		"filename a.go\n" +
+		"\tpackage main\n" +
+		"0000000000000000000000000000000000000000 2 2 1\n" +
+		"author Not Committed Yet\n" +
+		"author-time 1700000100\n" +
+		"filename a.go\n" +
+		"\tfunc main() {}\n"
+
+	lines := parseBlame(out)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "Alice", lines[0].author)
+	assert.Equal(t, "package main", lines[0].text)
+	assert.Equal(t, int64(1700000000), lines[0].time.Unix())
+	assert.Equal(t, "0000000000000000000000000000000000000000", lines[1].commit)
+	assert.Equal(t, "func main() {}", lines[1].text)
+}
+
+func TestBlameHeader(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := blameLine{commit: "aaa", author: "Alice", time: now.AddDate(-2, 0, 0)}
+	recent := blameLine{commit: "bbb", author: "Bob", time: now.AddDate(0, 0, -3)}
+	uncommitted := blameLine{commit: "0000000000000000000000000000000000000000", author: "Not Committed Yet", time: now}
+	with := func(line blameLine, text string) blameLine {
+		line.text = text
+		return line
+	}
+	blamed := []blameLine{
+		with(old, "package main"),
+		with(old, ""),
+		with(old, "func a() {}"),
+		with(recent, "func b() {}"),
+		with(old, "func c() {}"),
+		with(uncommitted, "func d() {}"),
+	}
+
+	// The blank line is gone and a truncation marker doesn't match any line
+	emitted := []string{"package main", "func a() {}", "func b() {}", "... [truncated] ...", "func c() {}", "func d() {}"}
+	assert.Equal(t, "#BLAME 1-2 Alice (2y); 3 Bob (3d); 5 Alice (2y); 6 uncommitted\n", blameHeader(emitted, blamed, now))
+
+	assert.Empty(t, blameHeader([]string{"unrelated"}, blamed, now))
+}
+
+func TestGenerateContentFileBlame(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	initGitRepo(t, testDir)
+
+	// An uncommitted change to a tracked file; the closing brace counts as
+	// changed too, as it gains a trailing newline
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "file1.go"), []byte("package main\nfunc main() {\n\n}\nfunc added() {}\n"), 0644))
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("file1.go\nfile2.js:1-1"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{blame: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#TYPE go\n#BLAME 1-2 Test Author (0d); 3-4 uncommitted\n#START")
+	assert.Contains(t, result, "#LINES 1-1 of 3\n#BLAME 1 Test Author (0d)\n#START")
+
+	result, err = generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, result, "#BLAME", "Blame annotations should be opt-in")
+
+	_, err = generateContentFileInternal(testDir, genOptions{blame: true, format: "jsonl"})
+	assert.EqualError(t, err, "-format jsonl can't be combined with -blame")
+}
+
+func TestGitBlameUntracked(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	initGitRepo(t, testDir)
+
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "untracked.go"), []byte("package main"), 0644))
+	_, ok := gitBlame(testDir, "untracked.go")
+	assert.False(t, ok, "Untracked file should not have blame")
+}
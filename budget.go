@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Units accepted by -fail-over-size, largest first
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a size such as 1048576, 512KB or 1.5MB into bytes
+func parseSize(value string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(trimmed, unit.suffix) {
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q (use bytes or a KB, MB or GB suffix, e.g. '512KB')", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// analyzeBudget holds the -fail-over-tokens and -fail-over-size limits;
+// zero disables a limit
+type analyzeBudget struct {
+	tokens int
+	size   int64
+}
+
+// budgetFromOptions parses the limits of the analyze options
+func budgetFromOptions(opts analyzeOptions) (analyzeBudget, error) {
+	budget := analyzeBudget{tokens: opts.failOverTokens}
+	if budget.tokens < 0 {
+		return budget, fmt.Errorf("invalid -fail-over-tokens value %d", opts.failOverTokens)
+	}
+	if opts.failOverSize != "" {
+		size, err := parseSize(opts.failOverSize)
+		if err != nil {
+			return budget, fmt.Errorf("-fail-over-size: %w", err)
+		}
+		budget.size = size
+	}
+	return budget, nil
+}
+
+// exceeded describes the limits a bundle of the given size and token
+// estimate goes over, or returns an empty string when it fits
+func (b analyzeBudget) exceeded(size int64, tokens int) string {
+	var over []string
+	if b.tokens > 0 && tokens > b.tokens {
+		over = append(over, fmt.Sprintf("~%d tokens, over the -fail-over-tokens limit of %d", tokens, b.tokens))
+	}
+	if b.size > 0 && size > b.size {
+		over = append(over, fmt.Sprintf("%d bytes, over the -fail-over-size limit of %d", size, b.size))
+	}
+	return strings.Join(over, "; ")
+}
+
+// resultTotals returns the size and token estimate of the whole result file
+func resultTotals() (int64, int, error) {
+	reader, err := openBundleFile(resultName)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer reader.Close()
+	scan, err := scanBundle(reader, countSymbols, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := reader.Close(); err != nil {
+		return 0, 0, err
+	}
+	return scan.size, (scan.runes + 3) / 4, nil
+}
+
+// fileListTotals returns the projected size and token estimate of the
+// files of the file list, as analyze -list reports them
+func fileListTotals(baseDir string) (int64, int, error) {
+	listed, err := listedFiles()
+	if err != nil {
+		return 0, 0, err
+	}
+	var size int64
+	tokens := 0
+	for _, file := range listed {
+		if info, err := os.Stat(filepath.Join(baseDir, entryPath(file))); err == nil && !info.IsDir() {
+			size += info.Size()
+			tokens += estimateFileTokens(info.Size())
+		}
+	}
+	return size, tokens, nil
+}
+
+// checkAnalyzeBudget compares the bundle, or with -list the file list,
+// against the -fail-over-tokens and -fail-over-size limits and returns the
+// exit code, so CI can keep a bundle within a model's context
+func checkAnalyzeBudget(baseDir string, opts analyzeOptions) int {
+	budget, err := budgetFromOptions(opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if budget == (analyzeBudget{}) {
+		return 0
+	}
+
+	var size int64
+	var tokens int
+	if opts.list {
+		size, tokens, err = fileListTotals(baseDir)
+	} else {
+		size, tokens, err = resultTotals()
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if over := budget.exceeded(size, tokens); over != "" {
+		fmt.Printf("Budget exceeded: %s\n", over)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"1048576", 1048576},
+		{"512KB", 512 * 1024},
+		{"1.5mb", 1536 * 1024},
+		{"2 GB", 2 << 30},
+		{"100B", 100},
+	}
+	for _, tc := range tests {
+		got, err := parseSize(tc.value)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.want, got, tc.value)
+	}
+
+	_, err := parseSize("lots")
+	assert.EqualError(t, err, `invalid size "lots" (use bytes or a KB, MB or GB suffix, e.g. '512KB')`)
+}
+
+func TestAnalyzeBudgetExceeded(t *testing.T) {
+	budget := analyzeBudget{tokens: 1000, size: 2048}
+	assert.Empty(t, budget.exceeded(2048, 1000))
+	assert.Equal(t, "~1001 tokens, over the -fail-over-tokens limit of 1000", budget.exceeded(100, 1001))
+	assert.Equal(t, "~5000 tokens, over the -fail-over-tokens limit of 1000; 20000 bytes, over the -fail-over-size limit of 2048",
+		budget.exceeded(20000, 5000))
+	assert.Empty(t, analyzeBudget{}.exceeded(1<<40, 1<<30), "Zero limits are disabled")
+}
+
+func TestAnalyzeCommandBudget(t *testing.T) {
+	writeTestResult(t, map[string]int{"src/main.go": 4000})
+	defer os.Remove(resultName)
+
+	run := func(args ...string) (int, string) {
+		flagSet := DefaultFlags()
+		require.NoError(t, flagSet.Parse(append([]string{"analyze"}, args...)))
+		var exitCode int
+		output := CaptureOutput(t, func() {
+			exitCode = runWithFlags(flagSet)
+		})
+		return exitCode, output
+	}
+
+	exitCode, output := run("-fail-over-tokens", "2000")
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "Analysis Report")
+
+	exitCode, output = run("-fail-over-tokens", "500")
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, "Analysis Report", "The report is printed before the gate")
+	assert.Contains(t, output, "Budget exceeded: ~1014 tokens, over the -fail-over-tokens limit of 500")
+
+	exitCode, output = run("-fail-over-size", "1KB")
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, "over the -fail-over-size limit of 1024")
+
+	exitCode, output = run("-fail-over-size", "huge")
+	assert.Equal(t, 1, exitCode)
+	assert.NotContains(t, output, "Analysis Report")
+	assert.Contains(t, output, "Error: -fail-over-size: invalid size")
+}
+
+func TestAnalyzeListBudget(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(fileListName, []byte("file1.go\nfile5.txt"), 0644))
+	defer os.Remove(fileListName)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"analyze", "-list", "-fail-over-tokens", "5", testDir}))
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, "File List Report")
+	assert.Contains(t, output, "Budget exceeded: ~17 tokens, over the -fail-over-tokens limit of 5")
+}
@@ -0,0 +1,95 @@
+// Package bundle reads and writes the file sections of skukozh result
+// files. A section looks like this:
+//
+//	#FILE path/to/file.go
+//	#TYPE go
+//	#START
+//	```go
+//	package main
+//	```
+//	#END
+//
+// The #FILE line may carry a section ID ("#FILE[017] path") and a size
+// annotation ("path (312 lines, ~2.4k tokens)"), and more header lines such
+// as #LINES or #GIT may follow #TYPE. The content is fenced with at least
+// three backticks, one more than the longest run of backticks in it, so a
+// section only ends at a line holding its own fence followed by #END. Lines
+// of the content that look like markers, including #FILE and #END, are
+// never mistaken for them.
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Section is a file section of a bundle
+type Section struct {
+	ID       int      // number of "#FILE[017] path", 0 when the section has none
+	Header   string   // value of the #FILE line, including a size annotation
+	Path     string   // Header without the size annotation
+	Type     string   // value of the #TYPE line
+	Fields   []string // other header lines between #TYPE and #START, e.g. "#LINES 1-20 of 80"
+	Language string   // info string of the opening fence
+	Content  string   // text between the fences
+
+	Line    int // line of the #FILE header, starting at 1
+	EndLine int // line of the #END marker
+	Offset  int // byte offset of the #FILE header
+	End     int // byte offset just past the #END line
+}
+
+// headerAnnotation matches the size annotation of a #FILE line
+var headerAnnotation = regexp.MustCompile(` \(\d+ lines?, ~[0-9.]+[kM]? tokens?\)$`)
+
+// HeaderPath returns the path of a #FILE line's value, dropping the size
+// annotation if present
+func HeaderPath(value string) string {
+	return headerAnnotation.ReplaceAllString(strings.TrimSpace(value), "")
+}
+
+// Fence returns the code fence for content: three backticks, or one more
+// than the longest run of backticks in it
+func Fence(content string) string {
+	longest, run := 0, 0
+	for i := 0; i < len(content); i++ {
+		if content[i] == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// WriteSection writes s in the result format, followed by a blank line.
+// The #FILE line holds s.Header, or s.Path when it's empty; the section ID
+// and the line and offset fields are not written.
+func WriteSection(w io.Writer, s Section) error {
+	header := s.Header
+	if header == "" {
+		header = s.Path
+	}
+	if strings.ContainsAny(header, "\r\n") {
+		return fmt.Errorf("bundle: line break in the #FILE header %q", header)
+	}
+	fence := Fence(s.Content)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#FILE %s\n#TYPE %s\n", header, s.Type)
+	for _, field := range s.Fields {
+		b.WriteString(strings.TrimSuffix(field, "\n") + "\n")
+	}
+	b.WriteString("#START\n" + fence + s.Language + "\n")
+	b.WriteString(s.Content)
+	if !strings.HasSuffix(s.Content, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString(fence + "\n#END\n\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
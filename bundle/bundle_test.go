@@ -0,0 +1,65 @@
+package bundle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderPath(t *testing.T) {
+	assert.Equal(t, "main.go", HeaderPath("main.go (312 lines, ~2.4k tokens)\n"))
+	assert.Equal(t, "notes (draft).md", HeaderPath("notes (draft).md"))
+}
+
+func TestFence(t *testing.T) {
+	assert.Equal(t, "```", Fence("package main\n"))
+	assert.Equal(t, "```", Fence("s := `raw`"))
+	assert.Equal(t, "````", Fence("```sh\nmake\n```\n"))
+	assert.Equal(t, "``````", Fence("x `````"))
+}
+
+func TestWriteSection(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, WriteSection(&b, Section{
+		Path:     "cmd/main.go",
+		Type:     "go",
+		Fields:   []string{"#LINES 1-2 of 40", "#GIT commit=abc\n"},
+		Language: "go",
+		Content:  "package main\nfunc main() {}",
+	}))
+	assert.Equal(t, "#FILE cmd/main.go\n#TYPE go\n#LINES 1-2 of 40\n#GIT commit=abc\n#START\n```go\npackage main\nfunc main() {}\n```\n#END\n\n", b.String())
+
+	err := WriteSection(&b, Section{Path: "evil\n#FILE x"})
+	assert.EqualError(t, err, "bundle: line break in the #FILE header \"evil\\n#FILE x\"")
+}
+
+func TestWriteParseRoundTrip(t *testing.T) {
+	// Content that looks like the markers of a section
+	contents := []string{
+		"```\n#END\n",
+		"#FILE other.go\n#TYPE go\n#START\n```go\npackage other\n```\n#END\n\n",
+		"````\n```\n#END\n````\n#END\n",
+		"#END\n```",
+		"\n",
+		"crlf\r\n```\r\n#END\r\n",
+	}
+
+	var b strings.Builder
+	b.WriteString("Prompt\n\n")
+	for i, content := range contents {
+		require.NoError(t, WriteSection(&b, Section{Path: string(rune('a'+i)) + ".md", Type: "md", Language: "markdown", Content: content}))
+	}
+
+	sections := Parse(b.String())
+	require.Len(t, sections, len(contents))
+	for i, section := range sections {
+		expected := contents[i]
+		if !strings.HasSuffix(expected, "\n") {
+			expected += "\n"
+		}
+		assert.Equal(t, string(rune('a'+i))+".md", section.Path)
+		assert.Equal(t, expected, section.Content, "section %d", i)
+	}
+}
@@ -0,0 +1,149 @@
+package bundle
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// headerLine matches a #FILE line, capturing the section ID and the value
+var headerLine = regexp.MustCompile(`^#FILE(?:\[(\d+)\])? (.*)$`)
+
+// Parser states
+const (
+	outside = iota // before the first section or after an #END line
+	header         // after #FILE, reading header lines until #START
+	opening        // after #START, expecting the opening fence
+	content        // between the fences
+)
+
+// Parser finds the sections of a bundle fed to it line by line, so a bundle
+// of any size can be read without holding it in memory. The zero value is
+// ready to use.
+type Parser struct {
+	state   int
+	section Section
+	fence   string
+	content strings.Builder
+	pending string // a line holding the fence, which ends the section if #END follows
+	line    int    // lines fed so far
+	offset  int    // bytes fed so far
+}
+
+// Feed processes the next line of a bundle, including its newline, and
+// returns the section it completes. An empty string, as read at the end of
+// a bundle ending in a newline, is ignored.
+func (p *Parser) Feed(line string) (Section, bool) {
+	if line == "" {
+		return Section{}, false
+	}
+	p.line++
+	start := p.offset
+	p.offset += len(line)
+	text := strings.TrimRight(line, "\r\n") // markers may have CRLF line endings
+
+	if p.state == content {
+		if p.pending != "" {
+			pending := p.pending
+			p.pending = ""
+			if text == "#END" {
+				s := p.section
+				s.Content = p.content.String()
+				s.EndLine, s.End = p.line, p.offset
+				p.state = outside
+				p.content.Reset()
+				return s, true
+			}
+			p.content.WriteString(pending)
+		}
+		if text == p.fence {
+			p.pending = line
+		} else {
+			p.content.WriteString(line)
+		}
+		return Section{}, false
+	}
+
+	// Outside the content, a #FILE line starts a new section and drops an
+	// unfinished header
+	if match := headerLine.FindStringSubmatch(text); match != nil {
+		id, _ := strconv.Atoi(match[1])
+		p.section = Section{ID: id, Header: match[2], Path: HeaderPath(match[2]), Line: p.line, Offset: start}
+		p.state = header
+		return Section{}, false
+	}
+
+	switch p.state {
+	case header:
+		if text == "#START" {
+			p.state = opening
+		} else if value, ok := strings.CutPrefix(text, "#TYPE "); ok {
+			p.section.Type = value
+		} else if strings.HasPrefix(text, "#") {
+			p.section.Fields = append(p.section.Fields, text)
+		}
+	case opening:
+		n := len(text) - len(strings.TrimLeft(text, "`"))
+		if n < 3 {
+			p.state = outside // Not a section after all
+			break
+		}
+		p.fence = text[:n]
+		p.section.Language = text[n:]
+		p.state = content
+	}
+	return Section{}, false
+}
+
+// Unfinished returns the section the bundle ended in without its #END line,
+// with the content read so far, e.g. for a bundle that is cut off
+func (p *Parser) Unfinished() (Section, bool) {
+	if p.state == outside {
+		return Section{}, false
+	}
+	s := p.section
+	s.Content = p.content.String() + p.pending
+	s.EndLine, s.End = p.line, p.offset
+	return s, true
+}
+
+// Parse returns the complete sections of a bundle
+func Parse(bundle string) []Section {
+	var p Parser
+	var sections []Section
+	for bundle != "" {
+		line := bundle
+		if i := strings.IndexByte(bundle, '\n'); i >= 0 {
+			line = bundle[:i+1]
+		}
+		bundle = bundle[len(line):]
+		if s, ok := p.Feed(line); ok {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// Read streams a bundle and calls fn for every complete section, stopping
+// at the first error fn returns
+func Read(r io.Reader, fn func(Section) error) error {
+	var p Parser
+	reader := bufio.NewReaderSize(r, 1<<20)
+	for {
+		// bufio.Reader rather than bufio.Scanner, as lines can be of any length
+		line, err := reader.ReadString('\n')
+		if s, ok := p.Feed(line); ok {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
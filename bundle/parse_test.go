@@ -0,0 +1,76 @@
+package bundle
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBundle = "Review this.\n\n" +
+	"#FILE[001] main.go (1 line, ~3 tokens)\n#TYPE go\n#GIT commit=abc\n#START\n```go\npackage main\n```\n#END\n\n" +
+	"#FILE[002] README.md\n#TYPE md\n#START\n```markdown\n```sh\nmake\n```\nDone.\n```\n#END\n\n" +
+	"Closing words.\n"
+
+func TestParse(t *testing.T) {
+	sections := Parse(testBundle)
+	require.Len(t, sections, 2)
+
+	assert.Equal(t, Section{
+		ID:       1,
+		Header:   "main.go (1 line, ~3 tokens)",
+		Path:     "main.go",
+		Type:     "go",
+		Fields:   []string{"#GIT commit=abc"},
+		Language: "go",
+		Content:  "package main\n",
+		Line:     3,
+		EndLine:  10,
+		Offset:   len("Review this.\n\n"),
+		End:      strings.Index(testBundle, "#END\n") + len("#END\n"),
+	}, sections[0])
+
+	// Bundles written with a three backtick fence around nested fences
+	// still parse, as a fence only closes the section before #END
+	assert.Equal(t, "README.md", sections[1].Path)
+	assert.Equal(t, "```sh\nmake\n```\nDone.\n", sections[1].Content)
+	assert.Equal(t, "\nClosing words.\n", testBundle[sections[1].End:])
+}
+
+func TestParseMalformed(t *testing.T) {
+	bundle := "#FILE no-start.go\n#TYPE go\n" +
+		"#FILE no-fence.go\n#TYPE go\n#START\npackage a\n" +
+		"#FILE ok.go\n#TYPE go\n#START\n```go\npackage ok\n```\n#END\n" +
+		"#FILE cut.go\n#TYPE go\n#START\n```go\npackage cut\n```\n"
+
+	var p Parser
+	var paths []string
+	for _, line := range strings.SplitAfter(bundle, "\n") {
+		if s, ok := p.Feed(line); ok {
+			paths = append(paths, s.Path)
+		}
+	}
+	assert.Equal(t, []string{"ok.go"}, paths)
+
+	unfinished, ok := p.Unfinished()
+	require.True(t, ok)
+	assert.Equal(t, "cut.go", unfinished.Path)
+	assert.Equal(t, "package cut\n```\n", unfinished.Content)
+	assert.Equal(t, 19, unfinished.EndLine)
+}
+
+func TestRead(t *testing.T) {
+	var paths []string
+	err := Read(strings.NewReader(testBundle), func(s Section) error {
+		paths = append(paths, s.Path)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "README.md"}, paths)
+
+	stop := errors.New("stop")
+	err = Read(strings.NewReader(testBundle), func(s Section) error { return stop })
+	assert.Equal(t, stop, err)
+}
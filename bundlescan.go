@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"skukozh/bundle"
+)
+
+// Amount of bundle text counted by one worker at a time
+const scanChunkSize = 4 << 20
+
+// bundleScan holds the totals of a result file read by scanBundle
+type bundleScan struct {
+	size    int64       // bytes of the uncompressed bundle
+	symbols int         // symbols in the count mode, see symbolCounter
+	runes   int         // characters, the base of token estimates
+	meta    []metaField // fields of the #SKUKOZH header, if any
+	files   []FileInfo  // parsed file sections in bundle order
+}
+
+// scanBundle reads a result file line by line, counting it and turning its
+// file sections into FileInfo with fileInfo on all CPUs. Only the chunks
+// and sections being worked on are held in memory, so multi-GB bundles
+// don't need to fit in it. Symbols are counted with countSymbols, or
+// another counter of symbolCounter. With a nil fileInfo only the totals are
+// computed.
+func scanBundle(r io.Reader, count func(string) int, fileInfo func(bundleSection) FileInfo) (bundleScan, error) {
+	var scan bundleScan
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	workers := runtime.GOMAXPROCS(0)
+	jobs := make(chan func(), workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				job()
+				wg.Done()
+			}
+		}()
+	}
+	submit := func(job func()) {
+		wg.Add(1)
+		jobs <- job
+	}
+	countChunk := func(text string) {
+		submit(func() {
+			symbols, runes := count(text), utf8.RuneCountInString(text)
+			mu.Lock()
+			scan.symbols += symbols
+			scan.runes += runes
+			mu.Unlock()
+		})
+	}
+
+	// Workers fill in the entries, which keep the order of the sections
+	var files []*FileInfo
+	var parser bundle.Parser
+	var chunk strings.Builder
+	reader := bufio.NewReaderSize(r, 1<<20)
+	var readErr error
+	for {
+		// bufio.Reader rather than bufio.Scanner, as minified files can
+		// have lines of any length
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if scan.size == 0 {
+				scan.meta = parseMetaHeader(line)
+			}
+			scan.size += int64(len(line))
+
+			chunk.WriteString(line)
+			if chunk.Len() >= scanChunkSize {
+				countChunk(chunk.String())
+				chunk.Reset()
+			}
+
+			if fileInfo != nil {
+				if section, ok := parser.Feed(line); ok {
+					file := new(FileInfo)
+					files = append(files, file)
+					submit(func() {
+						*file = fileInfo(bundleSection{path: section.Path, language: section.Language, content: section.Content})
+					})
+				}
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+	}
+	if chunk.Len() > 0 {
+		countChunk(chunk.String())
+	}
+	close(jobs)
+	wg.Wait()
+	if readErr != nil {
+		return bundleScan{}, readErr
+	}
+
+	scan.files = make([]FileInfo, len(files))
+	for i, file := range files {
+		scan.files[i] = *file
+	}
+	return scan, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanBundle(t *testing.T) {
+	bundle := "#SKUKOZH version=v1 files=4\nReview this.\n\n" +
+		"#FILE main.go (3 lines, ~7 tokens)\n#TYPE go\n#START\n```go\npackage main\n\nfunc main() {}\n```\n#END\n\n" +
+		"#FILE[002] docs/README.md\n#TYPE md\n#START\n````markdown\n```sh\nmake\n```\n#END\n#FILE fake.go\n````\n#END\n\n" +
+		"#FILE broken.go\n#TYPE go\n#START\nno fence\n" +
+		"#FILE empty.txt\n#TYPE txt\n#START\n```text\n```\n#END\n" +
+		"#FILE last.js\n#TYPE js\n#START\n```javascript\nconst s = \"ünïcode\";\n```\n#END"
+
+	var mu sync.Mutex
+	var sections []bundleSection
+	scan, err := scanBundle(strings.NewReader(bundle), countSymbols, func(section bundleSection) FileInfo {
+		mu.Lock()
+		sections = append(sections, section) // sections are parsed concurrently
+		mu.Unlock()
+		return FileInfo{path: section.path, size: int64(len(section.content))}
+	})
+	require.NoError(t, err)
+
+	expected := parseBundleSections(bundle)
+	require.Len(t, expected, 4)
+	assert.ElementsMatch(t, expected, sections)
+	var paths []string
+	for _, file := range scan.files {
+		paths = append(paths, file.path)
+	}
+	assert.Equal(t, []string{"main.go", "docs/README.md", "empty.txt", "last.js"}, paths, "files keep the bundle order")
+
+	assert.Equal(t, int64(len(bundle)), scan.size)
+	assert.Equal(t, countSymbols(bundle), scan.symbols)
+	assert.Equal(t, utf8.RuneCountInString(bundle), scan.runes)
+	assert.Equal(t, parseMetaHeader(bundle), scan.meta)
+}
+
+func TestScanBundleTotalsOnly(t *testing.T) {
+	// More than one chunk, split between multi-byte characters' lines
+	line := strings.Repeat("ж ", 500) + "\n"
+	bundle := strings.Repeat(line, 2*scanChunkSize/len(line)+1)
+
+	scan, err := scanBundle(strings.NewReader(bundle), countSymbols, nil)
+	require.NoError(t, err)
+	assert.Empty(t, scan.files)
+	assert.Nil(t, scan.meta)
+	assert.Equal(t, int64(len(bundle)), scan.size)
+	assert.Equal(t, countSymbols(bundle), scan.symbols)
+	assert.Equal(t, utf8.RuneCountInString(bundle), scan.runes)
+}
+
+func TestOpenBundleFileCompressed(t *testing.T) {
+	bundle := testBundle("main.go", "package main")
+	compressed, err := compressData([]byte(bundle), "gzip")
+	require.NoError(t, err)
+	name := filepath.Join(t.TempDir(), resultName)
+	require.NoError(t, os.WriteFile(name+".gz", compressed, 0644))
+
+	reader, err := openBundleFile(name)
+	require.NoError(t, err)
+	defer reader.Close()
+	scan, err := scanBundle(reader, countSymbols, func(section bundleSection) FileInfo {
+		return FileInfo{path: section.path}
+	})
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, int64(len(bundle)), scan.size)
+	assert.Equal(t, []FileInfo{{path: "main.go"}}, scan.files)
+
+	_, err = openBundleFile(filepath.Join(t.TempDir(), resultName))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Name of the cache file written by incremental gen runs
+const cacheName = "skukozh_cache.json"
+
+// genCacheEntry is a rendered section together with the file state it was rendered from
+type genCacheEntry struct {
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	Section fileSection `json:"section"`
+}
+
+// genCache stores rendered file sections between incremental gen runs
+type genCache struct {
+	Key   string                   `json:"key"`
+	Files map[string]genCacheEntry `json:"files"`
+
+	previous map[string]genCacheEntry
+}
+
+// genCacheKey identifies the settings a cache was built with. Options that
+// only affect the bundle as a whole are cleared so they don't invalidate it.
+func genCacheKey(baseDir string, opts genOptions) string {
+	if absDir, err := filepath.Abs(baseDir); err == nil {
+		baseDir = absDir
+	}
+	opts.summary = false
+	opts.dependencies = false
+	opts.squashMigs = ""
+	opts.refLocale = ""
+	opts.toc = false
+	opts.ids = false
+	opts.checksum = false
+	opts.compress = ""
+	opts.encrypt, opts.passphraseFile = false, ""
+	opts.upload = ""
+	opts.meta, opts.metaFlags = false, ""
+	opts.deterministic = false
+	opts.incremental = false
+	opts.noCache = false
+	opts.order = ""
+	opts.priority = nil
+	opts.only, opts.skip = nil, nil
+	opts.review = false
+	opts.reads = nil
+	opts.editorConfigs = nil
+	opts.template = ""
+	opts.format = ""
+	opts.prompt, opts.promptFile = "", ""
+	opts.promptSuffix, opts.promptSuffixFile = "", ""
+	opts.attach = nil
+	return fmt.Sprintf("%s %+v", baseDir, opts)
+}
+
+// loadGenCache reads the cache file, discarding it when it was built for
+// a different directory or with different options
+func loadGenCache(key string) *genCache {
+	cache := &genCache{Key: key, Files: make(map[string]genCacheEntry)}
+
+	content, err := os.ReadFile(besideResult(cacheName))
+	if err != nil {
+		return cache
+	}
+
+	var stored genCache
+	if err := json.Unmarshal(content, &stored); err != nil || stored.Key != key {
+		return cache
+	}
+	cache.previous = stored.Files
+	return cache
+}
+
+// lookup returns the cached section for a file if the file is unchanged
+func (c *genCache) lookup(file string, info os.FileInfo) (fileSection, bool) {
+	entry, ok := c.previous[file]
+	if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		return fileSection{}, false
+	}
+	return entry.Section, true
+}
+
+// store records the section rendered for a file in its current state
+func (c *genCache) store(file string, info os.FileInfo, section fileSection) {
+	c.Files[file] = genCacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Section: section,
+	}
+}
+
+// save writes the cache file, keeping only the files of the current run
+func (c *genCache) save() error {
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(besideResult(cacheName), content, 0644)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalGeneration(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("file1.go\nfile2.js"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+	os.Remove(cacheName)
+	defer os.Remove(cacheName)
+
+	opts := genOptions{incremental: true}
+	var first string
+	output := CaptureOutput(t, func() {
+		var err error
+		first, err = generateContentFileInternal(testDir, opts)
+		require.NoError(t, err)
+	})
+	assert.Contains(t, output, "Reused 0 of 2 files")
+	assert.FileExists(t, cacheName)
+
+	// Rewrite file1.go with same-sized content and the original mtime so it
+	// looks unchanged, and really change file2.js
+	file1 := filepath.Join(testDir, "file1.go")
+	info, err := os.Stat(file1)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file1, []byte("package xxxx\nfunc main() {\n\n}"), 0644))
+	require.NoError(t, os.Chtimes(file1, time.Now(), info.ModTime()))
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "file2.js"), []byte("function changed() {}"), 0644))
+
+	var second string
+	output = CaptureOutput(t, func() {
+		second, err = generateContentFileInternal(testDir, opts)
+		require.NoError(t, err)
+	})
+	assert.Contains(t, output, "Reused 1 of 2 files")
+	assert.Contains(t, second, "package main", "Unchanged file should come from the cache")
+	assert.Contains(t, second, "function changed()", "Changed file should be re-read")
+	assert.NotEqual(t, first, second)
+
+	// Different options invalidate the cache
+	output = CaptureOutput(t, func() {
+		_, err = generateContentFileInternal(testDir, genOptions{incremental: true, normalizeEOL: true})
+		require.NoError(t, err)
+	})
+	assert.Contains(t, output, "Reused 0 of 2 files")
+}
+
+func TestIncrementalGenerationRendersUncacheableSections(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		".editorconfig": "root = true\n[*]\ntab_width = 2\n",
+		"main.py":       "def main():\n\treturn 1\n",
+	})
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("main.py"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+	os.Remove(cacheName)
+	defer os.Remove(cacheName)
+
+	opts := genOptions{incremental: true, expandTabs: true}
+	var result string
+	var err error
+	output := CaptureOutput(t, func() {
+		result, err = generateContentFileInternal(testDir, opts)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, output, "Not reusing sections")
+	assert.Contains(t, result, "def main():\n  return 1")
+
+	// Editing .editorconfig changes the section although main.py is unchanged
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, ".editorconfig"), []byte("root = true\n[*]\ntab_width = 8\n"), 0644))
+	CaptureOutput(t, func() {
+		result, err = generateContentFileInternal(testDir, opts)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result, "def main():\n        return 1")
+	assert.NoFileExists(t, cacheName)
+}
+
+func TestGenCacheKeyIgnoresBundleOptions(t *testing.T) {
+	base := genCacheKey("dir", genOptions{maxFileTokens: 10})
+	assert.Equal(t, base, genCacheKey("dir", genOptions{maxFileTokens: 10, summary: true, checksum: true, compress: "gzip"}))
+	assert.NotEqual(t, base, genCacheKey("dir", genOptions{maxFileTokens: 20}))
+	assert.NotEqual(t, base, genCacheKey("other", genOptions{maxFileTokens: 10}))
+}
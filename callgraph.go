@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goFunc is a function or method in the call graph of a Go module
+type goFunc struct {
+	name  string   // package name and function, e.g. "store.Store.Get"
+	dir   string   // package directory
+	file  string   // file declaring the function
+	calls []string // keys of the functions it may call
+}
+
+// callGraph is a syntax-based call graph of the non-test Go files of a
+// module. Functions are keyed by package directory and name, e.g.
+// "internal/store.Store.Get". Without type information, a method call
+// x.M() is linked to every method M declared in the caller's package or in
+// the module packages it imports.
+type callGraph struct {
+	funcs   map[string]*goFunc
+	callers map[string][]string
+}
+
+// goSourceFile is a parsed Go file with its module-local imports
+type goSourceFile struct {
+	dir     string
+	parsed  *ast.File
+	imports map[string]string // import name -> package directory
+}
+
+// newCallGraph parses the non-test Go files among files
+func newCallGraph(baseDir string, files []string) *callGraph {
+	module := goModulePath(filepath.Join(baseDir, "go.mod"))
+	graph := &callGraph{funcs: make(map[string]*goFunc), callers: make(map[string][]string)}
+	methods := make(map[string][]string)        // method name -> keys
+	packageImports := make(map[string][]string) // package directory -> imported package directories
+
+	var sources []goSourceFile
+	fset := token.NewFileSet()
+	for _, file := range files {
+		file = filepath.ToSlash(file)
+		if !strings.HasSuffix(file, ".go") || isGoTestFile(file) {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, filepath.Join(baseDir, file), nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+
+		source := goSourceFile{dir: path.Dir(file), parsed: parsed, imports: goLocalImports(parsed, module)}
+		sources = append(sources, source)
+		for _, dir := range source.imports {
+			packageImports[source.dir] = append(packageImports[source.dir], dir)
+		}
+		for _, decl := range parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			key := source.dir + "." + goFuncName(fn)
+			graph.funcs[key] = &goFunc{name: parsed.Name.Name + "." + goFuncName(fn), dir: source.dir, file: file}
+			if fn.Recv != nil {
+				methods[fn.Name.Name] = append(methods[fn.Name.Name], key)
+			}
+		}
+	}
+
+	for _, source := range sources {
+		for _, decl := range source.parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			key := source.dir + "." + goFuncName(fn)
+			callees := graph.callees(source, fn.Body, methods, packageImports[source.dir])
+			graph.funcs[key].calls = callees
+			for _, callee := range callees {
+				graph.callers[callee] = append(graph.callers[callee], key)
+			}
+		}
+	}
+	return graph
+}
+
+// goLocalImports maps the names of the module-local imports of a file to
+// their package directories
+func goLocalImports(parsed *ast.File, module string) map[string]string {
+	imports := make(map[string]string)
+	if module == "" {
+		return imports
+	}
+	for _, spec := range parsed.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		var dir string
+		switch {
+		case importPath == module:
+			dir = "."
+		case strings.HasPrefix(importPath, module+"/"):
+			dir = strings.TrimPrefix(importPath, module+"/")
+		default:
+			continue
+		}
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = dir
+	}
+	return imports
+}
+
+// callees returns the keys of the functions a function body may call,
+// sorted and without duplicates. Method calls resolve to the methods of the
+// source package and of the imported packages.
+func (g *callGraph) callees(source goSourceFile, body *ast.BlockStmt, methods map[string][]string, imported []string) []string {
+	seen := make(map[string]bool)
+	add := func(key string) {
+		if _, ok := g.funcs[key]; ok {
+			seen[key] = true
+		}
+	}
+
+	ast.Inspect(body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			add(source.dir + "." + fun.Name)
+		case *ast.SelectorExpr:
+			if ident, ok := fun.X.(*ast.Ident); ok {
+				if dir, ok := source.imports[ident.Name]; ok {
+					add(dir + "." + fun.Sel.Name)
+					return true
+				}
+			}
+			for _, key := range methods[fun.Sel.Name] {
+				if dir := g.funcs[key].dir; dir == source.dir || contains(imported, dir) {
+					add(key)
+				}
+			}
+		}
+		return true
+	})
+
+	callees := make([]string, 0, len(seen))
+	for key := range seen {
+		callees = append(callees, key)
+	}
+	sort.Strings(callees)
+	return callees
+}
+
+// lookup returns the keys of the functions matching name, given as
+// package.Func or package.Type.Method, or as a key with the package directory
+func (g *callGraph) lookup(name string) []string {
+	var keys []string
+	for key, fn := range g.funcs {
+		if key == name || fn.name == name {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// neighborhood returns the functions reachable from the start functions by
+// following calls, and callers, up to hops times in each direction
+func (g *callGraph) neighborhood(start []string, hops int) map[string]bool {
+	found := make(map[string]bool)
+	for _, edges := range []func(string) []string{
+		func(key string) []string { return g.funcs[key].calls },
+		func(key string) []string { return g.callers[key] },
+	} {
+		frontier := start
+		visited := make(map[string]bool)
+		for _, key := range start {
+			visited[key] = true
+		}
+		for hop := 0; hop < hops && len(frontier) > 0; hop++ {
+			var next []string
+			for _, key := range frontier {
+				for _, neighbor := range edges(key) {
+					if !visited[neighbor] {
+						visited[neighbor] = true
+						next = append(next, neighbor)
+					}
+				}
+			}
+			frontier = next
+		}
+		for key := range visited {
+			found[key] = true
+		}
+	}
+	return found
+}
+
+// callNeighborhood returns the files declaring the function named by
+// around and its callers and callees up to hops calls away, sorted
+func callNeighborhood(baseDir string, files []string, around string, hops int) ([]string, error) {
+	graph := newCallGraph(baseDir, files)
+	start := graph.lookup(around)
+	if len(start) == 0 {
+		return nil, fmt.Errorf("function %s not found among the discovered Go files (use package.Func or package.Type.Method)", around)
+	}
+
+	included := make(map[string]bool)
+	for key := range graph.neighborhood(start, hops) {
+		included[graph.funcs[key].file] = true
+	}
+	neighborhood := make([]string, 0, len(included))
+	for file := range included {
+		neighborhood = append(neighborhood, file)
+	}
+	sort.Strings(neighborhood)
+	return neighborhood, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCallGraphModule writes a small Go module and returns its directory
+// and files
+func writeCallGraphModule(t *testing.T) (string, []string) {
+	testDir := t.TempDir()
+	sources := map[string]string{
+		"go.mod": "module example.com/app\n",
+		"cmd/api/main.go": `package main
+
+import "example.com/app/internal/server"
+
+func main() {
+	s := server.New()
+	s.Run()
+}
+`,
+		"internal/server/server.go": `package server
+
+import db "example.com/app/internal/store"
+
+type Server struct{ store *db.Store }
+
+func New() *Server { return &Server{store: db.Open()} }
+
+func (s *Server) Run() {
+	s.handle()
+}
+`,
+		"internal/server/handle.go": `package server
+
+func (s *Server) handle() {
+	s.store.Get("key")
+}
+`,
+		"internal/server/server_test.go": "package server\n\nfunc TestRun() { (&Server{}).Run() }\n",
+		"internal/store/store.go": `package store
+
+type Store struct{}
+
+func Open() *Store { return &Store{} }
+
+func (s *Store) Get(key string) string { return encode(key) }
+`,
+		"internal/store/encode.go": "package store\n\nfunc encode(s string) string { return s }\n",
+		"internal/cache/cache.go":  "package cache\n\ntype Cache struct{}\n\nfunc (c *Cache) Get(key string) string { return key }\n",
+	}
+	writeTestFiles(t, testDir, sources)
+
+	var files []string
+	for file := range sources {
+		if file != "go.mod" {
+			files = append(files, file)
+		}
+	}
+	return testDir, files
+}
+
+func TestCallNeighborhood(t *testing.T) {
+	testDir, files := writeCallGraphModule(t)
+
+	tests := []struct {
+		name     string
+		around   string
+		hops     int
+		expected []string
+	}{
+		{"Defining file only", "server.Server.Run", 0, []string{"internal/server/server.go"}},
+		{"Direct callers and callees", "server.Server.Run", 1, []string{"cmd/api/main.go", "internal/server/handle.go", "internal/server/server.go"}},
+		{
+			"Two hops follow method calls into imported packages",
+			"server.Server.Run", 2,
+			[]string{"cmd/api/main.go", "internal/server/handle.go", "internal/server/server.go", "internal/store/store.go"},
+		},
+		{"Callers only go up", "store.Store.Get", 2, []string{"internal/server/handle.go", "internal/server/server.go", "internal/store/encode.go", "internal/store/store.go"}},
+		{"Key with package directory", "internal/store.encode", 1, []string{"internal/store/encode.go", "internal/store/store.go"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			neighborhood, err := callNeighborhood(testDir, files, tc.around, tc.hops)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, neighborhood)
+		})
+	}
+
+	_, err := callNeighborhood(testDir, files, "server.Missing", 1)
+	assert.EqualError(t, err, "function server.Missing not found among the discovered Go files (use package.Func or package.Type.Method)")
+}
+
+func TestDepsAroundCommand(t *testing.T) {
+	testDir, _ := writeCallGraphModule(t)
+	defer os.Remove(fileListName)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"deps", "-around", "store.Store.Get", "-hops", "1", testDir}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "Found 3 files within 1 hops of store.Store.Get")
+	assert.Equal(t, fileListText(t, testDir, "-around=store.Store.Get", "internal/server/handle.go", "internal/store/encode.go", "internal/store/store.go"), ReadTestFile(t, fileListName))
+}
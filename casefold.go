@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Supported -case modes
+var caseModes = []string{"auto", "sensitive", "insensitive"}
+
+// resolveIgnoreCase decides whether gitignore patterns and tool file names
+// are matched case-insensitively under root. The "auto" mode asks the
+// filesystem.
+func resolveIgnoreCase(mode, root string) (bool, error) {
+	switch mode {
+	case "", "auto":
+		return isCaseInsensitiveFS(root), nil
+	case "sensitive":
+		return false, nil
+	case "insensitive":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown case mode %q (use %s)", mode, strings.Join(caseModes, ", "))
+	}
+}
+
+// isCaseInsensitiveFS reports whether the filesystem holding dir treats names
+// case-insensitively, as the default macOS and Windows filesystems do. It
+// looks up the closest path component containing letters with its case
+// swapped and checks whether that resolves to the same file.
+func isCaseInsensitiveFS(dir string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	for current := absDir; ; current = filepath.Dir(current) {
+		name := filepath.Base(current)
+		swapped := swapCase(name)
+		if swapped != name {
+			original, err := os.Stat(current)
+			if err != nil {
+				return false
+			}
+			other, err := os.Stat(filepath.Join(filepath.Dir(current), swapped))
+			return err == nil && os.SameFile(original, other)
+		}
+		if parent := filepath.Dir(current); parent == current {
+			return false
+		}
+	}
+}
+
+// swapCase turns upper case letters into lower case ones and vice versa
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveIgnoreCase(t *testing.T) {
+	ignoreCase, err := resolveIgnoreCase("sensitive", ".")
+	require.NoError(t, err)
+	assert.False(t, ignoreCase)
+
+	ignoreCase, err = resolveIgnoreCase("insensitive", ".")
+	require.NoError(t, err)
+	assert.True(t, ignoreCase)
+
+	_, err = resolveIgnoreCase("sometimes", ".")
+	assert.Error(t, err)
+
+	if runtime.GOOS == "linux" {
+		assert.False(t, isCaseInsensitiveFS(t.TempDir()), "Linux temp directories are case-sensitive")
+	}
+}
+
+func TestSwapCase(t *testing.T) {
+	assert.Equal(t, "sKUKOZH-1", swapCase("Skukozh-1"))
+	assert.Equal(t, "123", swapCase("123"))
+}
+
+func TestFindFilesCaseMode(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		".gitignore":     "*.log\ngenerated/\n",
+		"README.MD":      "readme",
+		"MAIN.GO":        "package main",
+		"DEBUG.LOG":      "log",
+		"Generated/x.md": "generated",
+	})
+
+	t.Run("Sensitive", func(t *testing.T) {
+		files, err := findFilesWithOptions(testDir, []string{".md", ".go", ".log"}, findOptions{caseMode: "sensitive"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"DEBUG.LOG", "Generated/x.md", "MAIN.GO", "README.MD"}, files)
+	})
+
+	t.Run("Insensitive", func(t *testing.T) {
+		files, err := findFilesWithOptions(testDir, []string{".md", ".go", ".log"}, findOptions{caseMode: "insensitive"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"MAIN.GO", "README.MD"}, files)
+	})
+}
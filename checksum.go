@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	checksumHeader = "#CHECKSUMS\n"
+	checksumFooter = "#END CHECKSUMS\n"
+)
+
+// fileChecksum pairs a bundle path with the SHA-256 of its source file
+type fileChecksum struct {
+	path string
+	sum  string
+}
+
+// sha256Hex returns the hex-encoded SHA-256 digest of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checksumSection renders the integrity footer for a bundle body
+func checksumSection(body string, sums []fileChecksum) string {
+	var out strings.Builder
+	out.WriteString(checksumHeader)
+	for _, sum := range sums {
+		fmt.Fprintf(&out, "sha256 %s  %s\n", sum.sum, sum.path)
+	}
+	fmt.Fprintf(&out, "#BUNDLE sha256 %s\n", sha256Hex([]byte(body)))
+	out.WriteString(checksumFooter)
+	return out.String()
+}
+
+// parseChecksumSection splits a bundle into its body and the recorded
+// per-file and bundle checksums
+func parseChecksumSection(content string) (body string, sums []fileChecksum, bundleSum string, err error) {
+	idx := strings.LastIndex(content, checksumHeader)
+	if idx == -1 || (idx > 0 && content[idx-1] != '\n') {
+		return "", nil, "", fmt.Errorf("no checksum footer found (generate the bundle with -checksum)")
+	}
+
+	body = content[:idx]
+	for _, line := range strings.Split(content[idx+len(checksumHeader):], "\n") {
+		switch {
+		case strings.HasPrefix(line, "sha256 "):
+			fields := strings.SplitN(strings.TrimPrefix(line, "sha256 "), "  ", 2)
+			if len(fields) != 2 {
+				return "", nil, "", fmt.Errorf("malformed checksum line: %s", line)
+			}
+			sums = append(sums, fileChecksum{path: fields[1], sum: fields[0]})
+		case strings.HasPrefix(line, "#BUNDLE sha256 "):
+			bundleSum = strings.TrimPrefix(line, "#BUNDLE sha256 ")
+		}
+	}
+
+	if bundleSum == "" {
+		return "", nil, "", fmt.Errorf("checksum footer has no bundle checksum")
+	}
+	return body, sums, bundleSum, nil
+}
+
+// verifyBundle checks a bundle's integrity and compares its files against
+// the sources in baseDir, returning a report and the number of problems found
+func verifyBundle(content, baseDir string) (string, int, error) {
+	body, sums, bundleSum, err := parseChecksumSection(content)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var out strings.Builder
+	problems := 0
+
+	if sha256Hex([]byte(body)) == bundleSum {
+		out.WriteString("Bundle checksum: OK\n")
+	} else {
+		out.WriteString("Bundle checksum: MISMATCH (the result file was modified after generation)\n")
+		problems++
+	}
+
+	for _, sum := range sums {
+		data, err := os.ReadFile(filepath.Join(baseDir, sum.path))
+		switch {
+		case err != nil:
+			fmt.Fprintf(&out, "MISSING   %s\n", sum.path)
+			problems++
+		case sha256Hex(data) != sum.sum:
+			fmt.Fprintf(&out, "CHANGED   %s\n", sum.path)
+			problems++
+		default:
+			fmt.Fprintf(&out, "OK        %s\n", sum.path)
+		}
+	}
+
+	fmt.Fprintf(&out, "Verified %d files, %d problems found\n", len(sums), problems)
+	return out.String(), problems, nil
+}
+
+// verifyResultFile validates the result file against baseDir and returns the exit code
+func verifyResultFile(baseDir string) int {
+	content, err := readResultFile()
+	if err != nil {
+		fmt.Printf("Error reading result file: %v\n", err)
+		return 1
+	}
+
+	report, problems, err := verifyBundle(string(content), baseDir)
+	if err != nil {
+		fmt.Printf("Error verifying result file: %v\n", err)
+		return 1
+	}
+
+	fmt.Print(report)
+	if problems > 0 {
+		return 1
+	}
+	return 0
+}
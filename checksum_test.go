@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumRoundTrip(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("file1.go\nfile2.js"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{checksum: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#CHECKSUMS\n")
+	assert.Contains(t, result, "  file1.go\n")
+	assert.Contains(t, result, "#BUNDLE sha256 ")
+
+	t.Run("Unchanged directory verifies", func(t *testing.T) {
+		report, problems, err := verifyBundle(result, testDir)
+		require.NoError(t, err)
+		assert.Equal(t, 0, problems, report)
+		assert.Contains(t, report, "Bundle checksum: OK")
+	})
+
+	t.Run("Changed source file is reported", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(testDir, "file2.js"), []byte("changed"), 0644); err != nil {
+			t.Fatalf("Failed to modify file: %v", err)
+		}
+		report, problems, err := verifyBundle(result, testDir)
+		require.NoError(t, err)
+		assert.Equal(t, 1, problems)
+		assert.Contains(t, report, "CHANGED   file2.js")
+	})
+
+	t.Run("Tampered bundle is reported", func(t *testing.T) {
+		tampered := strings.Replace(result, "package main", "package evil", 1)
+		report, _, err := verifyBundle(tampered, testDir)
+		require.NoError(t, err)
+		assert.Contains(t, report, "Bundle checksum: MISMATCH")
+	})
+
+	t.Run("Bundle without footer is rejected", func(t *testing.T) {
+		_, _, err := verifyBundle("#FILE a.go\n", testDir)
+		assert.Error(t, err)
+	})
+}
+
+func TestVerifyCommand(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("file1.go"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+	defer os.Remove("skukozh_result.txt")
+
+	CaptureOutput(t, func() {
+		generateContentFile(testDir, genOptions{checksum: true})
+	})
+
+	flagSet := DefaultFlags()
+	flagSet.Parse([]string{"verify", testDir})
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 0, exitCode, output)
+	assert.Contains(t, output, "Verified 1 files, 0 problems found")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// File the chunk command writes its JSONL output to
+const chunksName = "skukozh_chunks.jsonl"
+
+// chunkOptions holds the settings of the chunk command
+type chunkOptions struct {
+	maxTokens int // estimated tokens per chunk
+	overlap   int // estimated tokens repeated from the end of the previous chunk
+}
+
+// chunkOptionsFromFlags builds chunk options from the provided FlagSet
+func chunkOptionsFromFlags(fs *flag.FlagSet) chunkOptions {
+	maxTokens, _ := strconv.Atoi(fs.Lookup("chunk-tokens").Value.String())
+	overlap, _ := strconv.Atoi(fs.Lookup("overlap").Value.String())
+	return chunkOptions{
+		maxTokens: maxTokens,
+		overlap:   overlap,
+	}
+}
+
+// validate checks that the chunk size and overlap make sense together
+func (o chunkOptions) validate() error {
+	if o.maxTokens <= 0 {
+		return fmt.Errorf("-chunk-tokens must be positive")
+	}
+	if o.overlap < 0 || o.overlap >= o.maxTokens {
+		return fmt.Errorf("-overlap must be between 0 and -chunk-tokens - 1")
+	}
+	return nil
+}
+
+// chunkRecord is one line of the JSONL output. Line numbers are 1-based and
+// count the lines of the file as they appear in the bundle.
+type chunkRecord struct {
+	ID        string `json:"id"`
+	File      string `json:"file"`
+	Language  string `json:"language,omitempty"`
+	Chunk     int    `json:"chunk"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Tokens    int    `json:"tokens"`
+	Text      string `json:"text"`
+}
+
+// lineRange is a half-open range of line indexes
+type lineRange struct {
+	start, end int
+}
+
+// chunkLines splits lines into ranges of at most maxTokens estimated tokens,
+// each starting with up to overlap tokens of lines from the end of the
+// previous range. Chunks never split a line, so a single line above the
+// limit makes a chunk of its own.
+func chunkLines(lines []string, maxTokens, overlap int) []lineRange {
+	var ranges []lineRange
+	for start := 0; start < len(lines); {
+		end, tokens := start, 0
+		for end < len(lines) {
+			lineTokens := estimateTokens(lines[end] + "\n")
+			if end > start && tokens+lineTokens > maxTokens {
+				break
+			}
+			tokens += lineTokens
+			end++
+		}
+		ranges = append(ranges, lineRange{start, end})
+		if end == len(lines) {
+			break
+		}
+
+		// Step back over the overlap, but always make progress
+		next, overlapTokens := end, 0
+		for next > start+1 {
+			lineTokens := estimateTokens(lines[next-1] + "\n")
+			if overlapTokens+lineTokens > overlap {
+				break
+			}
+			overlapTokens += lineTokens
+			next--
+		}
+		start = next
+	}
+	return ranges
+}
+
+// chunkBundle splits every file section of a bundle into chunk records
+func chunkBundle(content string, opts chunkOptions) []chunkRecord {
+	var records []chunkRecord
+	for _, section := range parseBundleSections(content) {
+		text := strings.TrimSuffix(section.content, "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		lines := strings.Split(text, "\n")
+		for i, r := range chunkLines(lines, opts.maxTokens, opts.overlap) {
+			chunkText := strings.Join(lines[r.start:r.end], "\n")
+			records = append(records, chunkRecord{
+				ID:        fmt.Sprintf("%s#%d", section.path, i),
+				File:      section.path,
+				Language:  section.language,
+				Chunk:     i,
+				StartLine: r.start + 1,
+				EndLine:   r.end,
+				Tokens:    estimateTokens(chunkText),
+				Text:      chunkText,
+			})
+		}
+	}
+	return records
+}
+
+// chunkResultFileInternal splits the result file into chunks, writes them
+// to the chunks file and returns a report
+func chunkResultFileInternal(opts chunkOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	content, err := readResultFile()
+	if err != nil {
+		return "", err
+	}
+
+	records := chunkBundle(string(content), opts)
+
+	var out strings.Builder
+	files := make(map[string]bool)
+	encoder := json.NewEncoder(&out)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return "", err
+		}
+		files[record.File] = true
+	}
+	if err := writeFileAtomic(besideResult(chunksName), []byte(out.String()), 0644); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Wrote %d chunks from %d files to %s\n", len(records), len(files), besideResult(chunksName)), nil
+}
+
+// chunkResultFile splits the result file into JSONL chunks
+func chunkResultFile(opts chunkOptions) {
+	report, err := chunkResultFileInternal(opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+
+	statusf("%s", report)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkLines(t *testing.T) {
+	// Every line is "xxxxxxx" plus a newline, two estimated tokens
+	lines := strings.Split(strings.Repeat("xxxxxxx\n", 10), "\n")[:10]
+
+	t.Run("Without overlap", func(t *testing.T) {
+		assert.Equal(t, []lineRange{{0, 3}, {3, 6}, {6, 9}, {9, 10}}, chunkLines(lines, 6, 0))
+	})
+
+	t.Run("With overlap", func(t *testing.T) {
+		assert.Equal(t, []lineRange{{0, 4}, {3, 7}, {6, 10}}, chunkLines(lines, 8, 2))
+	})
+
+	t.Run("Overlap always makes progress", func(t *testing.T) {
+		assert.Equal(t, []lineRange{{0, 2}, {1, 3}, {2, 4}}, chunkLines(lines[:4], 4, 3))
+	})
+
+	t.Run("Long line gets its own chunk", func(t *testing.T) {
+		long := []string{"a", strings.Repeat("y", 100), "b"}
+		assert.Equal(t, []lineRange{{0, 1}, {1, 2}, {2, 3}}, chunkLines(long, 10, 0))
+	})
+}
+
+func TestChunkResultFile(t *testing.T) {
+	defer os.Remove(resultName)
+	defer os.Remove(chunksName)
+
+	var lines []string
+	for i := 1; i <= 6; i++ {
+		lines = append(lines, strings.Repeat("x", 7))
+	}
+	bundle := "#FILE big.go\n#TYPE go\n#START\n```go\n" + strings.Join(lines, "\n") + "\n```\n#END\n\n" +
+		"#FILE small.md\n#TYPE md\n#START\n```markdown\n# Title\n```\n#END\n\n" +
+		"#FILE empty.txt\n#TYPE txt\n#START\n```text\n\n```\n#END\n\n"
+	require.NoError(t, os.WriteFile(resultName, []byte(bundle), 0644))
+
+	report, err := chunkResultFileInternal(chunkOptions{maxTokens: 8, overlap: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "Wrote 3 chunks from 2 files to "+chunksName+"\n", report)
+
+	var records []chunkRecord
+	for _, line := range strings.Split(strings.TrimSpace(ReadTestFile(t, chunksName)), "\n") {
+		var record chunkRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 3)
+
+	assert.Equal(t, chunkRecord{
+		ID: "big.go#0", File: "big.go", Language: "go", Chunk: 0,
+		StartLine: 1, EndLine: 4, Tokens: 8, Text: strings.Join(lines[:4], "\n"),
+	}, records[0])
+	assert.Equal(t, "big.go#1", records[1].ID)
+	assert.Equal(t, 4, records[1].StartLine)
+	assert.Equal(t, 6, records[1].EndLine)
+	assert.Equal(t, chunkRecord{
+		ID: "small.md#0", File: "small.md", Language: "markdown", Chunk: 0,
+		StartLine: 1, EndLine: 1, Tokens: 2, Text: "# Title",
+	}, records[2])
+
+	t.Run("Invalid options", func(t *testing.T) {
+		_, err := chunkResultFileInternal(chunkOptions{maxTokens: 0})
+		assert.ErrorContains(t, err, "-chunk-tokens")
+		_, err = chunkResultFileInternal(chunkOptions{maxTokens: 10, overlap: 10})
+		assert.ErrorContains(t, err, "-overlap")
+	})
+}
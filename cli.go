@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Short command aliases
+var commandAliases = map[string]string{
+	"f": "find",
+	"g": "gen",
+	"a": "analyze",
+}
+
+var findFlagNames = []string{
+	"ext", "not-ext", "grep", "grep-v", "newer", "max-depth", "known-files",
+	"no-ignore", "hidden", "verbose", "format", "case", "profile", "config", "detect", "no-tests", "tests-only", "with-subjects",
+	"with-docs", "root-files", "include-minified", "include-xml", "only-dirs", "ignore-dirs", "treat-as-text", "treat-as-binary",
+	"coverage", "coverage-below", "coverage-functions",
+}
+
+// Flags accepted after each command name
+var commandFlags = map[string][]string{
+	"find":  append([]string{"fail-on-errors"}, findFlagNames...),
+	"deps":  append([]string{"seed", "around", "hops"}, findFlagNames...),
+	"why":   findFlagNames,
+	"bench": findFlagNames,
+	"gen": {
+		"max-file-tokens", "preview-lines", "sample-rows", "normalize-eol", "expand-tabs", "strip-license-headers", "strip-front-matter", "notebook-markdown", "collapse-imports", "condense-schemas", "git-meta", "blame", "annotate",
+		"toc", "ids", "summary", "dependencies", "squash-migrations", "reference-locale", "meta", "deterministic", "checksum", "outline", "go-api-only", "go-strip-private", "order", "priority", "only", "skip", "all-profiles", "incremental", "no-cache", "compress",
+		"encrypt", "passphrase-file", "upload", "review", "config", "findings", "processors", "template", "prompt", "prompt-file", "attach", "prompt-suffix",
+		"prompt-suffix-file", "format",
+	},
+	"analyze":        {"count", "sort", "count-mode", "suggest", "target", "no-cache", "loc", "complexity", "list", "models", "format", "fail-over-tokens", "fail-over-size"},
+	"docs":           {},
+	"chunk":          {"chunk-tokens", "overlap"},
+	"ask":            {"provider", "model", "base-url", "max-tokens", "context-tokens", "prompt"},
+	"prompts":        {},
+	"verify":         {},
+	"preset":         {"filters-only"},
+	"cache":          {},
+	"diff":           {"unified"},
+	"decrypt":        {"passphrase-file"},
+	"locate":         {},
+	"extract-symbol": {},
+	"mcp":            {},
+}
+
+// Positional arguments of each command, shown in its help
+var commandArgs = map[string]string{
+	"find":           "<directory> [-- <path>...]",
+	"deps":           "<directory>",
+	"why":            "<directory> <path>",
+	"bench":          "<directory>",
+	"gen":            "[<directory>]",
+	"docs":           "[<directory>]",
+	"analyze":        "[<directory>]",
+	"chunk":          "",
+	"ask":            "[<question>]",
+	"prompts":        "[<name>]",
+	"verify":         "[<directory>]",
+	"preset":         "save|use|list [<name>] [<directory>]",
+	"cache":          "clean [<directory>]",
+	"diff":           "<old_result> <new_result>",
+	"decrypt":        "<file> [<output>]",
+	"locate":         "<id>",
+	"extract-symbol": "<file> <symbol>",
+	"mcp":            "",
+}
+
+// commandName resolves a command alias to the full command name
+func commandName(command string) string {
+	if name, ok := commandAliases[command]; ok {
+		return name
+	}
+	return command
+}
+
+// parseCommandArgs parses the arguments following a command name. Only the
+// flags of that command are accepted; they share their values with fs, so the
+// rest of the code reads them from fs no matter where they were given. Flags
+// and positional arguments may be mixed, and "--" ends flag parsing.
+func parseCommandArgs(fs *flag.FlagSet, command string, args []string) ([]string, error) {
+	sub := flag.NewFlagSet("skukozh "+command, flag.ContinueOnError)
+	sub.SetOutput(os.Stdout)
+	for _, name := range commandFlags[command] {
+		f := fs.Lookup(name)
+		sub.Var(f.Value, f.Name, f.Usage)
+	}
+	sub.Usage = func() {
+		fmt.Fprintln(sub.Output(), strings.TrimSpace("Usage: skukozh "+command+" [flags] "+commandArgs[command]))
+		if len(commandFlags[command]) > 0 {
+			fmt.Fprintln(sub.Output(), "\nFlags:")
+			sub.PrintDefaults()
+		}
+	}
+
+	var positional []string
+	for {
+		if err := sub.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := sub.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
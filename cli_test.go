@@ -2,6 +2,8 @@ package main
 
 import (
 	"flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"os"
 	"path/filepath"
@@ -294,6 +296,64 @@ func TestFlagIsolation(t *testing.T) {
 	os.Remove(file2)
 }
 
+func TestParseCommandArgs(t *testing.T) {
+	t.Run("Flags before and after positional arguments", func(t *testing.T) {
+		fs := DefaultFlags()
+		args, err := parseCommandArgs(fs, "find", []string{"--ext", "go", "src", "-hidden"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"src"}, args)
+		assert.Equal(t, "go", fs.Lookup("ext").Value.String())
+		assert.Equal(t, "true", fs.Lookup("hidden").Value.String())
+	})
+
+	t.Run("Double dash ends flag parsing", func(t *testing.T) {
+		fs := DefaultFlags()
+		args, err := parseCommandArgs(fs, "find", []string{"--", "-dir"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"-dir"}, args)
+	})
+
+	t.Run("Flags of other commands are rejected", func(t *testing.T) {
+		fs := DefaultFlags()
+		output := CaptureOutput(t, func() {
+			_, err := parseCommandArgs(fs, "analyze", []string{"--ext", "go"})
+			assert.Error(t, err)
+		})
+		assert.Contains(t, output, "flag provided but not defined: -ext")
+		assert.Contains(t, output, "Usage: skukozh analyze [flags]")
+	})
+}
+
+func TestSubcommandFlags(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	defer os.Remove("skukozh_file_list.txt")
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"f", "--ext", "php", testDir}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "Found 1 files")
+
+	content := ReadTestFile(t, "skukozh_file_list.txt")
+	assert.Equal(t, fileListText(t, testDir, "-ext=php", "subdir/file4.php"), content)
+
+	t.Run("Help exits cleanly", func(t *testing.T) {
+		flagSet := DefaultFlags()
+		require.NoError(t, flagSet.Parse([]string{"gen", "-h"}))
+		output := CaptureOutput(t, func() {
+			exitCode = runWithFlags(flagSet)
+		})
+		assert.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "-max-file-tokens")
+		assert.NotContains(t, output, "-seed")
+	})
+}
+
 // Add a suite-based test to demonstrate testify suite functionality
 type CLISuite struct {
 	suite.Suite
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Import sections with fewer imports are left alone by -collapse-imports
+const collapseImportsMin = 5
+
+// Number of imports named on a collapsed import line
+const collapseImportsShown = 3
+
+// Languages accepted by -collapse-imports, mapped to the fence languages
+// they cover
+var collapseImportLanguages = map[string][]string{
+	"go":   {"go"},
+	"java": {"java"},
+	"ts":   {"typescript", "tsx", "javascript", "jsx"},
+}
+
+var (
+	goImportSpec   = regexp.MustCompile(`^\s*(?:import\s+)?([\w.]+\s+)?"([^"]+)"`)
+	javaImportLine = regexp.MustCompile(`^import\s+(?:static\s+)?([\w.]+(?:\.\*)?)\s*;`)
+	jsImportSource = regexp.MustCompile(`(?:\bfrom\s*|^import\s*|\brequire\(\s*)['"]([^'"]+)['"]`)
+)
+
+// parseCollapseImports validates a -collapse-imports value: a
+// comma-separated list of go, java and ts, or all
+func parseCollapseImports(value string) ([]string, error) {
+	var languages []string
+	for _, item := range splitList(strings.ToLower(value)) {
+		switch {
+		case item == "all":
+			for _, name := range []string{"go", "java", "ts"} {
+				languages = append(languages, collapseImportLanguages[name]...)
+			}
+		case collapseImportLanguages[item] != nil:
+			languages = append(languages, collapseImportLanguages[item]...)
+		default:
+			return nil, fmt.Errorf("unknown -collapse-imports language %q (use go, java, ts or all)", item)
+		}
+	}
+	return languages, nil
+}
+
+// collapseImports replaces the import section of a file with a single
+// comment line naming the first imports, when the file's language is
+// among languages and the section holds at least collapseImportsMin imports
+func collapseImports(file string, content []byte, languages []string) ([]byte, bool) {
+	language := fenceLanguage(file, content)
+	if !contains(languages, language) {
+		return content, false
+	}
+
+	var parse func(lines []string, i int) (int, []string, bool)
+	switch language {
+	case "go":
+		parse = goImportStatement
+	case "java":
+		parse = javaImportStatement
+	default:
+		parse = jsImportStatement
+	}
+
+	lines := strings.Split(string(content), "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "import") {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return content, false
+	}
+
+	// The section runs over import statements and the blank lines between them
+	var names []string
+	end := start
+	for i := start; i < len(lines); {
+		if strings.TrimSpace(lines[i]) == "" {
+			i++
+			continue
+		}
+		next, statementNames, ok := parse(lines, i)
+		if !ok {
+			break
+		}
+		names = append(names, statementNames...)
+		end = next
+		i = next
+	}
+	if len(names) < collapseImportsMin {
+		return content, false
+	}
+
+	summary := "// imports: " + strings.Join(names[:collapseImportsShown], ", ")
+	summary += fmt.Sprintf(", +%d more", len(names)-collapseImportsShown)
+	collapsed := append(append(append([]string{}, lines[:start]...), summary), lines[end:]...)
+	return []byte(strings.Join(collapsed, "\n")), true
+}
+
+// goImportStatement parses the Go import declaration at line i, returning
+// the index of the line after it and the names of the imported packages
+func goImportStatement(lines []string, i int) (int, []string, bool) {
+	line := strings.TrimSpace(lines[i])
+	if !strings.HasPrefix(line, "import") {
+		return i, nil, false
+	}
+	if strings.TrimSpace(strings.TrimPrefix(line, "import")) != "(" {
+		match := goImportSpec.FindStringSubmatch(line)
+		if match == nil {
+			return i, nil, false
+		}
+		return i + 1, []string{goImportName(match)}, true
+	}
+
+	var names []string
+	for j := i + 1; j < len(lines); j++ {
+		spec := strings.TrimSpace(lines[j])
+		if spec == ")" {
+			return j + 1, names, true
+		}
+		if match := goImportSpec.FindStringSubmatch(spec); match != nil {
+			names = append(names, goImportName(match))
+		}
+	}
+	return i, nil, false // unterminated
+}
+
+// goImportName names an import spec by its alias or the last element of its path
+func goImportName(match []string) string {
+	if alias := strings.TrimSpace(match[1]); alias != "" && alias != "_" && alias != "." {
+		return alias
+	}
+	return path.Base(match[2])
+}
+
+// javaImportStatement parses the Java import at line i, named by the
+// imported class or member, or the package for wildcard imports
+func javaImportStatement(lines []string, i int) (int, []string, bool) {
+	match := javaImportLine.FindStringSubmatch(strings.TrimSpace(lines[i]))
+	if match == nil {
+		return i, nil, false
+	}
+	name := match[1]
+	if !strings.HasSuffix(name, ".*") {
+		name = name[strings.LastIndex(name, ".")+1:]
+	}
+	return i + 1, []string{name}, true
+}
+
+// jsImportStatement parses the JS/TS import at line i, which may span
+// several lines, named by its module specifier
+func jsImportStatement(lines []string, i int) (int, []string, bool) {
+	line := strings.TrimSpace(lines[i])
+	if !strings.HasPrefix(line, "import ") && !strings.HasPrefix(line, "import{") && !strings.HasPrefix(line, "import'") && !strings.HasPrefix(line, `import"`) {
+		return i, nil, false
+	}
+	for j := i; j < len(lines); j++ {
+		if match := jsImportSource.FindStringSubmatch(strings.TrimSpace(lines[j])); match != nil {
+			return j + 1, []string{match[1]}, true
+		}
+		if j > i && strings.HasPrefix(strings.TrimSpace(lines[j]), "import") {
+			break // the previous statement had no module specifier
+		}
+	}
+	return i, nil, false
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCollapseImports(t *testing.T) {
+	languages, err := parseCollapseImports("go, TS")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "typescript", "tsx", "javascript", "jsx"}, languages)
+
+	languages, err = parseCollapseImports("all")
+	require.NoError(t, err)
+	assert.Contains(t, languages, "java")
+
+	_, err = parseCollapseImports("go,python")
+	assert.EqualError(t, err, `unknown -collapse-imports language "python" (use go, java, ts or all)`)
+}
+
+func TestCollapseImports(t *testing.T) {
+	all, err := parseCollapseImports("all")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{
+			name:    "go block",
+			file:    "main.go",
+			content: "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\n\t\"strings\"\n\tpb \"example.com/api/v1\"\n\t_ \"embed\"\n\t\"net/http\"\n)\n\nfunc main() {}\n",
+			want:    "package main\n\n// imports: fmt, os, strings, +3 more\n\nfunc main() {}\n",
+		},
+		{
+			name:    "go short block",
+			file:    "main.go",
+			content: "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n",
+			want:    "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n",
+		},
+		{
+			name:    "java",
+			file:    "App.java",
+			content: "package app;\n\nimport java.util.List;\nimport java.util.Map;\nimport java.io.*;\n\nimport static org.junit.Assert.assertEquals;\nimport com.example.Service;\n\npublic class App {}\n",
+			want:    "package app;\n\n// imports: List, Map, java.io.*, +2 more\n\npublic class App {}\n",
+		},
+		{
+			name:    "typescript",
+			file:    "app.ts",
+			content: "import React from 'react';\nimport {\n  useState,\n  useEffect,\n} from \"react\";\nimport './styles.css';\nimport type { User } from './user';\nimport fs = require('fs');\n\nexport const x = 1;\n",
+			want:    "// imports: react, react, ./styles.css, +2 more\n\nexport const x = 1;\n",
+		},
+		{
+			name:    "unselected language",
+			file:    "app.py",
+			content: "import os\nimport sys\nimport re\nimport json\nimport time\n",
+			want:    "import os\nimport sys\nimport re\nimport json\nimport time\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _ := collapseImports(tc.file, []byte(tc.content), all)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+
+	// Only the selected languages are collapsed
+	content := []byte("import a.A;\nimport a.B;\nimport a.C;\nimport a.D;\nimport a.E;\n")
+	_, collapsed := collapseImports("App.java", content, collapseImportLanguages["go"])
+	assert.False(t, collapsed)
+}
+
+func TestGenerateContentFileCollapseImports(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go": "package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"os\"\n\t\"sort\"\n\t\"strings\"\n)\n\nfunc main() {}\n",
+	})
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("main.go"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{collapseImps: collapseImportLanguages["go"]})
+	require.NoError(t, err)
+	assert.Contains(t, result, "```go\npackage main\n// imports: fmt, io, os, +2 more\nfunc main() {}\n```")
+}
+
+func TestGenCommandRejectsUnknownCollapseLanguage(t *testing.T) {
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"gen", "-collapse-imports", "cobol", "."}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, `Error: unknown -collapse-imports language "cobol"`)
+}
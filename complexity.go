@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	cStyleBranches = regexp.MustCompile(`\b(if|for|while|case|catch)\b|&&|\|\|`)
+	shellBranches  = regexp.MustCompile(`\b(if|elif|for|while|until)\b|;;|&&|\|\|`)
+)
+
+// branchPatterns matches, per fence language, the decision points counted
+// for languages without a dedicated parser
+var branchPatterns = map[string]*regexp.Regexp{
+	"javascript": cStyleBranches,
+	"typescript": cStyleBranches,
+	"jsx":        cStyleBranches,
+	"tsx":        cStyleBranches,
+	"java":       cStyleBranches,
+	"c":          cStyleBranches,
+	"cpp":        cStyleBranches,
+	"csharp":     cStyleBranches,
+	"scala":      cStyleBranches,
+	"groovy":     cStyleBranches,
+	"kotlin":     regexp.MustCompile(`\b(if|for|while|when|catch)\b|&&|\|\|`),
+	"swift":      regexp.MustCompile(`\b(if|guard|for|while|case|catch)\b|&&|\|\|`),
+	"rust":       regexp.MustCompile(`\b(if|for|while)\b|=>|&&|\|\|`),
+	"php":        regexp.MustCompile(`\b(if|elseif|for|foreach|while|case|catch|and|or)\b|&&|\|\|`),
+	"python":     regexp.MustCompile(`\b(if|elif|for|while|except|case|and|or)\b`),
+	"ruby":       regexp.MustCompile(`\b(if|elsif|unless|while|until|for|when|rescue|and|or)\b|&&|\|\|`),
+	"perl":       regexp.MustCompile(`\b(if|elsif|unless|for|foreach|while|until)\b|&&|\|\|`),
+	"lua":        regexp.MustCompile(`\b(if|elseif|for|while|repeat|and|or)\b`),
+	"bash":       shellBranches,
+	"sh":         shellBranches,
+	"zsh":        shellBranches,
+}
+
+// fileComplexity is the cyclomatic complexity estimate of a file
+type fileComplexity struct {
+	score   int
+	hotspot string // most complex function with its score, if known
+}
+
+// complexityOf estimates the cyclomatic complexity of a file. Go files are
+// measured per function on the syntax tree and the functions are summed;
+// other languages count branching keywords and operators outside comment
+// lines, plus one for the file. The second return value is false for
+// languages without support.
+func complexityOf(file, language, content string) (fileComplexity, bool) {
+	if language == "go" {
+		if complexity, ok := complexityGo(file, content); ok {
+			return complexity, true
+		}
+	}
+
+	pattern, ok := branchPatterns[language]
+	if !ok {
+		return fileComplexity{}, false
+	}
+
+	syntax := commentSyntaxes[language]
+	score := 1
+	for _, line := range strings.Split(content, "\n") {
+		if hasAnyPrefix(strings.TrimSpace(line), syntax.line) {
+			continue
+		}
+		score += len(pattern.FindAllStringIndex(line, -1))
+	}
+	return fileComplexity{score: score}, true
+}
+
+// complexityGo sums the cyclomatic complexity of the functions of a Go file
+func complexityGo(file, content string) (fileComplexity, bool) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, content, 0)
+	if err != nil {
+		return fileComplexity{}, false
+	}
+
+	var complexity fileComplexity
+	hotspotScore := 0
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		score := 1 + goDecisionPoints(fn.Body)
+		complexity.score += score
+		if score > hotspotScore {
+			hotspotScore = score
+			complexity.hotspot = fmt.Sprintf("%s (%d)", goFuncName(fn), score)
+		}
+	}
+	return complexity, true
+}
+
+// goDecisionPoints counts the branches of a function body, including those
+// of the function literals inside it
+func goDecisionPoints(body *ast.BlockStmt) int {
+	points := 0
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			points++
+		case *ast.CaseClause:
+			if n.List != nil { // default isn't a decision
+				points++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				points++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				points++
+			}
+		}
+		return true
+	})
+	return points
+}
+
+// goFuncName returns a function's name, prefixed with its receiver type for
+// methods (e.g. "Server.Run")
+func goFuncName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	switch t := recv.(type) {
+	case *ast.IndexExpr: // generic receiver
+		recv = t.X
+	case *ast.IndexListExpr:
+		recv = t.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// writeComplexity prints the files with the highest complexity estimate
+func writeComplexity(out io.Writer, files []FileInfo, topCount int) {
+	var measured []FileInfo
+	for _, file := range files {
+		if file.complexity.score > 0 {
+			measured = append(measured, file)
+		}
+	}
+	sort.Slice(measured, func(i, j int) bool {
+		if measured[i].complexity.score != measured[j].complexity.score {
+			return measured[i].complexity.score > measured[j].complexity.score
+		}
+		return measured[i].path < measured[j].path
+	})
+
+	fmt.Fprintf(out, "Top %d most complex files:\n", topCount)
+	if len(measured) == 0 {
+		fmt.Fprintln(out, "No files in a supported language.")
+		fmt.Fprintln(out, "")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "File\tComplexity\tMost complex function")
+	fmt.Fprintln(w, "────\t──────────\t─────────────────────")
+	for i, file := range measured {
+		if i >= topCount {
+			break
+		}
+		hotspot := file.complexity.hotspot
+		if hotspot == "" {
+			hotspot = "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", file.path, file.complexity.score, hotspot)
+	}
+	w.Flush()
+	fmt.Fprintln(out, "")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplexityGo(t *testing.T) {
+	content := `package a
+func simple() {}
+type Server struct{}
+func (s *Server) Run(items []int, ch chan int) {
+	for _, item := range items {
+		if item > 0 && item < 10 {
+			continue
+		}
+	}
+	switch {
+	case len(items) == 0:
+	case len(items) == 1:
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	go func() {
+		if true || false {
+		}
+	}()
+}`
+	complexity, ok := complexityOf("a.go", "go", content)
+	require.True(t, ok)
+	// simple: 1; Run: 1 + range + if + && + 2 cases + 1 comm + if + || = 9
+	assert.Equal(t, fileComplexity{score: 10, hotspot: "Server.Run (9)"}, complexity)
+
+	// Content that doesn't parse falls back to nothing for Go
+	_, ok = complexityOf("b.go", "go", "package b\nfunc {")
+	assert.False(t, ok)
+}
+
+func TestComplexityKeywords(t *testing.T) {
+	content := "def f(x):\n    # if in a comment\n    if x and not x.y:\n        return 1\n    elif x:\n        pass\n    return [i for i in x]\n"
+	complexity, ok := complexityOf("f.py", "python", content)
+	require.True(t, ok)
+	assert.Equal(t, fileComplexity{score: 5}, complexity)
+
+	complexity, ok = complexityOf("f.js", "javascript", "// if\nif (a || b) { for (;;) {} }\n")
+	require.True(t, ok)
+	assert.Equal(t, 4, complexity.score)
+
+	_, ok = complexityOf("README.md", "markdown", "if this works")
+	assert.False(t, ok)
+}
+
+func TestAnalyzeComplexity(t *testing.T) {
+	defer os.Remove(resultName)
+	bundle := "#FILE a.go\n#TYPE go\n#START\n```go\npackage a\nfunc A(x int) {\nif x > 0 {\n}\n}\n```\n#END\n\n" +
+		"#FILE b.js\n#TYPE js\n#START\n```javascript\nif (a) {} else if (b) {} while (c) {}\n```\n#END\n\n" +
+		"#FILE c.md\n#TYPE md\n#START\n```markdown\nif\n```\n#END\n\n"
+	require.NoError(t, os.WriteFile(resultName, []byte(bundle), 0644))
+
+	output, err := analyzeResultFileInternal(analyzeOptions{topCount: 5, complexity: true})
+	require.NoError(t, err)
+	assert.Contains(t, output, `Top 5 most complex files:
+File  Complexity  Most complex function
+────  ──────────  ─────────────────────
+b.js  4           -
+a.go  2           A (2)
+`)
+	assert.NotContains(t, output[strings.Index(output, "most complex files"):], "c.md")
+}
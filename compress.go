@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// compressionExts maps supported -compress methods to result file suffixes
+var compressionExts = map[string]string{
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressData compresses data with the given method. zstd relies on the
+// zstd command line tool being installed.
+func compressData(data []byte, method string) ([]byte, error) {
+	switch method {
+	case "gzip":
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "zstd":
+		return runZstd(data, "-q", "-c")
+	default:
+		return nil, fmt.Errorf("unsupported compression %q (use gzip or zstd)", method)
+	}
+}
+
+// decompressData transparently decompresses gzip and zstd data, detected by
+// their magic bytes; anything else is returned unchanged
+func decompressData(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case bytes.HasPrefix(data, zstdMagic):
+		return runZstd(data, "-q", "-d", "-c")
+	default:
+		return data, nil
+	}
+}
+
+// runZstd pipes data through the zstd command line tool
+func runZstd(data []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return nil, fmt.Errorf("zstd compression requires the zstd command: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("zstd", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("zstd failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// readResultFile reads the result file, falling back to its compressed
+// variants when the plain file doesn't exist
+func readResultFile() ([]byte, error) {
+	return readBundleFile(resultName)
+}
+
+// compressedVariants lists the names readBundleFile and openBundleFile try
+// for a result file, in order
+func compressedVariants(name string) []string {
+	return []string{name, name + ".gz", name + ".zst"}
+}
+
+// removeStaleVariants deletes the variants of the result file name other
+// than the one just written, so a plain result left by an earlier gen
+// doesn't hide a new compressed one from the readers, or the other way round
+func removeStaleVariants(name, written string) error {
+	for _, variant := range compressedVariants(name) {
+		if variant == written {
+			continue
+		}
+		if err := os.Remove(variant); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBundleFile reads a result file, falling back to its compressed
+// variants when the plain file doesn't exist
+func readBundleFile(name string) ([]byte, error) {
+	content, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		for _, variant := range compressedVariants(name)[1:] {
+			if compressed, compressedErr := os.ReadFile(variant); compressedErr == nil {
+				content, err = compressed, nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decompressData(content)
+}
+
+// openBundleFile opens a result file for streaming, falling back to its
+// compressed variants like readBundleFile and decompressing on the fly
+func openBundleFile(name string) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		for _, variant := range compressedVariants(name)[1:] {
+			if compressed, compressedErr := os.Open(variant); compressedErr == nil {
+				file, err = compressed, nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(file)
+	magic, _ := buffered.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &bundleReader{Reader: zr, close: func() error {
+			zr.Close()
+			return file.Close()
+		}}, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		if _, err := exec.LookPath("zstd"); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("zstd compression requires the zstd command: %w", err)
+		}
+		var stderr bytes.Buffer
+		cmd := exec.Command("zstd", "-q", "-d", "-c")
+		cmd.Stdin = buffered
+		cmd.Stderr = &stderr
+		stdout, err := cmd.StdoutPipe()
+		if err == nil {
+			err = cmd.Start()
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		output := &eofReader{Reader: stdout}
+		return &bundleReader{Reader: output, close: func() error {
+			defer file.Close()
+			if !output.eof {
+				// zstd blocks writing to the full pipe when the reader
+				// stops early, so Wait would never return: stop it, as
+				// the rest of its output isn't wanted
+				_ = cmd.Process.Kill()
+				_ = cmd.Wait()
+				return nil
+			}
+			if err := cmd.Wait(); err != nil {
+				return fmt.Errorf("zstd failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+			}
+			return nil
+		}}, nil
+	default:
+		return &bundleReader{Reader: buffered, close: file.Close}, nil
+	}
+}
+
+// eofReader notes whether its reader was read to the end
+type eofReader struct {
+	io.Reader
+	eof bool
+}
+
+func (r *eofReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// bundleReader streams a result file and releases what reading it needed
+type bundleReader struct {
+	io.Reader
+	close func() error
+}
+
+// Close may be called more than once; only the first call has an effect
+func (r *bundleReader) Close() error {
+	if r.close == nil {
+		return nil
+	}
+	err := r.close()
+	r.close = nil
+	return err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := []byte("#FILE main.go\n#TYPE go\n#START\n```go\npackage main\n```\n#END\n\n")
+
+	for method := range compressionExts {
+		t.Run(method, func(t *testing.T) {
+			if method == "zstd" {
+				if _, err := exec.LookPath("zstd"); err != nil {
+					t.Skip("zstd is not available")
+				}
+			}
+
+			compressed, err := compressData(data, method)
+			require.NoError(t, err)
+			assert.NotEqual(t, data, compressed)
+
+			decompressed, err := decompressData(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+		})
+	}
+
+	t.Run("Unsupported method", func(t *testing.T) {
+		_, err := compressData(data, "lzma")
+		assert.Error(t, err)
+	})
+
+	t.Run("Plain data is passed through", func(t *testing.T) {
+		plain, err := decompressData(data)
+		require.NoError(t, err)
+		assert.Equal(t, data, plain)
+	})
+}
+
+func TestAnalyzeCompressedResult(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("file1.go\nfile2.js"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+	os.Remove(resultName)
+	defer os.Remove(resultName + ".gz")
+
+	output := CaptureOutput(t, func() {
+		generateContentFile(testDir, genOptions{compress: "gzip"})
+	})
+	assert.Contains(t, output, "Content file saved to skukozh_result.txt.gz")
+	assert.False(t, FileExists(resultName), "Plain result file should not be written")
+
+	result, err := analyzeResultFileInternal(analyzeOptions{topCount: 5})
+	require.NoError(t, err)
+	assert.Contains(t, result, "file1.go")
+	assert.Contains(t, result, "file2.js")
+}
+
+func TestOpenBundleFileZstdEarlyClose(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd is not available")
+	}
+	// Far more than a pipe buffer, so zstd is still writing when reading stops
+	var data bytes.Buffer
+	for i := 0; data.Len() < 4<<20; i++ {
+		fmt.Fprintf(&data, "#FILE file%d.go\n#TYPE go\n#START\n```go\npackage main // %d\n```\n#END\n\n", i, i*7919)
+	}
+	compressed, err := compressData(data.Bytes(), "zstd")
+	require.NoError(t, err)
+	name := filepath.Join(t.TempDir(), "skukozh_result.txt.zst")
+	require.NoError(t, os.WriteFile(name, compressed, 0644))
+
+	reader, err := openBundleFile(strings.TrimSuffix(name, ".zst"))
+	require.NoError(t, err)
+	head := make([]byte, 13)
+	_, err = io.ReadFull(reader, head)
+	require.NoError(t, err)
+	assert.Equal(t, "#FILE file0.g", string(head))
+
+	closed := make(chan error, 1)
+	go func() { closed <- reader.Close() }()
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Close hung waiting for zstd")
+	}
+}
+
+func TestOpenBundleFileZstdError(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd is not available")
+	}
+	compressed, err := compressData([]byte(strings.Repeat("#FILE a.go\n", 1000)), "zstd")
+	require.NoError(t, err)
+	name := filepath.Join(t.TempDir(), "skukozh_result.txt")
+	require.NoError(t, os.WriteFile(name, compressed[:len(compressed)-4], 0644))
+
+	// A stream read to its end reports how zstd exited
+	reader, err := openBundleFile(name)
+	require.NoError(t, err)
+	_, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.ErrorContains(t, reader.Close(), "zstd failed: exit status 1")
+}
+
+func TestGenRemovesStaleResultVariants(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("file1.go"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+	require.NoError(t, os.WriteFile(resultName, []byte("#FILE stale.go\n"), 0644))
+	defer os.Remove(resultName)
+	defer os.Remove(resultName + ".gz")
+
+	CaptureOutput(t, func() {
+		generateContentFile(testDir, genOptions{compress: "gzip"})
+	})
+	assert.False(t, FileExists(resultName), "The older plain result should be removed")
+	content, err := readResultFile()
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "#FILE file1.go")
+
+	// Going back to a plain result removes the compressed one
+	CaptureOutput(t, func() {
+		generateContentFile(testDir, genOptions{})
+	})
+	assert.True(t, FileExists(resultName))
+	assert.False(t, FileExists(resultName+".gz"))
+}
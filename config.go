@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Config file read from the current directory unless -config names another
+const configName = ".skukozh.json"
+
+// config holds the settings of the config file
+type config struct {
+	Profiles       map[string]profile         `json:"profiles"`
+	OutputProfiles map[string]outputProfile   `json:"output_profiles"`
+	Processors     map[string]processorConfig `json:"processors"`
+	Hooks          map[string]string          `json:"hooks"`
+	TextExts       string                     `json:"text_exts"`   // like -treat-as-text
+	BinaryExts     string                     `json:"binary_exts"` // like -treat-as-binary
+}
+
+// loadConfig reads a config file. An empty path reads configName if it
+// exists and returns an empty config otherwise.
+func loadConfig(path string) (config, error) {
+	var cfg config
+
+	explicit := path != ""
+	if !explicit {
+		path = configName
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// configFromFlags reads the config file named by -config, or the default one
+func configFromFlags(fs *flag.FlagSet) (config, error) {
+	return loadConfig(fs.Lookup("config").Value.String())
+}
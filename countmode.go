@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Supported -count-mode values for the symbols of the analyze report
+var countModes = []string{"runes", "graphemes", "words"}
+
+// symbolCounter returns the function counting symbols in the -count-mode:
+// non-whitespace characters (runes, the default), user-perceived
+// characters (graphemes) or words
+func symbolCounter(mode string) (func(string) int, error) {
+	switch mode {
+	case "", "runes":
+		return countSymbols, nil
+	case "graphemes":
+		return countGraphemes, nil
+	case "words":
+		return countWords, nil
+	default:
+		return nil, fmt.Errorf("unknown count mode %q (use %s)", mode, strings.Join(countModes, ", "))
+	}
+}
+
+// countModeNote returns the note after the total symbols of the report,
+// naming the count mode unless it's the default
+func countModeNote(mode string) string {
+	if mode == "" || mode == "runes" {
+		return ""
+	}
+	return " (" + mode + ")"
+}
+
+// extendsGrapheme reports whether r continues the grapheme cluster of the
+// rune before it: combining marks, emoji modifiers and tags, Hangul vowel and
+// final jamo, and the rune after a zero width joiner. This covers the
+// clusters of source code and text without the full UAX #29 tables.
+func extendsGrapheme(prev, r rune) bool {
+	switch {
+	case prev == '\u200d': // zero width joiner, as in 👩‍💻
+		return true
+	case r == '\u200d':
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // emoji skin tone modifiers
+		return true
+	case r >= 0xe0020 && r <= 0xe007f: // emoji tag sequences
+		return true
+	case r >= 0x1160 && r <= 0x11ff, r >= 0xd7b0 && r <= 0xd7ff: // Hangul jamo after the leading consonant
+		return prev >= 0x1100 && prev <= 0x11ff || prev >= 0xa960 && prev <= 0xa97f || prev >= 0xd7b0 && prev <= 0xd7ff ||
+			prev >= 0xac00 && prev <= 0xd7a3
+	}
+	return false
+}
+
+// isRegionalIndicator reports whether r is half of a flag emoji
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// countGraphemes counts the grapheme clusters of text that aren't
+// whitespace, so "é" written with a combining accent, a flag or a family
+// emoji count once, as they're displayed
+func countGraphemes(text string) int {
+	count := 0
+	prev := rune(-1)
+	indicators := 0 // regional indicators in a row, paired into flags
+	for _, r := range text {
+		switch {
+		case r == '\n' && prev == '\r':
+		case prev >= 0 && extendsGrapheme(prev, r):
+		case isRegionalIndicator(r) && indicators%2 == 1:
+		default:
+			if !unicode.IsSpace(r) {
+				count++
+			}
+		}
+		if isRegionalIndicator(r) {
+			indicators++
+		} else {
+			indicators = 0
+		}
+		prev = r
+	}
+	return count
+}
+
+// isIdeograph reports whether r is written without spaces between words, so
+// each character counts as a word of its own
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// countWords counts the words of text: runs of letters, digits and
+// underscores, as in identifiers, plus one per Chinese or Japanese
+// character. Punctuation and operators aren't words.
+func countWords(text string) int {
+	count := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isIdeograph(r):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if !inWord {
+				count++
+			}
+			inWord = true
+		case inWord && unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+			// Combining marks stay part of the word
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountGraphemes(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"func main() {}", 12},
+		{"e\u0301te\u0301", 3},                                 // combining accents
+		{"日本語のコード", 7},                                         // one per character
+		{"\U0001F1EF\U0001F1F5\U0001F1EB\U0001F1F7", 2},        // flags pair regional indicators
+		{"\U0001F469\u200d\U0001F4BB \U0001F44D\U0001F3FD", 2}, // joined emoji and skin tones
+		{"\u1100\u1161\u11a8 \uac00\u11a8", 2},                 // Hangul jamo
+		{"a\r\nb", 2},                                          // whitespace isn't counted
+		{"", 0},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, countGraphemes(test.text), test.text)
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"func main() { fmt.Println(x_1) }", 5},
+		{"// 日本語のコード", 7},
+		{"사용자 이름", 2},
+		{"café naïve", 2},
+		{"== != {}", 0},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, countWords(test.text), test.text)
+	}
+}
+
+func TestAnalyzeCountMode(t *testing.T) {
+	require.NoError(t, os.WriteFile(resultName, []byte(testBundle("hello.go", "// こんにちは世界\nfunc hello() {}")), 0644))
+	defer os.Remove(resultName)
+
+	runes, err := analyzeResultFileInternal(analyzeOptions{topCount: 5})
+	require.NoError(t, err)
+	words, err := analyzeResultFileInternal(analyzeOptions{topCount: 5, countMode: "words"})
+	require.NoError(t, err)
+	assert.NotContains(t, runes, "(runes)")
+	assert.Contains(t, words, " (words)\n")
+	assert.Regexp(t, `hello\.go\s+[\d.]+\s+22\n`, runes)
+	assert.Regexp(t, `hello\.go\s+[\d.]+\s+9\n`, words)
+
+	csv, err := analyzeResultFileInternal(analyzeOptions{topCount: 5, countMode: "graphemes", format: "csv"})
+	require.NoError(t, err)
+	assert.Contains(t, csv, "hello.go,")
+
+	_, err = analyzeResultFileInternal(analyzeOptions{topCount: 5, countMode: "bytes"})
+	assert.EqualError(t, err, `unknown count mode "bytes" (use runes, graphemes, words)`)
+	_, err = analyzeFileListInternal(".", analyzeOptions{topCount: 5, countMode: "words"})
+	assert.ErrorContains(t, err, "-count-mode words needs the file content")
+}
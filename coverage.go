@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// coverageBlock is a run of lines a coverage profile reports on: a block of
+// a Go cover profile, or a single DA line of an lcov file
+type coverageBlock struct {
+	startLine, endLine int
+	statements         int
+	count              int // times the block ran
+}
+
+// coverageProfile holds the coverage blocks of each file named in a Go cover
+// profile or an lcov file, by the path the profile gives
+type coverageProfile map[string][]coverageBlock
+
+// loadCoverageProfile reads a Go cover profile, recognized by its "mode:"
+// line, or an lcov tracefile
+func loadCoverageProfile(name string) (coverageProfile, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		return parseGoCoverProfile(content)
+	case bytes.HasPrefix(trimmed, []byte("TN:")) || bytes.HasPrefix(trimmed, []byte("SF:")):
+		return parseLcov(content)
+	default:
+		return nil, fmt.Errorf("%s is neither a Go cover profile nor an lcov file", name)
+	}
+}
+
+// parseGoCoverProfile reads the blocks of a profile written by go test
+// -coverprofile: "file.go:12.30,14.2 3 1" lines after the mode line
+func parseGoCoverProfile(content []byte) (coverageProfile, error) {
+	profile := make(coverageProfile)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "mode:") {
+			continue
+		}
+		var file string
+		var block coverageBlock
+		var startCol, endCol int
+		colon := strings.LastIndex(text, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: invalid cover profile block %q", line, text)
+		}
+		file = text[:colon]
+		if _, err := fmt.Sscanf(text[colon+1:], "%d.%d,%d.%d %d %d", &block.startLine, &startCol, &block.endLine, &endCol, &block.statements, &block.count); err != nil {
+			return nil, fmt.Errorf("line %d: invalid cover profile block %q", line, text)
+		}
+		profile[file] = append(profile[file], block)
+	}
+	return profile, scanner.Err()
+}
+
+// parseLcov reads the line records of an lcov tracefile: each DA:line,hits
+// line of an SF: section counts as a one-statement block
+func parseLcov(content []byte) (coverageProfile, error) {
+	profile := make(coverageProfile)
+	file := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(text, "SF:"):
+			file = filepath.ToSlash(strings.TrimPrefix(text, "SF:"))
+			if _, ok := profile[file]; !ok {
+				profile[file] = nil
+			}
+		case text == "end_of_record":
+			file = ""
+		case strings.HasPrefix(text, "DA:") && file != "":
+			fields := strings.Split(strings.TrimPrefix(text, "DA:"), ",")
+			lineNumber, err1 := strconv.Atoi(fields[0])
+			var hits int
+			var err2 error
+			if len(fields) > 1 {
+				hits, err2 = strconv.Atoi(fields[1])
+			}
+			if len(fields) < 2 || err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("line %d: invalid lcov record %q", line, text)
+			}
+			profile[file] = append(profile[file], coverageBlock{startLine: lineNumber, endLine: lineNumber, statements: 1, count: hits})
+		}
+	}
+	return profile, scanner.Err()
+}
+
+// byFile matches the files of the profile to the found files, which are
+// relative to the walked root while profiles name Go files by import path
+// and lcov files often by absolute path: a profile file belongs to the
+// longest found path it ends with
+func (p coverageProfile) byFile(files []string) map[string][]coverageBlock {
+	sorted := append([]string{}, files...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	matched := make(map[string][]coverageBlock)
+	for name, blocks := range p {
+		name = strings.TrimPrefix(name, "./")
+		for _, file := range sorted {
+			if name == file || strings.HasSuffix(name, "/"+file) {
+				matched[file] = append(matched[file], blocks...)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// coveragePercent returns the share of statements of blocks that ran, in
+// percent, and false when blocks have no statements
+func coveragePercent(blocks []coverageBlock) (float64, bool) {
+	total, covered := 0, 0
+	for _, block := range blocks {
+		total += block.statements
+		if block.count > 0 {
+			covered += block.statements
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return 100 * float64(covered) / float64(total), true
+}
+
+// filterCoverage keeps the files whose coverage in the -coverage profile is
+// below threshold, reporting the others to skip
+func filterCoverage(files []string, profile coverageProfile, threshold float64, skip func(path, reason string)) []string {
+	coverage := profile.byFile(files)
+	var kept []string
+	for _, file := range files {
+		percent, ok := coveragePercent(coverage[file])
+		switch {
+		case !ok:
+			skip(file, "not in the -coverage profile")
+		case percent >= threshold:
+			skip(file, fmt.Sprintf("coverage at or above -coverage-below (%.1f%%)", percent))
+		default:
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// uncoveredFunctions replaces every Go file of files with entries naming
+// its functions and methods whose coverage is below threshold, such as
+// server.go#Server.Run, for gen to extract. Other files, and Go files that
+// can't be parsed, are kept whole.
+func uncoveredFunctions(root string, files []string, profile coverageProfile, threshold float64) []string {
+	coverage := profile.byFile(files)
+	var entries []string
+	for _, file := range files {
+		if filepath.Ext(file) != ".go" {
+			entries = append(entries, file)
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			entries = append(entries, file)
+			continue
+		}
+		fset := token.NewFileSet()
+		parsed, err := parser.ParseFile(fset, file, content, 0)
+		if err != nil {
+			entries = append(entries, file)
+			continue
+		}
+		for _, decl := range parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			start, end := fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+			var blocks []coverageBlock
+			for _, block := range coverage[file] {
+				if block.startLine >= start && block.endLine <= end {
+					blocks = append(blocks, block)
+				}
+			}
+			if percent, ok := coveragePercent(blocks); ok && percent < threshold {
+				entries = append(entries, file+"#"+goFuncName(fn))
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCoverageProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"cover.out": "mode: set\nexample.com/app/server.go:5.20,7.2 2 1\nexample.com/app/server.go:9.25,11.2 1 0\n",
+		"lcov.info": "TN:\nSF:/src/web/app.js\nFN:1,start\nDA:1,3\nDA:2,0\nDA:3,0\nend_of_record\n",
+		"bad.out":   "mode: set\nexample.com/app/server.go:5.20 2\n",
+		"notes.txt": "hello\n",
+	})
+
+	profile, err := loadCoverageProfile(filepath.Join(dir, "cover.out"))
+	require.NoError(t, err)
+	assert.Equal(t, coverageProfile{"example.com/app/server.go": {
+		{startLine: 5, endLine: 7, statements: 2, count: 1},
+		{startLine: 9, endLine: 11, statements: 1, count: 0},
+	}}, profile)
+	percent, ok := coveragePercent(profile["example.com/app/server.go"])
+	assert.True(t, ok)
+	assert.InDelta(t, 66.7, percent, 0.1)
+
+	profile, err = loadCoverageProfile(filepath.Join(dir, "lcov.info"))
+	require.NoError(t, err)
+	assert.Len(t, profile["/src/web/app.js"], 3)
+	percent, _ = coveragePercent(profile["/src/web/app.js"])
+	assert.InDelta(t, 33.3, percent, 0.1)
+
+	_, err = loadCoverageProfile(filepath.Join(dir, "bad.out"))
+	assert.ErrorContains(t, err, "line 2: invalid cover profile block")
+	_, err = loadCoverageProfile(filepath.Join(dir, "notes.txt"))
+	assert.ErrorContains(t, err, "is neither a Go cover profile nor an lcov file")
+
+	// Profile paths belong to the longest found path they end with
+	profile = coverageProfile{"example.com/app/pkg/util.go": {{startLine: 1, endLine: 2, statements: 1}}}
+	assert.Equal(t, []string{"pkg/util.go"}, coverageFiles(profile.byFile([]string{"util.go", "pkg/util.go"})))
+}
+
+func coverageFiles[V any](m map[string]V) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestFindCoverage(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"server.go": "package app\n\nfunc Start() {\n\tprintln(1)\n\tprintln(2)\n}\n\nfunc (s *Server) Stop() {\n\tprintln(3)\n}\n\ntype Server struct{}\n",
+		"util.go":   "package app\n\nfunc Helper() {\n\tprintln(4)\n}\n",
+		"README.md": "# App\n",
+		"cover.out": "mode: set\n" +
+			"example.com/app/server.go:3.14,6.2 2 1\n" +
+			"example.com/app/server.go:8.26,10.2 1 0\n" +
+			"example.com/app/util.go:3.15,5.2 1 1\n",
+	})
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	find := func(args ...string) []string {
+		CaptureOutput(t, func() {
+			require.Equal(t, 0, runCommandIn(t, dir, append([]string{"-list-file", listFile, "find"}, args...)...))
+		})
+		entries, err := readFileList(listFile)
+		require.NoError(t, err)
+		var paths []string
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+		return paths
+	}
+
+	assert.Equal(t, []string{"server.go"}, find("-coverage", "cover.out", "."))
+	assert.Equal(t, []string{"server.go#Server.Stop"}, find("-coverage", "cover.out", "-coverage-functions", "."))
+
+	output := CaptureOutput(t, func() {
+		runCommandIn(t, dir, "why", "-coverage", "cover.out", ".", "server.go")
+	})
+	assert.Contains(t, output, "coverage 66.7% is below -coverage-below (80%)")
+	output = CaptureOutput(t, func() {
+		runCommandIn(t, dir, "why", "-coverage", "cover.out", ".", "util.go")
+	})
+	assert.Contains(t, output, "coverage at or above -coverage-below (100.0%)")
+	output = CaptureOutput(t, func() {
+		runCommandIn(t, dir, "why", "-coverage", "cover.out", ".", "README.md")
+	})
+	assert.Contains(t, output, "not in the -coverage profile")
+
+	_, err := findOptionsFromFlags(flagSetWith(t, "-coverage-functions"))
+	assert.EqualError(t, err, "-coverage-functions only applies with -coverage")
+	_, err = findOptionsFromFlags(flagSetWith(t, "-coverage-below", "120"))
+	assert.EqualError(t, err, `invalid -coverage-below value "120" (use a percentage from 0 to 100)`)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// findDependencies writes a file list with the seed files and their
+// transitive in-repository imports, or with the files of the -around Go
+// function and its callers and callees
+func findDependencies(root string, supportedExts, excludedExts []string, fs *flag.FlagSet) {
+	seeds := splitList(fs.Lookup("seed").Value.String())
+	around := fs.Lookup("around").Value.String()
+	if len(seeds) == 0 && around == "" {
+		fmt.Println("No seed files given. Use -seed to name the files to start from, or -around to name a Go function.")
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+	hops, err := strconv.Atoi(fs.Lookup("hops").Value.String())
+	if err != nil || hops < 0 {
+		fmt.Printf("Error: invalid -hops value %q\n", fs.Lookup("hops").Value.String())
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+
+	defer applyFindFlags(fs)()
+
+	opts, err := findOptionsFromFlags(fs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+	opts.excludedExts = excludedExts
+	files, err := findFilesWithOptions(root, supportedExts, opts)
+	if err != nil {
+		fmt.Printf("Error walking directory: %v\n", err)
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+
+	for _, seed := range seeds {
+		if !contains(files, filepath.ToSlash(seed)) {
+			fmt.Printf("Seed file not found among the discovered files: %s\n", seed)
+			osExit(1)
+			return // This ensures the function stops here in tests
+		}
+	}
+
+	var closure []string
+	if len(seeds) > 0 {
+		closure = dependencyClosure(root, files, seeds)
+	}
+	if around != "" {
+		neighborhood, err := callNeighborhood(root, files, around, hops)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+			return // This ensures the function stops here in tests
+		}
+		for _, file := range neighborhood {
+			if !contains(closure, file) {
+				closure = append(closure, file)
+			}
+		}
+		sort.Strings(closure)
+	}
+
+	err = writeFileList(root, closure, nil, fs.Lookup("format").Value.String(), listFilters(fs, "deps", nil))
+	if err != nil {
+		fmt.Printf("Error writing file list: %v\n", err)
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+
+	if around != "" {
+		statusf("Found %d files within %d hops of %s. File list saved to %s\n", len(closure), hops, around, fileListName)
+		return
+	}
+	statusf("Found %d files reachable from %d seed files. File list saved to %s\n", len(closure), len(seeds), fileListName)
+}
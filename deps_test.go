@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencyClosure(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"go.mod":                     "module example.com/app\n",
+		"cmd/api/main.go":            "package main\n\nimport \"example.com/app/internal/handler\"\n",
+		"cmd/worker/main.go":         "package main\n\nimport \"example.com/app/internal/queue\"\n",
+		"internal/handler/h.go":      "package handler\n\nimport \"example.com/app/internal/store\"\n",
+		"internal/handler/h_test.go": "package handler\n",
+		"internal/store/s.go":        "package store\n",
+		"internal/queue/q.go":        "package queue\n",
+		"cmd/tool/main.go":           "package main\n\nfunc main() { run() }\n",
+		"cmd/tool/run.go":            "package main\n\nimport \"example.com/app/internal/queue\"\n\nfunc run() {}\n",
+		"cmd/tool/main_test.go":      "package main\n",
+		"app/main.py":                "import os\nfrom app.services import billing\nfrom .utils import helper as h\n",
+		"app/services/__init__.py":   "",
+		"app/services/billing.py":    "from ..models import Invoice\n",
+		"app/models.py":              "class Invoice: pass\n",
+		"app/utils.py":               "def helper(): pass\n",
+		"app/unused.py":              "",
+	})
+	files := []string{
+		"cmd/api/main.go", "cmd/worker/main.go", "internal/handler/h.go", "internal/handler/h_test.go",
+		"internal/store/s.go", "internal/queue/q.go", "cmd/tool/main.go", "cmd/tool/run.go", "cmd/tool/main_test.go",
+		"app/main.py", "app/services/__init__.py", "app/services/billing.py", "app/models.py", "app/utils.py", "app/unused.py",
+	}
+
+	t.Run("Go", func(t *testing.T) {
+		closure := dependencyClosure(testDir, files, []string{"cmd/api/main.go"})
+		assert.Equal(t, []string{"cmd/api/main.go", "internal/handler/h.go", "internal/store/s.go"}, closure)
+	})
+
+	t.Run("Go package of the seed", func(t *testing.T) {
+		closure := dependencyClosure(testDir, files, []string{"cmd/tool/main.go"})
+		assert.Equal(t, []string{"cmd/tool/main.go", "cmd/tool/run.go", "internal/queue/q.go"}, closure)
+	})
+
+	t.Run("Python", func(t *testing.T) {
+		closure := dependencyClosure(testDir, files, []string{"app/main.py"})
+		assert.Equal(t, []string{"app/main.py", "app/models.py", "app/services/__init__.py", "app/services/billing.py", "app/utils.py"}, closure)
+	})
+}
+
+func TestDepsCommand(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"src/index.ts":   "import { run } from './app';\n",
+		"src/app.ts":     "export function run() {}\n",
+		"src/unused.ts":  "export const x = 1;\n",
+		"src/styles.css": "body {}\n",
+	})
+	defer os.Remove("skukozh_file_list.txt")
+
+	flagSet := DefaultFlags()
+	flagSet.Parse([]string{"-seed", "src/index.ts", "deps", testDir})
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "Found 2 files reachable from 1 seed files")
+
+	list := ReadTestFile(t, "skukozh_file_list.txt")
+	assert.Equal(t, fileListText(t, testDir, "-seed=src/index.ts", "src/app.ts", "src/index.ts"), list)
+
+	t.Run("Missing seed", func(t *testing.T) {
+		originalOsExit := osExit
+		defer func() { osExit = originalOsExit }()
+		var exitCalled bool
+		osExit = func(code int) { exitCalled = true }
+
+		flagSet := DefaultFlags()
+		flagSet.Parse([]string{"-seed", "src/missing.ts", "deps", testDir})
+		output := CaptureOutput(t, func() {
+			runWithFlags(flagSet)
+		})
+		require.True(t, exitCalled)
+		assert.Contains(t, output, "Seed file not found")
+	})
+}
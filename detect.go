@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files counted at most when looking at the extension distribution
+const detectMaxFiles = 5000
+
+// A stack is detected by its distribution when at least this share of the
+// source files uses its extensions
+const detectMinShare = 0.25
+
+// stack is a language or framework recognized by -detect
+type stack struct {
+	name      string
+	manifests []string // file name patterns in the directory that mark the stack
+	sources   []string // extensions of its source files, counted for the distribution
+	exts      []string // extra default extensions of the stack
+}
+
+// stacks recognized by -detect, in the order they are reported
+var stacks = []stack{
+	{name: "go", manifests: []string{"go.mod", "go.work"}, sources: []string{".go"}, exts: []string{".mod", ".sum"}},
+	{name: "rails", manifests: []string{"config/application.rb"}, exts: []string{".rb", ".erb", ".rake", ".ru", ".yml"}},
+	{name: "ruby", manifests: []string{"Gemfile", "*.gemspec"}, sources: []string{".rb"}, exts: []string{".rake", ".gemspec", ".ru", ".yml"}},
+	{name: "node", manifests: []string{"package.json"}, sources: []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx"}, exts: []string{".json", ".html", ".css", ".scss", ".vue", ".svelte"}},
+	{name: "python", manifests: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt", "Pipfile"}, sources: []string{".py"}, exts: []string{".pyi", ".ipynb", ".toml", ".cfg", ".txt"}},
+	{name: "rust", manifests: []string{"Cargo.toml"}, sources: []string{".rs"}, exts: []string{".toml"}},
+	{name: "php", manifests: []string{"composer.json"}, sources: []string{".php"}, exts: []string{".phtml", ".twig", ".json"}},
+	{name: "jvm", manifests: []string{"pom.xml", "build.gradle", "build.gradle.kts"}, sources: []string{".java", ".kt", ".scala"}, exts: []string{".kts", ".gradle", ".xml", ".properties"}},
+	{name: "dotnet", manifests: []string{"*.csproj", "*.sln"}, sources: []string{".cs"}, exts: []string{".csproj", ".sln", ".json"}},
+	{name: "c", manifests: []string{"CMakeLists.txt"}, sources: []string{".c", ".h", ".cpp", ".hpp", ".cc"}, exts: []string{".cmake", ".txt"}},
+	{name: "swift", manifests: []string{"Package.swift"}, sources: []string{".swift"}},
+	{name: "elixir", manifests: []string{"mix.exs"}, sources: []string{".ex", ".exs"}},
+}
+
+// Extensions added for every detected stack: docs, config and scripts
+var stackCommonExts = []string{".md", ".yaml", ".yml", ".sh"}
+
+// detectedStack is a stack found in a directory with the reason it was picked
+type detectedStack struct {
+	stack
+	reason string
+}
+
+// detectStacks inspects the manifests at the top of root and the extension
+// distribution of the files below it
+func detectStacks(root string) []detectedStack {
+	counts := sourceExtCounts(root)
+	totalSources := 0
+	for _, count := range counts {
+		totalSources += count
+	}
+
+	var detected []detectedStack
+	for _, s := range stacks {
+		if manifest := findManifest(root, s.manifests); manifest != "" {
+			detected = append(detected, detectedStack{s, "manifest " + manifest})
+			continue
+		}
+		sources := 0
+		for _, ext := range s.sources {
+			sources += counts[ext]
+		}
+		if sources > 0 && float64(sources) >= detectMinShare*float64(totalSources) {
+			share := 100 * sources / totalSources
+			detected = append(detected, detectedStack{s, fmt.Sprintf("%d%% of source files", share)})
+		}
+	}
+	return detected
+}
+
+// findManifest returns the first file at the top of root matching one of
+// the patterns, or an empty string
+func findManifest(root string, patterns []string) string {
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				rel, _ := filepath.Rel(root, match)
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return ""
+}
+
+// sourceExtCounts counts the files below root per source extension of the
+// known stacks, skipping hidden and package directories
+func sourceExtCounts(root string) map[string]int {
+	sourceExts := make(map[string]bool)
+	for _, s := range stacks {
+		for _, ext := range s.sources {
+			sourceExts[ext] = true
+		}
+	}
+
+	counts := make(map[string]int)
+	seen := 0
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if d.IsDir() {
+			if isHidden(d.Name()) || containsIgnoreCase(ignoredDirs, d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if seen++; seen > detectMaxFiles {
+			return filepath.SkipAll
+		}
+		if ext := strings.ToLower(filepath.Ext(d.Name())); sourceExts[ext] {
+			counts[ext]++
+		}
+		return nil
+	})
+	return counts
+}
+
+// stackExts returns the default extensions of the detected stacks
+func stackExts(detected []detectedStack) []string {
+	var exts []string
+	add := func(list []string) {
+		for _, ext := range list {
+			if !contains(exts, ext) {
+				exts = append(exts, ext)
+			}
+		}
+	}
+	for _, s := range detected {
+		add(s.sources)
+		add(s.exts)
+	}
+	add(stackCommonExts)
+	return exts
+}
+
+// printDetectedStacks shows the detected stacks and the extensions they select
+func printDetectedStacks(detected []detectedStack, exts []string) {
+	if len(detected) == 0 {
+		fmt.Println("No stack detected, using the common text extensions")
+		return
+	}
+	var parts []string
+	for _, s := range detected {
+		parts = append(parts, fmt.Sprintf("%s (%s)", s.name, s.reason))
+	}
+	fmt.Printf("Detected stacks: %s\n", strings.Join(parts, ", "))
+	fmt.Printf("Default extensions: %s\n", strings.Join(exts, ", "))
+}
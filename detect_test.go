@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectStacks(t *testing.T) {
+	t.Run("Manifests", func(t *testing.T) {
+		testDir := t.TempDir()
+		writeTestFiles(t, testDir, map[string]string{
+			"Gemfile":               "source 'https://rubygems.org'\n",
+			"config/application.rb": "module App; end\n",
+			"app/models/user.rb":    "class User; end\n",
+		})
+
+		var names, reasons []string
+		for _, s := range detectStacks(testDir) {
+			names = append(names, s.name)
+			reasons = append(reasons, s.reason)
+		}
+		assert.Equal(t, []string{"rails", "ruby"}, names)
+		assert.Equal(t, []string{"manifest config/application.rb", "manifest Gemfile"}, reasons)
+	})
+
+	t.Run("Glob manifest", func(t *testing.T) {
+		testDir := t.TempDir()
+		writeTestFiles(t, testDir, map[string]string{"App.csproj": "<Project />\n"})
+
+		detected := detectStacks(testDir)
+		require.Len(t, detected, 1)
+		assert.Equal(t, "dotnet", detected[0].name)
+		assert.Equal(t, "manifest App.csproj", detected[0].reason)
+	})
+
+	t.Run("File distribution", func(t *testing.T) {
+		testDir := t.TempDir()
+		writeTestFiles(t, testDir, map[string]string{
+			"a.py":                     "",
+			"b.py":                     "",
+			"c.py":                     "",
+			"scripts/tool.js":          "",
+			"lib/x.rs":                 "",
+			"lib/y.rs":                 "",
+			"lib/z.rs":                 "",
+			"lib/w.rs":                 "",
+			"node_modules/dep/a.js":    "",
+			"node_modules/dep/b.js":    "",
+			"node_modules/dep/c.js":    "",
+			".cache/generated/one.js":  "",
+			".cache/generated/two.js":  "",
+			".cache/generated/more.js": "",
+		})
+
+		var names, reasons []string
+		for _, s := range detectStacks(testDir) {
+			names = append(names, s.name)
+			reasons = append(reasons, s.reason)
+		}
+		assert.Equal(t, []string{"python", "rust"}, names, "hidden and package directories aren't counted")
+		assert.Equal(t, []string{"37% of source files", "50% of source files"}, reasons)
+	})
+
+	t.Run("Nothing detected", func(t *testing.T) {
+		testDir := t.TempDir()
+		writeTestFiles(t, testDir, map[string]string{"notes.txt": "hello\n"})
+		assert.Empty(t, detectStacks(testDir))
+	})
+}
+
+func TestStackExts(t *testing.T) {
+	detected := []detectedStack{
+		{stack: stacks[0], reason: "manifest go.mod"},
+		{stack: stack{name: "extra", sources: []string{".go", ".tmpl"}}},
+	}
+	assert.Equal(t, []string{".go", ".mod", ".sum", ".tmpl", ".md", ".yaml", ".yml", ".sh"}, stackExts(detected))
+}
+
+func TestFindWithDetect(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"go.mod":          "module example.com/app\n",
+		"main.go":         "package main\n",
+		"README.md":       "# App\n",
+		"Dockerfile":      "FROM scratch\n",
+		"web/app.css":     "body {}\n",
+		"docs/notes.txt":  "notes\n",
+		"config/app.yaml": "port: 80\n",
+	})
+
+	skipped := make(map[string]string)
+	opts := findOptions{detect: true, onSkip: func(path, reason string) { skipped[path] = reason }}
+	var files []string
+	output := CaptureOutput(t, func() {
+		defer applyFindFlags(flagSetWith(t, "-verbose"))()
+		var err error
+		files, err = findFilesWithOptions(testDir, nil, opts)
+		require.NoError(t, err)
+	})
+	assert.Equal(t, []string{"Dockerfile", "README.md", "config/app.yaml", "go.mod", "main.go"}, files)
+	assert.Equal(t, "extension not among the extensions of the detected stacks", skipped["web/app.css"])
+	assert.Equal(t, "outside detected stacks", skipCategory(skipped["web/app.css"]))
+	assert.Contains(t, output, "Detected stacks: go (manifest go.mod)")
+	assert.Contains(t, output, "Default extensions: .go, .mod, .sum, .md, .yaml, .yml, .sh")
+
+	// An explicit -ext replaces the detected extensions
+	files, err := findFilesWithOptions(testDir, []string{".css"}, findOptions{detect: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web/app.css"}, files)
+
+	included, reason, err := explainPath(testDir, "main.go", nil, findOptions{detect: true})
+	require.NoError(t, err)
+	assert.True(t, included)
+	assert.Equal(t, "extension is among the extensions of the detected stacks", reason)
+}
+
+// flagSetWith returns the default flags parsed from args
+func flagSetWith(t *testing.T, args ...string) *flag.FlagSet {
+	fs := DefaultFlags()
+	require.NoError(t, fs.Parse(args))
+	return fs
+}
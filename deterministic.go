@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// deterministicOptions returns the options of a -deterministic run, which
+// normalizes line endings and sorts the files unless -order says otherwise
+func deterministicOptions(opts genOptions) (genOptions, error) {
+	if !opts.deterministic {
+		return opts, nil
+	}
+
+	// Options whose output depends on the time or on randomness
+	var conflicts []string
+	for name, set := range map[string]bool{
+		"-blame":   opts.blame,   // ages are relative to now
+		"-encrypt": opts.encrypt, // every run uses a new salt and nonce
+	} {
+		if set {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return opts, fmt.Errorf("-deterministic can't be combined with %s", strings.Join(conflicts, ", "))
+	}
+
+	opts.normalizeEOL = true
+	if opts.order == "" {
+		opts.order = "alpha"
+	}
+	return opts, nil
+}
+
+// normalizeEntry writes a file list entry with forward slashes and without
+// a leading "./", so the same file always gets the same #FILE line
+func normalizeEntry(file string) string {
+	file = filepath.ToSlash(file)
+	for strings.HasPrefix(file, "./") {
+		file = strings.TrimPrefix(file, "./")
+	}
+	return file
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEntry(t *testing.T) {
+	assert.Equal(t, "main.go", normalizeEntry("./main.go"))
+	assert.Equal(t, "cmd/app/main.go", normalizeEntry("././cmd/app/main.go"))
+	assert.Equal(t, "main.go:10-20", normalizeEntry("./main.go:10-20"))
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "crlf.go"), []byte("package main\r\n\r\nfunc main() {}\r\n"), 0644))
+	defer os.Remove(fileListName)
+
+	opts := genOptions{deterministic: true, meta: true, summary: true, toc: true, checksum: true, metaFlags: "-deterministic"}
+	generate := func(list string) string {
+		t.Helper()
+		require.NoError(t, os.WriteFile(fileListName, []byte(list), 0644))
+		result, err := generateContentFileInternal(testDir, opts)
+		require.NoError(t, err)
+		return result
+	}
+
+	// The order and spelling of the file list don't change the bundle
+	first := generate("subdir/file3.go\n./file1.go\ncrlf.go\n")
+	second := generate("./crlf.go\nfile1.go\n././subdir/file3.go\n")
+	assert.Equal(t, first, second)
+	assert.Equal(t, first, generate("subdir/file3.go\n./file1.go\ncrlf.go\n"), "repeated runs are byte-identical")
+
+	assert.NotContains(t, first, "\r")
+	assert.NotContains(t, first, "generated_at=")
+	assert.True(t, strings.HasPrefix(first, "#SKUKOZH version="+buildVersion()+" root="+filepath.Base(testDir)+" exts=go files=3 flags=-deterministic\n"))
+	assert.Less(t, strings.Index(first, "#FILE crlf.go\n"), strings.Index(first, "#FILE file1.go\n"))
+	assert.Less(t, strings.Index(first, "#FILE file1.go\n"), strings.Index(first, "#FILE subdir/file3.go\n"))
+
+	// An explicit -order still applies
+	opts.order = "size"
+	bySize := generate("crlf.go\nfile1.go\n")
+	assert.Less(t, strings.Index(bySize, "#FILE file1.go\n"), strings.Index(bySize, "#FILE crlf.go\n"))
+
+	_, err := generateContentFileInternal(testDir, genOptions{deterministic: true, blame: true, encrypt: true})
+	assert.EqualError(t, err, "-deterministic can't be combined with -blame, -encrypt")
+}
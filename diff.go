@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lines of context around changes in unified diffs
+const diffContext = 3
+
+// Above this many edits, a file's diff replaces all old lines by all new
+// ones instead of searching further for the shortest edit script
+const maxDiffEdits = 1000
+
+// diffOp is one line of an edit script: ' ' keeps, '-' deletes and '+'
+// inserts a line
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// diffLines computes a shortest edit script turning a into b with Myers'
+// algorithm
+func diffLines(a, b []string) []diffOp {
+	// Common prefix and suffix are kept as they are
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// myersDiff runs Myers' algorithm, keeping a snapshot of the furthest
+// reaching paths of every round to backtrack the edit script
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceAll(a, b)
+		}
+		// Round d reads v[k-1] and v[k+1] for k in [-d, d]
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+// backtrackDiff walks the snapshots of myersDiff back from the end of both
+// inputs and returns the edit script in order
+func backtrackDiff(a, b []string, trace [][]int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[y-1]})
+				y--
+			} else {
+				ops = append(ops, diffOp{'-', a[x-1]})
+				x--
+			}
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceAll is the edit script deleting all of a and inserting all of b
+func replaceAll(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// unifiedDiff renders the differences between a and b as a unified diff
+// with diffContext lines of context. It returns an empty string when the
+// inputs are equal.
+func unifiedDiff(oldName, newName string, a, b []string) string {
+	ops := diffLines(a, b)
+
+	// Lines of a and b consumed before each op
+	oldPos := make([]int, len(ops)+1)
+	newPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Merge changes separated by at most twice the context into one hunk
+		start := max(0, i-diffContext)
+		end := i
+		for j := i + 1; j < len(ops) && j <= end+2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			}
+		}
+		stop := min(len(ops), end+diffContext+1)
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(oldPos[start], oldPos[stop]-oldPos[start]),
+			hunkRange(newPos[start], newPos[stop]-newPos[start]))
+		for _, op := range ops[start:stop] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+		i = stop
+	}
+	return out.String()
+}
+
+// hunkRange formats the line range of a hunk the way diff -u does
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// sectionLines splits the content of a bundle section into lines
+func sectionLines(content string) []string {
+	content = strings.TrimSuffix(content, "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
+
+// diffBundles compares the file sections of two bundles and reports added,
+// removed and changed files with their size and token deltas, followed by
+// per-file unified diffs if requested
+func diffBundles(oldContent, newContent string, unified bool) string {
+	oldFiles := make(map[string]string)
+	for _, section := range parseBundleSections(oldContent) {
+		oldFiles[section.path] = section.content
+	}
+	newFiles := make(map[string]string)
+	for _, section := range parseBundleSections(newContent) {
+		newFiles[section.path] = section.content
+	}
+
+	paths := make([]string, 0, len(oldFiles)+len(newFiles))
+	for path := range oldFiles {
+		paths = append(paths, path)
+	}
+	for path := range newFiles {
+		if _, ok := oldFiles[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var report, diffs strings.Builder
+	added, removed, changed, unchanged := 0, 0, 0, 0
+	oldTokens, newTokens := 0, 0
+	for _, path := range paths {
+		oldText, inOld := oldFiles[path]
+		newText, inNew := newFiles[path]
+		oldTokens += estimateTokens(oldText)
+		newTokens += estimateTokens(newText)
+
+		sizeDelta := len(newText) - len(oldText)
+		tokenDelta := estimateTokens(newText) - estimateTokens(oldText)
+		oldName, newName := "a/"+path, "b/"+path
+		switch {
+		case !inOld:
+			fmt.Fprintf(&report, "ADDED     %s (%+d bytes, %+d tokens)\n", path, sizeDelta, tokenDelta)
+			oldName = "/dev/null"
+			added++
+		case !inNew:
+			fmt.Fprintf(&report, "REMOVED   %s (%+d bytes, %+d tokens)\n", path, sizeDelta, tokenDelta)
+			newName = "/dev/null"
+			removed++
+		case oldText != newText:
+			fmt.Fprintf(&report, "CHANGED   %s (%+d bytes, %+d tokens)\n", path, sizeDelta, tokenDelta)
+			changed++
+		default:
+			unchanged++
+			continue
+		}
+
+		if unified {
+			diffs.WriteString(unifiedDiff(oldName, newName, sectionLines(oldText), sectionLines(newText)))
+		}
+	}
+
+	fmt.Fprintf(&report, "%d added, %d removed, %d changed, %d unchanged files\n", added, removed, changed, unchanged)
+	fmt.Fprintf(&report, "Tokens: %s -> %s (%+d)\n", formatTokens(oldTokens), formatTokens(newTokens), newTokens-oldTokens)
+	if diffs.Len() > 0 {
+		report.WriteString("\n")
+		report.WriteString(diffs.String())
+	}
+	return report.String()
+}
+
+// diffResultFiles compares two result files and returns the exit code
+func diffResultFiles(oldName, newName string, unified bool) int {
+	oldContent, err := readBundleFile(oldName)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", oldName, err)
+		return 1
+	}
+	newContent, err := readBundleFile(newName)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", newName, err)
+		return 1
+	}
+
+	fmt.Print(diffBundles(string(oldContent), string(newContent), unified))
+	return 0
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	// Applying the edit script must give back both inputs, with as few edits
+	// as the longest common subsequence allows
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+	lcs := func(a, b []string) int {
+		dp := make([][]int, len(a)+1)
+		for i := range dp {
+			dp[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					dp[i][j] = dp[i+1][j+1] + 1
+				} else {
+					dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+				}
+			}
+		}
+		return dp[0][0]
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		var gotA, gotB []string
+		edits := 0
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				gotA = append(gotA, op.text)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.text)
+			}
+			if op.kind != ' ' {
+				edits++
+			}
+		}
+		require.Equal(t, fmt.Sprint(a), fmt.Sprint(gotA), "a=%v b=%v", a, b)
+		require.Equal(t, fmt.Sprint(b), fmt.Sprint(gotB), "a=%v b=%v", a, b)
+		require.Equal(t, len(a)+len(b)-2*lcs(a, b), edits, "a=%v b=%v", a, b)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	var oldLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("line %d", i))
+	}
+	newLines := append([]string(nil), oldLines...)
+	newLines[1] = "line two"
+	newLines = append(newLines[:15], newLines[16:]...) // drop line 16
+	newLines = append(newLines, "line 21")
+
+	assert.Equal(t, `--- a/f.txt
++++ b/f.txt
+@@ -1,5 +1,5 @@
+ line 1
+-line 2
++line two
+ line 3
+ line 4
+ line 5
+@@ -13,8 +13,8 @@
+ line 13
+ line 14
+ line 15
+-line 16
+ line 17
+ line 18
+ line 19
+ line 20
++line 21
+`, unifiedDiff("a/f.txt", "b/f.txt", oldLines, newLines))
+
+	assert.Equal(t, "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hello\n", unifiedDiff("/dev/null", "b/new.txt", nil, []string{"hello"}))
+	assert.Empty(t, unifiedDiff("a/f.txt", "b/f.txt", oldLines, oldLines))
+}
+
+func TestDiffResultFiles(t *testing.T) {
+	section := func(path, content string) string {
+		return "#FILE " + path + "\n#TYPE txt\n#START\n```text\n" + content + "\n```\n#END\n\n"
+	}
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.txt")
+	newPath := filepath.Join(dir, "new.txt")
+	require.NoError(t, os.WriteFile(oldPath, []byte(section("same.txt", "same")+section("changed.txt", "one\ntwo")+section("gone.txt", "bye")), 0644))
+	require.NoError(t, os.WriteFile(newPath, []byte(section("same.txt", "same")+section("changed.txt", "one\ntwo\nthree")+section("new.txt", "hello world")), 0644))
+
+	t.Run("Report", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			assert.Equal(t, 0, diffResultFiles(oldPath, newPath, false))
+		})
+		assert.Equal(t, `CHANGED   changed.txt (+6 bytes, +2 tokens)
+REMOVED   gone.txt (-4 bytes, -1 tokens)
+ADDED     new.txt (+12 bytes, +3 tokens)
+1 added, 1 removed, 1 changed, 1 unchanged files
+Tokens: 5 -> 9 (+4)
+`, output)
+	})
+
+	t.Run("Unified diffs", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			assert.Equal(t, 0, diffResultFiles(oldPath, newPath, true))
+		})
+		assert.Contains(t, output, "--- a/changed.txt\n+++ b/changed.txt\n@@ -1,2 +1,3 @@\n one\n two\n+three\n")
+		assert.Contains(t, output, "--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n")
+		assert.Contains(t, output, "--- /dev/null\n+++ b/new.txt\n")
+		assert.NotContains(t, output, "same.txt")
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			assert.Equal(t, 1, diffResultFiles(filepath.Join(dir, "missing.txt"), newPath, false))
+		})
+		assert.True(t, strings.HasPrefix(output, "Error reading "))
+	})
+}
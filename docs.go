@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Directories whose index files count as key documentation
+var docsDirs = []string{"docs", "doc"}
+
+// docRank returns the position of a key documentation file among the docs
+// placed first by -with-docs, and false for other files. READMEs at any
+// depth, CONTRIBUTING and ARCHITECTURE.md files and the index files of
+// docs/ directories are key documentation.
+func docRank(relPath string) (int, bool) {
+	name := strings.ToLower(path.Base(relPath))
+	stem := strings.TrimSuffix(name, path.Ext(name))
+	switch {
+	case strings.HasPrefix(name, "readme"):
+		return 0, true
+	case name == "architecture.md":
+		return 1, true
+	case stem == "contributing":
+		return 2, true
+	case stem == "index" && containsIgnoreCase(docsDirs, path.Base(path.Dir(relPath))):
+		return 3, true
+	}
+	return 0, false
+}
+
+// isKeyDoc reports whether a file is included by -with-docs
+func isKeyDoc(relPath string) bool {
+	_, ok := docRank(relPath)
+	return ok
+}
+
+// docsFirst moves the key documentation files to the front of a sorted file
+// list: shallower files first, then README, ARCHITECTURE.md, CONTRIBUTING
+// and docs index files. The other files keep their order.
+func docsFirst(files []string) []string {
+	var docs, rest []string
+	for _, file := range files {
+		if isKeyDoc(file) {
+			docs = append(docs, file)
+		} else {
+			rest = append(rest, file)
+		}
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		di, dj := strings.Count(docs[i], "/"), strings.Count(docs[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		ri, _ := docRank(docs[i])
+		rj, _ := docRank(docs[j])
+		return ri < rj
+	})
+	return append(docs, rest...)
+}
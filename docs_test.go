@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocsFirst(t *testing.T) {
+	files := []string{
+		"CONTRIBUTING.md",
+		"README.md",
+		"api/README.md",
+		"docs/guide.md",
+		"docs/index.md",
+		"main.go",
+		"ARCHITECTURE.md",
+		"zz.go",
+	}
+	assert.Equal(t, []string{
+		"README.md",
+		"ARCHITECTURE.md",
+		"CONTRIBUTING.md",
+		"api/README.md",
+		"docs/index.md",
+		"docs/guide.md",
+		"main.go",
+		"zz.go",
+	}, docsFirst(files))
+}
+
+func TestFindWithDocs(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":          "package main\n",
+		"README.md":        "# Project\n",
+		"CONTRIBUTING":     "Send patches\n",
+		"ARCHITECTURE.md":  "# Layout\n",
+		"docs/index.rst":   "Docs\n",
+		"docs/usage.md":    "Usage\n",
+		"pkg/api/README":   "API\n",
+		"notes/index.md":   "Not docs\n",
+		"vendor/README.md": "Vendored\n",
+	})
+
+	fs := flagSetWith(t, "-with-docs", "-ext", "go", "-not-ext", ".md")
+	opts, err := findOptionsFromFlags(fs)
+	require.NoError(t, err)
+	_, opts.excludedExts = extFiltersFromFlags(fs)
+
+	files, err := findFilesWithOptions(testDir, []string{".go"}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"README.md",
+		"ARCHITECTURE.md",
+		"CONTRIBUTING",
+		"docs/index.rst",
+		"pkg/api/README",
+		"main.go",
+	}, files)
+
+	included, reason, err := explainPath(testDir, "docs/index.rst", []string{".go"}, opts)
+	require.NoError(t, err)
+	assert.True(t, included)
+	assert.Equal(t, "key documentation included by -with-docs", reason)
+
+	// Without the flag the extension filter applies to docs as well
+	files, err = findFilesInternal(testDir, []string{".go"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, files)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Name of the files holding per-directory editor settings
+const editorConfigName = ".editorconfig"
+
+// Tab width of files without an .editorconfig setting or a language default
+const defaultTabWidth = 4
+
+// Tab widths of the languages usually indented by two columns, by extension
+var languageTabWidths = map[string]int{
+	".yaml": 2, ".yml": 2, ".rb": 2, ".js": 2, ".jsx": 2, ".ts": 2, ".tsx": 2,
+	".json": 2, ".html": 2, ".css": 2, ".scss": 2, ".vue": 2, ".tf": 2,
+}
+
+// Files whose tabs mean something, kept as they are by -expand-tabs
+var tabSignificantFiles = []string{"Makefile", "GNUmakefile", "makefile", "*.mk", "*.tsv"}
+
+// editorConfigSection is a [glob] section of an .editorconfig file
+type editorConfigSection struct {
+	pattern    *regexp.Regexp    // paths relative to the file's directory it applies to
+	properties map[string]string // lowercased keys and values
+}
+
+// editorConfig is a parsed .editorconfig file
+type editorConfig struct {
+	root     bool // root = true, files above aren't read
+	sections []editorConfigSection
+}
+
+// editorConfigs holds the .editorconfig files a gen run has parsed by
+// directory, nil for directories without one, so each is read once. A nil
+// editorConfigs parses the files every time.
+type editorConfigs map[string]*editorConfig
+
+// load returns the parsed .editorconfig file of dir, or nil if it has none
+func (c editorConfigs) load(dir string) *editorConfig {
+	if config, ok := c[dir]; ok {
+		return config
+	}
+	content, err := os.ReadFile(filepath.Join(dir, editorConfigName))
+	var config *editorConfig
+	if err == nil {
+		config = parseEditorConfig(content)
+	}
+	if c != nil {
+		c[dir] = config
+	}
+	return config
+}
+
+// parseEditorConfig reads the root flag and the sections of an .editorconfig
+// file. Sections with a glob that can't be compiled are skipped.
+func parseEditorConfig(content []byte) *editorConfig {
+	config := &editorConfig{}
+	var section *editorConfigSection
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = nil
+			if pattern, err := editorConfigPattern(line[1 : len(line)-1]); err == nil {
+				config.sections = append(config.sections, editorConfigSection{pattern: pattern, properties: make(map[string]string)})
+				section = &config.sections[len(config.sections)-1]
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch {
+		case section != nil:
+			section.properties[key] = value
+		case len(config.sections) == 0 && key == "root":
+			config.root = value == "true"
+		}
+	}
+	return config
+}
+
+// editorConfigPattern compiles an .editorconfig section glob. Globs without
+// a slash match file names in any directory below the file; the others
+// match paths relative to its directory. Supports *, **, ?, [...], [!...]
+// and {a,b}.
+func editorConfigPattern(glob string) (*regexp.Regexp, error) {
+	var re strings.Builder
+	if strings.Contains(glob, "/") {
+		glob = strings.TrimPrefix(glob, "/")
+		re.WriteString("^")
+	} else {
+		re.WriteString("^(?:.*/)?")
+	}
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end
+		case c == '{':
+			re.WriteString("(?:")
+			braces++
+		case c == '}' && braces > 0:
+			re.WriteString(")")
+			braces--
+		case c == ',' && braces > 0:
+			re.WriteString("|")
+		case c == '\\' && i+1 < len(glob):
+			re.WriteString(regexp.QuoteMeta(glob[i+1 : i+2]))
+			i++
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// tabWidth returns the width tabs of file are expanded to: tab_width or a
+// numeric indent_size from the .editorconfig files of its directory and the
+// ones above, closer files and later sections winning, or else the default
+// of its language
+func (c editorConfigs) tabWidth(baseDir, file string) int {
+	path, err := filepath.Abs(filepath.Join(baseDir, file))
+	if err != nil {
+		path = filepath.Join(baseDir, file)
+	}
+
+	// Settings of the closest files are applied last
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if config := c.load(dir); config != nil {
+			dirs = append(dirs, dir)
+			if config.root {
+				break
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	properties := make(map[string]string)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(dirs[i], path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, section := range c.load(dirs[i]).sections {
+			if section.pattern.MatchString(rel) {
+				for key, value := range section.properties {
+					properties[key] = value
+				}
+			}
+		}
+	}
+
+	for _, key := range []string{"tab_width", "indent_size"} {
+		if width, err := strconv.Atoi(properties[key]); err == nil && width > 0 {
+			return width
+		}
+	}
+	if width, ok := languageTabWidths[strings.ToLower(filepath.Ext(file))]; ok {
+		return width
+	}
+	return defaultTabWidth
+}
+
+// tabsSignificant reports whether the tabs of file are part of its syntax
+func tabsSignificant(file string) bool {
+	name := filepath.Base(file)
+	for _, pattern := range tabSignificantFiles {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expandTabs replaces the tabs of content with spaces up to the next
+// multiple of width, counting columns in characters, so indentation and
+// aligned comments look the same as in an editor
+func expandTabs(content []byte, width int) []byte {
+	if !bytes.ContainsRune(content, '\t') {
+		return content
+	}
+	var out bytes.Buffer
+	out.Grow(len(content))
+	column := 0
+	for len(content) > 0 {
+		r, size := utf8.DecodeRune(content)
+		switch r {
+		case '\t':
+			spaces := width - column%width
+			out.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+		case '\n':
+			out.WriteByte('\n')
+			column = 0
+		default:
+			out.Write(content[:size])
+			column++
+		}
+		content = content[size:]
+	}
+	return out.Bytes()
+}
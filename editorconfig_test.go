@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandTabs(t *testing.T) {
+	assert.Equal(t, "    x := 1\n        y", string(expandTabs([]byte("\tx := 1\n\t\ty"), 4)))
+	assert.Equal(t, "ab  // c\nщё  d", string(expandTabs([]byte("ab\t// c\nщё\td"), 4)), "tabs align to the next stop")
+	assert.Equal(t, "no tabs", string(expandTabs([]byte("no tabs"), 8)))
+}
+
+func TestEditorConfigPattern(t *testing.T) {
+	tests := []struct {
+		glob    string
+		path    string
+		matches bool
+	}{
+		{"*", "src/app.py", true},
+		{"*.py", "src/app.py", true},
+		{"*.py", "src/app.pyc", false},
+		{"*.{yml,yaml}", "ci/deploy.yaml", true},
+		{"lib/**.js", "lib/a/b.js", true},
+		{"lib/*.js", "lib/a/b.js", false},
+		{"/Makefile", "Makefile", true},
+		{"/Makefile", "sub/Makefile", false},
+		{"[!a]*.go", "main.go", true},
+		{"[!a]*.go", "app.go", false},
+		{"file?.txt", "file1.txt", true},
+	}
+	for _, test := range tests {
+		pattern, err := editorConfigPattern(test.glob)
+		require.NoError(t, err, test.glob)
+		assert.Equal(t, test.matches, pattern.MatchString(test.path), "%s on %s", test.glob, test.path)
+	}
+}
+
+func TestTabWidth(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"project/.editorconfig":     "root = true\n\n[*]\nindent_style = tab\n\n[*.py]\nindent_size = 3\n\n[legacy/**]\ntab_width = 8\n",
+		"project/sub/.editorconfig": "[*.py]\nindent_size = tab\ntab_width = 5\n",
+		".editorconfig":             "[*]\nindent_size = 7\n",
+	})
+	project := filepath.Join(dir, "project")
+	configs := make(editorConfigs)
+
+	assert.Equal(t, 3, configs.tabWidth(project, "app.py"))
+	assert.Equal(t, 5, configs.tabWidth(project, "sub/app.py"), "closer files win")
+	assert.Equal(t, 8, configs.tabWidth(project, "legacy/old.py"), "later sections win")
+	assert.Equal(t, 2, configs.tabWidth(project, "ci.yml"), "language default")
+	assert.Equal(t, 4, configs.tabWidth(project, "main.go"))
+	assert.Equal(t, 7, configs.tabWidth(dir, "main.go"), "files above root = true aren't read")
+	assert.Equal(t, 5, editorConfigs(nil).tabWidth(project, "sub/app.py"))
+}
+
+func TestGenExpandTabs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		".editorconfig": "[*.py]\nindent_size = 2\n",
+		"app.py":        "def f():\n\treturn 1\n",
+		"Makefile":      "all:\n\tgo build\n",
+	})
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	require.NoError(t, os.WriteFile(listFile, []byte("app.py\nMakefile\n"), 0644))
+	result := filepath.Join(t.TempDir(), "bundle.txt")
+
+	CaptureOutput(t, func() {
+		require.Equal(t, 0, runCommandIn(t, dir, "-list-file", listFile, "-result-file", result, "gen", "-expand-tabs", "."))
+	})
+	bundle := ReadTestFile(t, result)
+	assert.Contains(t, bundle, "def f():\n  return 1\n")
+	assert.Contains(t, bundle, "all:\n\tgo build\n", "tabs in Makefiles are syntax")
+	assert.NoDirExists(t, filepath.Join(dir, ".skukozh", "cache"), "widths can change without the file changing")
+}
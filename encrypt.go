@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Suffix added to encrypted result files
+const encryptedExt = ".enc"
+
+// Environment variable holding the passphrase when -passphrase-file isn't given
+const passphraseEnv = "SKUKOZH_PASSPHRASE"
+
+// Encrypted files start with the magic, followed by the salt and the nonce.
+// The whole header is authenticated along with the ciphertext.
+var encryptionMagic = []byte("skukozh-aes256gcm-v1\n")
+
+const (
+	encryptionSaltSize   = 16
+	encryptionKeySize    = 32 // AES-256
+	encryptionIterations = 600000
+)
+
+// encryptionPassphrase reads the passphrase from the file named by
+// -passphrase-file, or from SKUKOZH_PASSPHRASE. Only the trailing newline
+// of the file is dropped.
+func encryptionPassphrase(passphraseFile string) ([]byte, error) {
+	var passphrase string
+	if passphraseFile != "" {
+		content, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading passphrase file: %w", err)
+		}
+		passphrase = strings.TrimSuffix(strings.TrimSuffix(string(content), "\n"), "\r")
+	} else {
+		passphrase = os.Getenv(passphraseEnv)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("no passphrase: set %s or use -passphrase-file", passphraseEnv)
+	}
+	return []byte(passphrase), nil
+}
+
+// encryptData encrypts data with AES-256-GCM under a key derived from the
+// passphrase with PBKDF2-HMAC-SHA256 and a random salt
+func encryptData(data, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newEncryptionAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, encryptionMagic...), salt...), nonce...)
+	return aead.Seal(header, nonce, data, header), nil
+}
+
+// decryptData reverses encryptData. A wrong passphrase and a tampered file
+// both fail authentication and can't be told apart.
+func decryptData(data, passphrase []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptionMagic) {
+		return nil, errors.New("not an encrypted skukozh file")
+	}
+	salt := data[len(encryptionMagic):]
+	if len(salt) < encryptionSaltSize {
+		return nil, errors.New("encrypted file is truncated")
+	}
+	salt = salt[:encryptionSaltSize]
+	aead, err := newEncryptionAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	headerSize := len(encryptionMagic) + encryptionSaltSize + aead.NonceSize()
+	if len(data) < headerSize+aead.Overhead() {
+		return nil, errors.New("encrypted file is truncated")
+	}
+	header := data[:headerSize]
+	plain, err := aead.Open(nil, header[headerSize-aead.NonceSize():], data[headerSize:], header)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted file")
+	}
+	return plain, nil
+}
+
+// newEncryptionAEAD returns the AES-256-GCM cipher for a passphrase and salt
+func newEncryptionAEAD(passphrase, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2SHA256(passphrase, salt, encryptionIterations, encryptionKeySize))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key from a password as specified in RFC 8018
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	var counter [4]byte
+	key := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		key = prf.Sum(key)
+		t := key[len(key)-hashLen:]
+		copy(u, t)
+
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+	return key[:keyLen]
+}
+
+// decryptResultFile decrypts a file written by gen -encrypt into output, or
+// next to it without the .enc suffix, and returns the exit code
+func decryptResultFile(path, output, passphraseFile string) int {
+	if output == "" {
+		if !strings.HasSuffix(path, encryptedExt) {
+			fmt.Printf("Error: %s doesn't end in %s; name the output file as well\n", path, encryptedExt)
+			return 1
+		}
+		output = strings.TrimSuffix(path, encryptedExt)
+	}
+
+	passphrase, err := encryptionPassphrase(passphraseFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Error reading encrypted file: %v\n", err)
+		return 1
+	}
+	plain, err := decryptData(data, passphrase)
+	if err != nil {
+		fmt.Printf("Error: %s: %v\n", path, err)
+		return 1
+	}
+	if err := writeFileAtomic(output, plain, 0644); err != nil {
+		fmt.Printf("Error writing decrypted file: %v\n", err)
+		return 1
+	}
+
+	statusf("Decrypted file saved to %s\n", output)
+	return 0
+}
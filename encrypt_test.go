@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// Test vectors from RFC 7914, section 11, and the common SHA-256 set
+	key := pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64)
+	assert.Equal(t, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783", hex.EncodeToString(key))
+	key = pbkdf2SHA256([]byte("password"), []byte("salt"), 4096, 32)
+	assert.Equal(t, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a", hex.EncodeToString(key))
+}
+
+func TestEncryptData(t *testing.T) {
+	plain := []byte("#FILE main.go\n#TYPE go\n#START\npackage main\n#END\n")
+	encrypted, err := encryptData(plain, []byte("secret"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "package main")
+
+	// Salts and nonces are random, so the same input encrypts differently
+	again, err := encryptData(plain, []byte("secret"))
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	decrypted, err := decryptData(encrypted, []byte("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, decrypted)
+
+	_, err = decryptData(encrypted, []byte("wrong"))
+	assert.EqualError(t, err, "wrong passphrase or corrupted file")
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(encryptionMagic)] ^= 1 // the salt is authenticated as well
+	_, err = decryptData(tampered, []byte("secret"))
+	assert.EqualError(t, err, "wrong passphrase or corrupted file")
+
+	_, err = decryptData(encrypted[:len(encryptionMagic)+20], []byte("secret"))
+	assert.EqualError(t, err, "encrypted file is truncated")
+	_, err = decryptData(plain, []byte("secret"))
+	assert.EqualError(t, err, "not an encrypted skukozh file")
+}
+
+func TestEncryptionPassphrase(t *testing.T) {
+	t.Setenv(passphraseEnv, "")
+	_, err := encryptionPassphrase("")
+	assert.EqualError(t, err, "no passphrase: set SKUKOZH_PASSPHRASE or use -passphrase-file")
+
+	t.Setenv(passphraseEnv, "from env")
+	passphrase, err := encryptionPassphrase("")
+	require.NoError(t, err)
+	assert.Equal(t, "from env", string(passphrase))
+
+	// The file takes precedence and loses only its trailing newline
+	path := filepath.Join(t.TempDir(), "passphrase")
+	require.NoError(t, os.WriteFile(path, []byte(" from file \n"), 0600))
+	passphrase, err = encryptionPassphrase(path)
+	require.NoError(t, err)
+	assert.Equal(t, " from file ", string(passphrase))
+}
+
+func TestGenEncryptAndDecrypt(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	t.Setenv(passphraseEnv, "correct horse")
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "find", "."}))
+	var exitCode int
+	CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "gen", "-compress", "gzip", "-encrypt", "."}))
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Equal(t, "Content file saved to skukozh_result.txt.gz.enc\n", output)
+	assert.NoFileExists(t, filepath.Join(testDir, resultName+".gz"))
+
+	t.Setenv(passphraseEnv, "wrong horse")
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "decrypt", resultName + ".gz.enc"}))
+	output = CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: skukozh_result.txt.gz.enc: wrong passphrase or corrupted file\n", output)
+
+	// The decrypted bundle is still compressed, which analyze reads as usual
+	t.Setenv(passphraseEnv, "correct horse")
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "decrypt", resultName + ".gz.enc"}))
+	output = CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Equal(t, "Decrypted file saved to skukozh_result.txt.gz\n", output)
+	data, err := os.ReadFile(filepath.Join(testDir, resultName+".gz"))
+	require.NoError(t, err)
+	content, err := decompressData(data)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "#FILE subdir/file3.go")
+}
+
+func TestGenEncryptWithoutPassphrase(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	t.Setenv(passphraseEnv, "")
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "gen", "-encrypt", "."}))
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: no passphrase: set SKUKOZH_PASSPHRASE or use -passphrase-file\n", output)
+}
+
+func TestDecryptOutputName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.bin")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = decryptResultFile(path, "", "")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: "+path+" doesn't end in .enc; name the output file as well\n", output)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Supported -format values for the result written by gen
+var resultFormats = []string{"text", "jsonl", "sqlite"}
+
+// Extensions of the result files written by gen -format jsonl and -format
+// sqlite, which replace the one of the result file
+const (
+	jsonlResultExt  = ".jsonl"
+	sqliteResultExt = ".db"
+)
+
+// Schema of the database written by gen -format sqlite
+const sqliteSchema = "CREATE TABLE files (path TEXT PRIMARY KEY, ext TEXT, size INTEGER, symbols INTEGER, content TEXT);\n"
+
+// resultFileName returns the name of the result file for a -format value
+func resultFileName(format string) string {
+	switch format {
+	case "jsonl":
+		return strings.TrimSuffix(resultName, filepath.Ext(resultName)) + jsonlResultExt
+	case "sqlite":
+		return strings.TrimSuffix(resultName, filepath.Ext(resultName)) + sqliteResultExt
+	default:
+		return resultName
+	}
+}
+
+// jsonlRecord is one line of a JSONL result, one per file
+type jsonlRecord struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Content  string `json:"content"`
+	SHA      string `json:"sha"` // SHA-256 of the file on disk
+	LOC      int    `json:"loc"` // non-blank lines before truncation
+}
+
+// jsonlRecordLine renders a file section as a JSONL line
+func jsonlRecordLine(section fileSection) (string, error) {
+	line, err := json.Marshal(jsonlRecord{
+		Path:     section.Path,
+		Language: section.Language,
+		Content:  section.Content,
+		SHA:      section.SHA256,
+		LOC:      section.Lines,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(line) + "\n", nil
+}
+
+// sqliteInsert renders a file section as an INSERT statement for the files
+// table. Text is hex-encoded so any content survives the sqlite3 shell.
+func sqliteInsert(section fileSection) string {
+	sqlText := func(s string) string {
+		return "CAST(X'" + hex.EncodeToString([]byte(s)) + "' AS TEXT)"
+	}
+	return fmt.Sprintf("INSERT INTO files VALUES (%s, %s, %d, %d, %s);\n",
+		sqlText(section.Path),
+		sqlText(strings.ToLower(path.Ext(section.Path))),
+		len(section.Content),
+		countSymbols(section.Content),
+		sqlText(section.Content))
+}
+
+// sqliteScript wraps the INSERT statements into a script creating the database
+func sqliteScript(inserts string) string {
+	return "BEGIN;\n" + sqliteSchema + inserts + "COMMIT;\n"
+}
+
+// sqliteDatabase runs a SQL script with the sqlite3 command line tool and
+// returns the resulting database file
+func sqliteDatabase(script string) ([]byte, error) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil, fmt.Errorf("-format sqlite requires the sqlite3 command: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "skukozh-*.db")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("sqlite3", "-bail", tmp.Name())
+	cmd.Stdin = strings.NewReader(script)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sqlite3 failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return os.ReadFile(tmp.Name())
+}
+
+// resultData turns the generated result into the bytes of the result file
+func resultData(result, format string) ([]byte, error) {
+	if format == "sqlite" {
+		return sqliteDatabase(result)
+	}
+	return []byte(result), nil
+}
+
+// checkResultFormat rejects a -format value that gen doesn't know and
+// options that only apply to the text layout
+func checkResultFormat(opts genOptions) error {
+	if opts.format != "" && !contains(resultFormats, opts.format) {
+		return fmt.Errorf("unknown format %q (use %s)", opts.format, strings.Join(resultFormats, " or "))
+	}
+	if opts.format == "" || opts.format == "text" {
+		return nil
+	}
+
+	var conflicts []string
+	for name, set := range map[string]bool{
+		"-summary":           opts.summary,
+		"-dependencies":      opts.dependencies,
+		"-squash-migrations": opts.squashMigs != "",
+		"-reference-locale":  opts.refLocale != "",
+		"-attach":            len(opts.attach) > 0,
+		"-meta":              opts.meta,
+		"-checksum":          opts.checksum,
+		"-annotate":          opts.annotate,
+		"-blame":             opts.blame,
+		"-toc":               opts.toc,
+		"-ids":               opts.ids,
+		"-template":          opts.template != "",
+		"-prompt":            opts.prompt != "" || opts.promptFile != "" || opts.promptSuffix != "" || opts.promptSuffixFile != "",
+	} {
+		if set {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("-format %s can't be combined with %s", opts.format, strings.Join(conflicts, ", "))
+	}
+	return nil
+}
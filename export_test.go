@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateJSONL(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"README.md": "# Title\n",
+	})
+	require.NoError(t, os.WriteFile(fileListName, []byte("main.go\nREADME.md"), 0644))
+	defer os.Remove(fileListName)
+
+	result, err := generateContentFileInternal(testDir, genOptions{format: "jsonl"})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(result, "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	var record jsonlRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, jsonlRecord{
+		Path:     "main.go",
+		Language: "go",
+		Content:  "package main\nfunc main() {}",
+		SHA:      sha256Hex([]byte("package main\n\nfunc main() {}\n")),
+		LOC:      2,
+	}, record)
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, "README.md", record.Path)
+	assert.Equal(t, "markdown", record.Language)
+}
+
+func TestCheckResultFormat(t *testing.T) {
+	assert.NoError(t, checkResultFormat(genOptions{}))
+	assert.NoError(t, checkResultFormat(genOptions{format: "text", summary: true}))
+	assert.NoError(t, checkResultFormat(genOptions{format: "jsonl", order: "alpha", outline: true}))
+
+	err := checkResultFormat(genOptions{format: "jsonl", summary: true, checksum: true, promptFile: "p.txt"})
+	assert.EqualError(t, err, "-format jsonl can't be combined with -checksum, -prompt, -summary")
+	assert.EqualError(t, checkResultFormat(genOptions{format: "sqlite", template: "t.tmpl"}), "-format sqlite can't be combined with -template")
+
+	assert.ErrorContains(t, checkResultFormat(genOptions{format: "yaml"}), `unknown format "yaml"`)
+}
+
+func TestResultFileName(t *testing.T) {
+	assert.Equal(t, resultName, resultFileName("text"))
+	assert.Equal(t, resultName, resultFileName(""))
+	assert.Equal(t, "skukozh_result.jsonl", resultFileName("jsonl"))
+	assert.Equal(t, "skukozh_result.db", resultFileName("sqlite"))
+}
+
+func TestGenerateSQLite(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 is not available")
+	}
+
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"src/main.go": "package main\n\nfunc main() { println(\"it's\") }\n",
+		"README.md":   "# Title\n",
+	})
+	require.NoError(t, os.WriteFile(fileListName, []byte("src/main.go\nREADME.md"), 0644))
+	defer os.Remove(fileListName)
+
+	result, err := generateContentFileInternal(testDir, genOptions{format: "sqlite"})
+	require.NoError(t, err)
+	data, err := resultData(result, "sqlite")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "SQLite format 3\x00"))
+
+	dbPath := filepath.Join(t.TempDir(), "skukozh_result.db")
+	require.NoError(t, os.WriteFile(dbPath, data, 0644))
+	out, err := exec.Command("sqlite3", dbPath, "SELECT path, ext, size, symbols, content FROM files ORDER BY path").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "README.md|.md|7|6|# Title\n"+
+		"src/main.go|.go|44|38|package main\nfunc main() { println(\"it's\") }\n", string(out))
+}
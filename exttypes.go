@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"slices"
+)
+
+// extOverrides returns the suffixes treated as text, added to the default
+// text extensions, and the suffixes treated as binary, never included. The
+// -treat-as-text and -treat-as-binary flags add to the text_exts and
+// binary_exts of the config file and win over them for the same suffix.
+func extOverrides(fs *flag.FlagSet) (text, binary []string, err error) {
+	cfg, err := configFromFlags(fs)
+	if err != nil {
+		return nil, nil, err
+	}
+	flagText, _ := parseExtFilter(fs.Lookup("treat-as-text").Value.String())
+	flagBinary, _ := parseExtFilter(fs.Lookup("treat-as-binary").Value.String())
+	configText, _ := parseExtFilter(cfg.TextExts)
+	configBinary, _ := parseExtFilter(cfg.BinaryExts)
+
+	for _, ext := range configText {
+		if !slices.Contains(flagBinary, ext) {
+			text = append(text, ext)
+		}
+	}
+	for _, ext := range configBinary {
+		if !slices.Contains(flagText, ext) {
+			binary = append(binary, ext)
+		}
+	}
+	return append(text, flagText...), append(binary, flagBinary...), nil
+}
+
+// defaultTextExts returns the extensions find includes without -ext: the
+// common text extensions, or those of the detected stacks, plus the ones
+// treated as text
+func defaultTextExts(exts, text []string) []string {
+	return append(slices.Clone(exts), text...)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtOverrides(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"text_exts": "dat,.fixture", "binary_exts": "ts,bin"}`), 0644))
+
+	fs := DefaultFlags()
+	require.NoError(t, fs.Set("config", configFile))
+	text, binary, err := extOverrides(fs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".dat", ".fixture"}, text)
+	assert.Equal(t, []string{".ts", ".bin"}, binary)
+
+	// Flags win over the config file for the same suffix
+	require.NoError(t, fs.Set("treat-as-text", "ts"))
+	require.NoError(t, fs.Set("treat-as-binary", "dat"))
+	text, binary, err = extOverrides(fs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".fixture", ".ts"}, text)
+	assert.Equal(t, []string{".bin", ".dat"}, binary)
+}
+
+func TestFindTreatAsTextAndBinary(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":            "package main\n",
+		"fixtures/input.dat": "name,age\n",
+		"video/clip.ts":      "G@\x00\x10",
+		"web/app.ts":         "export const x = 1\n",
+	})
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	find := func(args ...string) []string {
+		CaptureOutput(t, func() {
+			require.Equal(t, 0, runCommandIn(t, dir, append([]string{"-list-file", listFile, "find"}, args...)...))
+		})
+		entries, err := readFileList(listFile)
+		require.NoError(t, err)
+		var paths []string
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+		return paths
+	}
+
+	assert.Equal(t, []string{"main.go", "video/clip.ts", "web/app.ts"}, find("."))
+	assert.Equal(t, []string{"fixtures/input.dat", "main.go"}, find("-treat-as-text", "dat", "-treat-as-binary", "ts", "."))
+	assert.Equal(t, []string{"main.go"}, find("-ext", "go,ts", "-treat-as-binary", "ts", "."), "binary wins over -ext")
+
+	var output string
+	output = CaptureOutput(t, func() {
+		runCommandIn(t, dir, "why", "-treat-as-binary", "ts", ".", "video/clip.ts")
+	})
+	assert.Equal(t, "video/clip.ts is excluded: extension treated as binary (-treat-as-binary or binary_exts)\n", output)
+	output = CaptureOutput(t, func() {
+		runCommandIn(t, dir, "why", "-treat-as-text", "dat", ".", "fixtures/input.dat")
+	})
+	assert.Equal(t, "fixtures/input.dat is included: extension treated as text (-treat-as-text or text_exts)\n", output)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directory of the per-file cache below a project's root, shared by gen and
+// analyze -list
+var fileCacheDir = filepath.Join(".skukozh", "cache")
+
+// Extension of the files of the per-file cache
+const fileCacheExt = ".json"
+
+// fileCache stores the sections gen renders for the files below a project's
+// root, one cache file per path, modification time, size and options, so
+// unchanged files aren't read and processed again. A nil fileCache caches
+// nothing.
+type fileCache struct {
+	dir     string // cache directory
+	options string // hash of the options the sections are rendered with
+}
+
+// cacheableSections reports whether rendered sections only change with
+// their file and the options, so they can be cached by file size and
+// modification time: #GIT and #BLAME headers change with new commits, and
+// expanded tabs with .editorconfig files
+func cacheableSections(opts genOptions) bool {
+	return !opts.gitMeta && !opts.blame && !opts.expandTabs
+}
+
+// openFileCache returns the cache of the sections rendered with opts below
+// baseDir, or nil when opts disable it or the sections can't be cached,
+// see cacheableSections.
+func openFileCache(baseDir string, opts genOptions) *fileCache {
+	if opts.noCache || !cacheableSections(opts) {
+		return nil
+	}
+	options := sha256.Sum256([]byte(buildVersion() + " " + genCacheKey(baseDir, opts)))
+	return &fileCache{
+		dir:     filepath.Join(baseDir, fileCacheDir),
+		options: hex.EncodeToString(options[:]),
+	}
+}
+
+// path returns the cache file of the file list entry in the given state
+func (c *fileCache) path(entry string, info os.FileInfo) string {
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%s", entry, info.ModTime().UnixNano(), info.Size(), c.options)))
+	return filepath.Join(c.dir, hex.EncodeToString(key[:])+fileCacheExt)
+}
+
+// lookup returns the cached section of the file list entry if the file is
+// unchanged since it was stored
+func (c *fileCache) lookup(entry string, info os.FileInfo) (fileSection, bool) {
+	if c == nil || info == nil {
+		return fileSection{}, false
+	}
+	content, err := os.ReadFile(c.path(entry, info))
+	if err != nil {
+		return fileSection{}, false
+	}
+	var section fileSection
+	if err := json.Unmarshal(content, &section); err != nil {
+		return fileSection{}, false
+	}
+	return section, true
+}
+
+// store records the section rendered for the file list entry in its
+// current state. The cache only saves work, so failing to write it is
+// ignored.
+func (c *fileCache) store(entry string, info os.FileInfo, section fileSection) {
+	if c == nil || info == nil {
+		return
+	}
+	content, err := json.Marshal(section)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+	// Written atomically, so concurrent runs never read half a section
+	_ = writeFileAtomic(c.path(entry, info), content, 0644)
+}
+
+// cleanCache removes the per-file cache below root and the cache of
+// -incremental and returns the number of files removed
+func cleanCache(root string) (int, error) {
+	dir := filepath.Join(root, fileCacheDir)
+	files, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	removed := 0
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), fileCacheExt) {
+			removed++
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, err
+	}
+
+	if err := os.Remove(besideResult(cacheName)); err == nil {
+		removed++
+	} else if !os.IsNotExist(err) {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// runCache handles "cache clean" and returns the exit code. The cache is
+// cleaned below the directory argument, which defaults to the current
+// directory.
+func runCache(args []string) int {
+	if len(args) < 2 || len(args) > 3 || args[1] != "clean" {
+		fmt.Print(usage)
+		return 1
+	}
+	root := "."
+	if len(args) == 3 {
+		root = args[2]
+	}
+	removed, err := cleanCache(root)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	statusf("Removed %d cached files\n", removed)
+	return 0
+}
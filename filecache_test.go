@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCache(t *testing.T) {
+	dir, out := t.TempDir(), t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":   "package main\n\n\n\nfunc main() {}\n",
+		"README.md": "# App\n",
+	})
+	listFile := filepath.Join(out, "list.txt")
+	require.NoError(t, os.WriteFile(listFile, []byte("main.go\nREADME.md\n"), 0644))
+	run := func(args ...string) (string, int) {
+		var exitCode int
+		output := CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, dir, append([]string{"-list-file", listFile, "-result-file", filepath.Join(out, "bundle.txt")}, args...)...)
+		})
+		return output, exitCode
+	}
+
+	_, exitCode := run("gen", ".")
+	require.Equal(t, 0, exitCode)
+	entries, err := os.ReadDir(filepath.Join(dir, ".skukozh", "cache"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	t.Run("Unchanged files come from the cache", func(t *testing.T) {
+		// Same size and modification time, so the file looks unchanged
+		path := filepath.Join(dir, "main.go")
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, []byte("package xxxx\n\n\n\nfunc main() {}\n"), 0644))
+		require.NoError(t, os.Chtimes(path, time.Now(), info.ModTime()))
+
+		_, exitCode := run("gen", ".")
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, ReadTestFile(t, filepath.Join(out, "bundle.txt")), "package main")
+
+		_, exitCode = run("gen", "-no-cache", ".")
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, ReadTestFile(t, filepath.Join(out, "bundle.txt")), "package xxxx")
+
+		// Other options render the file again
+		_, exitCode = run("gen", "-outline", ".")
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, ReadTestFile(t, filepath.Join(out, "bundle.txt")), "package xxxx")
+	})
+
+	t.Run("analyze -list counts the cached sections", func(t *testing.T) {
+		output, exitCode := run("analyze", "-list", ".")
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "Estimated tokens: ~9\n", "main.go counts without its blank lines")
+
+		output, exitCode = run("analyze", "-list", "-no-cache", ".")
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "Estimated tokens: ~10\n", "estimated from the sizes")
+	})
+
+	t.Run("find skips the cache", func(t *testing.T) {
+		_, exitCode := run("find", "-hidden", "-ext", "go,json", "-format", "json", ".")
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, ReadTestFile(t, listFile), `"ignoredReason": "tool cache directory"`)
+	})
+
+	t.Run("Git headers aren't cached", func(t *testing.T) {
+		assert.Nil(t, openFileCache(dir, genOptions{gitMeta: true}))
+		assert.Nil(t, openFileCache(dir, genOptions{blame: true}))
+		assert.Nil(t, openFileCache(dir, genOptions{noCache: true}))
+		assert.NotNil(t, openFileCache(dir, genOptions{}))
+	})
+
+	t.Run("cache clean", func(t *testing.T) {
+		output, exitCode := run("cache", "clean", ".")
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "Removed 4 cached files")
+		assert.NoDirExists(t, filepath.Join(dir, ".skukozh", "cache"))
+
+		output, exitCode = run("cache", "clean", ".")
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "Removed 0 cached files")
+
+		_, exitCode = run("cache", "purge")
+		assert.Equal(t, 1, exitCode)
+	})
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported -format values for the file list
+var fileListFormats = []string{"text", "json"}
+
+// fileListHeader is the first record of a JSON file list, recording what
+// the #ROOT and #FILTERS lines record in a text list
+type fileListHeader struct {
+	Root    string `json:"root"`              // absolute directory the paths are relative to
+	Filters string `json:"filters,omitempty"` // find flags the list was made with
+}
+
+// fileListEntry describes a path in a JSON file list. Paths left out by
+// find are listed with the reason they were skipped.
+type fileListEntry struct {
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	ModTime       time.Time `json:"mtime"`
+	Ext           string    `json:"ext"`
+	IgnoredReason string    `json:"ignoredReason,omitempty"`
+	Priority      int       `json:"priority,omitempty"` // rank with -order priority, 1 first
+	Include       string    `json:"include,omitempty"`  // another file list included in place of the entry
+}
+
+// fileListEntries builds the JSON file list entries for the found files and
+// the skipped paths, sorted by path
+func fileListEntries(root string, files []string, skipped map[string]string) []fileListEntry {
+	entries := make([]fileListEntry, 0, len(files)+len(skipped))
+	add := func(file, reason string) {
+		entry := fileListEntry{Path: file, Ext: strings.ToLower(path.Ext(file)), IgnoredReason: reason}
+		if info, err := os.Stat(filepath.Join(root, file)); err == nil {
+			entry.Size = info.Size()
+			entry.ModTime = info.ModTime().UTC()
+			if info.IsDir() {
+				entry.Ext = ""
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, file := range files {
+		add(file, "")
+	}
+	for file, reason := range skipped {
+		add(file, reason)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
+
+// Start of a text file list entry including another list, as in @core.txt
+const fileListIncludePrefix = "@"
+
+// Starts of paths a text file list writes after "./", so @types/index.ts
+// isn't read back as an include, nor #notes.md as a comment
+var escapedListPrefixes = []string{fileListIncludePrefix, "#"}
+
+// escapeListPath returns the text file list line for the path file
+func escapeListPath(file string) string {
+	if hasAnyPrefix(file, escapedListPrefixes) {
+		return "./" + file
+	}
+	return file
+}
+
+// unescapeListPath reverses escapeListPath for a path read from a text list
+func unescapeListPath(line string) string {
+	if file, ok := strings.CutPrefix(line, "./"); ok && hasAnyPrefix(file, escapedListPrefixes) {
+		return file
+	}
+	return line
+}
+
+// Header lines of a text file list, written before the paths. Other lines
+// starting with # are comments.
+const (
+	fileListVersion       = "#SKUKOZH-LIST v2"
+	fileListRootPrefix    = "#ROOT "
+	fileListFiltersPrefix = "#FILTERS "
+)
+
+// listAnnotation matches an annotation at the end of a text file list
+// entry, such as "priority=1" or "lines=120-240"
+var listAnnotation = regexp.MustCompile(`\s+(priority|lines)=(\S*)$`)
+
+// writeFileList writes the file list in the given format, recording the
+// absolute root directory so gen can run from anywhere, and the find flags
+// the list was made with. Skipped paths are only recorded by the JSON
+// format.
+func writeFileList(root string, files []string, skipped map[string]string, format, filters string) error {
+	if format != "json" {
+		lines := make([]string, len(files))
+		for i, file := range files {
+			lines[i] = escapeListPath(file)
+		}
+		return writeFileListLines(root, lines, filters)
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	records := []any{fileListHeader{Root: filepath.ToSlash(absRoot), Filters: filters}}
+	for _, entry := range fileListEntries(root, files, skipped) {
+		records = append(records, entry)
+	}
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fileListName, append(content, '\n'), 0644)
+}
+
+// writeFileListLines writes a text file list of lines, such as the ones
+// formatFileListEntry returns, after the header of root and filters
+func writeFileListLines(root string, lines []string, filters string) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	header := []string{fileListVersion, fileListRootPrefix + filepath.ToSlash(absRoot)}
+	if filters != "" {
+		header = append(header, fileListFiltersPrefix+filters)
+	}
+	return writeFileAtomic(fileListName, []byte(strings.Join(append(header, lines...), "\n")), 0644)
+}
+
+// listFilters describes the flags of command set on fs and the paths find
+// was limited to, e.g. "-ext=go -hidden -- cmd", for the #FILTERS header
+func listFilters(fs *flag.FlagSet, command string, paths []string) string {
+	filters := commandFlagArgs(fs, command, "verbose", "format")
+	if len(paths) > 0 {
+		filters = strings.TrimSpace(filters + " -- " + strings.Join(paths, " "))
+	}
+	return filters
+}
+
+// formatFileListEntry returns the line of a text file list for entry,
+// followed by its annotations
+func formatFileListEntry(entry fileListEntry) string {
+	line := escapeListPath(entry.Path)
+	if entry.Include != "" {
+		line = fileListIncludePrefix + entry.Include
+	}
+	if entry.Priority != 0 {
+		return fmt.Sprintf("%s priority=%d", line, entry.Priority)
+	}
+	return line
+}
+
+// parseFileList returns the files of a text or JSON file list. Entries of a
+// JSON list that have an ignored reason and included lists are left out.
+func parseFileList(content []byte) ([]string, error) {
+	entries, err := parseFileListEntries(content)
+	if err != nil {
+		return nil, err
+	}
+	return entryPaths(entries), nil
+}
+
+// entryPaths returns the paths of file list entries, skipping includes
+func entryPaths(entries []fileListEntry) []string {
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Include == "" {
+			files = append(files, entry.Path)
+		}
+	}
+	return files
+}
+
+// listedFiles returns the files of the file list, including the files of
+// the lists it includes
+func listedFiles() ([]string, error) {
+	entries, err := readFileList(fileListName)
+	if err != nil {
+		return nil, err
+	}
+	return entryPaths(entries), nil
+}
+
+// readFileList returns the entries of the file list name, replacing every
+// @list entry with the entries of the list it includes. Included paths are
+// relative to the directory of the including list. An entry listed more
+// than once is kept where it first appears, and an include's priority
+// applies to its entries that have none.
+func readFileList(name string) ([]fileListEntry, error) {
+	seen := make(map[string]bool)
+	return expandFileList(name, nil, seen)
+}
+
+// expandFileList reads the file list name for readFileList; including holds
+// the lists including it, to detect cycles
+func expandFileList(name string, including []string, seen map[string]bool) ([]fileListEntry, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		if len(including) > 0 {
+			return nil, fmt.Errorf("invalid file list %s: %w", including[len(including)-1], err)
+		}
+		return nil, err
+	}
+	entries, err := parseFileListEntries(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file list %s: %w", name, err)
+	}
+
+	absName, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	including = append(including, absName)
+
+	var expanded []fileListEntry
+	for _, entry := range entries {
+		if entry.Include == "" {
+			if !seen[entry.Path] {
+				seen[entry.Path] = true
+				expanded = append(expanded, entry)
+			}
+			continue
+		}
+
+		included := filepath.FromSlash(entry.Include)
+		if !filepath.IsAbs(included) {
+			included = filepath.Join(filepath.Dir(absName), included)
+		}
+		if contains(including, included) {
+			return nil, fmt.Errorf("file list %s includes itself through %s", included, name)
+		}
+		nested, err := expandFileList(included, including, seen)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range nested {
+			if file.Priority == 0 {
+				file.Priority = entry.Priority
+			}
+			expanded = append(expanded, file)
+		}
+	}
+	return expanded, nil
+}
+
+// parseFileListEntries returns the entries of a text or JSON file list with
+// their annotations. The header record of a JSON list has no path and is
+// skipped. A lines= annotation is turned into a line range suffix
+// of the path, as in "src/server.go:120-240".
+func parseFileListEntries(content []byte) ([]fileListEntry, error) {
+	var entries []fileListEntry
+
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("[")) {
+		var all []fileListEntry
+		if err := json.Unmarshal(content, &all); err != nil {
+			return nil, err
+		}
+		for _, entry := range all {
+			if entry.IgnoredReason == "" && (entry.Path != "" || entry.Include != "") {
+				entries = append(entries, entry)
+			}
+		}
+		return entries, nil
+	}
+
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry := fileListEntry{Path: line}
+		lines := ""
+		for match := listAnnotation.FindStringSubmatch(entry.Path); match != nil; match = listAnnotation.FindStringSubmatch(entry.Path) {
+			entry.Path = strings.TrimSuffix(entry.Path, match[0])
+			switch match[1] {
+			case "priority":
+				priority, err := strconv.Atoi(match[2])
+				if err != nil || priority < 1 {
+					return nil, fmt.Errorf("line %d: priority must be a positive number, got %q", i+1, match[2])
+				}
+				entry.Priority = priority
+			case "lines":
+				lines = match[2]
+			}
+		}
+		if include, ok := strings.CutPrefix(entry.Path, fileListIncludePrefix); ok {
+			if lines != "" {
+				return nil, fmt.Errorf("line %d: lines= can't annotate the included list %s", i+1, include)
+			}
+			entry.Path, entry.Include = "", include
+		} else {
+			entry.Path = unescapeListPath(entry.Path)
+			if lines != "" {
+				if _, _, ok, _ := parseLineRange(entry.Path); ok {
+					return nil, fmt.Errorf("line %d: %s has both a line range and lines=%s", i+1, entry.Path, lines)
+				}
+				entry.Path += ":" + lines
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseFileListHeader returns the root directory and the filters recorded
+// in a text or JSON file list. Both are empty for lists written without
+// them, such as plain lists of paths.
+func parseFileListHeader(content []byte) (root, filters string) {
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("[")) {
+		// Lists of earlier versions repeat the header fields in every entry
+		var records []fileListHeader
+		if err := json.Unmarshal(content, &records); err != nil || len(records) == 0 {
+			return "", ""
+		}
+		return filepath.FromSlash(records[0].Root), records[0].Filters
+	}
+
+	// The header ends at the first entry
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if value, ok := strings.CutPrefix(line, fileListRootPrefix); ok && root == "" {
+			root = filepath.FromSlash(value)
+		} else if value, ok := strings.CutPrefix(line, fileListFiltersPrefix); ok && filters == "" {
+			filters = value
+		} else if line != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+	}
+	return root, filters
+}
+
+// directoryArg returns the directory argument following the command name,
+// or the root recorded in the file list when it's omitted
+func directoryArg(args []string) (string, error) {
+	if len(args) > 1 {
+		return args[1], nil
+	}
+	return fileListRoot()
+}
+
+// fileListRoot returns the root directory recorded in the file list, for
+// commands run without a directory argument
+func fileListRoot() (string, error) {
+	content, err := os.ReadFile(fileListName)
+	if err != nil {
+		return "", err
+	}
+	root, _ := parseFileListHeader(content)
+	if root == "" {
+		return "", fmt.Errorf("%s records no root directory; give the directory or run find again", fileListName)
+	}
+	return root, nil
+}
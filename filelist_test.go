@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFileList(t *testing.T) {
+	t.Run("Text list", func(t *testing.T) {
+		files, err := parseFileList([]byte("a.go\n\nsub/b.go\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.go", "sub/b.go"}, files)
+	})
+
+	t.Run("JSON list skips ignored entries", func(t *testing.T) {
+		content := `[
+  {"path": "a.go", "size": 10, "ext": ".go"},
+  {"path": "node_modules", "ignoredReason": "package directory"},
+  {"path": "sub/b.go", "size": 20, "ext": ".go"}
+]`
+		files, err := parseFileList([]byte(content))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.go", "sub/b.go"}, files)
+	})
+
+	t.Run("Header and comments", func(t *testing.T) {
+		content := []byte("#SKUKOZH-LIST v2\n#ROOT /src/app\n#FILTERS -ext=go\n# entry points\na.go\n  # indented comment\n#ROOT /elsewhere\n./#b.go\n")
+		files, err := parseFileList(content)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.go", "#b.go"}, files)
+
+		root, filters := parseFileListHeader(content)
+		assert.Equal(t, filepath.FromSlash("/src/app"), root)
+		assert.Equal(t, "-ext=go", filters)
+		root, filters = parseFileListHeader([]byte("a.go\n#ROOT /src/app\n"))
+		assert.Empty(t, root, "the header ends at the first entry")
+		assert.Empty(t, filters)
+		root, filters = parseFileListHeader([]byte(`[{"root": "/src/app", "filters": "-hidden", "path": "a.go"}]`))
+		assert.Equal(t, filepath.FromSlash("/src/app"), root)
+		assert.Equal(t, "-hidden", filters)
+
+		// JSON lists start with a header record
+		content = []byte(`[{"root": "/src/app", "filters": "-ext=go"}, {"path": "a.go", "ext": ".go"}]`)
+		root, filters = parseFileListHeader(content)
+		assert.Equal(t, filepath.FromSlash("/src/app"), root)
+		assert.Equal(t, "-ext=go", filters)
+		files, err = parseFileList(content)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.go"}, files)
+	})
+
+	t.Run("Annotations", func(t *testing.T) {
+		entries, err := parseFileListEntries([]byte("main.go priority=1\nserver.go lines=120-240  priority=2\nmy notes.md\nconfig.go:42\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []fileListEntry{
+			{Path: "main.go", Priority: 1},
+			{Path: "server.go:120-240", Priority: 2},
+			{Path: "my notes.md"},
+			{Path: "config.go:42"},
+		}, entries)
+		assert.Equal(t, "main.go priority=1", formatFileListEntry(entries[0]))
+		assert.Equal(t, "my notes.md", formatFileListEntry(entries[2]))
+
+		_, err = parseFileListEntries([]byte("a.go\nb.go priority=high\n"))
+		assert.ErrorContains(t, err, "line 2: priority must be a positive number")
+		_, err = parseFileListEntries([]byte("b.go:1-5 lines=6-9\n"))
+		assert.ErrorContains(t, err, "both a line range and lines=6-9")
+	})
+
+	t.Run("Malformed JSON list", func(t *testing.T) {
+		_, err := parseFileList([]byte(`[{"path": `))
+		assert.Error(t, err)
+	})
+}
+
+func TestReadFileList(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"lists/core.txt":     "# shared by every feature\nmain.go\n@../base.txt\nutil.go priority=3\n",
+		"base.txt":           "go.mod\nmain.go\n",
+		"feature.txt":        "#ROOT /src/app\n@lists/core.txt priority=2\napi.go priority=1\nutil.go\n",
+		"cycle/a.txt":        "a.go\n@b.txt\n",
+		"cycle/b.txt":        "b.go\n@a.txt\n",
+		"broken/main.txt":    "@missing.txt\n",
+		"broken/invalid.txt": "@bad.txt\n",
+		"broken/bad.txt":     "a.go priority=0\n",
+	})
+
+	entries, err := readFileList(filepath.Join(dir, "feature.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, []fileListEntry{
+		{Path: "main.go", Priority: 2},
+		{Path: "go.mod", Priority: 2},
+		{Path: "util.go", Priority: 3},
+		{Path: "api.go", Priority: 1},
+	}, entries, "included entries take the include's priority and are kept where they first appear")
+
+	_, err = readFileList(filepath.Join(dir, "cycle", "a.txt"))
+	assert.ErrorContains(t, err, "a.txt includes itself through")
+	_, err = readFileList(filepath.Join(dir, "broken", "main.txt"))
+	assert.ErrorContains(t, err, "missing.txt")
+	assert.True(t, os.IsNotExist(errors.Unwrap(err)))
+	_, err = readFileList(filepath.Join(dir, "broken", "invalid.txt"))
+	assert.ErrorContains(t, err, "invalid file list "+filepath.Join(dir, "broken", "bad.txt")+": line 1")
+
+	_, err = parseFileListEntries([]byte("@core.txt lines=1-5\n"))
+	assert.ErrorContains(t, err, "can't annotate the included list core.txt")
+
+	t.Run("gen", func(t *testing.T) {
+		writeTestFiles(t, dir, map[string]string{"main.go": "package main\n", "util.go": "package main\n"})
+		require.NoError(t, os.WriteFile(fileListName, []byte("util.go\n@"+filepath.ToSlash(filepath.Join(dir, "lists", "core.txt"))+"\n"), 0644))
+		defer os.Remove(fileListName)
+
+		content, err := generateContentFileInternal(dir, genOptions{})
+		require.NoError(t, err)
+		var paths []string
+		for _, section := range parseBundleSections(content) {
+			paths = append(paths, section.path)
+		}
+		assert.Equal(t, []string{"util.go", "main.go"}, paths, "go.mod of base.txt doesn't exist")
+	})
+}
+
+func TestFindFilesJSONFormat(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":                   "package main\n",
+		"notes.bin":                 "data",
+		"node_modules/lib/index.js": "module.exports = {}\n",
+	})
+	defer os.Remove(fileListName)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"find", "-format", "json", testDir}))
+
+	var exitCode int
+	CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+
+	var records []json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(ReadTestFile(t, fileListName)), &records))
+	require.NotEmpty(t, records)
+	assert.JSONEq(t, `{"root": `+strconv.Quote(filepath.ToSlash(testDir))+`}`, string(records[0]), "the header comes first")
+
+	byPath := make(map[string]fileListEntry)
+	for _, record := range records[1:] {
+		assert.NotContains(t, string(record), `"root"`, "entries don't repeat the header")
+		var entry fileListEntry
+		require.NoError(t, json.Unmarshal(record, &entry))
+		byPath[entry.Path] = entry
+	}
+	assert.Equal(t, fileListEntry{
+		Path:    "main.go",
+		Size:    13,
+		ModTime: byPath["main.go"].ModTime,
+		Ext:     ".go",
+	}, byPath["main.go"])
+	assert.False(t, byPath["main.go"].ModTime.IsZero())
+	assert.Equal(t, "package directory", byPath["node_modules"].IgnoredReason)
+	assert.Equal(t, "extension not among the default text extensions (binary or unknown file type)", byPath["notes.bin"].IgnoredReason)
+
+	// gen accepts the JSON list and skips the ignored entries
+	content, err := generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, content, "#FILE main.go")
+	assert.NotContains(t, content, "notes.bin")
+}
+
+func TestFindGenRoundTripEscapedPaths(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.ts":          "export {}\n",
+		"@types/index.ts":  "export type ID = string\n",
+		"@types/extra.txt": "notes\n",
+		"#notes.md":        "# Notes\n",
+	})
+	defer os.Remove(fileListName)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"find", testDir}))
+	var exitCode int
+	CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Contains(t, ReadTestFile(t, fileListName), "\n./@types/index.ts\n")
+	assert.Contains(t, ReadTestFile(t, fileListName), "\n./#notes.md\n")
+
+	files, err := listedFiles()
+	require.NoError(t, err)
+	assert.Contains(t, files, "@types/index.ts")
+	assert.Contains(t, files, "#notes.md")
+
+	content, err := generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, content, "#FILE @types/index.ts")
+	assert.Contains(t, content, "#FILE main.ts")
+	assert.Contains(t, content, "#FILE #notes.md")
+	assert.Equal(t, "./@types/index.ts priority=1", formatFileListEntry(fileListEntry{Path: "@types/index.ts", Priority: 1}))
+}
+
+func TestWriteFileListText(t *testing.T) {
+	defer os.Remove(fileListName)
+
+	root := t.TempDir()
+	require.NoError(t, writeFileList(root, []string{"a.go", "b.go"}, map[string]string{"c.bin": "skipped"}, "text", "-ext=go"))
+	assert.Equal(t, fileListText(t, root, "-ext=go", "a.go", "b.go"), ReadTestFile(t, fileListName))
+
+	root, filters := parseFileListHeader([]byte(ReadTestFile(t, fileListName)))
+	assert.Equal(t, filepath.Clean(root), root)
+	assert.Equal(t, "-ext=go", filters)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// finding is an issue a static analysis tool reported at a line of a file
+type finding struct {
+	Path     string // file as the report names it, absolute or relative to the current directory
+	Line     int    // line of the issue, starting at 1; 0 for the whole file
+	Severity string // error, warning, note, ...; empty if the report has none
+	Tool     string // tool or analyzer that reported it
+	Rule     string // rule ID, if any
+	Message  string
+}
+
+// findingsReport holds the findings read with -findings, by the path their
+// report names
+type findingsReport map[string][]finding
+
+// loadFindings reads a SARIF log, the output of go vet -json or an eslint
+// -f json report
+func loadFindings(name string) (findingsReport, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var findings []finding
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		findings, err = parseESLintFindings(trimmed)
+	case bytes.Contains(trimmed, []byte(`"runs"`)) && bytes.Contains(trimmed, []byte(`"version"`)):
+		findings, err = parseSARIFFindings(trimmed)
+	default:
+		findings, err = parseGoVetFindings(trimmed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid -findings report %s: %v", name, err)
+	}
+
+	report := make(findingsReport)
+	for _, f := range findings {
+		f.Message = strings.Join(strings.Fields(f.Message), " ")
+		report[f.Path] = append(report[f.Path], f)
+	}
+	return report, nil
+}
+
+// parseSARIFFindings reads the results of every run of a SARIF 2.1 log at
+// their first physical location
+func parseSARIFFindings(content []byte) ([]finding, error) {
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Name string `json:"name"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(content, &log); err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	for _, run := range log.Runs {
+		for _, result := range run.Results {
+			if len(result.Locations) == 0 {
+				continue
+			}
+			location := result.Locations[0].PhysicalLocation
+			path := strings.TrimPrefix(location.ArtifactLocation.URI, "file://")
+			if unescaped, err := url.PathUnescape(path); err == nil {
+				path = unescaped
+			}
+			level := result.Level
+			if level == "" {
+				level = "warning" // the SARIF default
+			}
+			findings = append(findings, finding{
+				Path:     path,
+				Line:     location.Region.StartLine,
+				Severity: level,
+				Tool:     run.Tool.Driver.Name,
+				Rule:     result.RuleID,
+				Message:  result.Message.Text,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// parseGoVetFindings reads the output of go vet -json: one object per
+// package, mapping each analyzer to its diagnostics, after "# package"
+// comment lines
+func parseGoVetFindings(content []byte) ([]finding, error) {
+	var lines [][]byte
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			lines = append(lines, line)
+		}
+	}
+
+	var findings []finding
+	decoder := json.NewDecoder(bytes.NewReader(bytes.Join(lines, []byte("\n"))))
+	for {
+		var packages map[string]map[string]json.RawMessage
+		if err := decoder.Decode(&packages); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for _, analyzers := range packages {
+			for analyzer, raw := range analyzers {
+				// Analyzers that failed report an {"error": ...} object instead
+				var diagnostics []struct {
+					Posn    string `json:"posn"`
+					Message string `json:"message"`
+				}
+				if json.Unmarshal(raw, &diagnostics) != nil {
+					continue
+				}
+				for _, d := range diagnostics {
+					path, line := splitPosition(d.Posn)
+					findings = append(findings, finding{Path: path, Line: line, Tool: "go vet", Rule: analyzer, Message: d.Message})
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// splitPosition splits a "file.go:12:3" position into the file and line
+func splitPosition(posn string) (string, int) {
+	path := posn
+	for i := 0; i < 2; i++ {
+		colon := strings.LastIndex(path, ":")
+		if colon < 0 {
+			break
+		}
+		if _, err := strconv.Atoi(path[colon+1:]); err != nil {
+			break
+		}
+		path = path[:colon]
+	}
+	line, _ := strconv.Atoi(strings.SplitN(strings.TrimPrefix(posn, path+":"), ":", 2)[0])
+	return path, line
+}
+
+// eslintSeverities names the numeric severities of eslint messages
+var eslintSeverities = map[int]string{1: "warning", 2: "error"}
+
+// parseESLintFindings reads the messages of an eslint -f json report
+func parseESLintFindings(content []byte) ([]finding, error) {
+	var results []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID   string `json:"ruleId"`
+			Severity int    `json:"severity"`
+			Message  string `json:"message"`
+			Line     int    `json:"line"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(content, &results); err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	for _, result := range results {
+		for _, m := range result.Messages {
+			findings = append(findings, finding{
+				Path:     result.FilePath,
+				Line:     m.Line,
+				Severity: eslintSeverities[m.Severity],
+				Tool:     "eslint",
+				Rule:     m.RuleID,
+				Message:  m.Message,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// forFile returns the findings reported for file, a path relative to
+// baseDir, sorted by line. Reports name files by absolute path or relative
+// to the directory the tool ran in, which is taken to be either baseDir or
+// the current directory.
+func (r findingsReport) forFile(baseDir, file string) []finding {
+	if len(r) == 0 {
+		return nil
+	}
+	abs, err := filepath.Abs(filepath.Join(baseDir, file))
+	if err != nil {
+		return nil
+	}
+
+	var findings []finding
+	for path, reported := range r {
+		matches := filepath.ToSlash(filepath.Clean(path)) == file
+		if !matches {
+			if resolved, err := filepath.Abs(path); err == nil {
+				matches = resolved == abs
+			}
+		}
+		if matches {
+			findings = append(findings, reported...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings
+}
+
+// findingComment returns the comment line reporting f, written with the
+// line comment of language or, for languages with only block comments, a
+// block comment
+func findingComment(language string, f finding) string {
+	label := f.Tool
+	if f.Rule != "" {
+		label = strings.TrimSpace(label + " " + f.Rule)
+	}
+	if f.Severity != "" {
+		label += ", " + f.Severity
+	}
+	text := fmt.Sprintf("FINDING line %d [%s]: %s", f.Line, label, f.Message)
+	if f.Line == 0 {
+		text = fmt.Sprintf("FINDING [%s]: %s", label, f.Message)
+	}
+
+	syntax, ok := commentSyntaxes[language]
+	switch {
+	case !ok:
+		return "# " + text
+	case len(syntax.line) > 0:
+		return syntax.line[0] + " " + text
+	default:
+		return syntax.blockStart + " " + text + " " + syntax.blockEnd
+	}
+}
+
+// insertFindings adds a comment after each line of content with findings,
+// indented like that line. firstLine is the line of the file content starts
+// at, for line ranges and symbols; findings outside content are dropped,
+// and findings about the whole file go before the first line.
+func insertFindings(language string, content []byte, firstLine int, findings []finding) []byte {
+	if len(findings) == 0 {
+		return content
+	}
+	lines := strings.Split(string(content), "\n")
+	after := make(map[int][]string) // comments by the index of the line they follow, -1 for the top
+	for _, f := range findings {
+		index := f.Line - firstLine
+		switch {
+		case f.Line == 0:
+			after[-1] = append(after[-1], findingComment(language, f))
+		case index >= 0 && index < len(lines):
+			indent := lines[index][:len(lines[index])-len(strings.TrimLeft(lines[index], " \t"))]
+			after[index] = append(after[index], indent+findingComment(language, f))
+		}
+	}
+
+	var out []string
+	out = append(out, after[-1]...)
+	for i, line := range lines {
+		out = append(out, line)
+		out = append(out, after[i]...)
+	}
+	return []byte(strings.Join(out, "\n"))
+}
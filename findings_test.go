@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFindings(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"results.sarif": `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "CodeQL"}}, "results": [
+			{"ruleId": "go/sql-injection", "level": "error", "message": {"text": "Query built from\nuser input"},
+			 "locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///src/app/db%20layer.go"}, "region": {"startLine": 14}}}]},
+			{"ruleId": "go/unused", "message": {"text": "Unused"}, "locations": [{"physicalLocation": {"artifactLocation": {"uri": "app.go"}, "region": {"startLine": 3}}}]},
+			{"ruleId": "no-location", "message": {"text": "Skipped"}}]}]}`,
+		"vet.json":    "# example.com/app\n" + `{"example.com/app": {"printf": [{"posn": "/src/app/main.go:12:3", "message": "Sprintf format %d has arg s of wrong type string"}], "broken": {"error": "failed"}}}` + "\n",
+		"eslint.json": `[{"filePath": "/src/web/app.js", "messages": [{"ruleId": "no-unused-vars", "severity": 2, "message": "'x' is defined but never used.", "line": 3}]}]`,
+		"bad.json":    `{"example.com/app": `,
+	})
+
+	report, err := loadFindings(filepath.Join(dir, "results.sarif"))
+	require.NoError(t, err)
+	assert.Equal(t, findingsReport{
+		"/src/app/db layer.go": {{Path: "/src/app/db layer.go", Line: 14, Severity: "error", Tool: "CodeQL", Rule: "go/sql-injection", Message: "Query built from user input"}},
+		"app.go":               {{Path: "app.go", Line: 3, Severity: "warning", Tool: "CodeQL", Rule: "go/unused", Message: "Unused"}},
+	}, report)
+
+	report, err = loadFindings(filepath.Join(dir, "vet.json"))
+	require.NoError(t, err)
+	assert.Equal(t, findingsReport{
+		"/src/app/main.go": {{Path: "/src/app/main.go", Line: 12, Tool: "go vet", Rule: "printf", Message: "Sprintf format %d has arg s of wrong type string"}},
+	}, report)
+
+	report, err = loadFindings(filepath.Join(dir, "eslint.json"))
+	require.NoError(t, err)
+	assert.Equal(t, findingsReport{
+		"/src/web/app.js": {{Path: "/src/web/app.js", Line: 3, Severity: "error", Tool: "eslint", Rule: "no-unused-vars", Message: "'x' is defined but never used."}},
+	}, report)
+
+	_, err = loadFindings(filepath.Join(dir, "bad.json"))
+	assert.ErrorContains(t, err, "invalid -findings report")
+}
+
+func TestInsertFindings(t *testing.T) {
+	content := []byte("func main() {\n\tfmt.Printf(\"%d\", s)\n}\n")
+	findings := []finding{
+		{Line: 0, Tool: "staticcheck", Message: "package has no doc"},
+		{Line: 2, Tool: "go vet", Rule: "printf", Message: "wrong type"},
+		{Line: 9, Tool: "go vet", Message: "past the end"},
+	}
+	assert.Equal(t, "// FINDING [staticcheck]: package has no doc\nfunc main() {\n\tfmt.Printf(\"%d\", s)\n\t// FINDING line 2 [go vet printf]: wrong type\n}\n",
+		string(insertFindings("go", content, 1, findings)))
+
+	// Ranges start further down the file
+	assert.Equal(t, "\tfmt.Printf(\"%d\", s)\n\t// FINDING line 2 [go vet printf]: wrong type",
+		string(insertFindings("go", []byte("\tfmt.Printf(\"%d\", s)"), 2, findings[1:])))
+
+	assert.Equal(t, "<p>\n<!-- FINDING line 1 [htmlhint, error]: unclosed -->",
+		string(insertFindings("html", []byte("<p>"), 1, []finding{{Line: 1, Tool: "htmlhint", Severity: "error", Message: "unclosed"}})))
+}
+
+func TestGenerateContentFileFindings(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tfmt.Printf(\"%d\", s)\n}\n",
+		"util.go": "package main\n",
+	})
+	absMain, err := filepath.Abs(filepath.Join(testDir, "main.go"))
+	require.NoError(t, err)
+	report := findingsReport{absMain: {{Path: absMain, Line: 4, Tool: "go vet", Rule: "printf", Message: "wrong type"}}}
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("main.go\nutil.go"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{findings: report, noCache: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "\tfmt.Printf(\"%d\", s)\n\t// FINDING line 4 [go vet printf]: wrong type\n}\n")
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("main.go:3-4"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	result, err = generateContentFileInternal(testDir, genOptions{findings: report, noCache: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "func main() {\n\tfmt.Printf(\"%d\", s)\n\t// FINDING line 4 [go vet printf]: wrong type\n```")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// skipCategories groups skip reasons for the find summary, matched by prefix
+var skipCategories = []struct {
+	prefix string
+	label  string
+}{
+	{"hidden", "hidden"},
+	{"path ignored by profile ", "profile"},
+	{"path ignored by -no-tests", "tests"},
+	{"not test code", "not tests"},
+	{"not in the -coverage profile", "no coverage data"},
+	{"coverage at or above", "well covered"},
+	{"path ignored by ", "gitignored"},
+	{"package directory", "ignored directories"},
+	{"Go build dir", "ignored directories"},
+	{"directory named by -ignore-dirs", "ignored directories"},
+	{"extension not among the default text extensions", "binary or unknown type"},
+	{"extension treated as binary", "binary or unknown type"},
+	{"extension not among the extensions of the detected stacks", "outside detected stacks"},
+	{"extension not in -ext filter", "wrong extension"},
+	{"excluded extension", "wrong extension"},
+	{"path below -max-depth", "too deep"},
+	{"outside the requested paths", "outside requested paths"},
+	{"directory not in -only-dirs", "outside requested paths"},
+	{"modified before -newer", "too old"},
+	{"content doesn't match", "content mismatch"},
+	{"minified content", "minified"},
+	{"large XML", "large or generated XML"},
+	{"generated XML", "large or generated XML"},
+	{"tool file", "tool files"},
+	{"tool cache", "tool files"},
+}
+
+// skipCategory returns the summary label for a skip reason
+func skipCategory(reason string) string {
+	for _, category := range skipCategories {
+		if strings.HasPrefix(reason, category.prefix) {
+			return category.label
+		}
+	}
+	return "other"
+}
+
+// skipSummary groups skipped paths by category, in skipCategories order
+func skipSummary(skipped map[string]string) (labels []string, groups map[string][]string) {
+	groups = make(map[string][]string)
+	for path, reason := range skipped {
+		label := skipCategory(reason)
+		groups[label] = append(groups[label], path)
+	}
+
+	for _, category := range skipCategories {
+		if len(groups[category.label]) > 0 && !contains(labels, category.label) {
+			labels = append(labels, category.label)
+		}
+	}
+	if len(groups["other"]) > 0 {
+		labels = append(labels, "other")
+	}
+	for _, label := range labels {
+		sort.Strings(groups[label])
+	}
+	return labels, groups
+}
+
+// printSkipSummary prints the number of skipped paths per category and,
+// when verbose, the paths themselves
+func printSkipSummary(skipped map[string]string, verbose bool) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	labels, groups := skipSummary(skipped)
+	counts := make([]string, 0, len(labels))
+	for _, label := range labels {
+		counts = append(counts, fmt.Sprintf("%d %s", len(groups[label]), label))
+	}
+	statusf("Skipped %d paths: %s\n", len(skipped), strings.Join(counts, ", "))
+
+	if !verbose {
+		return
+	}
+	for _, label := range labels {
+		fmt.Printf("  %s:\n", label)
+		for _, path := range groups[label] {
+			fmt.Printf("    %s (%s)\n", path, skipped[path])
+		}
+	}
+}
+
+// Number of inaccessible paths find names unless -verbose is set
+const accessErrorSamples = 5
+
+// accessErrorReason returns the reason the walk couldn't access a path,
+// without the path the error repeats
+func accessErrorReason(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// printAccessErrors prints the number of paths find couldn't access by
+// reason, followed by the first of them or, when verbose, all of them. They
+// are printed even with -quiet, as the file list misses whatever lies below.
+func printAccessErrors(accessErrors map[string]string, verbose bool) {
+	if len(accessErrors) == 0 {
+		return
+	}
+
+	paths := make([]string, 0, len(accessErrors))
+	counts := make(map[string]int)
+	var reasons []string
+	for path, reason := range accessErrors {
+		paths = append(paths, path)
+		if counts[reason] == 0 {
+			reasons = append(reasons, reason)
+		}
+		counts[reason]++
+	}
+	sort.Strings(paths)
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	summary := make([]string, len(reasons))
+	for i, reason := range reasons {
+		summary[i] = fmt.Sprintf("%d %s", counts[reason], reason)
+	}
+
+	fmt.Printf("Couldn't access %d paths: %s\n", len(paths), strings.Join(summary, ", "))
+	shown := paths
+	if !verbose && len(paths) > accessErrorSamples {
+		shown = paths[:accessErrorSamples]
+	}
+	for _, path := range shown {
+		fmt.Printf("  %s (%s)\n", path, accessErrors[path])
+	}
+	if len(shown) < len(paths) {
+		fmt.Printf("  and %d more (use -verbose to list them all)\n", len(paths)-len(shown))
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipSummary(t *testing.T) {
+	skipped := map[string]string{
+		".env":         "hidden file",
+		".git":         "hidden directory",
+		"debug.log":    "path ignored by .gitignore:3 (*.log)",
+		"logo.png":     "extension not among the default text extensions (binary or unknown file type)",
+		"node_modules": "package directory",
+		"_build":       "Go build dir",
+		"old.go":       "modified before -newer",
+		"weird":        "something else",
+	}
+
+	labels, groups := skipSummary(skipped)
+	assert.Equal(t, []string{"hidden", "gitignored", "ignored directories", "binary or unknown type", "too old", "other"}, labels)
+	assert.Equal(t, []string{".env", ".git"}, groups["hidden"])
+	assert.Equal(t, []string{"_build", "node_modules"}, groups["ignored directories"])
+
+	t.Run("Counts line", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			printSkipSummary(skipped, false)
+		})
+		assert.Equal(t, "Skipped 8 paths: 2 hidden, 1 gitignored, 2 ignored directories, 1 binary or unknown type, 1 too old, 1 other\n", output)
+	})
+
+	t.Run("Verbose lists paths", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			printSkipSummary(skipped, true)
+		})
+		assert.Contains(t, output, "  gitignored:\n    debug.log (path ignored by .gitignore:3 (*.log))\n")
+	})
+
+	t.Run("Nothing skipped", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			printSkipSummary(nil, true)
+		})
+		assert.Empty(t, output)
+	})
+}
+
+func TestPrintAccessErrors(t *testing.T) {
+	assert.Equal(t, "permission denied", accessErrorReason(&fs.PathError{Op: "open", Path: "/src/private", Err: fs.ErrPermission}))
+	assert.Equal(t, "boom", accessErrorReason(errors.New("boom")))
+
+	accessErrors := map[string]string{
+		"a/private": "permission denied",
+		"b/private": "permission denied",
+		"c/private": "permission denied",
+		"d/mnt":     "input/output error",
+		"e/private": "permission denied",
+		"f/private": "permission denied",
+	}
+	output := CaptureOutput(t, func() {
+		printAccessErrors(accessErrors, false)
+	})
+	assert.Equal(t, "Couldn't access 6 paths: 5 permission denied, 1 input/output error\n"+
+		"  a/private (permission denied)\n  b/private (permission denied)\n  c/private (permission denied)\n"+
+		"  d/mnt (input/output error)\n  e/private (permission denied)\n"+
+		"  and 1 more (use -verbose to list them all)\n", output)
+
+	output = CaptureOutput(t, func() {
+		printAccessErrors(accessErrors, true)
+	})
+	assert.Contains(t, output, "  f/private (permission denied)\n")
+	assert.NotContains(t, output, "more")
+
+	assert.Empty(t, CaptureOutput(t, func() {
+		printAccessErrors(nil, true)
+	}))
+}
+
+func TestFindPermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions don't apply to root")
+	}
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main\n", "private/secret.go": "package private\n"})
+	require.NoError(t, os.Chmod(filepath.Join(dir, "private"), 0))
+	defer os.Chmod(filepath.Join(dir, "private"), 0755)
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, dir, "-list-file", listFile, "-quiet", "find", "-ext", "go", ".")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "Couldn't access 1 paths: 1 permission denied\n  private (permission denied)\n")
+	assert.Equal(t, fileListText(t, dir, "-ext=go", "main.go"), ReadTestFile(t, listFile))
+
+	require.NoError(t, os.Remove(listFile))
+	originalOsExit := osExit
+	defer func() { osExit = originalOsExit }()
+	osExit = func(code int) { exitCode = code }
+	output = CaptureOutput(t, func() {
+		runCommandIn(t, dir, "-list-file", listFile, "find", "-ext", "go", "-fail-on-errors", ".")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, "Error: couldn't access 1 paths; "+listFile+" was left unchanged")
+	assert.NoFileExists(t, listFile)
+}
+
+func TestFindIgnoreDirs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":                      "package main\n",
+		"generated/api.go":             "package generated\n",
+		"internal/db/migrations/1.go":  "package migrations\n",
+		"internal/db/Migrations.md":    "# Migrations\n",
+		"internal/fixtures/sample.txt": "sample\n",
+	})
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	var output string
+	output = CaptureOutput(t, func() {
+		require.Equal(t, 0, runCommandIn(t, dir, "-list-file", listFile, "find", "-hidden", "-ignore-dirs", "generated, migrations/,fixtures", "."))
+	})
+	entries, err := readFileList(listFile)
+	require.NoError(t, err)
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	assert.Equal(t, []string{"internal/db/Migrations.md", "main.go"}, paths, "only directories are skipped, at any depth and even with -hidden")
+	assert.Contains(t, output, "3 ignored directories")
+}
+
+func TestFindOnlyDirs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod":             "module app\n",
+		"README.md":          "# App\n",
+		"cmd/app/main.go":    "package main\n",
+		"internal/db/db.go":  "package db\n",
+		"scripts/release.sh": "#!/bin/sh\n",
+		"docs/src/guide.md":  "# Guide\n",
+	})
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	var output string
+	output = CaptureOutput(t, func() {
+		require.Equal(t, 0, runCommandIn(t, dir, "-list-file", listFile, "find", "-only-dirs", "cmd,internal/,src", "."))
+	})
+	entries, err := readFileList(listFile)
+	require.NoError(t, err)
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	assert.Equal(t, []string{"README.md", "cmd/app/main.go", "go.mod", "internal/db/db.go"}, paths, "only top-level directories are matched")
+	assert.Contains(t, output, "2 outside requested paths")
+
+	fs := DefaultFlags()
+	require.NoError(t, fs.Set("only-dirs", "internal/db"))
+	_, err = findOptionsFromFlags(fs)
+	assert.EqualError(t, err, `-only-dirs takes top-level directory names, not "internal/db"; give deeper paths after the directory instead`)
+}
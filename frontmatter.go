@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Extensions of the Markdown files -strip-front-matter applies to
+var frontMatterExts = []string{".md", ".markdown", ".mdx"}
+
+// Opening and closing lines of YAML and TOML front matter blocks
+var frontMatterDelims = map[string][]string{
+	"---": {"---", "..."},
+	"+++": {"+++"},
+}
+
+// stripFrontMatter removes the YAML (---) or TOML (+++) front matter block
+// at the top of a Markdown file, along with the blank lines after it. A
+// block that isn't closed is kept, as it's more likely a horizontal rule.
+func stripFrontMatter(file string, content []byte) ([]byte, bool) {
+	if !contains(frontMatterExts, strings.ToLower(filepath.Ext(file))) {
+		return content, false
+	}
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	lines := strings.SplitAfter(text, "\n")
+	closers, ok := frontMatterDelims[strings.TrimRight(lines[0], " \t\r\n")]
+	if !ok {
+		return content, false
+	}
+	for end := 1; end < len(lines); end++ {
+		if !contains(closers, strings.TrimRight(lines[end], " \t\r\n")) {
+			continue
+		}
+		end++
+		for end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+			end++
+		}
+		return []byte(strings.Join(lines[end:], "")), true
+	}
+	return content, false
+}
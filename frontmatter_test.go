@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		want     string
+		stripped bool
+	}{
+		{
+			name:     "YAML",
+			file:     "post.md",
+			content:  "---\ntitle: Hello\ntags: [a, b]\n---\n\n# Hello\n",
+			want:     "# Hello\n",
+			stripped: true,
+		},
+		{
+			name:     "YAML closed with dots",
+			file:     "post.markdown",
+			content:  "---\r\ntitle: Hello\r\n...\r\nText\r\n",
+			want:     "Text\r\n",
+			stripped: true,
+		},
+		{
+			name:     "TOML",
+			file:     "content/post.MD",
+			content:  "\ufeff+++\ntitle = \"Hello\"\n+++\nText\n",
+			want:     "Text\n",
+			stripped: true,
+		},
+		{
+			name:    "unclosed block is a horizontal rule",
+			file:    "post.md",
+			content: "---\n\nText\n",
+			want:    "---\n\nText\n",
+		},
+		{
+			name:    "not at the top",
+			file:    "post.md",
+			content: "# Title\n---\na: b\n---\n",
+			want:    "# Title\n---\na: b\n---\n",
+		},
+		{
+			name:    "not Markdown",
+			file:    "config.yaml",
+			content: "---\na: b\n---\n",
+			want:    "---\na: b\n---\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, stripped := stripFrontMatter(tc.file, []byte(tc.content))
+			assert.Equal(t, tc.want, string(got))
+			assert.Equal(t, tc.stripped, stripped)
+		})
+	}
+}
+
+func TestGenerateContentFileStripFrontMatter(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"docs/intro.md": "---\nlayout: page\npermalink: /intro/\n---\n# Intro\n",
+	})
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("docs/intro.md\ndocs/intro.md:1-2"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{stripFront: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "```markdown\n# Intro\n```")
+	assert.Contains(t, result, "#LINES 1-2 of 5\n#START\n```markdown\n---\nlayout: page\n", "Line ranges are kept as selected")
+
+	result, err = generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, result, "permalink: /intro/")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// gitFileMeta holds the git history details of a single file
+type gitFileMeta struct {
+	commit  string
+	author  string
+	date    string
+	commits int
+}
+
+// runGit runs a git command inside dir and returns its trimmed output
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitMetaForFile looks up the last commit and commit count for a file.
+// The second return value is false when the file has no git history.
+func gitMetaForFile(baseDir, file string) (gitFileMeta, bool) {
+	last, err := runGit(baseDir, "log", "-1", "--format=%h%x09%an%x09%as", "--", file)
+	if err != nil || last == "" {
+		return gitFileMeta{}, false
+	}
+
+	parts := strings.SplitN(last, "\t", 3)
+	if len(parts) != 3 {
+		return gitFileMeta{}, false
+	}
+
+	meta := gitFileMeta{
+		commit: parts[0],
+		author: parts[1],
+		date:   parts[2],
+	}
+
+	count, err := runGit(baseDir, "rev-list", "--count", "HEAD", "--", file)
+	if err == nil {
+		meta.commits, _ = strconv.Atoi(count)
+	}
+
+	return meta, true
+}
+
+// header formats the metadata as a bundle header line
+func (m gitFileMeta) header() string {
+	return fmt.Sprintf("#GIT commit=%s author=%q date=%s commits=%d\n", m.commit, m.author, m.date, m.commits)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initGitRepo turns dir into a git repository and commits all of its files
+func initGitRepo(t *testing.T, dir string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	commands := [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=Test Author", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	}
+	for _, args := range commands {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
+
+func TestGitMetaForFile(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	initGitRepo(t, testDir)
+
+	if err := os.WriteFile(filepath.Join(testDir, "untracked.go"), []byte("package main"), 0644); err != nil {
+		t.Fatalf("Failed to create untracked file: %v", err)
+	}
+
+	meta, ok := gitMetaForFile(testDir, "file1.go")
+	require.True(t, ok, "Tracked file should have git metadata")
+	assert.Equal(t, "Test Author", meta.author)
+	assert.Equal(t, 1, meta.commits)
+	assert.NotEmpty(t, meta.commit)
+	assert.NotEmpty(t, meta.date)
+
+	_, ok = gitMetaForFile(testDir, "untracked.go")
+	assert.False(t, ok, "Untracked file should not have git metadata")
+}
+
+func TestGenerateContentFileGitMeta(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	initGitRepo(t, testDir)
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("file1.go"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{gitMeta: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, `#GIT commit=`)
+	assert.Contains(t, result, `author="Test Author"`)
+	assert.Contains(t, result, "commits=1")
+
+	result, err = generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, result, "#GIT", "Git metadata should be opt-in")
+}
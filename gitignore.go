@@ -0,0 +1,279 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignoreRule represents a single rule from a .gitignore file
+type gitignoreRule struct {
+	pattern   string // pattern without negation and trailing slash, leading slash kept
+	isDir     bool
+	isNegated bool
+	line      int    // line number in the .gitignore file
+	text      string // rule as written in the .gitignore file
+	base      string // directory of the .gitignore file, relative to the root
+	source    string // path of the .gitignore file, relative to the root
+	foldCase  bool   // match regardless of case, like git with core.ignorecase
+
+	re *regexp.Regexp
+}
+
+// parseGitignore reads a .gitignore file and returns the parsed rules
+func parseGitignore(path string) ([]gitignoreRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []gitignoreRule
+	for i, line := range strings.Split(string(content), "\n") {
+		if rule, ok := parseGitignoreLine(line); ok {
+			rule.line = i + 1
+			rule.source = ".gitignore"
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// loadGitignore reads the .gitignore file of a directory below root, if there
+// is one, scoping its rules to that directory
+func loadGitignore(root, relDir string, foldCase bool) ([]gitignoreRule, error) {
+	gitignorePath := filepath.Join(root, filepath.FromSlash(relDir), ".gitignore")
+	if _, err := os.Stat(gitignorePath); err != nil {
+		return nil, nil
+	}
+
+	rules, err := parseGitignore(gitignorePath)
+	if err != nil {
+		return nil, err
+	}
+	if relDir == "." {
+		relDir = ""
+	}
+	for i := range rules {
+		rules[i].base = relDir
+		rules[i].source = path.Join(relDir, ".gitignore")
+		if foldCase {
+			rules[i].foldCase = true
+			rules[i].compile()
+		}
+	}
+	return rules, nil
+}
+
+// parseGitignoreLine parses one .gitignore line. The second return value is
+// false for blank lines and comments.
+func parseGitignoreLine(line string) (gitignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are ignored unless escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignoreRule{}, false
+	}
+
+	rule := gitignoreRule{text: line}
+
+	// Check for negated pattern
+	if strings.HasPrefix(line, "!") {
+		rule.isNegated = true
+		line = line[1:]
+	}
+
+	// Check if pattern is for directories
+	if strings.HasSuffix(line, "/") {
+		rule.isDir = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+
+	rule.pattern = line
+	rule.compile()
+	return rule, true
+}
+
+// anchored reports whether the pattern is matched against the path relative
+// to the .gitignore directory instead of against the file name. Patterns
+// containing a slash anywhere but at the end are anchored.
+func (r *gitignoreRule) anchored() bool {
+	return strings.Contains(r.pattern, "/")
+}
+
+// compile translates the pattern into a regular expression
+func (r *gitignoreRule) compile() {
+	flags := ""
+	if r.foldCase {
+		flags = "(?i)"
+	}
+	r.re = regexp.MustCompile(flags + "^" + wildmatchRegexp(strings.TrimPrefix(r.pattern, "/")) + "$")
+}
+
+// matches checks the rule against a slash-separated path relative to the root
+func (r *gitignoreRule) matches(relPath string, isDir bool) bool {
+	if r.isDir && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		relPath = relPath[len(r.base)+1:]
+	}
+	if r.re == nil {
+		r.compile()
+	}
+	if r.anchored() {
+		return r.re.MatchString(relPath)
+	}
+	return r.re.MatchString(path.Base(relPath))
+}
+
+// wildmatchRegexp translates a gitignore glob into a regular expression
+// following git's wildmatch rules: '*' and '?' don't match '/', '**' matches
+// across directories when it forms a whole path component, character classes
+// support negation, ranges and POSIX names, and '\' escapes the next character.
+func wildmatchRegexp(pattern string) string {
+	var re strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			j := i
+			for j < len(pattern) && pattern[j] == '*' {
+				j++
+			}
+			wholeComponent := j-i >= 2 && (i == 0 || pattern[i-1] == '/') && (j == len(pattern) || pattern[j] == '/')
+			switch {
+			case !wholeComponent:
+				re.WriteString("[^/]*")
+			case j == len(pattern):
+				// Trailing "/**" matches everything inside
+				re.WriteString(".*")
+			default:
+				// Leading "**/" and inner "/**/" match zero or more directories
+				re.WriteString("(?:.*/)?")
+				j++ // the slash is part of the match
+			}
+			i = j - 1
+		case '?':
+			re.WriteString("[^/]")
+		case '[':
+			class, n := wildmatchClass(pattern[i:])
+			if n == 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			re.WriteString(class)
+			i += n - 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			} else {
+				re.WriteString(`\\`)
+			}
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return re.String()
+}
+
+// wildmatchClass translates the bracket expression at the start of pattern
+// and returns it with the number of bytes consumed, or 0 if it isn't closed
+func wildmatchClass(pattern string) (string, int) {
+	var class strings.Builder
+	class.WriteString("[")
+
+	i := 1
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		class.WriteString("^/")
+		i++
+	}
+	for first := true; i < len(pattern); first = false {
+		c := pattern[i]
+		switch {
+		case c == ']' && !first:
+			class.WriteString("]")
+			return class.String(), i + 1
+		case c == '[' && strings.HasPrefix(pattern[i:], "[:"):
+			end := strings.Index(pattern[i+2:], ":]")
+			if end < 0 {
+				class.WriteString(`\[`)
+				i++
+				continue
+			}
+			class.WriteString(pattern[i : i+2+end+2])
+			i += 2 + end + 2
+			continue
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			class.WriteString(`\` + pattern[i:i+1])
+		case c == '-':
+			class.WriteString("-")
+		case strings.ContainsRune(`\[]^`, rune(c)):
+			class.WriteString(`\` + string(c))
+		default:
+			class.WriteByte(c)
+		}
+		i++
+	}
+	return "", 0
+}
+
+// matchGitignorePattern checks if a path, or one of its parent directories,
+// matches a gitignore pattern
+func matchGitignorePattern(path string, pattern string) bool {
+	rule, ok := parseGitignoreLine(pattern)
+	if !ok {
+		return false
+	}
+	_, ignored := gitignoreMatch(path, []gitignoreRule{rule}, false)
+	return ignored
+}
+
+// isIgnoredByGitignore checks if a file should be ignored based on gitignore rules
+func isIgnoredByGitignore(relPath string, rules []gitignoreRule, isDir bool) bool {
+	_, isIgnored := gitignoreMatch(relPath, rules, isDir)
+	return isIgnored
+}
+
+// gitignoreMatch checks if a path should be ignored based on gitignore rules
+// and returns the rule that ignored it. Like git, rules are evaluated in
+// order with the last matching rule winning, and a path inside an ignored
+// directory is ignored no matter what later rules say about the path itself.
+func gitignoreMatch(relPath string, rules []gitignoreRule, isDir bool) (gitignoreRule, bool) {
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+
+	// A path can't be re-included if one of its parent directories is excluded
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if rule, ignored := lastGitignoreMatch(strings.Join(parts[:i], "/"), rules, true); ignored {
+			return rule, true
+		}
+	}
+	return lastGitignoreMatch(relPath, rules, isDir)
+}
+
+// lastGitignoreMatch returns the last rule matching a path and whether that
+// rule ignores it
+func lastGitignoreMatch(relPath string, rules []gitignoreRule, isDir bool) (gitignoreRule, bool) {
+	var matched gitignoreRule
+	found := false
+	for i := range rules {
+		rule := rules[i]
+		if rule.matches(relPath, isDir) {
+			matched, found = rule, true
+		}
+	}
+	return matched, found && !matched.isNegated
+}
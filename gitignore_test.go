@@ -0,0 +1,210 @@
+package main
+
+import (
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWildmatchRegexp(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"*.txt", "notes.txt", true},
+		{"*.txt", "dir/notes.txt", false},
+		{"?.go", "a.go", true},
+		{"?.go", "ab.go", false},
+		{"file[0-9].txt", "file7.txt", true},
+		{"file[0-9].txt", "filex.txt", false},
+		{"file[!0-9].txt", "filex.txt", true},
+		{"file[^0-9].txt", "file7.txt", false},
+		{"[]a].md", "].md", true},
+		{"[[:upper:]]*.md", "README.md", true},
+		{"[[:upper:]]*.md", "readme.md", false},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "ab/b", false},
+		{"**/cache", "cache", true},
+		{"**/cache", "x/y/cache", true},
+		{"logs/**", "logs/a/b.log", true},
+		{"logs/**", "logs", false},
+		{"a**b", "axxb", true},
+		{"a**b", "a/b", false},
+		{"[unclosed", "[unclosed", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.pattern+" "+tc.path, func(t *testing.T) {
+			re := "^" + wildmatchRegexp(tc.pattern) + "$"
+			assert.Equal(t, tc.expected, gitignoreRuleFor(t, tc.pattern).re.MatchString(tc.path), "regexp %s", re)
+		})
+	}
+}
+
+func gitignoreRuleFor(t *testing.T, line string) gitignoreRule {
+	t.Helper()
+	rule, ok := parseGitignoreLine(line)
+	require.True(t, ok, "line %q should parse", line)
+	return rule
+}
+
+func TestGitignoreMatch(t *testing.T) {
+	parse := func(content string) []gitignoreRule {
+		var rules []gitignoreRule
+		for i, line := range strings.Split(content, "\n") {
+			if rule, ok := parseGitignoreLine(line); ok {
+				rule.line = i + 1
+				rules = append(rules, rule)
+			}
+		}
+		return rules
+	}
+
+	tests := []struct {
+		name     string
+		rules    string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"Anchored pattern matches root only", "/root_only.txt", "root_only.txt", false, true},
+		{"Anchored pattern skips nested files", "/root_only.txt", "sub/root_only.txt", false, false},
+		{"Pattern with inner slash is anchored", "docs/*.md", "src/docs/a.md", false, false},
+		{"Unanchored pattern matches at any depth", "*.log", "a/b/c.log", false, true},
+		{"Directory rule ignores files inside", "build/", "build/out/app.js", false, true},
+		{"Directory rule skips files with that name", "build/", "build", false, false},
+		{"Last matching rule wins", "*.log\n!keep.log", "keep.log", false, false},
+		{"Later rule excludes again", "!keep.log\n*.log", "keep.log", false, true},
+		{"Excluded parent can't be re-included", "logs/\n!logs/keep.log", "logs/keep.log", false, true},
+		{"Re-included with parent contents pattern", "logs/*\n!logs/keep.log", "logs/keep.log", false, false},
+		{"Escaped hash", `\#notes`, "#notes", false, true},
+		{"Escaped bang", `\!important`, "!important", false, true},
+		{"Trailing spaces are ignored", "tmp   ", "tmp", false, true},
+		{"Escaped trailing space is kept", `tmp\ `, "tmp ", false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ignored := gitignoreMatch(tc.path, parse(tc.rules), tc.isDir)
+			assert.Equal(t, tc.expected, ignored)
+		})
+	}
+
+	t.Run("Nested rules are scoped to their directory", func(t *testing.T) {
+		rules := parse("/generated.go\n*.tmp")
+		for i := range rules {
+			rules[i].base = "pkg"
+		}
+		assert.True(t, isIgnoredByGitignore("pkg/generated.go", rules, false))
+		assert.False(t, isIgnoredByGitignore("generated.go", rules, false))
+		assert.False(t, isIgnoredByGitignore("pkg/sub/generated.go", rules, false))
+		assert.True(t, isIgnoredByGitignore("pkg/sub/x.tmp", rules, false))
+		assert.False(t, isIgnoredByGitignore("x.tmp", rules, false))
+	})
+}
+
+// TestGitignoreMatchesGit compares the matcher with git's own view of which
+// files are ignored
+func TestGitignoreMatchesGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		".gitignore": strings.Join([]string{
+			"# comments and blank lines are skipped",
+			"",
+			"*.log",
+			"!keep.log",
+			"/root_only.txt",
+			"build/",
+			"docs/**/draft-*.md",
+			"cache/*",
+			"!cache/.keep",
+			"data[0-9].csv",
+			"temp?",
+			"**/generated/**",
+		}, "\n"),
+		"pkg/.gitignore":              "/local.txt\n*.bak\n!important.bak\n",
+		"app.go":                      "",
+		"debug.log":                   "",
+		"keep.log":                    "",
+		"sub/trace.log":               "",
+		"sub/keep.log":                "",
+		"root_only.txt":               "",
+		"sub/root_only.txt":           "",
+		"build/out.js":                "",
+		"src/build/out.js":            "",
+		"build.go":                    "",
+		"docs/draft-a.md":             "",
+		"docs/guide/draft-b.md":       "",
+		"docs/guide/final.md":         "",
+		"cache/.keep":                 "",
+		"cache/blob.bin":              "",
+		"data1.csv":                   "",
+		"data10.csv":                  "",
+		"tempA":                       "",
+		"tempAB":                      "",
+		"api/generated/client.go":     "",
+		"pkg/local.txt":               "",
+		"pkg/sub/local.txt":           "",
+		"local.txt":                   "",
+		"pkg/old.bak":                 "",
+		"pkg/important.bak":           "",
+		"old.bak":                     "",
+		"pkg/generated/nested/x.go":   "",
+		"vendor/lib/build/keep.go":    "",
+		"vendor/lib/build.log.d/x.go": "",
+	})
+	initGitRepo(t, testDir)
+
+	out, err := exec.Command("git", "-C", testDir, "ls-files", "--others", "--ignored", "--exclude-standard").Output()
+	require.NoError(t, err)
+	expected := strings.Fields(string(out))
+	sort.Strings(expected)
+
+	out, err = exec.Command("git", "-C", testDir, "ls-files", "--cached", "--others", "--exclude-standard").Output()
+	require.NoError(t, err)
+	tracked := strings.Fields(string(out))
+
+	all := append(append([]string(nil), expected...), tracked...)
+	sort.Strings(all)
+
+	// Load the .gitignore of every directory the way find does
+	rules, err := loadGitignore(testDir, ".", false)
+	require.NoError(t, err)
+	dirs := make(map[string]bool)
+	for _, file := range all {
+		for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	var sortedDirs []string
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+	for _, dir := range sortedDirs {
+		nested, err := loadGitignore(testDir, dir, false)
+		require.NoError(t, err)
+		rules = append(rules, nested...)
+	}
+
+	var ignored []string
+	for _, file := range all {
+		if isIgnoredByGitignore(file, rules, false) {
+			ignored = append(ignored, file)
+		}
+	}
+	assert.Equal(t, expected, ignored)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// applyGlobalFlags applies the flags that hold for every command: -chdir
+// switches to another directory, so the file list, the result and every
+// other file the tool reads or writes resolve relative to it, -list-file and
+// -result-file move the file list and the result, and -quiet silences status
+// messages. skukozh never colors its output, so -no-color needs no handling.
+// The returned function restores the previous state.
+func applyGlobalFlags(fs *flag.FlagSet) (func(), error) {
+	quietValue, _ := strconv.ParseBool(fs.Lookup("quiet").Value.String())
+
+	var origDir string
+	if dir := fs.Lookup("chdir").Value.String(); dir != "" {
+		var err error
+		if origDir, err = os.Getwd(); err != nil {
+			return func() {}, err
+		}
+		if err := os.Chdir(dir); err != nil {
+			return func() {}, fmt.Errorf("-chdir: %w", err)
+		}
+	}
+
+	origList, origResult := fileListName, resultName
+	for _, f := range []struct {
+		flag string
+		name *string
+	}{{"list-file", &fileListName}, {"result-file", &resultName}} {
+		value := fs.Lookup(f.flag).Value.String()
+		if value == "" {
+			continue
+		}
+		// Absolute, so the path stays the same wherever the command works
+		path, err := filepath.Abs(value)
+		if err != nil {
+			if origDir != "" {
+				_ = os.Chdir(origDir)
+			}
+			fileListName, resultName = origList, origResult
+			return func() {}, fmt.Errorf("-%s: %w", f.flag, err)
+		}
+		*f.name = path
+	}
+
+	flagMutex.Lock()
+	origQuiet := *quiet
+	*quiet = quietValue
+	flagMutex.Unlock()
+
+	return func() {
+		flagMutex.Lock()
+		*quiet = origQuiet
+		flagMutex.Unlock()
+		fileListName, resultName = origList, origResult
+		if origDir != "" {
+			_ = os.Chdir(origDir)
+		}
+	}, nil
+}
+
+// besideResult returns the path of an intermediate file such as the cache,
+// which is kept in the directory of the result file
+func besideResult(name string) string {
+	return filepath.Join(filepath.Dir(resultName), name)
+}
+
+// statusf prints a status message unless -quiet is set
+func statusf(format string, args ...any) {
+	flagMutex.Lock()
+	quietValue := *quiet
+	flagMutex.Unlock()
+	if !quietValue {
+		fmt.Printf(format, args...)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChdirAndQuiet(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "-quiet", "-no-color", "find", "."}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 0, exitCode)
+	assert.Empty(t, output, "-quiet should silence status messages")
+
+	// The file list is written in the -chdir directory and the working
+	// directory is restored afterwards
+	assert.Contains(t, ReadTestFile(t, filepath.Join(testDir, fileListName)), "subdir/file3.go")
+	assert.NoFileExists(t, filepath.Join(wd, fileListName))
+	current, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, wd, current)
+
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "gen", "."}))
+	output = CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "Content file saved to skukozh_result.txt\n", output)
+	assert.FileExists(t, filepath.Join(testDir, resultName))
+}
+
+func TestListAndResultFiles(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	work := t.TempDir()
+	listFile := filepath.Join(work, "lists", "php.txt")
+	resultFile := filepath.Join(work, "out", "php.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(listFile), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Dir(resultFile), 0755))
+
+	run := func(args ...string) (int, string) {
+		t.Helper()
+		flagSet := DefaultFlags()
+		require.NoError(t, flagSet.Parse(append([]string{"-list-file", listFile, "-result-file", resultFile}, args...)))
+		var exitCode int
+		output := CaptureOutput(t, func() {
+			exitCode = runWithFlags(flagSet)
+		})
+		return exitCode, output
+	}
+
+	exitCode, _ := run("find", "-ext", "php", testDir)
+	require.Equal(t, 0, exitCode)
+	assert.Equal(t, fileListText(t, testDir, "-ext=php", "subdir/file4.php"), ReadTestFile(t, listFile))
+
+	// gen finds the directory in the list and writes the cache next to the result
+	exitCode, output := run("gen", "-incremental")
+	require.Equal(t, 0, exitCode, output)
+	assert.Contains(t, output, "Content file saved to "+resultFile+"\n")
+	assert.Contains(t, ReadTestFile(t, resultFile), "#FILE subdir/file4.php\n")
+	assert.FileExists(t, filepath.Join(work, "out", cacheName))
+
+	exitCode, output = run("locate", "1")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "subdir/file4.php: lines 1-9 of "+resultFile+"\n", output)
+
+	// The defaults apply again after the run
+	assert.Equal(t, "skukozh_file_list.txt", fileListName)
+	assert.Equal(t, "skukozh_result.txt", resultName)
+	assert.NoFileExists(t, fileListName)
+	assert.NoFileExists(t, resultName)
+}
+
+func TestGenWithoutRoot(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, fileListName), []byte("main.go\n"), 0644))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "gen")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: skukozh_file_list.txt records no root directory; give the directory or run find again\n", output)
+}
+
+func TestChdirMissingDirectory(t *testing.T) {
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", filepath.Join(t.TempDir(), "missing"), "find", "."}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, "Error: -chdir:")
+}
+
+func TestQuietRejectsReview(t *testing.T) {
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-quiet", "gen", "-review", "."}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: -review asks for input and can't be combined with -quiet\n", output)
+}
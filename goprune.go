@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// isGoTestFile reports whether a path is a Go test file
+func isGoTestFile(file string) bool {
+	return strings.HasSuffix(file, "_test.go")
+}
+
+// pruneGo rewrites Go source using the AST. With apiOnly, only exported
+// declarations (and their doc comments) are kept; with stripPrivate, the
+// bodies of unexported functions and methods are removed. The second return
+// value is false when the file can't be parsed.
+func pruneGo(file string, content []byte, apiOnly, stripPrivate bool) ([]byte, bool) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, content, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+	comments := ast.NewCommentMap(fset, parsed, parsed.Comments)
+
+	var decls []ast.Decl
+	for _, decl := range parsed.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			exported := isExportedFunc(d)
+			if apiOnly && !exported {
+				continue
+			}
+			if stripPrivate && !exported {
+				d.Body = nil
+			}
+		case *ast.GenDecl:
+			if apiOnly {
+				switch d.Tok {
+				case token.IMPORT:
+					continue
+				case token.TYPE:
+					if !keepExportedTypes(d) {
+						continue
+					}
+				case token.CONST, token.VAR:
+					if !keepExportedSpecs(d) {
+						continue
+					}
+				}
+			}
+		}
+		decls = append(decls, decl)
+	}
+	parsed.Decls = decls
+
+	// Drop the comments that belonged to removed declarations and bodies
+	parsed.Comments = comments.Filter(parsed).Comments()
+
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, parsed); err != nil {
+		return nil, false
+	}
+	return out.Bytes(), true
+}
+
+// isExportedFunc reports whether a function, or a method on an exported type, is exported
+func isExportedFunc(decl *ast.FuncDecl) bool {
+	if !decl.Name.IsExported() {
+		return false
+	}
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return true
+	}
+
+	recvType := decl.Recv.List[0].Type
+	for {
+		switch t := recvType.(type) {
+		case *ast.StarExpr:
+			recvType = t.X
+		case *ast.IndexExpr:
+			recvType = t.X
+		case *ast.IndexListExpr:
+			recvType = t.X
+		case *ast.Ident:
+			return t.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+// keepExportedTypes drops the unexported type specs of a declaration and
+// reports whether anything is left
+func keepExportedTypes(decl *ast.GenDecl) bool {
+	var specs []ast.Spec
+	for _, spec := range decl.Specs {
+		if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.Name.IsExported() {
+			specs = append(specs, spec)
+		}
+	}
+	decl.Specs = specs
+	if len(specs) == 1 {
+		decl.Lparen = token.NoPos
+	}
+	return len(specs) > 0
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goPruneSource = `package store
+
+import "strings"
+
+// MaxItems limits the store size
+const MaxItems = 10
+
+var cache = map[string]string{}
+
+// Store keeps items
+type Store struct {
+	items []string
+}
+
+type entry struct{}
+
+// Add appends a normalized item
+func (s *Store) Add(item string) {
+	s.items = append(s.items, normalize(item))
+}
+
+// normalize lowercases an item
+func normalize(item string) string {
+	// trim first
+	return strings.ToLower(strings.TrimSpace(item))
+}
+
+func (e entry) Exported() {}
+`
+
+func TestPruneGoAPIOnly(t *testing.T) {
+	pruned, ok := pruneGo("store.go", []byte(goPruneSource), true, false)
+	require.True(t, ok)
+
+	result := string(pruned)
+	assert.Contains(t, result, "// MaxItems limits the store size")
+	assert.Contains(t, result, "type Store struct")
+	assert.Contains(t, result, "// Add appends a normalized item")
+	assert.Contains(t, result, "s.items = append(s.items, normalize(item))")
+	assert.NotContains(t, result, "import")
+	assert.NotContains(t, result, "cache")
+	assert.NotContains(t, result, "type entry")
+	assert.NotContains(t, result, "func normalize")
+	assert.NotContains(t, result, "normalize lowercases")
+	assert.NotContains(t, result, "Exported()", "Methods of unexported types are not part of the API")
+}
+
+func TestPruneGoStripPrivate(t *testing.T) {
+	pruned, ok := pruneGo("store.go", []byte(goPruneSource), false, true)
+	require.True(t, ok)
+
+	result := string(pruned)
+	assert.Contains(t, result, "import \"strings\"")
+	assert.Contains(t, result, "func normalize(item string) string\n")
+	assert.Contains(t, result, "// normalize lowercases an item")
+	assert.NotContains(t, result, "ToLower")
+	assert.NotContains(t, result, "// trim first")
+	assert.Contains(t, result, "s.items = append(s.items, normalize(item))")
+}
+
+func TestGenerateContentFileGoAPIOnly(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"store.go":      goPruneSource,
+		"store_test.go": "package store\n",
+	})
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("store.go\nstore_test.go"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{goAPIOnly: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#FILE store.go")
+	assert.NotContains(t, result, "#FILE store_test.go")
+	assert.NotContains(t, result, "func normalize")
+}
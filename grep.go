@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// filterByContent keeps the files whose content matches include (when set)
+// and doesn't match exclude (when set). Unreadable files are dropped.
+func filterByContent(root string, files []string, include, exclude *regexp.Regexp) []string {
+	var kept []string
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			continue
+		}
+		if include != nil && !include.Match(content) {
+			continue
+		}
+		if exclude != nil && exclude.Match(content) {
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
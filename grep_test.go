@@ -0,0 +1,48 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterByContent(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"payment.go": "type PaymentService struct{}\n",
+		"order.go":   "// uses PaymentService\n// Deprecated\n",
+		"user.go":    "type User struct{}\n",
+	})
+	files := []string{"order.go", "payment.go", "user.go"}
+
+	t.Run("Include matching files", func(t *testing.T) {
+		kept := filterByContent(testDir, files, regexp.MustCompile(`PaymentService`), nil)
+		assert.Equal(t, []string{"order.go", "payment.go"}, kept)
+	})
+
+	t.Run("Exclude matching files", func(t *testing.T) {
+		kept := filterByContent(testDir, files, nil, regexp.MustCompile(`Deprecated`))
+		assert.Equal(t, []string{"payment.go", "user.go"}, kept)
+	})
+
+	t.Run("Include and exclude combined", func(t *testing.T) {
+		kept := filterByContent(testDir, files, regexp.MustCompile(`PaymentService`), regexp.MustCompile(`Deprecated`))
+		assert.Equal(t, []string{"payment.go"}, kept)
+	})
+}
+
+func TestFindOptionsFromFlagsGrep(t *testing.T) {
+	fs := DefaultFlags()
+	require.NoError(t, fs.Parse([]string{"-grep", "Payment(Service)?", "find", "."}))
+	opts, err := findOptionsFromFlags(fs)
+	require.NoError(t, err)
+	require.NotNil(t, opts.grep)
+	assert.Nil(t, opts.grepExclude)
+
+	fs = DefaultFlags()
+	require.NoError(t, fs.Parse([]string{"-grep-v", "(", "find", "."}))
+	_, err = findOptionsFromFlags(fs)
+	assert.Error(t, err)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Hooks that can be registered under "hooks" in the config file
+var hookNames = []string{"pre_find", "post_find", "pre_gen", "post_gen"}
+
+// hookEnv is the context passed to a hook in SKUKOZH_* environment variables
+type hookEnv struct {
+	directory string // directory argument of the command
+	output    string // file list or result file the command writes
+	files     int    // number of files in the file list, -1 when unknown
+}
+
+// hooksFromFlags returns the hooks of the config file. They only run with
+// -hooks, as a config file checked into a repository could otherwise run
+// any command; without it a status line names the hooks left out.
+func hooksFromFlags(fs *flag.FlagSet) (map[string]string, error) {
+	cfg, err := configFromFlags(fs)
+	if err != nil {
+		return nil, err
+	}
+	if enabled, _ := strconv.ParseBool(fs.Lookup("hooks").Value.String()); !enabled {
+		if len(cfg.Hooks) > 0 {
+			names := make([]string, 0, len(cfg.Hooks))
+			for name := range cfg.Hooks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			statusf("Not running the %s hooks of the config file; pass -hooks before the command to run them\n", strings.Join(names, ", "))
+		}
+		return nil, nil
+	}
+
+	var unknown []string
+	for name := range cfg.Hooks {
+		if !contains(hookNames, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown hook %q (use %s)", unknown[0], strings.Join(hookNames, ", "))
+	}
+	return cfg.Hooks, nil
+}
+
+// runHook runs the shell command registered for a hook, if any. Its output
+// goes to the terminal and a non-zero exit status is returned as an error.
+func runHook(hooks map[string]string, name string, env hookEnv) error {
+	command := hooks[name]
+	if command == "" {
+		return nil
+	}
+
+	directory, err := filepath.Abs(env.directory)
+	if err != nil {
+		return err
+	}
+	output, err := filepath.Abs(env.output)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"SKUKOZH_HOOK="+name,
+		"SKUKOZH_DIRECTORY="+directory,
+		"SKUKOZH_OUTPUT="+output,
+	)
+	if env.files >= 0 {
+		cmd.Env = append(cmd.Env, "SKUKOZH_FILE_COUNT="+strconv.Itoa(env.files))
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %v", name, err)
+	}
+	return nil
+}
+
+// runWithHooks runs a command between its pre_ and post_ hooks and returns
+// the exit code. A failing pre_ hook keeps the command from running. The
+// command runs holding the lock of its output, so concurrent runs writing
+// the same file take turns.
+func runWithHooks(fs *flag.FlagSet, command, directory, output string, run func()) int {
+	hooks, err := hooksFromFlags(fs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	// The file list find is about to replace says nothing about its result
+	env := hookEnv{directory: directory, output: output, files: -1}
+	if command != "find" {
+		env.files = fileListCount()
+	}
+	if err := runHook(hooks, "pre_"+command, env); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if code := runLocked(output, run); code != 0 {
+		return code
+	}
+
+	env.files = fileListCount()
+	if err := runHook(hooks, "post_"+command, env); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// fileListCount returns the number of files in the file list, or -1 when
+// it can't be read
+func fileListCount() int {
+	files, err := listedFiles()
+	if err != nil {
+		return -1
+	}
+	return len(files)
+}
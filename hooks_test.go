@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeHooksConfig writes a config file registering hooks and returns its path
+func writeHooksConfig(t *testing.T, hooks string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hooks.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"hooks": `+hooks+`}`), 0644))
+	return path
+}
+
+func TestHooksAroundFindAndGen(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	logFile := filepath.Join(t.TempDir(), "hooks.log")
+	record := `echo \"$SKUKOZH_HOOK $SKUKOZH_FILE_COUNT $SKUKOZH_OUTPUT $SKUKOZH_DIRECTORY\" >> ` + logFile
+	configPath := writeHooksConfig(t, `{"pre_find": "`+record+`", "post_find": "`+record+`", "post_gen": "`+record+`; echo uploaded"}`)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "-hooks", "find", "-config", configPath, "."}))
+	var exitCode int
+	CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "-hooks", "gen", "-config", configPath, "-compress", "gzip", "."}))
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "uploaded\n", "hook output should go to the terminal")
+
+	// The pre_find hook runs before a file list exists and gets no count
+	dir, err := filepath.EvalSymlinks(testDir)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(ReadTestFile(t, logFile)), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "pre_find  "+filepath.Join(dir, fileListName)+" "+dir, lines[0])
+	assert.Equal(t, "post_find 6 "+filepath.Join(dir, fileListName)+" "+dir, lines[1])
+	assert.Equal(t, "post_gen 6 "+filepath.Join(dir, resultName+".gz")+" "+dir, lines[2])
+}
+
+func TestFailingPreHookStopsCommand(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	configPath := writeHooksConfig(t, `{"pre_gen": "echo not today >&2; exit 3"}`)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "-hooks", "find", "-config", configPath, "."}))
+	var exitCode int
+	CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "-hooks", "gen", "-config", configPath, "."}))
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: pre_gen hook failed: exit status 3\n", output)
+	assert.NoFileExists(t, filepath.Join(testDir, resultName))
+
+	// Without -hooks the config file's hooks don't run
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "gen", "-config", configPath, "."}))
+	CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 0, exitCode)
+	assert.FileExists(t, filepath.Join(testDir, resultName))
+}
+
+func TestRepositoryHooksDontRunByDefault(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	marker := filepath.Join(t.TempDir(), "ran")
+	config := `{"hooks": {"pre_find": "touch ` + marker + `", "post_gen": "touch ` + marker + `"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, ".skukozh.json"), []byte(config), 0644))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "find", ".")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "Not running the post_gen, pre_find hooks of the config file; pass -hooks before the command to run them\n")
+	CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "gen", ".")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.FileExists(t, filepath.Join(testDir, resultName))
+	assert.NoFileExists(t, marker, "hooks of a repository's config file need -hooks")
+
+	CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "-hooks", "find", ".")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.FileExists(t, marker)
+}
+
+func TestFailingPostHook(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	configPath := writeHooksConfig(t, `{"post_find": "false"}`)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "-quiet", "-hooks", "find", "-config", configPath, "."}))
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: post_find hook failed: exit status 1\n", output)
+	assert.FileExists(t, filepath.Join(testDir, fileListName), "the file list is written before the post hook")
+}
+
+func TestUnknownHook(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	configPath := writeHooksConfig(t, `{"after_gen": "true"}`)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "-hooks", "find", "-config", configPath, "."}))
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: unknown hook \"after_gen\" (use pre_find, post_find, pre_gen, post_gen)\n", output)
+	assert.NoFileExists(t, filepath.Join(testDir, fileListName))
+}
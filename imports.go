@@ -0,0 +1,289 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Regular expressions matching module specifiers in JS/TS import statements
+var jsImportPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^\s*(?:import|export)\b[^'"]*?\bfrom\s*['"]([^'"]+)['"]`),
+	regexp.MustCompile(`(?m)^\s*import\s*['"]([^'"]+)['"]`),
+	regexp.MustCompile(`\brequire\(\s*['"]([^'"]+)['"]\s*\)`),
+	regexp.MustCompile(`\bimport\(\s*['"]([^'"]+)['"]\s*\)`),
+}
+
+// Regular expressions matching Python import statements
+var (
+	pyImportPattern     = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+([\w.]+(?:[ \t]*,[ \t]*[\w.]+)*)`)
+	pyFromImportPattern = regexp.MustCompile(`(?m)^[ \t]*from[ \t]+(\.*[\w.]*)[ \t]+import[ \t]+(?:\(([^)]*)\)|([\w \t,*]+))`)
+)
+
+// Source roots tried when resolving absolute Python imports
+var pySourceRoots = []string{"", "src"}
+
+// Suffixes tried when resolving extensionless JS/TS module specifiers
+var jsResolveSuffixes = []string{
+	"", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs",
+	"/index.ts", "/index.tsx", "/index.js", "/index.jsx",
+}
+
+// importResolver maps import statements to files inside a repository
+type importResolver struct {
+	baseDir  string
+	goModule string
+	files    map[string]bool     // candidate files, slash-separated and relative to baseDir
+	goDirs   map[string][]string // directory -> non-test Go files in it
+}
+
+// newImportResolver creates a resolver that only resolves imports to the given files
+func newImportResolver(baseDir string, files []string) *importResolver {
+	r := &importResolver{
+		baseDir:  baseDir,
+		goModule: goModulePath(filepath.Join(baseDir, "go.mod")),
+		files:    make(map[string]bool),
+		goDirs:   make(map[string][]string),
+	}
+	for _, file := range files {
+		file = filepath.ToSlash(file)
+		r.files[file] = true
+		if strings.HasSuffix(file, ".go") && !strings.HasSuffix(file, "_test.go") {
+			dir := path.Dir(file)
+			r.goDirs[dir] = append(r.goDirs[dir], file)
+		}
+	}
+	for dir := range r.goDirs {
+		sort.Strings(r.goDirs[dir])
+	}
+	return r
+}
+
+// imports returns the repository files imported by file, sorted and without duplicates
+func (r *importResolver) imports(file string) []string {
+	file = filepath.ToSlash(file)
+	content, err := os.ReadFile(filepath.Join(r.baseDir, file))
+	if err != nil {
+		return nil
+	}
+
+	var deps []string
+	switch strings.ToLower(path.Ext(file)) {
+	case ".go":
+		deps = r.goImports(file, content)
+	case ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".vue", ".svelte":
+		deps = r.jsImports(file, content)
+	case ".py":
+		deps = r.pyImports(file, content)
+	}
+
+	sort.Strings(deps)
+	unique := deps[:0]
+	for i, dep := range deps {
+		if dep != file && (i == 0 || dep != deps[i-1]) {
+			unique = append(unique, dep)
+		}
+	}
+	return unique
+}
+
+// goImports resolves the module-local packages imported by a Go file to their files
+func (r *importResolver) goImports(file string, content []byte) []string {
+	if r.goModule == "" {
+		return nil
+	}
+
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, content, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	var deps []string
+	for _, spec := range parsed.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		var dir string
+		switch {
+		case importPath == r.goModule:
+			dir = "."
+		case strings.HasPrefix(importPath, r.goModule+"/"):
+			dir = strings.TrimPrefix(importPath, r.goModule+"/")
+		default:
+			continue
+		}
+		deps = append(deps, r.goDirs[dir]...)
+	}
+	return deps
+}
+
+// jsImports resolves the relative module specifiers of a JS/TS file to files
+func (r *importResolver) jsImports(file string, content []byte) []string {
+	var deps []string
+	for _, pattern := range jsImportPatterns {
+		for _, match := range pattern.FindAllStringSubmatch(string(content), -1) {
+			spec := match[1]
+			if !strings.HasPrefix(spec, ".") {
+				continue // packages from node_modules are not part of the repository
+			}
+			target := path.Join(path.Dir(file), spec)
+			for _, suffix := range jsResolveSuffixes {
+				if r.files[target+suffix] {
+					deps = append(deps, target+suffix)
+					break
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// pyImports resolves the absolute and relative imports of a Python file to
+// modules and packages inside the repository
+func (r *importResolver) pyImports(file string, content []byte) []string {
+	var deps []string
+	for _, match := range pyImportPattern.FindAllStringSubmatch(string(content), -1) {
+		for _, module := range strings.Split(match[1], ",") {
+			deps = append(deps, r.pyModule("", strings.TrimSpace(module))...)
+		}
+	}
+
+	for _, match := range pyFromImportPattern.FindAllStringSubmatch(string(content), -1) {
+		module := match[1]
+		base := ""
+		if strings.HasPrefix(module, ".") {
+			// Relative import: each extra dot walks one package up
+			dots := len(module) - len(strings.TrimLeft(module, "."))
+			base = path.Dir(file)
+			for i := 1; i < dots; i++ {
+				base = path.Dir(base)
+			}
+			module = module[dots:]
+		}
+
+		deps = append(deps, r.pyModule(base, module)...)
+		// Imported names may be submodules ("from pkg import mod")
+		for _, name := range strings.Split(match[2]+","+match[3], ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || name == "*" {
+				continue
+			}
+			name = strings.Fields(name)[0] // drop "as alias"
+			submodule := name
+			if module != "" {
+				submodule = module + "." + name
+			}
+			deps = append(deps, r.pyModule(base, submodule)...)
+		}
+	}
+	return deps
+}
+
+// pyModule resolves a dotted module name to a module file or package
+// __init__.py. A non-empty base resolves relative to that directory,
+// otherwise the repository source roots are tried.
+func (r *importResolver) pyModule(base, module string) []string {
+	roots := pySourceRoots
+	if base != "" {
+		roots = []string{base}
+	}
+
+	modulePath := strings.ReplaceAll(module, ".", "/")
+	for _, root := range roots {
+		target := path.Join(root, modulePath)
+		if modulePath == "" {
+			target = path.Clean(root)
+		}
+		for _, candidate := range []string{target + ".py", target + "/__init__.py"} {
+			if r.files[strings.TrimPrefix(candidate, "./")] {
+				return []string{strings.TrimPrefix(candidate, "./")}
+			}
+		}
+	}
+	return nil
+}
+
+// dependencyClosure returns the seed files plus every file they import,
+// directly or transitively, sorted alphabetically. Go seeds bring the other
+// files of their package, whose declarations they use without importing.
+func dependencyClosure(baseDir string, files, seeds []string) []string {
+	resolver := newImportResolver(baseDir, files)
+
+	included := make(map[string]bool)
+	queue := make([]string, 0, len(seeds))
+	add := func(file string) {
+		if !included[file] {
+			included[file] = true
+			queue = append(queue, file)
+		}
+	}
+	for _, seed := range seeds {
+		seed = filepath.ToSlash(seed)
+		add(seed)
+		if strings.HasSuffix(seed, ".go") {
+			for _, sibling := range resolver.goDirs[path.Dir(seed)] {
+				add(sibling)
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		for _, dep := range resolver.imports(file) {
+			if !included[dep] {
+				included[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	closure := make([]string, 0, len(included))
+	for file := range included {
+		closure = append(closure, file)
+	}
+	sort.Strings(closure)
+	return closure
+}
+
+// dependencyOrder sorts files so that every file comes after the files it
+// imports. Independent files and import cycles fall back to alphabetical order.
+func dependencyOrder(baseDir string, files []string) []string {
+	resolver := newImportResolver(baseDir, files)
+
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+
+	inList := make(map[string]bool, len(files))
+	for _, file := range files {
+		inList[file] = true
+	}
+
+	visited := make(map[string]bool, len(files))
+	ordered := make([]string, 0, len(files))
+	var visit func(file string)
+	visit = func(file string) {
+		if visited[file] {
+			return
+		}
+		visited[file] = true
+		for _, dep := range resolver.imports(file) {
+			if inList[dep] {
+				visit(dep)
+			}
+		}
+		ordered = append(ordered, file)
+	}
+
+	for _, file := range sorted {
+		visit(file)
+	}
+	return ordered
+}
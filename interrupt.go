@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// State reported when the run is interrupted, guarded by interruptMutex
+var (
+	interruptMutex sync.Mutex
+	tempFiles      = make(map[string]bool) // temporary files and locks of the run, removed on exit
+	progress       string                  // what the running command has completed so far
+)
+
+// setProgress records what the running command has completed, for the
+// message printed when it's interrupted
+func setProgress(format string, args ...any) {
+	interruptMutex.Lock()
+	progress = fmt.Sprintf(format, args...)
+	interruptMutex.Unlock()
+}
+
+// trackTempFile registers a file the run removes when it exits early
+func trackTempFile(name string) {
+	interruptMutex.Lock()
+	tempFiles[name] = true
+	interruptMutex.Unlock()
+}
+
+// untrackTempFile forgets a file registered with trackTempFile
+func untrackTempFile(name string) {
+	interruptMutex.Lock()
+	delete(tempFiles, name)
+	interruptMutex.Unlock()
+}
+
+// removeTempFiles removes the files registered with trackTempFile. The
+// caller holds interruptMutex.
+func removeTempFiles() {
+	for name := range tempFiles {
+		os.Remove(name)
+		delete(tempFiles, name)
+	}
+}
+
+// exitCleanly removes the temporary files and locks of the run and exits
+func exitCleanly(code int) {
+	interruptMutex.Lock()
+	removeTempFiles()
+	interruptMutex.Unlock()
+	os.Exit(code)
+}
+
+// writeFileAtomic writes data to name through a temporary file in the same
+// directory renamed over it, so readers never see a partly written file and
+// an interrupted run leaves the previous content in place. As with
+// os.WriteFile, new files get perm less the umask, a replaced file keeps
+// its mode, and a symlink keeps pointing to the file it names, which gets
+// the new content.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(name); err == nil {
+		name = target
+	}
+	replaced, statErr := os.Stat(name)
+	if statErr == nil {
+		perm = replaced.Mode().Perm()
+	}
+
+	temp, err := createTempFile(name, perm)
+	if err != nil {
+		return err
+	}
+	trackTempFile(temp.Name())
+
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && statErr == nil {
+		// The umask applied to the temporary file mustn't change the mode
+		err = os.Chmod(temp.Name(), perm)
+	}
+
+	// Rename under the lock, so an interrupt either finds the temporary
+	// file to remove or the rename done
+	interruptMutex.Lock()
+	defer interruptMutex.Unlock()
+	if err == nil {
+		err = os.Rename(temp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	delete(tempFiles, temp.Name())
+	return err
+}
+
+// createTempFile creates a temporary file next to name. Unlike
+// os.CreateTemp, which always uses 0600, it's created with perm, so the
+// umask applies as for any new file.
+func createTempFile(name string, perm os.FileMode) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".tmp-")
+	for try := 0; try < 10000; try++ {
+		file, err := os.OpenFile(prefix+strconv.FormatUint(uint64(rand.Uint32()), 10), os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !os.IsExist(err) {
+			return file, err
+		}
+	}
+	return nil, &os.PathError{Op: "createtemp", Path: prefix + "*", Err: os.ErrExist}
+}
+
+// interrupted removes the temporary files and locks of the run, reports what was
+// completed and returns the exit code for the signal
+func interrupted(sig os.Signal) int {
+	interruptMutex.Lock()
+	defer interruptMutex.Unlock()
+	removeTempFiles()
+
+	message := "\nInterrupted"
+	if progress != "" {
+		message += " after " + progress
+	}
+	fmt.Printf("%s; files still being written keep their previous content\n", message)
+
+	if sig == syscall.SIGTERM {
+		return 143
+	}
+	return 130
+}
+
+// handleInterrupts exits through interrupted on SIGINT and SIGTERM
+func handleInterrupts() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		osExit(interrupted(<-signals))
+	}()
+}
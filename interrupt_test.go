@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "result.txt")
+	require.NoError(t, writeFileAtomic(name, []byte("previous"), 0640))
+	require.NoError(t, writeFileAtomic(name, []byte("complete"), 0644))
+	assert.Equal(t, "complete", ReadTestFile(t, name))
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm(), "perm applies to new files")
+
+	// New files get the same mode as with os.WriteFile, umask included
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plain.txt"), nil, 0666))
+	require.NoError(t, writeFileAtomic(filepath.Join(dir, "atomic.txt"), nil, 0666))
+	plain, err := os.Stat(filepath.Join(dir, "plain.txt"))
+	require.NoError(t, err)
+	atomic, err := os.Stat(filepath.Join(dir, "atomic.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, plain.Mode().Perm(), atomic.Mode().Perm())
+	require.NoError(t, os.Remove(filepath.Join(dir, "plain.txt")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "atomic.txt")))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary file is left behind")
+	assert.Empty(t, tempFiles)
+
+	// A write that can't finish leaves the old content
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "taken"), 0755))
+	assert.Error(t, writeFileAtomic(filepath.Join(dir, "taken"), []byte("x"), 0644))
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	temp := filepath.Join(dir, ".skukozh_result.txt.tmp-1")
+	require.NoError(t, os.WriteFile(temp, []byte("half a bun"), 0644))
+	interruptMutex.Lock()
+	tempFiles[temp] = true
+	interruptMutex.Unlock()
+	setProgress("rendering %d of %d files", 120, 500)
+	defer setProgress("")
+
+	var code int
+	output := CaptureOutput(t, func() {
+		code = interrupted(os.Interrupt)
+	})
+	assert.Equal(t, 130, code)
+	assert.Equal(t, "\nInterrupted after rendering 120 of 500 files; files still being written keep their previous content\n", output)
+	assert.NoFileExists(t, temp)
+	assert.Empty(t, tempFiles)
+
+	setProgress("")
+	output = CaptureOutput(t, func() {
+		code = interrupted(syscall.SIGTERM)
+	})
+	assert.Equal(t, 143, code)
+	assert.Equal(t, "\nInterrupted; files still being written keep their previous content\n", output)
+}
+
+func TestWriteFileAtomicKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "result.txt")
+	require.NoError(t, os.WriteFile(name, []byte("previous"), 0600))
+
+	// The mode of an existing file is kept
+	require.NoError(t, writeFileAtomic(name, []byte("complete"), 0644))
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// A symlinked result still points to its target
+	link := filepath.Join(dir, "latest.txt")
+	if err := os.Symlink(name, link); err != nil {
+		t.Skip("symlinks are not available")
+	}
+	require.NoError(t, writeFileAtomic(link, []byte("newer"), 0644))
+	info, err = os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	assert.Equal(t, "newer", ReadTestFile(t, name))
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// extLanguages maps file extensions to Markdown fence language identifiers
+var extLanguages = map[string]string{
+	// Programming languages
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".mjs":   "javascript",
+	".cjs":   "javascript",
+	".ts":    "typescript",
+	".java":  "java",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cc":    "cpp",
+	".cxx":   "cpp",
+	".hpp":   "cpp",
+	".hh":    "cpp",
+	".cs":    "csharp",
+	".php":   "php",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".swift": "swift",
+	".kt":    "kotlin",
+	".kts":   "kotlin",
+	".scala": "scala",
+	".lua":   "lua",
+	".pl":    "perl",
+	".r":     "r",
+	".sql":   "sql",
+	".proto": "protobuf",
+	// Web
+	".html":   "html",
+	".htm":    "html",
+	".css":    "css",
+	".scss":   "scss",
+	".sass":   "sass",
+	".less":   "less",
+	".jsx":    "jsx",
+	".tsx":    "tsx",
+	".vue":    "vue",
+	".svelte": "svelte",
+	".erb":    "erb",
+	// Config files
+	".json": "json",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".toml": "toml",
+	".xml":  "xml",
+	".ini":  "ini",
+	".env":  "dotenv",
+	".mod":  "go-mod",
+	".sum":  "text",
+	// Documentation
+	".md":   "markdown",
+	".txt":  "text",
+	".rst":  "rst",
+	".adoc": "asciidoc",
+	// Shell scripts
+	".sh":   "bash",
+	".bash": "bash",
+	".zsh":  "zsh",
+	".fish": "fish",
+	".bat":  "batch",
+	".cmd":  "batch",
+	".ps1":  "powershell",
+}
+
+// fileNameLanguages maps well-known file names to fence language identifiers
+var fileNameLanguages = map[string]string{
+	"dockerfile":     "dockerfile",
+	"containerfile":  "dockerfile",
+	"makefile":       "makefile",
+	"gnumakefile":    "makefile",
+	"jenkinsfile":    "groovy",
+	"vagrantfile":    "ruby",
+	"gemfile":        "ruby",
+	"rakefile":       "ruby",
+	"go.mod":         "go-mod",
+	"cmakelists.txt": "cmake",
+}
+
+// shebangLanguages maps interpreter names found in shebang lines to fence language identifiers
+var shebangLanguages = map[string]string{
+	"sh":      "sh",
+	"bash":    "bash",
+	"zsh":     "zsh",
+	"fish":    "fish",
+	"python":  "python",
+	"python2": "python",
+	"python3": "python",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+}
+
+// fenceLanguage returns the Markdown fence language identifier for a file,
+// looking at its name, its extension and finally its shebang line
+func fenceLanguage(path string, content []byte) string {
+	name := strings.ToLower(filepath.Base(path))
+	if lang, ok := fileNameLanguages[name]; ok {
+		return lang
+	}
+	if strings.HasPrefix(name, "dockerfile.") || strings.HasSuffix(name, ".dockerfile") {
+		return "dockerfile"
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if lang, ok := extLanguages[ext]; ok {
+		return lang
+	}
+
+	if lang := shebangLanguage(content); lang != "" {
+		return lang
+	}
+
+	return strings.TrimPrefix(ext, ".")
+}
+
+// shebangLanguage detects the script language from a "#!" first line
+func shebangLanguage(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+
+	firstLine := content[2:]
+	if idx := bytes.IndexByte(firstLine, '\n'); idx != -1 {
+		firstLine = firstLine[:idx]
+	}
+
+	fields := strings.Fields(string(firstLine))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Handle "#!/usr/bin/env python3" as well as "#!/bin/bash"
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = field
+				break
+			}
+		}
+	}
+
+	return shebangLanguages[strings.TrimSpace(interpreter)]
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// licenseWords marks a comment block as a license header
+var licenseWords = regexp.MustCompile(`(?i)\b(licen[sc]e[ds]?|copyright)\b|\(c\)|©|SPDX-License-Identifier`)
+
+// Line comment prefixes recognized at the top of a file
+var licenseLinePrefixes = []string{"//", "#", "--", ";", "%", "'"}
+
+// Delimiters of block comments recognized at the top of a file
+var licenseBlockComments = [][2]string{{"/*", "*/"}, {"<!--", "-->"}, {"{-", "-}"}, {`"""`, `"""`}, {"=begin", "=end"}}
+
+// stripLicenseHeader removes the first comment block of a file when it
+// mentions a license or copyright. A shebang, encoding or build constraint
+// line before it is kept, as are the lines after it.
+func stripLicenseHeader(content []byte) ([]byte, bool) {
+	lines := strings.SplitAfter(string(content), "\n")
+
+	start := 0
+	for start < len(lines) && isHeaderDirective(lines[start]) {
+		start++
+	}
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	if start == len(lines) {
+		return content, false
+	}
+
+	end, ok := commentBlockEnd(lines, start)
+	if !ok || !licenseWords.MatchString(strings.Join(lines[start:end], "")) {
+		return content, false
+	}
+	// A Go package doc comment is attached to the package clause
+	if end < len(lines) && strings.HasPrefix(lines[end], "package ") {
+		return content, false
+	}
+
+	// Drop the blank lines separating the header from the code as well
+	for end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+		end++
+	}
+	stripped := strings.Join(lines[:start], "") + strings.Join(lines[end:], "")
+	return []byte(stripped), true
+}
+
+// isHeaderDirective reports whether a line must stay at the top of a file:
+// a shebang, a Python encoding declaration or a Go build constraint
+func isHeaderDirective(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "#!") ||
+		(strings.HasPrefix(trimmed, "#") && strings.Contains(trimmed, "coding")) ||
+		strings.HasPrefix(trimmed, "//go:build") || strings.HasPrefix(trimmed, "// +build") ||
+		strings.HasPrefix(trimmed, "<?php") || strings.HasPrefix(trimmed, "<?xml")
+}
+
+// commentBlockEnd returns the index of the line after the comment block
+// starting at line start: a block comment, or consecutive line comments
+// using the same prefix
+func commentBlockEnd(lines []string, start int) (int, bool) {
+	first := strings.TrimSpace(lines[start])
+
+	for _, delims := range licenseBlockComments {
+		if !strings.HasPrefix(first, delims[0]) {
+			continue
+		}
+		rest := first[len(delims[0]):]
+		if strings.Contains(rest, delims[1]) {
+			return start + 1, true
+		}
+		for i := start + 1; i < len(lines); i++ {
+			if strings.Contains(lines[i], delims[1]) {
+				return i + 1, true
+			}
+		}
+		return start, false // unterminated
+	}
+
+	for _, prefix := range licenseLinePrefixes {
+		if !strings.HasPrefix(first, prefix) {
+			continue
+		}
+		end := start + 1
+		for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), prefix) {
+			end++
+		}
+		return end, true
+	}
+	return start, false
+}
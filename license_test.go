@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripLicenseHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		want     string
+		stripped bool
+	}{
+		{
+			name:     "go line comments",
+			content:  "// Copyright 2024 The Authors. All rights reserved.\n// Use of this source code is governed by a BSD-style license.\n\npackage main\n",
+			want:     "package main\n",
+			stripped: true,
+		},
+		{
+			name:     "block comment",
+			content:  "/*\n * Licensed under the Apache License, Version 2.0\n */\n\nimport x from 'y';\n",
+			want:     "import x from 'y';\n",
+			stripped: true,
+		},
+		{
+			name:     "shebang kept",
+			content:  "#!/usr/bin/env python\n# -*- coding: utf-8 -*-\n# SPDX-License-Identifier: MIT\n\nimport os\n",
+			want:     "#!/usr/bin/env python\n# -*- coding: utf-8 -*-\nimport os\n",
+			stripped: true,
+		},
+		{
+			name:     "html comment",
+			content:  "<!-- Copyright (c) Example Corp -->\n<div></div>\n",
+			want:     "<div></div>\n",
+			stripped: true,
+		},
+		{
+			name:    "ordinary comment",
+			content: "// Package main runs the server.\npackage main\n",
+			want:    "// Package main runs the server.\npackage main\n",
+		},
+		{
+			name:    "go package doc mentioning licenses",
+			content: "// Package license checks license headers.\npackage license\n",
+			want:    "// Package license checks license headers.\npackage license\n",
+		},
+		{
+			name:    "license comment after code",
+			content: "package main\n\n// Copyright 2024\n",
+			want:    "package main\n\n// Copyright 2024\n",
+		},
+		{
+			name:    "unterminated block",
+			content: "/* Copyright 2024\nint x;\n",
+			want:    "/* Copyright 2024\nint x;\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, stripped := stripLicenseHeader([]byte(tc.content))
+			assert.Equal(t, tc.want, string(got))
+			assert.Equal(t, tc.stripped, stripped)
+		})
+	}
+}
+
+func TestGenerateContentFileStripLicenseHeaders(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go": "// Copyright 2024 Example\n// Licensed under MIT\n\npackage main\n\nfunc main() {}\n",
+	})
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("main.go\nmain.go:1-2"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{stripLicense: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "```go\npackage main\nfunc main() {}\n```")
+	assert.Contains(t, result, "#LINES 1-2 of 6\n#START\n```go\n// Copyright 2024 Example\n", "Line ranges are kept as selected")
+
+	result, err = generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, result, "```go\n// Copyright 2024 Example\n")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// lineRangeSuffix matches a line range appended to a file list entry, such
+// as src/foo.go:120-240 or src/foo.go:42
+var lineRangeSuffix = regexp.MustCompile(`^(.+):(\d+)(?:-(\d+))?$`)
+
+// lineSelection selects lines start to end of a file, counted from 1
+type lineSelection struct {
+	start int
+	end   int
+}
+
+// parseLineRange splits a file list entry into the file path and the line
+// range it selects, if any
+func parseLineRange(entry string) (string, lineSelection, bool, error) {
+	match := lineRangeSuffix.FindStringSubmatch(entry)
+	if match == nil {
+		return entry, lineSelection{}, false, nil
+	}
+
+	r := lineSelection{}
+	r.start, _ = strconv.Atoi(match[2])
+	r.end = r.start
+	if match[3] != "" {
+		r.end, _ = strconv.Atoi(match[3])
+	}
+	if r.start < 1 || r.end < r.start {
+		return match[1], r, true, fmt.Errorf("invalid line range %s:%s", match[1], strings.TrimPrefix(entry, match[1]+":"))
+	}
+	return match[1], r, true, nil
+}
+
+// entryPath returns the file path of a file list entry, dropping its line
+// range or symbol
+func entryPath(entry string) string {
+	if path, _, ok := parseSymbolEntry(entry); ok {
+		return path
+	}
+	path, _, _, _ := parseLineRange(entry)
+	return path
+}
+
+// slice returns the selected lines of content and a description of the
+// range such as "120-240 of 812". The end of the range is clamped to the
+// file length.
+func (r lineSelection) slice(content []byte, file string) ([]byte, string, error) {
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if r.start > len(lines) {
+		return nil, "", fmt.Errorf("line range %d-%d is outside %s (%d lines)", r.start, r.end, file, len(lines))
+	}
+	end := min(r.end, len(lines))
+	described := fmt.Sprintf("%d-%d of %d", r.start, end, len(lines))
+	return []byte(strings.Join(lines[r.start-1:end], "\n") + "\n"), described, nil
+}
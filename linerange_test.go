@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		entry  string
+		path   string
+		r      lineSelection
+		ranged bool
+		err    string
+	}{
+		{"src/foo.go", "src/foo.go", lineSelection{}, false, ""},
+		{"src/foo.go:120-240", "src/foo.go", lineSelection{120, 240}, true, ""},
+		{"src/foo.go:42", "src/foo.go", lineSelection{42, 42}, true, ""},
+		{"C:/src/foo.go", "C:/src/foo.go", lineSelection{}, false, ""},
+		{"src/foo.go:0-3", "src/foo.go", lineSelection{0, 3}, true, "invalid line range src/foo.go:0-3"},
+		{"src/foo.go:9-3", "src/foo.go", lineSelection{9, 3}, true, "invalid line range src/foo.go:9-3"},
+	}
+	for _, tc := range tests {
+		path, r, ranged, err := parseLineRange(tc.entry)
+		assert.Equal(t, tc.path, path, tc.entry)
+		assert.Equal(t, tc.ranged, ranged, tc.entry)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			continue
+		}
+		require.NoError(t, err, tc.entry)
+		assert.Equal(t, tc.r, r, tc.entry)
+	}
+}
+
+func TestGenerateLineRanges(t *testing.T) {
+	testDir := t.TempDir()
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, "line "+strings.Repeat("x", i))
+	}
+	writeTestFiles(t, testDir, map[string]string{
+		"big.txt": strings.Join(lines, "\n") + "\n",
+		"main.go": "package main\n",
+	})
+
+	tmpDir := t.TempDir()
+	oldWd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(tmpDir))
+	defer os.Chdir(oldWd)
+
+	require.NoError(t, os.WriteFile(fileListName, []byte("big.txt:3-4\nmain.go\nbig.txt:9-20"), 0644))
+	result, err := generateContentFileInternal(testDir, genOptions{checksum: true})
+	require.NoError(t, err)
+
+	assert.Contains(t, result, "#FILE big.txt\n#TYPE txt\n#LINES 3-4 of 10\n#START\n```text\nline xxx\nline xxxx\n```\n#END\n")
+	assert.Contains(t, result, "#LINES 9-10 of 10\n#START\n```text\nline xxxxxxxxx\nline xxxxxxxxxx\n```\n", "the end is clamped")
+	assert.Contains(t, result, "#FILE main.go\n#TYPE go\n#START\n")
+
+	// Checksums cover the whole file, so verify still works
+	report, problems, err := verifyBundle(result, testDir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, problems, report)
+
+	t.Run("Range past the end of the file", func(t *testing.T) {
+		_, err := renderFileSection(testDir, "big.txt:11-12", genOptions{})
+		assert.EqualError(t, err, "line range 11-12 is outside big.txt (10 lines)")
+	})
+
+	t.Run("Template field", func(t *testing.T) {
+		templatePath := filepath.Join(tmpDir, "section.tmpl")
+		require.NoError(t, os.WriteFile(templatePath, []byte("{{.Path}} [{{.Range}}]\n"), 0644))
+		require.NoError(t, os.WriteFile(fileListName, []byte("big.txt:2-3\nmain.go"), 0644))
+		result, err := generateContentFileInternal(testDir, genOptions{template: templatePath})
+		require.NoError(t, err)
+		assert.Equal(t, "big.txt [2-3 of 10]\nmain.go []\n", result)
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// analyzeFileList prints the projected size of the bundle for the file list
+func analyzeFileList(baseDir string, opts analyzeOptions) {
+	output, err := analyzeFileListInternal(baseDir, opts)
+	if err != nil {
+		fmt.Printf("Error reading file list: %v\n", err)
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+
+	fmt.Print(output)
+}
+
+// estimateFileTokens estimates the tokens of a file from its size on disk
+func estimateFileTokens(size int64) int {
+	return int((size + 3) / 4)
+}
+
+// analyzeFileListInternal reports the sizes and token estimates of the files
+// in the file list, statting them below baseDir instead of reading the
+// result file. Files gen rendered with its default flags since their last
+// change count the tokens of their cached section; the others are estimated
+// from their size, an upper bound of what gen produces after dropping blank
+// lines.
+func analyzeFileListInternal(baseDir string, opts analyzeOptions) (string, error) {
+	if opts.loc || opts.complexity {
+		return "", fmt.Errorf("-loc and -complexity need the file content, run gen and analyze the result file instead")
+	}
+	if opts.sort == "symbols" || opts.sort == "loc" {
+		return "", fmt.Errorf("-sort %s needs the file content, run gen and analyze the result file instead", opts.sort)
+	}
+	if _, err := symbolCounter(opts.countMode); err != nil {
+		return "", err
+	}
+	if countModeNote(opts.countMode) != "" {
+		return "", fmt.Errorf("-count-mode %s needs the file content, run gen and analyze the result file instead", opts.countMode)
+	}
+	target, err := targetFromOptions(opts)
+	if err != nil {
+		return "", err
+	}
+
+	listed, err := listedFiles()
+	if err != nil {
+		return "", err
+	}
+
+	var stored *fileCache
+	if !opts.noCache {
+		stored = openFileCache(baseDir, genOptionsFromFlags(DefaultFlags()))
+	}
+
+	var files []FileInfo
+	var missing []string
+	var totalSize int64
+	totalTokens := 0
+	for _, file := range listed {
+		info, err := os.Stat(filepath.Join(baseDir, entryPath(file)))
+		if err != nil || info.IsDir() {
+			missing = append(missing, file)
+			continue
+		}
+		tokens := estimateFileTokens(info.Size())
+		if section, ok := stored.lookup(file, info); ok {
+			tokens = estimateTokens(section.Content)
+		}
+		files = append(files, FileInfo{path: file, size: info.Size(), tokens: tokens})
+		totalSize += info.Size()
+		totalTokens += tokens
+	}
+
+	if err := sortFiles(files, opts.sort); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "\nFile List Report")
+	fmt.Fprintln(&buf, "================")
+	if len(missing) > 0 {
+		fmt.Fprintf(&buf, "Files: %d (%d missing)\n", len(files), len(missing))
+	} else {
+		fmt.Fprintf(&buf, "Files: %d\n", len(files))
+	}
+	fmt.Fprintf(&buf, "Total file size: %.2f MB\n", float64(totalSize)/(1024*1024))
+	fmt.Fprintf(&buf, "Estimated tokens: ~%s\n\n", formatTokens(totalTokens))
+
+	if len(files) > 0 {
+		fmt.Fprintln(&buf, topFilesTitle(opts.sort, opts.topCount))
+		w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "File\tSize (KB)\tTokens")
+		fmt.Fprintln(w, "────\t────────\t──────")
+		for i, file := range files {
+			if i >= opts.topCount {
+				break
+			}
+			fmt.Fprintf(w, "%s\t%.2f\t~%s\n", file.path, float64(file.size)/1024, formatTokens(file.tokens))
+		}
+		w.Flush()
+		fmt.Fprintln(&buf, "")
+	}
+
+	if opts.suggest {
+		writeSuggestions(&buf, files)
+	}
+	if opts.models {
+		writeModelEstimates(&buf, int(totalSize))
+	}
+	if opts.target != "" {
+		writeTargetReport(&buf, target, totalTokens, files)
+	}
+
+	if len(missing) > 0 {
+		fmt.Fprintln(&buf, "Missing files:")
+		for _, file := range missing {
+			fmt.Fprintf(&buf, "  %s\n", file)
+		}
+		fmt.Fprintln(&buf, "")
+	}
+	return buf.String(), nil
+}
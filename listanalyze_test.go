@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeFileList(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"big.go":    strings.Repeat("x", 4000),
+		"docs/a.md": strings.Repeat("y", 400),
+		"docs/b.md": "short",
+	})
+	require.NoError(t, os.WriteFile(fileListName, []byte("big.go\ndocs/a.md\ndocs/b.md\ngone.go"), 0644))
+	defer os.Remove(fileListName)
+
+	output, err := analyzeFileListInternal(testDir, analyzeOptions{topCount: 2})
+	require.NoError(t, err)
+	assert.Equal(t, `
+File List Report
+================
+Files: 3 (1 missing)
+Total file size: 0.00 MB
+Estimated tokens: ~1.1k
+
+Top 2 largest files:
+File       Size (KB)  Tokens
+────       ────────   ──────
+big.go     3.91       ~1.0k
+docs/a.md  0.39       ~100
+
+Missing files:
+  gone.go
+
+`, output)
+
+	t.Run("Suggestions", func(t *testing.T) {
+		output, err := analyzeFileListInternal(testDir, analyzeOptions{topCount: 2, suggest: true})
+		require.NoError(t, err)
+		assert.Contains(t, output, "excluding *.md under docs/ saves ~102 tokens")
+	})
+
+	t.Run("Content-based reports are rejected", func(t *testing.T) {
+		_, err := analyzeFileListInternal(testDir, analyzeOptions{topCount: 2, loc: true})
+		assert.ErrorContains(t, err, "-loc and -complexity need the file content")
+		_, err = analyzeFileListInternal(testDir, analyzeOptions{topCount: 2, sort: "symbols"})
+		assert.ErrorContains(t, err, "-sort symbols needs the file content")
+	})
+
+	t.Run("Sorted by tokens", func(t *testing.T) {
+		output, err := analyzeFileListInternal(testDir, analyzeOptions{topCount: 1, sort: "tokens"})
+		require.NoError(t, err)
+		assert.Contains(t, output, "Top 1 files by tokens:\nFile    Size (KB)  Tokens\n")
+	})
+
+	t.Run("Command line", func(t *testing.T) {
+		fs := DefaultFlags()
+		require.NoError(t, fs.Parse([]string{"analyze", "-list", testDir}))
+		output := CaptureOutput(t, func() {
+			assert.Equal(t, 0, runWithFlags(fs))
+		})
+		assert.Contains(t, output, "Files: 3 (1 missing)")
+	})
+}
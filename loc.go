@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// commentSyntax describes how a language writes comments
+type commentSyntax struct {
+	line       []string // prefixes starting a comment that runs to the end of the line
+	blockStart string   // opening delimiter of block comments, empty if there are none
+	blockEnd   string
+}
+
+var (
+	cStyleComments = commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/"}
+	hashComments   = commentSyntax{line: []string{"#"}}
+	markupComments = commentSyntax{blockStart: "<!--", blockEnd: "-->"}
+)
+
+// commentSyntaxes maps fence language identifiers to their comment syntax.
+// Lines of languages missing here all count as code.
+var commentSyntaxes = map[string]commentSyntax{
+	"go":         cStyleComments,
+	"go-mod":     {line: []string{"//"}},
+	"javascript": cStyleComments,
+	"typescript": cStyleComments,
+	"jsx":        cStyleComments,
+	"tsx":        cStyleComments,
+	"java":       cStyleComments,
+	"c":          cStyleComments,
+	"cpp":        cStyleComments,
+	"csharp":     cStyleComments,
+	"rust":       cStyleComments,
+	"swift":      cStyleComments,
+	"kotlin":     cStyleComments,
+	"scala":      cStyleComments,
+	"groovy":     cStyleComments,
+	"protobuf":   cStyleComments,
+	"scss":       cStyleComments,
+	"less":       cStyleComments,
+	"css":        {blockStart: "/*", blockEnd: "*/"},
+	"php":        {line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"},
+	"python":     hashComments,
+	"ruby":       {line: []string{"#"}, blockStart: "=begin", blockEnd: "=end"},
+	"perl":       hashComments,
+	"r":          hashComments,
+	"bash":       hashComments,
+	"sh":         hashComments,
+	"zsh":        hashComments,
+	"fish":       hashComments,
+	"powershell": {line: []string{"#"}, blockStart: "<#", blockEnd: "#>"},
+	"yaml":       hashComments,
+	"toml":       hashComments,
+	"dotenv":     hashComments,
+	"makefile":   hashComments,
+	"dockerfile": hashComments,
+	"cmake":      hashComments,
+	"ini":        {line: []string{";", "#"}},
+	"batch":      {line: []string{"::", "REM ", "rem "}},
+	"sql":        {line: []string{"--"}, blockStart: "/*", blockEnd: "*/"},
+	"lua":        {line: []string{"--"}, blockStart: "--[[", blockEnd: "]]"},
+	"html":       markupComments,
+	"xml":        markupComments,
+	"vue":        markupComments,
+	"svelte":     markupComments,
+	"markdown":   markupComments,
+}
+
+// lineCounts holds cloc-style line counts
+type lineCounts struct {
+	code    int
+	comment int
+	blank   int
+}
+
+// add accumulates other into c
+func (c *lineCounts) add(other lineCounts) {
+	c.code += other.code
+	c.comment += other.comment
+	c.blank += other.blank
+}
+
+// countLines classifies every line of content as code, comment or blank.
+// Block comments are only recognized when they start a line, so delimiters
+// inside strings aren't mistaken for comments; a line holding both code and
+// a comment counts as code.
+func countLines(content, language string) lineCounts {
+	var counts lineCounts
+	content = strings.TrimSuffix(content, "\n")
+	if content == "" {
+		return counts
+	}
+
+	syntax := commentSyntaxes[language]
+	inBlock := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			counts.blank++
+		case inBlock:
+			idx := strings.Index(trimmed, syntax.blockEnd)
+			if idx == -1 {
+				counts.comment++
+				continue
+			}
+			inBlock = false
+			if strings.TrimSpace(trimmed[idx+len(syntax.blockEnd):]) == "" {
+				counts.comment++
+			} else {
+				counts.code++
+			}
+		case syntax.blockStart != "" && strings.HasPrefix(trimmed, syntax.blockStart):
+			rest := trimmed[len(syntax.blockStart):]
+			idx := strings.Index(rest, syntax.blockEnd)
+			switch {
+			case idx == -1:
+				inBlock = true
+				counts.comment++
+			case strings.TrimSpace(rest[idx+len(syntax.blockEnd):]) == "":
+				counts.comment++
+			default:
+				counts.code++
+			}
+		case hasAnyPrefix(trimmed, syntax.line):
+			counts.comment++
+		default:
+			counts.code++
+		}
+	}
+	return counts
+}
+
+// hasAnyPrefix reports whether s starts with one of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeLineCounts prints code, comment and blank line counts per language
+// and for the files with the most code
+func writeLineCounts(out io.Writer, files []FileInfo, topCount int) {
+	type languageCounts struct {
+		language string
+		files    int
+		lineCounts
+	}
+	byLanguage := make(map[string]*languageCounts)
+	var total lineCounts
+	for _, file := range files {
+		language := file.language
+		if language == "" {
+			language = "other"
+		}
+		counts, ok := byLanguage[language]
+		if !ok {
+			counts = &languageCounts{language: language}
+			byLanguage[language] = counts
+		}
+		counts.files++
+		counts.add(file.lines)
+		total.add(file.lines)
+	}
+
+	languages := make([]*languageCounts, 0, len(byLanguage))
+	for _, counts := range byLanguage {
+		languages = append(languages, counts)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].code != languages[j].code {
+			return languages[i].code > languages[j].code
+		}
+		return languages[i].language < languages[j].language
+	})
+
+	fmt.Fprintln(out, "Lines by language:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Language\tFiles\tCode\tComment\tBlank")
+	fmt.Fprintln(w, "────────\t─────\t────\t───────\t─────")
+	for _, counts := range languages {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", counts.language, counts.files, counts.code, counts.comment, counts.blank)
+	}
+	fmt.Fprintf(w, "Total\t%d\t%d\t%d\t%d\n", len(files), total.code, total.comment, total.blank)
+	w.Flush()
+	fmt.Fprintln(out, "")
+
+	byCode := append([]FileInfo(nil), files...)
+	sort.Slice(byCode, func(i, j int) bool {
+		if byCode[i].lines.code != byCode[j].lines.code {
+			return byCode[i].lines.code > byCode[j].lines.code
+		}
+		return byCode[i].path < byCode[j].path
+	})
+
+	fmt.Fprintf(out, "Top %d files by lines of code:\n", topCount)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "File\tLanguage\tCode\tComment\tBlank")
+	fmt.Fprintln(w, "────\t────────\t────\t───────\t─────")
+	for i, file := range byCode {
+		if i >= topCount {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", file.path, file.language, file.lines.code, file.lines.comment, file.lines.blank)
+	}
+	w.Flush()
+	fmt.Fprintln(out, "")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		content  string
+		want     lineCounts
+	}{
+		{
+			name:     "Go with line and block comments",
+			language: "go",
+			content:  "// Package a\npackage a\n\n/*\nblock\n*/\nvar glob = \"src/*.go\" // trailing\n/* one line */\n/* done */ x := 1\n",
+			want:     lineCounts{code: 3, comment: 5, blank: 1},
+		},
+		{
+			name:     "Python",
+			language: "python",
+			content:  "#!/usr/bin/env python3\n# comment\nimport os\n\n\nprint(os.name)",
+			want:     lineCounts{code: 2, comment: 2, blank: 2},
+		},
+		{
+			name:     "Lua block comment takes precedence over line comment",
+			language: "lua",
+			content:  "--[[\nlong\n]]\n-- short\nprint(1)\n",
+			want:     lineCounts{code: 1, comment: 4},
+		},
+		{
+			name:     "Markup",
+			language: "html",
+			content:  "<!-- header -->\n<p>text</p>\n<!--\nmulti\n-->",
+			want:     lineCounts{code: 1, comment: 4},
+		},
+		{
+			name:     "Unknown language is all code",
+			language: "brainfuck",
+			content:  "# not a comment\n++>\n",
+			want:     lineCounts{code: 2},
+		},
+		{
+			name:     "Empty",
+			language: "go",
+			content:  "",
+			want:     lineCounts{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, countLines(tt.content, tt.language))
+		})
+	}
+}
+
+func TestAnalyzeLineCounts(t *testing.T) {
+	defer os.Remove(resultName)
+	bundle := "#FILE a.go\n#TYPE go\n#START\n```go\n// doc\npackage a\nfunc A() {}\n```\n#END\n\n" +
+		"#FILE b.go\n#TYPE go\n#START\n```go\npackage a\n```\n#END\n\n" +
+		"#FILE run.py\n#TYPE py\n#START\n```python\n# run it\n# really\nmain()\n```\n#END\n\n"
+	require.NoError(t, os.WriteFile(resultName, []byte(bundle), 0644))
+
+	output, err := analyzeResultFileInternal(analyzeOptions{topCount: 2, loc: true})
+	require.NoError(t, err)
+
+	assert.Contains(t, output, `Lines by language:
+Language  Files  Code  Comment  Blank
+────────  ─────  ────  ───────  ─────
+go        2      3     1        0
+python    1      1     2        0
+Total     3      4     3        0
+`)
+	assert.Contains(t, output, `Top 2 files by lines of code:
+File  Language  Code  Comment  Blank
+────  ────────  ────  ───────  ─────
+a.go  go        2     1        0
+b.go  go        1     0        0
+`)
+
+	output, err = analyzeResultFileInternal(analyzeOptions{topCount: 2})
+	require.NoError(t, err)
+	assert.NotContains(t, output, "Lines by language")
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Extensions of translation catalogs
+var catalogExts = []string{".json", ".yaml", ".yml", ".po", ".properties", ".arb", ".ftl", ".strings", ".stringsdict", ".xlf", ".xliff", ".toml"}
+
+// Directory names that hold translation catalogs
+var localeDirNames = []string{"locales", "locale", "i18n", "l10n", "lang", "langs", "languages", "translations", "po"}
+
+// Extensions of catalogs named after their locale wherever they are, as in
+// messages_fr.properties or app_de.arb
+var suffixCatalogExts = []string{".properties", ".arb"}
+
+// localeCode matches locale names such as en, pt-BR, pt_br, zh-Hans or
+// es-419. Three-letter languages are left out, as they can't be told from
+// catalog names like app or web.
+var localeCode = regexp.MustCompile(`^[a-z]{2}(?:[-_](?:[A-Za-z]{2}|[A-Za-z]{4}|[0-9]{3}))*$`)
+
+// localeCatalog is a translation catalog: the locale of a file and the
+// catalog it translates, its path with the locale replaced by {locale}
+type localeCatalog struct {
+	file    string
+	locale  string
+	catalog string
+}
+
+// normalizeLocale folds the case and separator of a locale name, so pt_BR
+// and pt-br are the same locale
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// inLocaleDir reports whether one of dirs is a directory of translations
+func inLocaleDir(dirs []string) bool {
+	for _, dir := range dirs {
+		if containsIgnoreCase(localeDirNames, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCatalog recognizes a translation catalog by its locale: the file
+// name (locales/fr.json), a suffix of it (config/locales/devise.fr.yml,
+// messages_fr.properties) or a directory (public/locales/fr/common.json,
+// locale/fr/LC_MESSAGES/django.po, fr.lproj/Localizable.strings)
+func parseCatalog(file string) (localeCatalog, bool) {
+	ext := strings.ToLower(path.Ext(file))
+	if !contains(catalogExts, ext) {
+		return localeCatalog{}, false
+	}
+	parts := strings.Split(file, "/")
+	dirs, base := parts[:len(parts)-1], parts[len(parts)-1]
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	withLocale := func(index int, prefix, locale, suffix string) (localeCatalog, bool) {
+		catalog := append([]string{}, parts...)
+		catalog[index] = prefix + "{locale}" + suffix
+		return localeCatalog{file: file, locale: locale, catalog: strings.Join(catalog, "/")}, true
+	}
+
+	if localeCode.MatchString(stem) && inLocaleDir(dirs) {
+		return withLocale(len(parts)-1, "", stem, path.Ext(base))
+	}
+	if inLocaleDir(dirs) || contains(suffixCatalogExts, ext) {
+		// Try the shortest suffix first, so my_app_en reads as en
+		for i := len(stem) - 1; i > 0; i-- {
+			if (stem[i] == '.' || stem[i] == '_') && localeCode.MatchString(stem[i+1:]) {
+				return withLocale(len(parts)-1, stem[:i+1], stem[i+1:], path.Ext(base))
+			}
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		switch {
+		case strings.HasSuffix(dirs[i], ".lproj") && localeCode.MatchString(strings.TrimSuffix(dirs[i], ".lproj")):
+			return withLocale(i, "", strings.TrimSuffix(dirs[i], ".lproj"), ".lproj")
+		case localeCode.MatchString(dirs[i]) && inLocaleDir(dirs[:i]):
+			return withLocale(i, "", dirs[i], "")
+		}
+	}
+	return localeCatalog{}, false
+}
+
+// localeGroup is a translation catalog with the files of its locales
+type localeGroup struct {
+	catalog   string
+	reference string   // locale kept in the result
+	omitted   []string // other locales, left out
+}
+
+// referenceFile picks the file of the reference locale among the locales of
+// a catalog: the exact locale, or else the first regional variant of its
+// language, so en picks en-GB when there is no en
+func referenceFile(catalogs []localeCatalog, reference string) (localeCatalog, bool) {
+	reference = normalizeLocale(reference)
+	var variant *localeCatalog
+	for i, c := range catalogs {
+		locale := normalizeLocale(c.locale)
+		if locale == reference {
+			return c, true
+		}
+		if variant == nil && strings.HasPrefix(locale, reference+"-") {
+			variant = &catalogs[i]
+		}
+	}
+	if variant != nil {
+		return *variant, true
+	}
+	return localeCatalog{}, false
+}
+
+// collapseLocales keeps the reference locale of every translation catalog
+// with more than one locale in files, and returns the groups listing the
+// locales left out. Catalogs without the reference locale are kept whole, as
+// are entries with line ranges or symbols.
+func collapseLocales(files []string, reference string) ([]string, []localeGroup) {
+	byCatalog := make(map[string][]localeCatalog)
+	var catalogs []string
+	for _, file := range files {
+		if entryPath(file) != file {
+			continue
+		}
+		if c, ok := parseCatalog(file); ok {
+			if byCatalog[c.catalog] == nil {
+				catalogs = append(catalogs, c.catalog)
+			}
+			byCatalog[c.catalog] = append(byCatalog[c.catalog], c)
+		}
+	}
+
+	omitted := make(map[string]bool)
+	var groups []localeGroup
+	for _, catalog := range catalogs {
+		locales := byCatalog[catalog]
+		kept, ok := referenceFile(locales, reference)
+		if len(locales) < 2 || !ok {
+			continue
+		}
+		group := localeGroup{catalog: catalog, reference: kept.locale}
+		for _, c := range locales {
+			if c.file != kept.file {
+				omitted[c.file] = true
+				group.omitted = append(group.omitted, c.locale)
+			}
+		}
+		sort.Strings(group.omitted)
+		groups = append(groups, group)
+	}
+
+	var result []string
+	for _, file := range files {
+		if !omitted[file] {
+			result = append(result, file)
+		}
+	}
+	return result, groups
+}
+
+// renderLocales formats the #LOCALES section naming the locales of each
+// catalog left out by -reference-locale, placed after the file sections
+func renderLocales(groups []localeGroup) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	out.WriteString("#LOCALES\n")
+	for _, group := range groups {
+		fmt.Fprintf(&out, "%s: %s included; %s left out\n", group.catalog, group.reference, strings.Join(group.omitted, ", "))
+	}
+	out.WriteString("#END LOCALES\n\n")
+	return out.String()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCatalog(t *testing.T) {
+	tests := []struct {
+		file    string
+		locale  string
+		catalog string
+	}{
+		{"src/locales/pt-BR.json", "pt-BR", "src/locales/{locale}.json"},
+		{"config/locales/devise.fr.yml", "fr", "config/locales/devise.{locale}.yml"},
+		{"public/locales/de/common.json", "de", "public/locales/{locale}/common.json"},
+		{"app/locale/fr/LC_MESSAGES/django.po", "fr", "app/locale/{locale}/LC_MESSAGES/django.po"},
+		{"Resources/zh-Hans.lproj/Localizable.strings", "zh-Hans", "Resources/{locale}.lproj/Localizable.strings"},
+		{"src/main/resources/messages_en_US.properties", "en_US", "src/main/resources/messages_{locale}.properties"},
+		{"lib/l10n/my_app_es.arb", "es", "lib/l10n/my_app_{locale}.arb"},
+		{"po/ja.po", "ja", "po/{locale}.po"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			c, ok := parseCatalog(tc.file)
+			require.True(t, ok)
+			assert.Equal(t, tc.locale, c.locale)
+			assert.Equal(t, tc.catalog, c.catalog)
+		})
+	}
+
+	for _, file := range []string{"src/en.json", "locales/README.md", "config/database.yml", "src/main/resources/messages.properties"} {
+		_, ok := parseCatalog(file)
+		assert.False(t, ok, file)
+	}
+}
+
+func TestCollapseLocales(t *testing.T) {
+	files := []string{
+		"main.go",
+		"locales/de.json", "locales/en.json", "locales/fr.json",
+		"i18n/en-GB.yml", "i18n/de.yml",
+		"po/de.po", "po/fr.po",
+		"docs/locales/es.json",
+		"locales/ja.json:1-10",
+	}
+	kept, groups := collapseLocales(files, "en")
+	assert.Equal(t, []string{"main.go", "locales/en.json", "i18n/en-GB.yml", "po/de.po", "po/fr.po", "docs/locales/es.json", "locales/ja.json:1-10"}, kept)
+	assert.Equal(t, []localeGroup{
+		{catalog: "locales/{locale}.json", reference: "en", omitted: []string{"de", "fr"}},
+		{catalog: "i18n/{locale}.yml", reference: "en-GB", omitted: []string{"de"}},
+	}, groups)
+
+	// Locale names match whatever their case and separator
+	kept, _ = collapseLocales([]string{"locales/pt_BR.json", "locales/en.json"}, "pt-br")
+	assert.Equal(t, []string{"locales/pt_BR.json"}, kept)
+}
+
+func TestGenerateContentFileReferenceLocale(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":                     "package main\n",
+		"public/locales/en/app.json":  `{"hello": "Hello"}`,
+		"public/locales/de/app.json":  `{"hello": "Hallo"}`,
+		"public/locales/fr/app.json":  `{"hello": "Bonjour"}`,
+		"public/locales/en/auth.json": `{"login": "Log in"}`,
+	})
+	list := "main.go\npublic/locales/en/app.json\npublic/locales/de/app.json\npublic/locales/fr/app.json\npublic/locales/en/auth.json"
+	if err := os.WriteFile("skukozh_file_list.txt", []byte(list), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{refLocale: "en"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#FILE public/locales/en/app.json")
+	assert.Contains(t, result, "#FILE public/locales/en/auth.json")
+	assert.NotContains(t, result, "Hallo")
+	assert.NotContains(t, result, "Bonjour")
+	assert.Contains(t, result, "#LOCALES\npublic/locales/{locale}/app.json: en included; de, fr left out\n#END LOCALES\n")
+}
+
+func TestGenCommandRejectsInvalidReferenceLocale(t *testing.T) {
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"gen", "-reference-locale", "English", "."}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, `Error: invalid -reference-locale "English"`)
+}
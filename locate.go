@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"skukozh/bundle"
+)
+
+// sectionID formats the ID of the n-th section of a bundle with total
+// sections, zero-padded to at least three digits
+func sectionID(n, total int) string {
+	width := len(strconv.Itoa(total))
+	if width < 3 {
+		width = 3
+	}
+	return fmt.Sprintf("%0*d", width, n)
+}
+
+// withSectionID adds the section ID to the #FILE line of a rendered section
+func withSectionID(text, id string) string {
+	return strings.Replace(text, "#FILE ", "#FILE["+id+"] ", 1)
+}
+
+// parseSectionID parses a section ID given as 17, 017 or [017]
+func parseSectionID(value string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"))
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid section ID %q", value)
+	}
+	return id, nil
+}
+
+// locatedSection is a file section found by its ID
+type locatedSection struct {
+	path  string
+	start int // line of the #FILE header, starting at 1
+	end   int // line of the #END marker
+}
+
+// locateSection finds the section with the given ID in a bundle. Sections
+// without an ID are numbered in order, so bundles generated without -ids
+// can be searched too. The bundle is read line by line, so it doesn't need
+// to fit in memory.
+func locateSection(r io.Reader, id int) (locatedSection, error) {
+	var parser bundle.Parser
+	sections := 0
+	matches := func(section bundle.Section) bool {
+		sections++
+		return section.ID == id || (section.ID == 0 && sections == id)
+	}
+
+	reader := bufio.NewReaderSize(r, 1<<20)
+	lines := 1 // counting the empty line after the last newline
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return locatedSection{}, err
+		}
+		lines += strings.Count(line, "\n")
+		if section, ok := parser.Feed(line); ok && matches(section) {
+			return locatedSection{path: section.Path, start: section.Line, end: section.EndLine}, nil
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if section, ok := parser.Unfinished(); ok && matches(section) {
+		// An unfinished section runs to the end of the bundle
+		return locatedSection{path: section.Path, start: section.Line, end: lines}, nil
+	}
+	return locatedSection{}, fmt.Errorf("no section %d in %s (%d sections)", id, resultName, sections)
+}
+
+// locateResultSection prints the path and line range of a section of the
+// result file and returns the exit code
+func locateResultSection(value string) int {
+	id, err := parseSectionID(value)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	reader, err := openBundleFile(resultName)
+	if err != nil {
+		fmt.Printf("Error reading result file: %v\n", err)
+		return 1
+	}
+	defer reader.Close()
+
+	section, err := locateSection(reader, id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%s: lines %d-%d of %s\n", section.path, section.start, section.end, resultName)
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSectionID(t *testing.T) {
+	assert.Equal(t, "007", sectionID(7, 20))
+	assert.Equal(t, "0042", sectionID(42, 1500))
+
+	for _, value := range []string{"17", "017", "[017]"} {
+		id, err := parseSectionID(value)
+		require.NoError(t, err)
+		assert.Equal(t, 17, id)
+	}
+	for _, value := range []string{"", "abc", "0", "[-1]"} {
+		_, err := parseSectionID(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestGenerateWithIDs(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	require.NoError(t, os.WriteFile(fileListName, []byte("file1.go\nfile2.js\nsubdir/file3.go"), 0644))
+	defer os.Remove(fileListName)
+
+	result, err := generateContentFileInternal(testDir, genOptions{ids: true, annotate: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#FILE[001] file1.go (3 lines, ~7 tokens)\n")
+	assert.Contains(t, result, "#FILE[003] subdir/file3.go")
+
+	var paths []string
+	for _, section := range parseBundleSections(result) {
+		paths = append(paths, section.path)
+	}
+	assert.Equal(t, []string{"file1.go", "file2.js", "subdir/file3.go"}, paths)
+
+	section, err := locateSection(strings.NewReader(result), 2)
+	require.NoError(t, err)
+	assert.Equal(t, "file2.js", section.path)
+	lines := strings.Split(result, "\n")
+	assert.Equal(t, "#FILE[002] file2.js (2 lines, ~5 tokens)", lines[section.start-1])
+	assert.Equal(t, "#END", lines[section.end-1])
+
+	_, err = locateSection(strings.NewReader(result), 4)
+	assert.EqualError(t, err, "no section 4 in skukozh_result.txt (3 sections)")
+}
+
+func TestLocateWithoutIDs(t *testing.T) {
+	content := "Prompt\n\n#FILE a.go\n#TYPE go\n#START\n```go\n#END\n```\n#END\n\n#FILE b.go\n#TYPE go\n#START\n```go\npackage b\n```\n#END\n\n"
+
+	section, err := locateSection(strings.NewReader(content), 1)
+	require.NoError(t, err)
+	assert.Equal(t, locatedSection{path: "a.go", start: 3, end: 9}, section, "a #END line inside the content doesn't end the section")
+
+	section, err = locateSection(strings.NewReader(content), 2)
+	require.NoError(t, err)
+	assert.Equal(t, locatedSection{path: "b.go", start: 11, end: 17}, section)
+}
+
+func TestLocateCommand(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	require.NoError(t, os.WriteFile(fileListName, []byte("file1.go\nfile2.js"), 0644))
+	defer os.Remove(fileListName)
+	result, err := generateContentFileInternal(testDir, genOptions{ids: true})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(resultName, []byte(result), 0644))
+	defer os.Remove(resultName)
+
+	var code int
+	output := CaptureOutput(t, func() {
+		fs := DefaultFlags()
+		require.NoError(t, fs.Parse([]string{"locate", "[002]"}))
+		code = runWithFlags(fs)
+	})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "file2.js: lines 11-18 of skukozh_result.txt\n", output)
+
+	output = CaptureOutput(t, func() {
+		fs := DefaultFlags()
+		require.NoError(t, fs.Parse([]string{"locate", "x"}))
+		code = runWithFlags(fs)
+	})
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "Error: invalid section ID \"x\"\n", output)
+}
+
+func TestLocateUnfinishedSection(t *testing.T) {
+	content := "#FILE a.go\n#TYPE go\n#START\n```go\npackage a\n"
+
+	section, err := locateSection(strings.NewReader(content), 1)
+	require.NoError(t, err)
+	assert.Equal(t, locatedSection{path: "a.go", start: 1, end: 6}, section, "the section runs to the last line")
+}
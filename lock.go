@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Suffix of the lock file next to an output while a run writes it
+const lockExt = ".lock"
+
+// Age after which a lock file is taken to be left behind by a killed run
+const staleLockAge = 10 * time.Minute
+
+// How long a run waits for another one to release an output, and how often
+// it checks. A run holding a lock touches it every lockRefreshInterval, so
+// a long gen isn't taken for a killed run.
+var (
+	lockTimeout         = 30 * time.Second
+	lockPollInterval    = 100 * time.Millisecond
+	lockRefreshInterval = staleLockAge / 4
+)
+
+// lockOutput takes the lock of the output file name, waiting while another
+// skukozh run holds it, and returns the function releasing it. The lock is a
+// file created next to the output, so it works the same on every platform
+// and for runs in other containers sharing the directory.
+func lockOutput(name string) (func(), error) {
+	lockName := name + lockExt
+	deadline := time.Now().Add(lockTimeout)
+	waiting := false
+	for {
+		file, err := os.OpenFile(lockName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "pid %d, %s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			trackTempFile(lockName)
+			done := make(chan struct{})
+			go refreshLock(lockName, done)
+			unlock := func() {
+				close(done)
+				untrackTempFile(lockName)
+				os.Remove(lockName)
+			}
+			if err != nil {
+				unlock()
+				return nil, err
+			}
+			return unlock, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockName); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockName)
+			continue
+		}
+		if time.Now().After(deadline) {
+			holder, _ := os.ReadFile(lockName)
+			return nil, fmt.Errorf("another skukozh run (%s) is writing %s; remove %s if it's no longer running",
+				strings.TrimSpace(string(holder)), name, lockName)
+		}
+		if !waiting {
+			statusf("Waiting for another skukozh run writing %s\n", name)
+			waiting = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// refreshLock keeps the modification time of the lock file name current
+// until done is closed or the file is gone, removed by an interrupt
+func refreshLock(name string, done <-chan struct{}) {
+	ticker := time.NewTicker(lockRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			if err := os.Chtimes(name, now, now); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// runLocked runs a command holding the lock of its output and returns the
+// exit code, 1 when the lock can't be taken
+func runLocked(output string, run func()) int {
+	unlock, err := lockOutput(output)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	defer unlock()
+	run()
+	return 0
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockOutput(t *testing.T) {
+	origTimeout, origInterval := lockTimeout, lockPollInterval
+	lockTimeout, lockPollInterval = 200*time.Millisecond, 10*time.Millisecond
+	defer func() { lockTimeout, lockPollInterval = origTimeout, origInterval }()
+
+	name := filepath.Join(t.TempDir(), "skukozh_result.txt")
+	unlock, err := lockOutput(name)
+	require.NoError(t, err)
+	assert.FileExists(t, name+lockExt)
+
+	t.Run("Held locks time out", func(t *testing.T) {
+		var err error
+		output := CaptureOutput(t, func() {
+			_, err = lockOutput(name)
+		})
+		assert.ErrorContains(t, err, "another skukozh run (pid ")
+		assert.ErrorContains(t, err, "is writing "+name+"; remove "+name+lockExt+" if it's no longer running")
+		assert.Equal(t, "Waiting for another skukozh run writing "+name+"\n", output)
+	})
+
+	t.Run("Waiting runs take the released lock", func(t *testing.T) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			unlock()
+		}()
+		var next func()
+		var err error
+		CaptureOutput(t, func() {
+			next, err = lockOutput(name)
+		})
+		require.NoError(t, err)
+		next()
+		assert.NoFileExists(t, name+lockExt)
+	})
+
+	t.Run("Stale locks are taken over", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(name+lockExt, []byte("pid 1\n"), 0644))
+		old := time.Now().Add(-staleLockAge - time.Minute)
+		require.NoError(t, os.Chtimes(name+lockExt, old, old))
+		unlock, err := lockOutput(name)
+		require.NoError(t, err)
+		unlock()
+	})
+
+	t.Run("Held locks are kept fresh", func(t *testing.T) {
+		origRefresh := lockRefreshInterval
+		lockRefreshInterval = 10 * time.Millisecond
+		defer func() { lockRefreshInterval = origRefresh }()
+
+		unlock, err := lockOutput(name)
+		require.NoError(t, err)
+		defer unlock()
+		old := time.Now().Add(-staleLockAge - time.Minute)
+		require.NoError(t, os.Chtimes(name+lockExt, old, old))
+		assert.Eventually(t, func() bool {
+			info, err := os.Stat(name + lockExt)
+			return err == nil && time.Since(info.ModTime()) < staleLockAge
+		}, time.Second, 10*time.Millisecond, "a long run keeps its lock from looking stale")
+	})
+
+	t.Run("Interrupts release the lock", func(t *testing.T) {
+		_, err := lockOutput(name)
+		require.NoError(t, err)
+		CaptureOutput(t, func() {
+			interrupted(os.Interrupt)
+		})
+		assert.NoFileExists(t, name+lockExt)
+	})
+}
+
+func TestConcurrentGen(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"a.go": "package a\n"})
+	result := filepath.Join(t.TempDir(), "bundle.txt")
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	require.NoError(t, os.WriteFile(listFile, []byte("a.go\n"), 0644))
+
+	// A run holding the lock keeps a second one waiting until it's done
+	unlock, err := lockOutput(result)
+	require.NoError(t, err)
+	done := make(chan int)
+	go func() {
+		done <- runCommandIn(t, dir, "-quiet", "-list-file", listFile, "-result-file", result, "gen", ".")
+	}()
+	time.Sleep(3 * lockPollInterval)
+	assert.NoFileExists(t, result)
+	unlock()
+	assert.Equal(t, 0, <-done)
+	assert.Contains(t, ReadTestFile(t, result), "#FILE a.go")
+	assert.NoFileExists(t, result+lockExt)
+}
+
+func TestLockedOutputsOfOtherCommands(t *testing.T) {
+	origTimeout, origInterval := lockTimeout, lockPollInterval
+	lockTimeout, lockPollInterval = 100*time.Millisecond, 10*time.Millisecond
+	defer func() { lockTimeout, lockPollInterval = origTimeout, origInterval }()
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"main.go": "package main\n"})
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	unlock, err := lockOutput(listFile)
+	require.NoError(t, err)
+	defer unlock()
+
+	// deps writes the file list like find, so it waits for the lock too
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, dir, "-list-file", listFile, "deps", "-seed", "main.go", ".")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, "is writing "+listFile)
+	assert.NoFileExists(t, listFile)
+
+	// A find running meanwhile doesn't list the lock of another run
+	require.NoError(t, os.WriteFile(filepath.Join(dir, resultName+".gz"+lockExt), []byte("pid 1\n"), 0644))
+	included, reason, err := explainPath(dir, resultName+".gz"+lockExt, nil, findOptions{})
+	require.NoError(t, err)
+	assert.False(t, included)
+	assert.Equal(t, "tool file in root", reason)
+	assert.True(t, isToolFile(fileListName+lockExt, false))
+	assert.False(t, isToolFile("notes.lock", false))
+}
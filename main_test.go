@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os"
@@ -178,27 +179,30 @@ func TestFindFiles(t *testing.T) {
 			supportedExts:    []string{},
 			noIgnoreValue:    false,
 			hiddenValue:      false,
-			expectedCount:    5,
+			expectedCount:    6,
 			expectedPrefix:   "",
-			shouldNotContain: []string{"ignoreme.txt", "test.log", "ignored_dir/file.txt"},
+			shouldContain:    []string{"empty.txt", "file5.txt"},
+			shouldNotContain: []string{"ignoreme.txt", "test.log", "ignored_dir/file.txt", "ignored_dir/keep.txt", "image.jpg", ".hidden.txt", "vendor/package.js"},
 		},
 		{
 			name:             "No ignore",
 			supportedExts:    []string{},
 			noIgnoreValue:    true,
 			hiddenValue:      false,
-			expectedCount:    9,
+			expectedCount:    10,
 			expectedPrefix:   "",
-			shouldNotContain: []string{"ignoreme.txt", "test.log", "ignored_dir/file.txt"},
+			shouldContain:    []string{".hidden.txt", ".hiddendir/file.txt", "vendor/package.js"},
+			shouldNotContain: []string{"ignoreme.txt", "test.log", "ignored_dir/file.txt", "image.jpg"},
 		},
 		{
-			name:           "Hidden flag enabled",
-			supportedExts:  []string{},
-			noIgnoreValue:  false,
-			hiddenValue:    true,
-			expectedCount:  12,
-			expectedPrefix: "",
-			shouldContain:  []string{"ignoreme.txt", "test.log", "ignored_dir/file.txt", "ignored_dir/keep.txt"},
+			name:             "Hidden flag enabled",
+			supportedExts:    []string{},
+			noIgnoreValue:    false,
+			hiddenValue:      true,
+			expectedCount:    13,
+			expectedPrefix:   "",
+			shouldContain:    []string{"ignoreme.txt", "ignored_dir/file.txt", "ignored_dir/keep.txt", ".hidden.txt", "vendor/package.js"},
+			shouldNotContain: []string{"test.log", "image.jpg"},
 		},
 		{
 			name:           "Go files only",
@@ -309,6 +313,118 @@ func TestFindFiles(t *testing.T) {
 	}
 }
 
+func TestFindFilesWellKnownFiles(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	for _, name := range []string{"Dockerfile", "Makefile", "LICENSE", ".editorconfig", "go.mod", "Justfile", "random"} {
+		if err := os.WriteFile(filepath.Join(testDir, name), []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	t.Run("Default extensions include well-known files", func(t *testing.T) {
+		files, err := findFilesInternal(testDir, nil)
+		require.NoError(t, err)
+		assert.Subset(t, files, []string{"Dockerfile", "Makefile", "LICENSE", ".editorconfig", "go.mod"})
+		assert.NotContains(t, files, "Justfile")
+		assert.NotContains(t, files, "random")
+	})
+
+	t.Run("Extra known files are configurable", func(t *testing.T) {
+		files, err := findFilesWithOptions(testDir, nil, findOptions{knownFiles: []string{"Justfile"}})
+		require.NoError(t, err)
+		assert.Contains(t, files, "Justfile")
+	})
+
+	t.Run("Explicit extensions skip well-known files", func(t *testing.T) {
+		files, err := findFilesInternal(testDir, []string{".go"})
+		require.NoError(t, err)
+		assert.NotContains(t, files, "Dockerfile")
+		assert.NotContains(t, files, "go.mod")
+	})
+}
+
+func TestFindFilesExcludedExts(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	for _, name := range []string{"main.go", "main_test.go", "app.js", "app.min.js"} {
+		if err := os.WriteFile(filepath.Join(testDir, name), []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	t.Run("Negated suffix is excluded", func(t *testing.T) {
+		include, exclude := parseExtFilter("go,!_test.go")
+		files, err := findFilesWithOptions(testDir, include, findOptions{excludedExts: exclude})
+		require.NoError(t, err)
+		assert.Contains(t, files, "main.go")
+		assert.NotContains(t, files, "main_test.go")
+		assert.NotContains(t, files, "app.js")
+	})
+
+	t.Run("Exclusions alone keep the default extensions", func(t *testing.T) {
+		files, err := findFilesWithOptions(testDir, nil, findOptions{excludedExts: []string{".min.js"}})
+		require.NoError(t, err)
+		assert.Contains(t, files, "app.js")
+		assert.NotContains(t, files, "app.min.js")
+	})
+}
+
+func TestFindFilesMaxDepth(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":          "package main",
+		"pkg/api/api.go":   "package api",
+		"pkg/pkg.go":       "package pkg",
+		"pkg/api/v1/v1.go": "package v1",
+	})
+
+	tests := []struct {
+		maxDepth int
+		expected []string
+	}{
+		{1, []string{"main.go"}},
+		{2, []string{"main.go", "pkg/pkg.go"}},
+		{0, []string{"main.go", "pkg/api/api.go", "pkg/api/v1/v1.go", "pkg/pkg.go"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("Depth %d", tc.maxDepth), func(t *testing.T) {
+			files, err := findFilesWithOptions(testDir, []string{".go"}, findOptions{maxDepth: tc.maxDepth})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, files)
+		})
+	}
+}
+
+func TestFindFilesPathPrefixes(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"README.md":          "readme",
+		"src/app/main.go":    "package main",
+		"src/lib/lib.go":     "package lib",
+		"docs/guide.md":      "guide",
+		"scripts/release.sh": "echo",
+	})
+
+	files, err := findFilesWithOptions(testDir, nil, findOptions{pathPrefixes: []string{"src/app", "docs"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs/guide.md", "src/app/main.go"}, files)
+
+	t.Run("Prefixes are given after --", func(t *testing.T) {
+		defer os.Remove(fileListName)
+
+		flagSet := DefaultFlags()
+		require.NoError(t, flagSet.Parse([]string{"find", "--ext", "go", testDir, "--", "src/lib/", "docs"}))
+		CaptureOutput(t, func() {
+			assert.Equal(t, 0, runWithFlags(flagSet))
+		})
+		assert.Equal(t, fileListText(t, testDir, "-ext=go -- src/lib/ docs", "src/lib/lib.go"), ReadTestFile(t, fileListName))
+	})
+}
+
 func TestFindFilesErrors(t *testing.T) {
 	// Test with a non-existent directory
 	nonExistentDir := "/non/existent/directory"
@@ -335,7 +451,7 @@ func TestFindFilesErrors(t *testing.T) {
 	output := CaptureOutput(t, func() {
 		// Create a temporary FlagSet for this test
 		tempFlags := DefaultFlags()
-		findFiles(nonExistentDir, nil, tempFlags)
+		findFiles(nonExistentDir, nil, nil, nil, tempFlags)
 	})
 
 	// Verify exit was called
@@ -372,7 +488,7 @@ func TestGenerateContentFile(t *testing.T) {
 	defer os.Remove("skukozh_file_list.txt")
 	defer os.Remove("skukozh_result.txt")
 
-	generateContentFile(testDir)
+	generateContentFile(testDir, genOptions{})
 
 	// Check if the result file was created
 	if !FileExists("skukozh_result.txt") {
@@ -401,11 +517,102 @@ func TestGenerateContentFile(t *testing.T) {
 	if !strings.Contains(result, "```go") {
 		t.Errorf("Result does not contain go code block")
 	}
-	if !strings.Contains(result, "```js") {
+	if !strings.Contains(result, "```javascript") {
 		t.Errorf("Result does not contain js code block")
 	}
 }
 
+func TestGenerateContentFileTruncation(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	// Create a file that is well above the token limit
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprintf("line %d with some padding text", i))
+	}
+	if err := os.WriteFile(filepath.Join(testDir, "big.txt"), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatalf("Failed to create big file: %v", err)
+	}
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("big.txt\nfile1.go"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{maxFileTokens: 100, previewLines: 3})
+	require.NoError(t, err)
+
+	assert.Contains(t, result, "line 1 with", "Head lines should be kept")
+	assert.Contains(t, result, "line 100 with", "Tail lines should be kept")
+	assert.NotContains(t, result, "line 50 with", "Middle lines should be dropped")
+	assert.Contains(t, result, "... [truncated 94 lines] ...", "Truncation marker should be present")
+	assert.Contains(t, result, "func main()", "Small files should not be truncated")
+}
+
+func TestGenerateContentFileNormalizeEOL(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(testDir, "windows.txt"), []byte("\xef\xbb\xbffirst\r\nsecond\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to create CRLF file: %v", err)
+	}
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("windows.txt"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, result, "\r", "CRLF should be kept by default")
+
+	result, err = generateContentFileInternal(testDir, genOptions{normalizeEOL: true})
+	require.NoError(t, err)
+	assert.NotContains(t, result, "\r", "CR characters should be removed")
+	assert.NotContains(t, result, "\xef\xbb\xbf", "BOM should be removed")
+	assert.Contains(t, result, "first\nsecond\n")
+}
+
+func TestGenerateContentFileWithMarkers(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	content := "# Usage\n```sh\nmake\n```\n#END\n#FILE fake.go\n"
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "README.md"), []byte(content), 0644))
+	require.NoError(t, os.WriteFile(fileListName, []byte("README.md\nfile1.go"), 0644))
+	defer os.Remove(fileListName)
+
+	result, err := generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#START\n````markdown\n"+content+"````\n#END\n", "the fence is longer than the backticks of the file")
+	assert.Contains(t, result, "#START\n```go\n")
+
+	sections := parseBundleSections(result)
+	require.Len(t, sections, 2)
+	assert.Equal(t, "README.md", sections[0].path)
+	assert.Equal(t, content, sections[0].content)
+	assert.Equal(t, "file1.go", sections[1].path)
+}
+
+func TestGenerateContentFileAnnotatedList(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	list := "#SKUKOZH-LIST v2\n#ROOT " + filepath.ToSlash(testDir) + "\n# helpers first\nfile1.go\nsubdir/file3.go priority=2\nsubdir/file4.php lines=1-2 priority=1\n"
+	require.NoError(t, os.WriteFile(fileListName, []byte(list), 0644))
+	defer os.Remove(fileListName)
+
+	result, err := generateContentFileInternal(testDir, genOptions{order: "priority"})
+	require.NoError(t, err)
+	sections := parseBundleSections(result)
+	require.Len(t, sections, 3)
+	assert.Equal(t, "subdir/file4.php", sections[0].path)
+	assert.Equal(t, "subdir/file3.go", sections[1].path)
+	assert.Equal(t, "file1.go", sections[2].path)
+	assert.Contains(t, result, "#LINES 1-2 of")
+	assert.NotContains(t, result, "helpers first")
+}
+
 func TestGenerateContentFileErrors(t *testing.T) {
 	// Setup - create test directory
 	testDir, cleanup := setupTestDir(t)
@@ -417,7 +624,7 @@ func TestGenerateContentFileErrors(t *testing.T) {
 		os.Remove("skukozh_file_list.txt")
 
 		// Test the internal function
-		_, err := generateContentFileInternal(testDir)
+		_, err := generateContentFileInternal(testDir, genOptions{})
 		if err == nil {
 			t.Errorf("Expected error for missing file list, got nil")
 		}
@@ -429,7 +636,7 @@ func TestGenerateContentFileErrors(t *testing.T) {
 		}
 
 		output := CaptureOutput(t, func() {
-			generateContentFile(testDir)
+			generateContentFile(testDir, genOptions{})
 		})
 
 		// Verify exit was called
@@ -454,7 +661,7 @@ func TestGenerateContentFileErrors(t *testing.T) {
 		defer os.Remove("skukozh_file_list.txt")
 
 		// Test the internal function
-		output, err := generateContentFileInternal(testDir)
+		output, err := generateContentFileInternal(testDir, genOptions{})
 		if err != nil {
 			t.Errorf("Did not expect error from internal function: %v", err)
 		}
@@ -466,7 +673,7 @@ func TestGenerateContentFileErrors(t *testing.T) {
 
 		// Also test the main function
 		capturedOutput := CaptureOutput(t, func() {
-			generateContentFile(testDir)
+			generateContentFile(testDir, genOptions{})
 		})
 
 		if !strings.Contains(capturedOutput, "Error reading file") {
@@ -507,7 +714,7 @@ function test() {
 
 	// Capture stdout using our utility
 	output := CaptureOutput(t, func() {
-		analyzeResultFile(5)
+		analyzeResultFile(analyzeOptions{topCount: 5})
 	})
 
 	// Verify output contains expected information
@@ -533,7 +740,7 @@ func TestAnalyzeResultFileErrors(t *testing.T) {
 		os.Remove("skukozh_result.txt")
 
 		// Test with internal function
-		_, err := analyzeResultFileInternal(10)
+		_, err := analyzeResultFileInternal(analyzeOptions{topCount: 10})
 		if err == nil {
 			t.Errorf("Expected error for missing result file, got nil")
 		}
@@ -545,7 +752,7 @@ func TestAnalyzeResultFileErrors(t *testing.T) {
 		}
 
 		output := CaptureOutput(t, func() {
-			analyzeResultFile(10)
+			analyzeResultFile(analyzeOptions{topCount: 10})
 		})
 
 		// Verify exit was called
@@ -567,7 +774,7 @@ func TestAnalyzeResultFileErrors(t *testing.T) {
 		defer os.Remove("skukozh_result.txt")
 
 		// Test with internal function
-		result, err := analyzeResultFileInternal(10)
+		result, err := analyzeResultFileInternal(analyzeOptions{topCount: 10})
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -578,7 +785,7 @@ func TestAnalyzeResultFileErrors(t *testing.T) {
 
 		// Test with main function
 		output := CaptureOutput(t, func() {
-			analyzeResultFile(10)
+			analyzeResultFile(analyzeOptions{topCount: 10})
 		})
 
 		if !strings.Contains(output, "No files found") {
@@ -596,7 +803,7 @@ func TestAnalyzeResultFileErrors(t *testing.T) {
 		defer os.Remove("skukozh_result.txt")
 
 		// Test with internal function
-		result, err := analyzeResultFileInternal(10)
+		result, err := analyzeResultFileInternal(analyzeOptions{topCount: 10})
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -607,7 +814,7 @@ func TestAnalyzeResultFileErrors(t *testing.T) {
 
 		// Test with main function
 		output := CaptureOutput(t, func() {
-			analyzeResultFile(10)
+			analyzeResultFile(analyzeOptions{topCount: 10})
 		})
 
 		// Check that analysis runs without crashing
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Lockfiles left out of the bundle by -dependencies, whose direct
+// dependencies the dependencies section lists instead
+var lockfileNames = []string{
+	"go.sum", "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+	"Cargo.lock", "composer.lock", "Gemfile.lock", "poetry.lock", "Pipfile.lock", "uv.lock",
+}
+
+// dependency is a direct dependency declared in a manifest
+type dependency struct {
+	name    string
+	version string // version or constraint as written, empty if none
+}
+
+// dependencyGroup holds the dependencies of one kind declared in a manifest
+type dependencyGroup struct {
+	manifest string // manifest file name
+	kind     string // "dev" for development dependencies, empty otherwise
+	deps     []dependency
+}
+
+// manifestParsers read the direct dependencies of the manifests -dependencies
+// recognizes, in the order the groups are listed
+var manifestParsers = []struct {
+	name  string
+	parse func(content []byte) []dependencyGroup
+}{
+	{"go.mod", parseGoModDeps},
+	{"package.json", parsePackageJSONDeps},
+	{"composer.json", parseComposerDeps},
+	{"Cargo.toml", parseCargoDeps},
+	{"pyproject.toml", parsePyprojectDeps},
+	{"requirements.txt", parseRequirementsDeps},
+	{"Gemfile", parseGemfileDeps},
+}
+
+// isLockfile reports whether a file is a lockfile -dependencies leaves out
+func isLockfile(file string) bool {
+	return containsIgnoreCase(lockfileNames, filepath.Base(file))
+}
+
+// manifestDependencies reads the direct dependencies of the manifests at
+// the top of baseDir. Manifests that are missing or can't be parsed are
+// skipped.
+func manifestDependencies(baseDir string) []dependencyGroup {
+	var groups []dependencyGroup
+	for _, parser := range manifestParsers {
+		content, err := os.ReadFile(filepath.Join(baseDir, parser.name))
+		if err != nil {
+			continue
+		}
+		for _, group := range parser.parse(content) {
+			if len(group.deps) > 0 {
+				group.manifest = parser.name
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups
+}
+
+// renderDependencies formats the dependencies section placed at the top of
+// the bundle, one line per manifest and kind, or "" when there are none
+func renderDependencies(groups []dependencyGroup) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	out.WriteString("#DEPENDENCIES\n")
+	for _, group := range groups {
+		label := group.manifest
+		if group.kind != "" {
+			label += " (" + group.kind + ")"
+		}
+		items := make([]string, len(group.deps))
+		for i, dep := range group.deps {
+			items[i] = strings.TrimSpace(dep.name + " " + dep.version)
+		}
+		fmt.Fprintf(&out, "%s: %s\n", label, strings.Join(items, ", "))
+	}
+	out.WriteString("#END DEPENDENCIES\n\n")
+	return out.String()
+}
+
+// sortedDeps turns a name to version map from a JSON manifest into
+// dependencies sorted by name
+func sortedDeps(versions map[string]string) []dependency {
+	deps := make([]dependency, 0, len(versions))
+	for name, version := range versions {
+		deps = append(deps, dependency{name, version})
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].name < deps[j].name })
+	return deps
+}
+
+// parseGoModDeps reads the require directives of a go.mod file, leaving out
+// the ones marked // indirect
+func parseGoModDeps(content []byte) []dependencyGroup {
+	var deps []dependency
+	inBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "require (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inBlock:
+			continue
+		}
+		if strings.Contains(line, "// indirect") {
+			continue
+		}
+		line, _, _ = strings.Cut(line, "//")
+		if fields := strings.Fields(line); len(fields) == 2 {
+			deps = append(deps, dependency{fields[0], fields[1]})
+		}
+	}
+	return []dependencyGroup{{deps: deps}}
+}
+
+// parsePackageJSONDeps reads the dependencies and devDependencies of a
+// package.json file
+func parsePackageJSONDeps(content []byte) []dependencyGroup {
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil
+	}
+	return []dependencyGroup{{deps: sortedDeps(pkg.Dependencies)}, {kind: "dev", deps: sortedDeps(pkg.DevDependencies)}}
+}
+
+// parseComposerDeps reads the require and require-dev sections of a
+// composer.json file
+func parseComposerDeps(content []byte) []dependencyGroup {
+	var pkg struct {
+		Require    map[string]string `json:"require"`
+		RequireDev map[string]string `json:"require-dev"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil
+	}
+	return []dependencyGroup{{deps: sortedDeps(pkg.Require)}, {kind: "dev", deps: sortedDeps(pkg.RequireDev)}}
+}
+
+// tomlString matches a quoted TOML string
+var tomlString = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// tomlVersion matches the version key of an inline TOML table
+var tomlVersion = regexp.MustCompile(`\bversion\s*=\s*["']([^"']*)["']`)
+
+// parseCargoDeps reads the [dependencies] and [dev-dependencies] tables of a
+// Cargo.toml file. Dependencies without a version, such as path or git
+// ones, are listed by name.
+func parseCargoDeps(content []byte) []dependencyGroup {
+	groups := []dependencyGroup{{}, {kind: "dev"}}
+	current := -1
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			switch line {
+			case "[dependencies]":
+				current = 0
+			case "[dev-dependencies]":
+				current = 1
+			default:
+				current = -1
+			}
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if current < 0 || !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dep := dependency{name: strings.TrimSpace(name)}
+		value = strings.TrimSpace(value)
+		if match := tomlVersion.FindStringSubmatch(value); strings.HasPrefix(value, "{") && match != nil {
+			dep.version = match[1]
+		} else if match := tomlString.FindStringSubmatch(value); !strings.HasPrefix(value, "{") && match != nil {
+			dep.version = match[1] + match[2]
+		}
+		groups[current].deps = append(groups[current].deps, dep)
+	}
+	return groups
+}
+
+// pythonRequirement splits a PEP 508 requirement such as "requests>=2.31"
+// into the package name and its version constraint
+var pythonRequirement = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*(?:\[[^\]]*\])?)\s*(.*)$`)
+
+// parsePythonRequirement parses one requirement, dropping environment
+// markers after ";"
+func parsePythonRequirement(requirement string) (dependency, bool) {
+	requirement, _, _ = strings.Cut(requirement, ";")
+	match := pythonRequirement.FindStringSubmatch(strings.TrimSpace(requirement))
+	if match == nil {
+		return dependency{}, false
+	}
+	return dependency{match[1], strings.ReplaceAll(match[2], " ", "")}, true
+}
+
+// parsePyprojectDeps reads the [project] dependencies array of a
+// pyproject.toml file, or the [tool.poetry.dependencies] table
+func parsePyprojectDeps(content []byte) []dependencyGroup {
+	var deps []dependency
+	section := ""
+	inArray := false
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+		if !inArray && strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		switch {
+		case section == "[project]" && (inArray || strings.HasPrefix(line, "dependencies")):
+			if !inArray {
+				_, value, _ := strings.Cut(line, "=")
+				line = strings.TrimPrefix(strings.TrimSpace(value), "[")
+				inArray = true
+			}
+			end := strings.Contains(line, "]")
+			for _, match := range tomlString.FindAllStringSubmatch(line, -1) {
+				if dep, ok := parsePythonRequirement(match[1] + match[2]); ok {
+					deps = append(deps, dep)
+				}
+			}
+			inArray = !end
+		case section == "[tool.poetry.dependencies]":
+			name, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(name) == "python" {
+				continue
+			}
+			dep := dependency{name: strings.TrimSpace(name)}
+			if match := tomlString.FindStringSubmatch(value); match != nil {
+				dep.version = match[1] + match[2]
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return []dependencyGroup{{deps: deps}}
+}
+
+// parseRequirementsDeps reads a requirements.txt file, skipping comments
+// and pip options such as -r and -e
+func parseRequirementsDeps(content []byte) []dependencyGroup {
+	var deps []dependency
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if dep, ok := parsePythonRequirement(line); ok {
+			deps = append(deps, dep)
+		}
+	}
+	return []dependencyGroup{{deps: deps}}
+}
+
+// gemLine matches a gem declaration of a Gemfile and its first constraint
+var gemLine = regexp.MustCompile(`^gem\s+["']([^"']+)["'](?:\s*,\s*["']([^"']+)["'])?`)
+
+// parseGemfileDeps reads the gem declarations of a Gemfile, those in
+// development and test groups as dev dependencies
+func parseGemfileDeps(content []byte) []dependencyGroup {
+	groups := []dependencyGroup{{}, {kind: "dev"}}
+	var stack []bool // whether each open block is a development or test group
+	dev := func() bool {
+		for _, d := range stack {
+			if d {
+				return true
+			}
+		}
+		return false
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "group ") && strings.HasSuffix(line, " do"):
+			stack = append(stack, strings.Contains(line, ":development") || strings.Contains(line, ":test"))
+		case strings.HasSuffix(line, " do"):
+			stack = append(stack, false)
+		case line == "end" && len(stack) > 0:
+			stack = stack[:len(stack)-1]
+		default:
+			if match := gemLine.FindStringSubmatch(line); match != nil {
+				i := 0
+				if dev() {
+					i = 1
+				}
+				groups[i].deps = append(groups[i].deps, dependency{match[1], match[2]})
+			}
+		}
+	}
+	return groups
+}
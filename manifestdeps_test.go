@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestDependencies(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod":           "module app\n\ngo 1.23\n\nrequire github.com/spf13/cobra v1.8.0\n\nrequire (\n\tgithub.com/stretchr/testify v1.9.0\n\tgolang.org/x/sys v0.20.0 // indirect\n)\n",
+		"package.json":     `{"name": "web", "dependencies": {"react": "^18.2.0", "axios": "1.6.0"}, "devDependencies": {"jest": "^29.0.0"}}`,
+		"composer.json":    `{"require": {"php": ">=8.1", "laravel/framework": "^10.0"}}`,
+		"Cargo.toml":       "[package]\nname = \"app\"\nversion = \"0.1.0\"\n\n[dependencies]\nserde = { version = \"1.0\", features = [\"derive\"] }\ntokio = \"1.35\"\nlocal = { path = \"../local\" }\n\n[dev-dependencies]\ncriterion = \"0.5\"\n",
+		"pyproject.toml":   "[project]\nname = \"app\"\nversion = \"1.0\"\ndependencies = [\n  \"requests>=2.31\",\n  \"uvicorn[standard] == 0.27; python_version > '3.8'\",\n]\n\n[tool.black]\nline-length = 100\n",
+		"requirements.txt": "# web\nflask==3.0.0\n-r dev.txt\ngunicorn\n",
+		"Gemfile":          "source 'https://rubygems.org'\n\ngem 'rails', '~> 7.1'\ngem \"pg\"\n\ngroup :development, :test do\n  gem 'rspec-rails'\nend\n",
+	})
+
+	assert.Equal(t, "#DEPENDENCIES\n"+
+		"go.mod: github.com/spf13/cobra v1.8.0, github.com/stretchr/testify v1.9.0\n"+
+		"package.json: axios 1.6.0, react ^18.2.0\n"+
+		"package.json (dev): jest ^29.0.0\n"+
+		"composer.json: laravel/framework ^10.0, php >=8.1\n"+
+		"Cargo.toml: serde 1.0, tokio 1.35, local\n"+
+		"Cargo.toml (dev): criterion 0.5\n"+
+		"pyproject.toml: requests >=2.31, uvicorn[standard] ==0.27\n"+
+		"requirements.txt: flask ==3.0.0, gunicorn\n"+
+		"Gemfile: rails ~> 7.1, pg\n"+
+		"Gemfile (dev): rspec-rails\n"+
+		"#END DEPENDENCIES\n\n", renderDependencies(manifestDependencies(dir)))
+
+	assert.Empty(t, renderDependencies(manifestDependencies(t.TempDir())))
+}
+
+func TestParsePoetryDeps(t *testing.T) {
+	groups := parsePyprojectDeps([]byte("[tool.poetry.dependencies]\npython = \"^3.11\"\nfastapi = \"^0.110\"\nsqlalchemy = {version = \"^2.0\", extras = [\"asyncio\"]}\n\n[tool.poetry.group.dev.dependencies]\npytest = \"^8\"\n"))
+	assert.Equal(t, []dependencyGroup{{deps: []dependency{{"fastapi", "^0.110"}, {"sqlalchemy", "^2.0"}}}}, groups)
+}
+
+func TestGenerateContentFileDependencies(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go": "package main\n",
+		"go.mod":  "module app\n\nrequire github.com/spf13/cobra v1.8.0\n",
+		"go.sum":  strings.Repeat("github.com/spf13/cobra v1.8.0 h1:xxx=\n", 50),
+	})
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("main.go\ngo.mod\ngo.sum"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{dependencies: true, summary: true})
+	require.NoError(t, err)
+	assert.Regexp(t, `^#SUMMARY\n(.+\n)+#END SUMMARY\n\n#DEPENDENCIES\ngo.mod: github.com/spf13/cobra v1.8.0\n#END DEPENDENCIES\n\n#FILE main.go\n`, result)
+	assert.Contains(t, result, "#FILE go.mod\n")
+	assert.NotContains(t, result, "#FILE go.sum")
+
+	result, err = generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, result, "#DEPENDENCIES")
+	assert.Contains(t, result, "#FILE go.sum")
+
+	_, err = generateContentFileInternal(testDir, genOptions{dependencies: true, format: "jsonl"})
+	assert.EqualError(t, err, "-format jsonl can't be combined with -dependencies")
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// MCP protocol versions the server speaks, newest first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes used by the MCP server
+const (
+	mcpParseError     = -32700
+	mcpInvalidRequest = -32600
+	mcpMethodNotFound = -32601
+	mcpInvalidParams  = -32602
+)
+
+// mcpToolCommands maps MCP tool names to the commands whose flags they accept
+var mcpToolCommands = map[string]string{
+	"find":     "find",
+	"generate": "gen",
+	"analyze":  "analyze",
+}
+
+// Tool descriptions shown to MCP clients
+var mcpToolDescriptions = map[string]string{
+	"find":     "Find files in a directory and save them as the file list used by generate. Returns the list.",
+	"generate": "Generate the content bundle from the file list of the last find and return it.",
+	"analyze":  "Report the size, token estimate and largest files of the last generated bundle, or with list, of the file list before generating.",
+}
+
+// Flags that make no sense over MCP: interactive prompts and binary output
+var mcpExcludedFlags = map[string]bool{
+	"review":          true,
+	"compress":        true,
+	"encrypt":         true,
+	"passphrase-file": true,
+
+	// External commands from the config file and uploads are only run from the command line
+	"processors": true,
+	"upload":     true,
+
+	// Exit code gates for CI, meaningless for a tool call
+	"fail-over-tokens": true,
+	"fail-over-size":   true,
+}
+
+// MCP resources exposing the files written by the tools
+var mcpResources = []struct {
+	uri         string
+	name        string
+	description string
+	read        func() ([]byte, error)
+}{
+	{"skukozh://file-list", fileListName, "File list written by the last find", func() ([]byte, error) { return os.ReadFile(fileListName) }},
+	{"skukozh://result", resultName, "Content bundle written by the last generate", readResultFile},
+}
+
+type mcpRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type mcpResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *mcpError       `json:"error,omitempty"`
+}
+
+type mcpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// serveMCP runs a Model Context Protocol server reading newline-delimited
+// JSON-RPC messages from in and writing responses to out
+func serveMCP(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	encoder := json.NewEncoder(out)
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			if response, ok := handleMCPMessage(line); ok {
+				if err := encoder.Encode(response); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// handleMCPMessage handles one JSON-RPC message. The second return value is
+// false for notifications, which get no response.
+func handleMCPMessage(line []byte) (mcpResponse, bool) {
+	var req mcpRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return mcpResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &mcpError{mcpParseError, err.Error()}}, true
+	}
+	if len(req.ID) == 0 {
+		return mcpResponse{}, false
+	}
+
+	response := mcpResponse{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		response.Error = &mcpError{mcpInvalidRequest, "invalid JSON-RPC 2.0 request"}
+		return response, true
+	}
+	response.Result, response.Error = handleMCPRequest(req.Method, req.Params)
+	return response, true
+}
+
+// handleMCPRequest dispatches an MCP request to its handler
+func handleMCPRequest(method string, params json.RawMessage) (any, *mcpError) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(params, &p)
+		version := mcpProtocolVersions[0]
+		if contains(mcpProtocolVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities": map[string]any{
+				"tools":     map[string]any{},
+				"resources": map[string]any{},
+			},
+			"serverInfo": map[string]any{"name": "skukozh", "version": buildVersion()},
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "tools/list":
+		return map[string]any{"tools": mcpToolList()}, nil
+
+	case "tools/call":
+		var p struct {
+			Name      string                     `json:"name"`
+			Arguments map[string]json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &mcpError{mcpInvalidParams, err.Error()}
+		}
+		if _, ok := mcpToolCommands[p.Name]; !ok {
+			return nil, &mcpError{mcpInvalidParams, fmt.Sprintf("unknown tool %q", p.Name)}
+		}
+		text, err := callMCPTool(p.Name, p.Arguments)
+		if err != nil {
+			return map[string]any{"content": []mcpContent{{"text", err.Error()}}, "isError": true}, nil
+		}
+		return map[string]any{"content": []mcpContent{{"text", text}}}, nil
+
+	case "resources/list":
+		resources := []map[string]any{}
+		for _, resource := range mcpResources {
+			if _, err := resource.read(); err == nil {
+				resources = append(resources, map[string]any{
+					"uri":         resource.uri,
+					"name":        resource.name,
+					"description": resource.description,
+					"mimeType":    "text/plain",
+				})
+			}
+		}
+		return map[string]any{"resources": resources}, nil
+
+	case "resources/read":
+		var p struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &mcpError{mcpInvalidParams, err.Error()}
+		}
+		for _, resource := range mcpResources {
+			if resource.uri != p.URI {
+				continue
+			}
+			content, err := resource.read()
+			if err != nil {
+				return nil, &mcpError{mcpInvalidParams, fmt.Sprintf("resource %s is not available: %v", p.URI, err)}
+			}
+			return map[string]any{"contents": []map[string]any{{
+				"uri":      resource.uri,
+				"mimeType": "text/plain",
+				"text":     string(content),
+			}}}, nil
+		}
+		return nil, &mcpError{mcpInvalidParams, fmt.Sprintf("unknown resource %q", p.URI)}
+
+	default:
+		return nil, &mcpError{mcpMethodNotFound, fmt.Sprintf("method %q not found", method)}
+	}
+}
+
+// mcpToolList describes the tools with input schemas built from the flags
+// of their commands
+func mcpToolList() []map[string]any {
+	defaults := DefaultFlags()
+
+	names := make([]string, 0, len(mcpToolCommands))
+	for name := range mcpToolCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tools []map[string]any
+	for _, name := range names {
+		command := mcpToolCommands[name]
+		properties := map[string]any{}
+		var required []string
+		if commandArgs[command] != "" {
+			properties["directory"] = map[string]any{"type": "string", "description": "Directory to work on"}
+			if !strings.HasPrefix(commandArgs[command], "[") {
+				required = append(required, "directory")
+			}
+		}
+
+		for _, flagName := range commandFlags[command] {
+			if mcpExcludedFlags[flagName] {
+				continue
+			}
+			f := defaults.Lookup(flagName)
+			properties[flagName] = map[string]any{"type": jsonSchemaType(f), "description": f.Usage}
+		}
+
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		tools = append(tools, map[string]any{
+			"name":        name,
+			"description": mcpToolDescriptions[name],
+			"inputSchema": schema,
+		})
+	}
+	return tools
+}
+
+// jsonSchemaType returns the JSON schema type of a flag's value
+func jsonSchemaType(f *flag.Flag) string {
+	if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+		return "boolean"
+	}
+	if getter, ok := f.Value.(flag.Getter); ok {
+		if _, isInt := getter.Get().(int); isInt {
+			return "integer"
+		}
+	}
+	return "string"
+}
+
+// mcpToolFlags turns tool arguments into a FlagSet, accepting only the flags
+// of the tool's command
+func mcpToolFlags(command string, arguments map[string]json.RawMessage) (*flag.FlagSet, string, error) {
+	fs := DefaultFlags()
+	directory := ""
+	for name, raw := range arguments {
+		// Numbers keep their text, so 1000000 doesn't become 1e+06
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, "", fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+		if name == "directory" {
+			directory = fmt.Sprint(value)
+			continue
+		}
+		if !contains(commandFlags[command], name) || mcpExcludedFlags[name] {
+			return nil, "", fmt.Errorf("unknown argument %q", name)
+		}
+		if err := fs.Set(name, fmt.Sprint(value)); err != nil {
+			return nil, "", fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+	}
+	if commandArgs[command] != "" && !strings.HasPrefix(commandArgs[command], "[") && directory == "" {
+		return nil, "", fmt.Errorf("missing required argument \"directory\"")
+	}
+	return fs, directory, nil
+}
+
+// callMCPTool runs a tool and returns its text output
+func callMCPTool(name string, arguments map[string]json.RawMessage) (string, error) {
+	command := mcpToolCommands[name]
+	fs, directory, err := mcpToolFlags(command, arguments)
+	if err != nil {
+		return "", err
+	}
+
+	switch command {
+	case "find":
+		defer applyFindFlags(fs)()
+		unlock, err := lockOutput(fileListName)
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+
+		if err := applyProfileFlags(fs); err != nil {
+			return "", err
+		}
+		opts, err := findOptionsFromFlags(fs)
+		if err != nil {
+			return "", err
+		}
+		include, exclude := extFiltersFromFlags(fs)
+		opts.excludedExts = exclude
+		files, err := findFilesWithOptions(directory, include, opts)
+		if err != nil {
+			return "", err
+		}
+		if err := writeFileList(directory, files, nil, "text", listFilters(fs, "find", nil)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Found %d files. File list saved to %s\n%s", len(files), fileListName, strings.Join(files, "\n")), nil
+
+	case "gen":
+		// As with the gen command, the file list's root is the default
+		if directory == "" {
+			if directory, err = fileListRoot(); err != nil {
+				return "", err
+			}
+		}
+		opts, err := checkedGenOptions(fs)
+		if err != nil {
+			return "", err
+		}
+		unlock, err := lockOutput(resultFileName(opts.format))
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+		result, err := generateContentFileInternal(directory, opts)
+		if err != nil {
+			return "", err
+		}
+		data, err := resultData(result, opts.format)
+		if err != nil {
+			return "", err
+		}
+		if err := writeFileAtomic(resultFileName(opts.format), data, 0644); err != nil {
+			return "", err
+		}
+		if opts.format == "sqlite" {
+			return fmt.Sprintf("Database saved to %s", resultFileName("sqlite")), nil
+		}
+		return result, nil
+
+	default:
+		opts := analyzeOptionsFromFlags(fs)
+		if opts.list {
+			if directory == "" {
+				directory = "."
+				if root, err := fileListRoot(); err == nil {
+					directory = root
+				}
+			}
+			return analyzeFileListInternal(directory, opts)
+		}
+		return analyzeResultFileInternal(opts)
+	}
+}
+
+// buildVersion returns the module version the binary was built from
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// runMCPServer serves MCP over stdin and stdout. Everything the commands
+// print goes to stderr, so stdout only carries protocol messages.
+func runMCPServer() int {
+	protocolOut := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = protocolOut }()
+
+	if err := serveMCP(os.Stdin, protocolOut); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving MCP: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runMCPSession sends the requests to the MCP server and returns the decoded
+// responses
+func runMCPSession(t *testing.T, requests ...string) []map[string]any {
+	t.Helper()
+	var out strings.Builder
+	require.NoError(t, serveMCP(strings.NewReader(strings.Join(requests, "\n")+"\n"), &out))
+
+	var responses []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var response map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &response))
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+func mcpToolText(t *testing.T, response map[string]any) (string, bool) {
+	t.Helper()
+	result := response["result"].(map[string]any)
+	content := result["content"].([]any)
+	require.Len(t, content, 1)
+	isError, _ := result["isError"].(bool)
+	return content[0].(map[string]any)["text"].(string), isError
+}
+
+func TestMCPProtocol(t *testing.T) {
+	responses := runMCPSession(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":"three","method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"nope"}`,
+		`not json`,
+	)
+	require.Len(t, responses, 5)
+
+	initResult := responses[0]["result"].(map[string]any)
+	assert.Equal(t, "2024-11-05", initResult["protocolVersion"])
+	assert.Equal(t, "skukozh", initResult["serverInfo"].(map[string]any)["name"])
+	assert.Contains(t, initResult["capabilities"], "tools")
+	assert.Contains(t, initResult["capabilities"], "resources")
+
+	assert.Equal(t, float64(2), responses[1]["id"])
+	assert.Equal(t, map[string]any{}, responses[1]["result"])
+
+	assert.Equal(t, "three", responses[2]["id"])
+	tools := responses[2]["result"].(map[string]any)["tools"].([]any)
+	require.Len(t, tools, 3)
+	byName := map[string]map[string]any{}
+	for _, tool := range tools {
+		byName[tool.(map[string]any)["name"].(string)] = tool.(map[string]any)
+	}
+	findSchema := byName["find"]["inputSchema"].(map[string]any)
+	assert.Equal(t, []any{"directory"}, findSchema["required"])
+	findProps := findSchema["properties"].(map[string]any)
+	assert.Equal(t, "string", findProps["ext"].(map[string]any)["type"])
+	assert.Equal(t, "boolean", findProps["hidden"].(map[string]any)["type"])
+	assert.Equal(t, "integer", findProps["max-depth"].(map[string]any)["type"])
+	genProps := byName["generate"]["inputSchema"].(map[string]any)["properties"].(map[string]any)
+	assert.NotContains(t, genProps, "review")
+	assert.NotContains(t, genProps, "compress")
+	assert.NotContains(t, byName["analyze"]["inputSchema"], "required")
+
+	assert.Equal(t, float64(mcpMethodNotFound), responses[3]["error"].(map[string]any)["code"])
+	assert.Equal(t, float64(mcpParseError), responses[4]["error"].(map[string]any)["code"])
+}
+
+func TestMCPTools(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":   "package main\n",
+		"util.go":   "package main\n\nfunc util() {}\n",
+		"README.md": "# Test\n",
+	})
+	defer os.Remove(fileListName)
+	defer os.Remove(resultName)
+
+	dir, err := json.Marshal(testDir)
+	require.NoError(t, err)
+
+	responses := runMCPSession(t,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"find","arguments":{"directory":`+string(dir)+`,"ext":"go"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"generate","arguments":{"directory":`+string(dir)+`,"order":"alpha"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"analyze","arguments":{"count":1}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"resources/read","params":{"uri":"skukozh://file-list"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"find","arguments":{"ext":"go"}}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"find","arguments":{"directory":".","count":3}}}`,
+		`{"jsonrpc":"2.0","id":8,"method":"tools/call","params":{"name":"generate","arguments":{"directory":".","order":"random"}}}`,
+		`{"jsonrpc":"2.0","id":9,"method":"tools/call","params":{"name":"deploy"}}`,
+	)
+	require.Len(t, responses, 9)
+
+	text, isError := mcpToolText(t, responses[0])
+	assert.False(t, isError)
+	assert.Contains(t, text, "Found 2 files.")
+	assert.Equal(t, fileListText(t, testDir, "-ext=go", "main.go", "util.go"), ReadTestFile(t, fileListName))
+
+	text, isError = mcpToolText(t, responses[1])
+	assert.False(t, isError)
+	assert.Contains(t, text, "func util() {}")
+	assert.NotContains(t, text, "# Test")
+	assert.Equal(t, text, ReadTestFile(t, resultName))
+
+	text, isError = mcpToolText(t, responses[2])
+	assert.False(t, isError)
+	assert.Contains(t, text, "util.go")
+
+	resources := responses[3]["result"].(map[string]any)["resources"].([]any)
+	assert.Len(t, resources, 2)
+
+	contents := responses[4]["result"].(map[string]any)["contents"].([]any)
+	assert.Equal(t, fileListText(t, testDir, "-ext=go", "main.go", "util.go"), contents[0].(map[string]any)["text"])
+
+	for i, want := range []string{`missing required argument "directory"`, `unknown argument "count"`, `unknown order "random"`} {
+		text, isError = mcpToolText(t, responses[5+i])
+		assert.True(t, isError)
+		assert.Contains(t, text, want)
+	}
+
+	assert.Equal(t, float64(mcpInvalidParams), responses[8]["error"].(map[string]any)["code"])
+}
+
+func TestMCPToolsDefaultToListRoot(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"a.go": "package a\n\nfunc A() {}\n",
+	})
+	defer os.Remove(fileListName)
+	defer os.Remove(resultName)
+
+	dir, err := json.Marshal(testDir)
+	require.NoError(t, err)
+	responses := runMCPSession(t,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"find","arguments":{"directory":`+string(dir)+`}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"generate","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"analyze","arguments":{"list":true}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"generate","arguments":{"collapse-imports":"cobol"}}}`,
+	)
+	require.Len(t, responses, 4)
+
+	text, isError := mcpToolText(t, responses[1])
+	assert.False(t, isError)
+	assert.Contains(t, text, "#FILE a.go")
+	assert.Contains(t, text, "func A() {}")
+
+	text, isError = mcpToolText(t, responses[2])
+	assert.False(t, isError)
+	assert.Contains(t, text, "a.go")
+
+	text, isError = mcpToolText(t, responses[3])
+	assert.True(t, isError, "generate checks its options as gen does")
+	assert.Contains(t, text, `unknown -collapse-imports language "cobol"`)
+
+	// Without a directory or a root in the file list, generate reports it
+	require.NoError(t, os.WriteFile(fileListName, []byte("a.go\n"), 0644))
+	responses = runMCPSession(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"generate","arguments":{}}}`)
+	text, isError = mcpToolText(t, responses[0])
+	assert.True(t, isError)
+	assert.Contains(t, text, "records no root directory")
+}
+
+func TestMCPToolFlagsKeepNumbers(t *testing.T) {
+	fs, directory, err := mcpToolFlags("gen", map[string]json.RawMessage{
+		"directory":       json.RawMessage(`"."`),
+		"max-file-tokens": json.RawMessage(`1000000`),
+		"preview-lines":   json.RawMessage(`5`),
+		"toc":             json.RawMessage(`true`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ".", directory)
+	assert.Equal(t, "1000000", fs.Lookup("max-file-tokens").Value.String())
+	assert.Equal(t, "5", fs.Lookup("preview-lines").Value.String())
+	assert.Equal(t, "true", fs.Lookup("toc").Value.String())
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Start of the metadata header line written by gen -meta
+const metaPrefix = "#SKUKOZH "
+
+// metaField is a key=value pair of the metadata header
+type metaField struct {
+	key, value string
+}
+
+// Labels analyze shows for the fields of the metadata header
+var metaLabels = map[string]string{
+	"version":      "Generated by",
+	"generated_at": "Generated at",
+	"root":         "Root",
+	"exts":         "Extensions",
+	"files":        "Files",
+	"flags":        "Gen flags",
+}
+
+// genFlagArgs returns the gen flags that differ from their defaults as
+// command line arguments, e.g. "-toc -max-file-tokens=2000"
+func genFlagArgs(fs *flag.FlagSet) string {
+	return commandFlagArgs(fs, "gen")
+}
+
+// commandFlagArgs returns the flags of command that differ from their
+// defaults as command line arguments, leaving out the flags named in except
+func commandFlagArgs(fs *flag.FlagSet, command string, except ...string) string {
+	var args []string
+	for _, name := range commandFlags[command] {
+		if contains(except, name) {
+			continue
+		}
+		f := fs.Lookup(name)
+		value := f.Value.String()
+		if value == f.DefValue {
+			continue
+		}
+		switch {
+		case value == "true":
+			args = append(args, "-"+name)
+		case value == "" || strings.ContainsAny(value, " \t\"'\\"):
+			args = append(args, "-"+name+"="+strconv.Quote(value))
+		default:
+			args = append(args, "-"+name+"="+value)
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// metaHeader renders the metadata header for a bundle of the given files
+func metaHeader(baseDir string, files []string, opts genOptions) string {
+	root := baseDir
+	if absDir, err := filepath.Abs(baseDir); err == nil {
+		root = absDir
+	}
+	if opts.deterministic {
+		// Checkouts in different places produce the same header
+		root = filepath.Base(root)
+	}
+
+	seen := make(map[string]bool)
+	var exts []string
+	for _, file := range files {
+		if ext := strings.TrimPrefix(path.Ext(file), "."); ext != "" && !seen[ext] {
+			seen[ext] = true
+			exts = append(exts, ext)
+		}
+	}
+	sort.Strings(exts)
+
+	fields := []metaField{
+		{"version", buildVersion()},
+		{"root", filepath.ToSlash(root)},
+		{"exts", strings.Join(exts, ",")},
+	}
+	if !opts.deterministic {
+		fields = append(fields, metaField{"generated_at", time.Now().UTC().Format(time.RFC3339)})
+	}
+	fields = append(fields, metaField{"files", strconv.Itoa(len(files))})
+	if opts.metaFlags != "" {
+		fields = append(fields, metaField{"flags", opts.metaFlags})
+	}
+	return renderMetaHeader(fields)
+}
+
+// renderMetaHeader formats the header line, quoting values that are empty
+// or contain spaces or quotes
+func renderMetaHeader(fields []metaField) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(metaPrefix))
+	for _, field := range fields {
+		value := field.value
+		if value == "" || strings.ContainsAny(value, " \t\"\\") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", field.key, value)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// parseMetaHeader returns the fields of the metadata header on the first
+// line of a bundle, or nil when it has none
+func parseMetaHeader(content string) []metaField {
+	line, _, _ := strings.Cut(content, "\n")
+	rest, ok := strings.CutPrefix(line, metaPrefix)
+	if !ok {
+		return nil
+	}
+
+	var fields []metaField
+	for rest = strings.TrimLeft(rest, " "); rest != ""; rest = strings.TrimLeft(rest, " ") {
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(after, `"`) {
+			quoted, err := strconv.QuotedPrefix(after)
+			if err != nil {
+				break
+			}
+			value, _ = strconv.Unquote(quoted)
+			rest = after[len(quoted):]
+		} else {
+			value, rest, _ = strings.Cut(after, " ")
+		}
+		fields = append(fields, metaField{key, value})
+	}
+	return fields
+}
+
+// writeMetaHeader prints the fields of a bundle's metadata header
+func writeMetaHeader(out io.Writer, fields []metaField) {
+	for _, field := range fields {
+		label := metaLabels[field.key]
+		if label == "" {
+			label = field.key
+		}
+		value := field.value
+		if field.key == "version" {
+			value = "skukozh " + value
+		}
+		fmt.Fprintf(out, "%s: %s\n", label, value)
+	}
+}
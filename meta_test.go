@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaHeaderRoundTrip(t *testing.T) {
+	fields := []metaField{
+		{"version", "v1.4.0"},
+		{"root", "/home/me/my project"},
+		{"exts", ""},
+		{"files", "3"},
+		{"flags", `-toc -prompt="Review \"this\""`},
+	}
+	header := renderMetaHeader(fields)
+	assert.Equal(t, `#SKUKOZH version=v1.4.0 root="/home/me/my project" exts="" files=3 flags="-toc -prompt=\"Review \\\"this\\\"\""`+"\n", header)
+	assert.Equal(t, fields, parseMetaHeader(header+"#FILE main.go\n"))
+
+	assert.Nil(t, parseMetaHeader("#FILE main.go\n#SKUKOZH version=v1\n"), "only the first line holds the header")
+}
+
+func TestGenFlagArgs(t *testing.T) {
+	flagSet := flagSetWith(t, "-meta", "-toc", "-max-file-tokens", "2000", "-prompt", "Find bugs", "-ext", "go")
+	assert.Equal(t, `-max-file-tokens=2000 -toc -meta -prompt="Find bugs"`, genFlagArgs(flagSet), "only changed gen flags, in gen flag order")
+}
+
+func TestGenerateWithMeta(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	var exitCode int
+	CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "find", ".")
+	})
+	require.Equal(t, 0, exitCode)
+	CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "gen", "-meta", "-checksum", "-prompt", "Review", ".")
+	})
+	require.Equal(t, 0, exitCode)
+
+	result := ReadTestFile(t, filepath.Join(testDir, resultName))
+	require.True(t, strings.HasPrefix(result, "#SKUKOZH "), "the header is the first line")
+	fields := make(map[string]string)
+	for _, field := range parseMetaHeader(result) {
+		fields[field.key] = field.value
+	}
+	dir, err := filepath.EvalSymlinks(testDir)
+	require.NoError(t, err)
+	assert.Equal(t, buildVersion(), fields["version"])
+	assert.Equal(t, filepath.ToSlash(dir), fields["root"])
+	assert.Equal(t, "go,js,php,txt", fields["exts"])
+	assert.Equal(t, "6", fields["files"])
+	assert.Equal(t, "-meta -checksum -prompt=Review", fields["flags"])
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ$`, fields["generated_at"])
+	assert.Contains(t, result, "\nReview\n\n#FILE ", "the prompt follows the header")
+
+	// The checksum covers the header, and analyze shows it
+	CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "verify", ".")
+	})
+	assert.Equal(t, 0, exitCode)
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "analyze")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "Generated by: skukozh "+buildVersion()+"\nRoot: "+filepath.ToSlash(dir)+"\nExtensions: go,js,php,txt\n")
+	assert.Contains(t, output, "Files: 6\nGen flags: -meta -checksum -prompt=Review\n\nTop 20 largest files:")
+}
+
+func TestMetaRejectedForOtherFormats(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(fileListName, []byte("file1.go\n"), 0644))
+	defer os.Remove(fileListName)
+
+	_, err := generateContentFileInternal(testDir, genOptions{meta: true, format: "jsonl"})
+	assert.EqualError(t, err, "-format jsonl can't be combined with -meta")
+}
@@ -0,0 +1,594 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"skukozh/bundle"
+)
+
+// Modes of -squash-migrations: the schema replayed from the migrations, or
+// one line per migration with its statements
+var squashModes = []string{"schema", "summary"}
+
+// Directories with fewer numbered SQL files aren't taken for migration
+// directories
+const migrationDirMin = 3
+
+// Number of words of a statement shown by -squash-migrations summary
+const migrationHeadWords = 8
+
+// migrationVersion matches the version prefix of a migration file or
+// directory name: 0001_init.sql, 20240101120000_users.up.sql, V1_2__init.sql
+// (Flyway), 003-seed.sql or 20240101120000_init/migration.sql (Prisma)
+var migrationVersion = regexp.MustCompile(`^[Vv]?(\d+(?:[._]\d+)*)(?:__|[_.\-])`)
+
+// downMigration matches the names of rollback migrations
+var downMigration = regexp.MustCompile(`(?i)(^|[._\-])down\.sql$`)
+
+// Markers starting the rollback part of goose and dbmate migrations
+var downMarkers = []string{"-- +goose Down", "-- migrate:down"}
+
+// migration is a numbered SQL file of a migration directory
+type migration struct {
+	file    string // path relative to the base directory
+	name    string // name shown for the migration, relative to its directory
+	version []int
+	down    bool // rollback migration, left out of the schema and summary
+}
+
+// migrationDir is a directory of sequential SQL migrations, in order
+type migrationDir struct {
+	dir        string
+	migrations []migration
+}
+
+// parseSquashMode validates a -squash-migrations value
+func parseSquashMode(value string) (string, error) {
+	if value != "" && !contains(squashModes, value) {
+		return "", fmt.Errorf("unknown -squash-migrations mode %q (use %s)", value, strings.Join(squashModes, " or "))
+	}
+	return value, nil
+}
+
+// parseMigration recognizes a migration by its versioned file name, or by
+// the versioned directory holding it as with Prisma, returning it with the
+// directory of the migrations
+func parseMigration(file string) (migration, string, bool) {
+	if strings.ToLower(path.Ext(file)) != ".sql" {
+		return migration{}, "", false
+	}
+	dir, base := path.Split(file)
+	dir = strings.TrimSuffix(dir, "/")
+	name := base
+	match := migrationVersion.FindStringSubmatch(base)
+	if match == nil && dir != "" {
+		parent, versioned := path.Split(dir)
+		if match = migrationVersion.FindStringSubmatch(versioned); match != nil {
+			dir, name = strings.TrimSuffix(parent, "/"), versioned+"/"+base
+		}
+	}
+	if match == nil {
+		return migration{}, "", false
+	}
+
+	var version []int
+	for _, part := range strings.FieldsFunc(match[1], func(r rune) bool { return r == '.' || r == '_' }) {
+		n, _ := strconv.Atoi(part)
+		version = append(version, n)
+	}
+	return migration{file: file, name: name, version: version, down: downMigration.MatchString(base)}, dir, true
+}
+
+// compareVersions orders migration versions numerically, part by part, so
+// V10 follows V9
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return len(a) - len(b)
+}
+
+// splitMigrations takes the files of sequential migration directories, with
+// at least migrationDirMin migrations, out of the file list. Entries with
+// line ranges or symbols stay in the list.
+func splitMigrations(files []string) ([]string, []migrationDir) {
+	byDir := make(map[string][]migration)
+	var dirs []string
+	for _, file := range files {
+		if entryPath(file) != file {
+			continue
+		}
+		if m, dir, ok := parseMigration(file); ok {
+			if byDir[dir] == nil {
+				dirs = append(dirs, dir)
+			}
+			byDir[dir] = append(byDir[dir], m)
+		}
+	}
+
+	squashed := make(map[string]bool)
+	var groups []migrationDir
+	for _, dir := range dirs {
+		migrations := byDir[dir]
+		if len(migrations) < migrationDirMin {
+			continue
+		}
+		sort.SliceStable(migrations, func(i, j int) bool {
+			if c := compareVersions(migrations[i].version, migrations[j].version); c != 0 {
+				return c < 0
+			}
+			return migrations[i].name < migrations[j].name
+		})
+		for _, m := range migrations {
+			squashed[m.file] = true
+		}
+		groups = append(groups, migrationDir{dir: dir, migrations: migrations})
+	}
+
+	var kept []string
+	for _, file := range files {
+		if !squashed[file] {
+			kept = append(kept, file)
+		}
+	}
+	return kept, groups
+}
+
+// upSQL returns the part of a migration applying it, without the rollback
+// part of goose and dbmate migrations
+func upSQL(content string) string {
+	for _, marker := range downMarkers {
+		if i := strings.Index(content, marker); i >= 0 {
+			content = content[:i]
+		}
+	}
+	return content
+}
+
+// dollarQuote matches the opening tag of a PostgreSQL dollar-quoted string
+var dollarQuote = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+// splitSQL splits SQL into statements, without comments and with their
+// whitespace collapsed, leaving quoted strings and dollar-quoted function
+// bodies whole
+func splitSQL(content string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if statement := strings.Join(strings.Fields(current.String()), " "); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(content[i+1:], c)
+			if end < 0 {
+				end = len(content) - i - 2
+			}
+			current.WriteString(content[i : i+end+2])
+			i += end + 1
+		case c == '$' && dollarQuote.MatchString(content[i:]):
+			tag := dollarQuote.FindString(content[i:])
+			end := strings.Index(content[i+len(tag):], tag)
+			if end < 0 {
+				end = len(content) - i - 2*len(tag)
+			}
+			current.WriteString(content[i : i+end+2*len(tag)])
+			i += end + 2*len(tag) - 1
+		case strings.HasPrefix(content[i:], "--") || c == '#' && (i == 0 || content[i-1] == '\n'):
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			current.WriteByte('\n')
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = len(content)
+				continue
+			}
+			i += end + 3
+			current.WriteByte(' ')
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// splitTopLevel splits s at the commas outside parentheses and quotes
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// identKey returns the key of a possibly quoted SQL identifier
+func identKey(name string) string {
+	return strings.ToLower(strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "").Replace(name))
+}
+
+// sqlColumn is a column of a replayed table: its name and its type and
+// constraints as declared
+type sqlColumn struct {
+	name       string
+	definition string
+}
+
+// sqlTable is a table of the replayed schema
+type sqlTable struct {
+	name        string
+	columns     []sqlColumn
+	constraints []string
+	options     string // table options after the column list, e.g. ENGINE=InnoDB
+}
+
+// column returns the index of a column of the table, or -1
+func (t *sqlTable) column(name string) int {
+	for i, c := range t.columns {
+		if identKey(c.name) == identKey(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// addElement adds a column or a table constraint of a CREATE TABLE or
+// ALTER TABLE ADD
+func (t *sqlTable) addElement(element string) {
+	fields := strings.Fields(element)
+	if len(fields) == 0 {
+		return
+	}
+	switch strings.ToLower(fields[0]) {
+	case "constraint", "primary", "unique", "foreign", "check", "key", "index", "exclude":
+		t.constraints = append(t.constraints, element)
+	default:
+		name, definition, _ := strings.Cut(element, " ")
+		if i := t.column(name); i >= 0 {
+			t.columns[i].definition = definition
+		} else {
+			t.columns = append(t.columns, sqlColumn{name, definition})
+		}
+	}
+}
+
+// sqlObject is an index, view, type, function or other object of the
+// replayed schema, kept as its last defining statement
+type sqlObject struct {
+	key       string // kind and name
+	table     string // key of the table of an index, dropped with it
+	statement string
+}
+
+// sqlSchema is the schema built by replaying the DDL of migrations
+type sqlSchema struct {
+	tables  []*sqlTable
+	objects []sqlObject
+	skipped int // statements that don't change the schema, such as INSERT
+}
+
+var (
+	createTableStmt  = regexp.MustCompile(`(?is)^create\s+(?:(?:global\s+|local\s+)?(?:temporary|temp)\s+)?(?:unlogged\s+)?table\s+(?:if\s+not\s+exists\s+)?(\S+?)\s*\((.*)\)\s*(.*)$`)
+	alterTableStmt   = regexp.MustCompile(`(?is)^alter\s+table\s+(?:if\s+exists\s+)?(?:only\s+)?(\S+)\s+(.*)$`)
+	dropTableStmt    = regexp.MustCompile(`(?is)^drop\s+table\s+(?:if\s+exists\s+)?(.+?)(?:\s+(?:cascade|restrict))?$`)
+	renameTableStmt  = regexp.MustCompile(`(?is)^rename\s+table\s+(\S+)\s+to\s+(\S+)$`)
+	createIndexStmt  = regexp.MustCompile(`(?is)^create\s+(?:unique\s+)?index\s+(?:concurrently\s+)?(?:if\s+not\s+exists\s+)?(\S+)\s+on\s+(?:only\s+)?([^\s(]+)`)
+	createObjectStmt = regexp.MustCompile(`(?is)^create\s+(?:or\s+replace\s+)?(materialized\s+view|view|type|function|procedure|trigger|sequence|extension|schema|domain)\s+(?:if\s+not\s+exists\s+)?([^\s(]+)`)
+	dropObjectStmt   = regexp.MustCompile(`(?is)^drop\s+(materialized\s+view|view|type|function|procedure|trigger|sequence|extension|schema|domain|index)\s+(?:concurrently\s+)?(?:if\s+exists\s+)?([^\s(,]+)`)
+
+	addConstraint = regexp.MustCompile(`(?is)^add\s+((?:constraint|primary|unique|foreign|check|exclude)\b.*)$`)
+	addColumn     = regexp.MustCompile(`(?is)^add\s+(?:column\s+)?(?:if\s+not\s+exists\s+)?(.+)$`)
+	dropConstr    = regexp.MustCompile(`(?is)^drop\s+constraint\s+(?:if\s+exists\s+)?(\S+)`)
+	dropColumn    = regexp.MustCompile(`(?is)^drop\s+(?:column\s+)?(?:if\s+exists\s+)?(\S+)`)
+	renameColumn  = regexp.MustCompile(`(?is)^rename\s+(?:column\s+)?(\S+)\s+to\s+(\S+)$`)
+	renameTo      = regexp.MustCompile(`(?is)^rename\s+to\s+(\S+)$`)
+	alterColumn   = regexp.MustCompile(`(?is)^alter\s+(?:column\s+)?(\S+)\s+(?:set\s+data\s+)?type\s+(.+)$`)
+	modifyColumn  = regexp.MustCompile(`(?is)^modify\s+(?:column\s+)?(.+)$`)
+	changeColumn  = regexp.MustCompile(`(?is)^change\s+(?:column\s+)?(\S+)\s+(.+)$`)
+	usingClause   = regexp.MustCompile(`(?is)\s+using\s+.*$`)
+)
+
+// objectKind normalizes the kind of a CREATE or DROP statement
+func objectKind(kind string) string {
+	return strings.ToLower(strings.Join(strings.Fields(kind), " "))
+}
+
+// table returns the table with the given name, or nil
+func (s *sqlSchema) table(name string) *sqlTable {
+	for _, t := range s.tables {
+		if identKey(t.name) == identKey(name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// dropObject removes the object with the given key
+func (s *sqlSchema) dropObject(key string) {
+	var kept []sqlObject
+	for _, o := range s.objects {
+		if o.key != key {
+			kept = append(kept, o)
+		}
+	}
+	s.objects = kept
+}
+
+// setObject adds an object, replacing an earlier definition in place
+func (s *sqlSchema) setObject(o sqlObject) {
+	for i := range s.objects {
+		if s.objects[i].key == o.key {
+			s.objects[i] = o
+			return
+		}
+	}
+	s.objects = append(s.objects, o)
+}
+
+// apply replays a statement of a migration. Statements that don't define
+// tables or other objects are counted as skipped.
+func (s *sqlSchema) apply(statement string) {
+	if m := createTableStmt.FindStringSubmatch(statement); m != nil {
+		t := &sqlTable{name: m[1], options: m[3]}
+		for _, element := range splitTopLevel(m[2]) {
+			t.addElement(element)
+		}
+		if old := s.table(m[1]); old != nil {
+			*old = *t
+		} else {
+			s.tables = append(s.tables, t)
+		}
+		return
+	}
+	if m := alterTableStmt.FindStringSubmatch(statement); m != nil {
+		if t := s.table(m[1]); t != nil {
+			for _, action := range splitTopLevel(m[2]) {
+				s.alter(t, action)
+			}
+			return
+		}
+	}
+	if m := dropTableStmt.FindStringSubmatch(statement); m != nil {
+		for _, name := range splitTopLevel(m[1]) {
+			s.dropTable(name)
+		}
+		return
+	}
+	if m := renameTableStmt.FindStringSubmatch(statement); m != nil {
+		s.renameTable(m[1], m[2])
+		return
+	}
+	if m := createIndexStmt.FindStringSubmatch(statement); m != nil {
+		s.setObject(sqlObject{key: "index " + identKey(m[1]), table: identKey(m[2]), statement: statement})
+		return
+	}
+	if m := createObjectStmt.FindStringSubmatch(statement); m != nil {
+		s.setObject(sqlObject{key: objectKind(m[1]) + " " + identKey(m[2]), statement: statement})
+		return
+	}
+	if m := dropObjectStmt.FindStringSubmatch(statement); m != nil {
+		s.dropObject(objectKind(m[1]) + " " + identKey(m[2]))
+		return
+	}
+	s.skipped++
+}
+
+// alter applies an action of an ALTER TABLE statement to a table
+func (s *sqlSchema) alter(t *sqlTable, action string) {
+	switch {
+	case addConstraint.MatchString(action):
+		t.constraints = append(t.constraints, addConstraint.FindStringSubmatch(action)[1])
+	case addColumn.MatchString(action):
+		t.addElement(addColumn.FindStringSubmatch(action)[1])
+	case dropConstr.MatchString(action):
+		name := identKey(dropConstr.FindStringSubmatch(action)[1])
+		var kept []string
+		for _, c := range t.constraints {
+			if fields := strings.Fields(c); len(fields) < 2 || !strings.EqualFold(fields[0], "constraint") || identKey(fields[1]) != name {
+				kept = append(kept, c)
+			}
+		}
+		t.constraints = kept
+	case dropColumn.MatchString(action):
+		if i := t.column(dropColumn.FindStringSubmatch(action)[1]); i >= 0 {
+			t.columns = append(t.columns[:i], t.columns[i+1:]...)
+		}
+	case renameTo.MatchString(action):
+		s.renameTable(t.name, renameTo.FindStringSubmatch(action)[1])
+	case renameColumn.MatchString(action):
+		m := renameColumn.FindStringSubmatch(action)
+		if i := t.column(m[1]); i >= 0 {
+			t.columns[i].name = m[2]
+		}
+	case alterColumn.MatchString(action):
+		m := alterColumn.FindStringSubmatch(action)
+		if i := t.column(m[1]); i >= 0 {
+			t.columns[i].definition = usingClause.ReplaceAllString(m[2], "")
+		}
+	case modifyColumn.MatchString(action):
+		t.addElement(modifyColumn.FindStringSubmatch(action)[1])
+	case changeColumn.MatchString(action):
+		m := changeColumn.FindStringSubmatch(action)
+		if i := t.column(m[1]); i >= 0 {
+			name, definition, _ := strings.Cut(m[2], " ")
+			t.columns[i] = sqlColumn{name, definition}
+		}
+	default:
+		// SET DEFAULT, SET NOT NULL and other changes keep the declared definition
+		s.skipped++
+	}
+}
+
+// dropTable removes a table and its indexes
+func (s *sqlSchema) dropTable(name string) {
+	var tables []*sqlTable
+	for _, t := range s.tables {
+		if identKey(t.name) != identKey(name) {
+			tables = append(tables, t)
+		}
+	}
+	s.tables = tables
+	var objects []sqlObject
+	for _, o := range s.objects {
+		if o.table != identKey(name) {
+			objects = append(objects, o)
+		}
+	}
+	s.objects = objects
+}
+
+// renameTable renames a table, keeping its indexes with it
+func (s *sqlSchema) renameTable(from, to string) {
+	if t := s.table(from); t != nil {
+		t.name = to
+	}
+	for i := range s.objects {
+		if s.objects[i].table == identKey(from) {
+			s.objects[i].table = identKey(to)
+		}
+	}
+}
+
+// render writes the replayed schema as CREATE statements: the tables in the
+// order they were created, then the other objects
+func (s *sqlSchema) render() string {
+	var out strings.Builder
+	for _, t := range s.tables {
+		var elements []string
+		for _, c := range t.columns {
+			elements = append(elements, strings.TrimSpace(c.name+" "+c.definition))
+		}
+		elements = append(elements, t.constraints...)
+		fmt.Fprintf(&out, "CREATE TABLE %s (\n  %s\n)", t.name, strings.Join(elements, ",\n  "))
+		if t.options != "" {
+			out.WriteString(" " + t.options)
+		}
+		out.WriteString(";\n")
+	}
+	for _, o := range s.objects {
+		out.WriteString(o.statement + ";\n")
+	}
+	return out.String()
+}
+
+// statementHead shortens a statement to its first words, before any
+// parenthesis, for -squash-migrations summary
+func statementHead(statement string) string {
+	if i := strings.IndexByte(statement, '('); i > 0 {
+		statement = statement[:i]
+	}
+	words := strings.Fields(statement)
+	if len(words) > migrationHeadWords {
+		words = append(words[:migrationHeadWords], "...")
+	}
+	return strings.Join(words, " ")
+}
+
+// squashMigrations renders the content of the #MIGRATIONS section of a
+// migration directory in the given mode, reading the migrations with read
+func squashMigrations(group migrationDir, mode string, read func(file string) ([]byte, error)) (string, error) {
+	var ups []migration
+	for _, m := range group.migrations {
+		if !m.down {
+			ups = append(ups, m)
+		}
+	}
+	if len(ups) == 0 {
+		return "", nil
+	}
+
+	var out strings.Builder
+	schema := &sqlSchema{}
+	for _, m := range ups {
+		content, err := read(m.file)
+		if err != nil {
+			return "", err
+		}
+		statements := splitSQL(upSQL(string(content)))
+		if mode == "summary" {
+			heads := make([]string, len(statements))
+			for i, statement := range statements {
+				heads[i] = statementHead(statement)
+			}
+			fmt.Fprintf(&out, "-- %s: %s\n", m.name, strings.Join(heads, "; "))
+			continue
+		}
+		for _, statement := range statements {
+			schema.apply(statement)
+		}
+	}
+
+	span := fmt.Sprintf("%d migrations, %s to %s", len(ups), ups[0].name, ups[len(ups)-1].name)
+	if downs := len(group.migrations) - len(ups); downs > 0 {
+		span += fmt.Sprintf(", %d down migrations left out", downs)
+	}
+	if mode == "summary" {
+		return "-- Statements of " + span + "\n" + out.String(), nil
+	}
+	header := "-- Schema after " + span + ", replayed from their DDL"
+	if schema.skipped > 0 {
+		header += fmt.Sprintf("; other statements left out: %d", schema.skipped)
+	}
+	return header + "\n" + schema.render(), nil
+}
+
+// renderMigrations formats the #MIGRATIONS sections that stand in for the
+// squashed migration directories, placed after the file sections
+func renderMigrations(baseDir string, groups []migrationDir, mode string, reads fileReads) string {
+	var out strings.Builder
+	for _, group := range groups {
+		content, err := squashMigrations(group, mode, func(file string) ([]byte, error) {
+			return reads.read(filepath.Join(baseDir, file))
+		})
+		if err != nil {
+			fmt.Printf("Error squashing migrations in %s: %v\n", group.dir, err)
+			continue
+		}
+		if content == "" {
+			continue
+		}
+		content = strings.TrimRight(content, "\n")
+		fence := bundle.Fence(content)
+		dir := group.dir
+		if dir == "" {
+			dir = "."
+		}
+		fmt.Fprintf(&out, "#MIGRATIONS %s (%s)\n#START\n%ssql\n%s\n%s\n#END MIGRATIONS\n\n", dir, mode, fence, content, fence)
+	}
+	return out.String()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSquashMode(t *testing.T) {
+	mode, err := parseSquashMode("summary")
+	require.NoError(t, err)
+	assert.Equal(t, "summary", mode)
+
+	_, err = parseSquashMode("latest")
+	assert.EqualError(t, err, `unknown -squash-migrations mode "latest" (use schema or summary)`)
+}
+
+func TestParseMigration(t *testing.T) {
+	tests := []struct {
+		file    string
+		dir     string
+		name    string
+		version []int
+		down    bool
+		ok      bool
+	}{
+		{file: "db/migrations/0001_init.sql", dir: "db/migrations", name: "0001_init.sql", version: []int{1}, ok: true},
+		{file: "migrations/20240101120000_users.down.sql", dir: "migrations", name: "20240101120000_users.down.sql", version: []int{20240101120000}, down: true, ok: true},
+		{file: "sql/V1_2__init.sql", dir: "sql", name: "V1_2__init.sql", version: []int{1, 2}, ok: true},
+		{file: "prisma/migrations/20240101_init/migration.sql", dir: "prisma/migrations", name: "20240101_init/migration.sql", version: []int{20240101}, ok: true},
+		{file: "schema.sql"},
+		{file: "db/0001_init.rb"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			m, dir, ok := parseMigration(tc.file)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.dir, dir)
+				assert.Equal(t, tc.name, m.name)
+				assert.Equal(t, tc.version, m.version)
+				assert.Equal(t, tc.down, m.down)
+			}
+		})
+	}
+}
+
+func TestSplitMigrations(t *testing.T) {
+	files := []string{
+		"main.go",
+		"sql/V10__c.sql", "sql/V2__b.sql", "sql/V1__a.sql",
+		"seeds/001_a.sql", "seeds/002_b.sql",
+		"sql/V3__d.sql:1-5",
+	}
+	kept, groups := splitMigrations(files)
+	assert.Equal(t, []string{"main.go", "seeds/001_a.sql", "seeds/002_b.sql", "sql/V3__d.sql:1-5"}, kept)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "sql", groups[0].dir)
+	var names []string
+	for _, m := range groups[0].migrations {
+		names = append(names, m.name)
+	}
+	assert.Equal(t, []string{"V1__a.sql", "V2__b.sql", "V10__c.sql"}, names)
+}
+
+func TestSplitSQL(t *testing.T) {
+	content := `-- comment; not a statement
+CREATE TABLE t (a text DEFAULT 'x;y'); /* block; comment */
+CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN; END; $body$ LANGUAGE plpgsql;
+# MySQL comment
+INSERT INTO t VALUES ('--')`
+	assert.Equal(t, []string{
+		"CREATE TABLE t (a text DEFAULT 'x;y')",
+		"CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN; END; $body$ LANGUAGE plpgsql",
+		"INSERT INTO t VALUES ('--')",
+	}, splitSQL(content))
+}
+
+func TestSquashMigrationsSchema(t *testing.T) {
+	files := map[string]string{
+		"0001_users.sql": "-- +goose Up\nCREATE TABLE users (\n  id bigserial PRIMARY KEY,\n  email text NOT NULL,\n  name text\n);\nCREATE UNIQUE INDEX users_email_idx ON users (email);\n-- +goose Down\nDROP TABLE users;\n",
+		"0002_legacy.sql": "CREATE TABLE legacy (id int);\nCREATE INDEX legacy_id_idx ON legacy (id);\nINSERT INTO legacy VALUES (1);\n" +
+			"ALTER TABLE users ADD COLUMN age int, DROP COLUMN name;\n",
+		"0003_posts.sql": "DROP TABLE IF EXISTS legacy;\nCREATE TABLE posts (id bigserial PRIMARY KEY, title varchar(100), CONSTRAINT title_unique UNIQUE (title));\n" +
+			"ALTER TABLE posts ALTER COLUMN title TYPE text USING title::text;\nALTER TABLE posts DROP CONSTRAINT title_unique;\n" +
+			"ALTER TABLE users RENAME COLUMN age TO birth_year;\nALTER TABLE posts RENAME TO articles;\n",
+		"0003_posts.down.sql": "DROP TABLE posts;\n",
+	}
+	var group migrationDir
+	for _, name := range []string{"0001_users.sql", "0002_legacy.sql", "0003_posts.sql", "0003_posts.down.sql"} {
+		m, _, ok := parseMigration(name)
+		require.True(t, ok)
+		group.migrations = append(group.migrations, m)
+	}
+	read := func(file string) ([]byte, error) { return []byte(files[file]), nil }
+
+	schema, err := squashMigrations(group, "schema", read)
+	require.NoError(t, err)
+	assert.Equal(t, "-- Schema after 3 migrations, 0001_users.sql to 0003_posts.sql, 1 down migrations left out, replayed from their DDL; other statements left out: 1\n"+
+		"CREATE TABLE users (\n  id bigserial PRIMARY KEY,\n  email text NOT NULL,\n  birth_year int\n);\n"+
+		"CREATE TABLE articles (\n  id bigserial PRIMARY KEY,\n  title text\n);\n"+
+		"CREATE UNIQUE INDEX users_email_idx ON users (email);\n", schema)
+
+	summary, err := squashMigrations(group, "summary", read)
+	require.NoError(t, err)
+	assert.Equal(t, "-- Statements of 3 migrations, 0001_users.sql to 0003_posts.sql, 1 down migrations left out\n"+
+		"-- 0001_users.sql: CREATE TABLE users; CREATE UNIQUE INDEX users_email_idx ON users\n"+
+		"-- 0002_legacy.sql: CREATE TABLE legacy; CREATE INDEX legacy_id_idx ON legacy; INSERT INTO legacy VALUES; ALTER TABLE users ADD COLUMN age int, DROP ...\n"+
+		"-- 0003_posts.sql: DROP TABLE IF EXISTS legacy; CREATE TABLE posts; ALTER TABLE posts ALTER COLUMN title TYPE text ...; ALTER TABLE posts DROP CONSTRAINT title_unique; ALTER TABLE users RENAME COLUMN age TO birth_year; ALTER TABLE posts RENAME TO articles\n", summary)
+}
+
+func TestGenerateContentFileSquashMigrations(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":                      "package main\n",
+		"db/migrations/0001_users.sql": "CREATE TABLE users (\n  id bigserial PRIMARY KEY,\n  email text NOT NULL\n);\n",
+		"db/migrations/0002_email.sql": "CREATE UNIQUE INDEX users_email_idx ON users (email);\nUPDATE users SET email = lower(email);\n",
+		"db/migrations/0003_posts.sql": "CREATE TABLE posts (\n  id bigserial PRIMARY KEY,\n  user_id bigint REFERENCES users (id)\n);\n",
+	})
+	list := "main.go\ndb/migrations/0001_users.sql\ndb/migrations/0002_email.sql\ndb/migrations/0003_posts.sql"
+	if err := os.WriteFile("skukozh_file_list.txt", []byte(list), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{squashMigs: "schema"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#FILE main.go")
+	assert.NotContains(t, result, "#FILE db/migrations")
+	assert.Contains(t, result, "#MIGRATIONS db/migrations (schema)\n#START\n```sql\n"+
+		"-- Schema after 3 migrations, 0001_users.sql to 0003_posts.sql, replayed from their DDL; other statements left out: 1\n"+
+		"CREATE TABLE users (\n  id bigserial PRIMARY KEY,\n  email text NOT NULL\n);\n"+
+		"CREATE TABLE posts (\n  id bigserial PRIMARY KEY,\n  user_id bigint REFERENCES users (id)\n);\n"+
+		"CREATE UNIQUE INDEX users_email_idx ON users (email);\n```\n#END MIGRATIONS\n")
+
+	// Without the flag the migrations are regular file sections
+	result, err = generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(result, "#FILE db/migrations/"))
+
+	_, err = generateContentFileInternal(testDir, genOptions{squashMigs: "summary", format: "jsonl"})
+	assert.EqualError(t, err, "-format jsonl can't be combined with -squash-migrations")
+}
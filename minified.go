@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Bytes of a file read to decide whether it's minified
+	minifiedSampleSize = 256 << 10
+	// Files smaller than this are kept however long their lines
+	minifiedMinSize = 2 << 10
+	// Average line length from which a file counts as minified
+	minifiedAvgLineLength = 300
+	// Line length from which a file counts as minified whatever the average
+	minifiedLongLine = 32 << 10
+)
+
+// minifiedReason returns why the content of a file looks minified or
+// bundled, or "" if it looks written by hand. Only the start of the file is
+// sampled, so a bundle of several megabytes on one line is caught without
+// reading it all.
+func minifiedReason(content []byte) string {
+	if len(content) < minifiedMinSize {
+		return ""
+	}
+	lines, longest, current := 0, 0, 0
+	for _, b := range content {
+		if b == '\n' {
+			lines++
+			longest = max(longest, current)
+			current = 0
+			continue
+		}
+		current++
+	}
+	if current > 0 {
+		lines++
+		longest = max(longest, current)
+	}
+	if average := len(content) / lines; average >= minifiedAvgLineLength {
+		return fmt.Sprintf("minified content (lines average %d characters, use -include-minified to keep it)", average)
+	}
+	if longest >= minifiedLongLine {
+		return fmt.Sprintf("minified content (a line of %d+ characters, use -include-minified to keep it)", longest)
+	}
+	return ""
+}
+
+// filterMinified drops the files whose content looks minified or bundled,
+// reporting each with the measurement that gave it away. Files that can't
+// be read are kept for gen to report.
+func filterMinified(root string, files []string, skip func(path, reason string)) []string {
+	var kept []string
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(root, file))
+		if err != nil {
+			kept = append(kept, file)
+			continue
+		}
+		sample, _ := io.ReadAll(io.LimitReader(f, minifiedSampleSize))
+		f.Close()
+		if reason := minifiedReason(sample); reason != "" {
+			skip(file, reason)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinifiedReason(t *testing.T) {
+	handWritten := strings.Repeat("function add(a, b) {\n  return a + b;\n}\n", 200)
+	assert.Empty(t, minifiedReason([]byte(handWritten)))
+	assert.Empty(t, minifiedReason([]byte(strings.Repeat("x", 1000))), "small files are kept")
+
+	minified := strings.Repeat("function add(a,b){return a+b};", 200)
+	assert.Equal(t, "minified content (lines average 6000 characters, use -include-minified to keep it)", minifiedReason([]byte(minified)))
+
+	// Short lines around one huge line, as in a bundle with a banner
+	bundled := strings.Repeat("// banner\n", 5000) + strings.Repeat("x", 40000) + "\n"
+	assert.Equal(t, "minified content (a line of 40000+ characters, use -include-minified to keep it)", minifiedReason([]byte(bundled)))
+}
+
+func TestFindMinified(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"src/app.js":         strings.Repeat("const x = 1;\n", 300),
+		"vendor.js":          strings.Repeat("var a=1;", 1000),
+		"fixtures/data.json": "[" + strings.Repeat(`{"id":1},`, 500) + "{}]",
+	})
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	find := func(args ...string) string {
+		return CaptureOutput(t, func() {
+			require.Equal(t, 0, runCommandIn(t, dir, append([]string{"-list-file", listFile, "find", "-ext", "js,json"}, args...)...))
+		})
+	}
+	listed := func() []string {
+		entries, err := readFileList(listFile)
+		require.NoError(t, err)
+		var paths []string
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+		return paths
+	}
+
+	output := find(".")
+	assert.Equal(t, []string{"src/app.js"}, listed())
+	assert.Contains(t, output, "2 minified")
+
+	output = find("-verbose", ".")
+	assert.Contains(t, output, "vendor.js")
+
+	find("-include-minified", ".")
+	assert.Equal(t, []string{"fixtures/data.json", "src/app.js", "vendor.js"}, listed())
+
+	find("-format", "json", ".")
+	list, err := os.ReadFile(listFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(list), `"ignoredReason": "minified content (lines average 8000 characters, use -include-minified to keep it)"`)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+)
+
+// tokenModel approximates the tokenizer of a model family by its average
+// number of characters per token on source code
+type tokenModel struct {
+	name          string
+	charsPerToken float64
+}
+
+// Models compared by analyze -models. Tokenizers with larger vocabularies
+// pack more characters into a token.
+var tokenModels = []tokenModel{
+	{name: "GPT-4o (o200k)", charsPerToken: 4.0},
+	{name: "Claude", charsPerToken: 3.5},
+	{name: "Llama 3", charsPerToken: 3.8},
+	{name: "Llama 2", charsPerToken: 3.2},
+}
+
+// Context sizes checked by analyze -models, in tokens
+var contextSizes = []struct {
+	label  string
+	tokens int
+}{
+	{"8k", 8 * 1024},
+	{"32k", 32 * 1024},
+	{"128k", 128000},
+	{"200k", 200000},
+}
+
+// estimate returns the approximate number of tokens of chars characters
+func (m tokenModel) estimate(chars int) int {
+	return int(math.Ceil(float64(chars) / m.charsPerToken))
+}
+
+// writeModelEstimates prints the token estimate of the bundle for each
+// model and whether it fits the common context sizes
+func writeModelEstimates(out io.Writer, chars int) {
+	fmt.Fprintln(out, "Token estimates by model:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "Model\tTokens")
+	for _, size := range contextSizes {
+		fmt.Fprintf(w, "\t%s", size.label)
+	}
+	fmt.Fprint(w, "\n─────\t──────")
+	for _, size := range contextSizes {
+		fmt.Fprintf(w, "\t%s", strings.Repeat("─", len(size.label)))
+	}
+	fmt.Fprintln(w)
+
+	for _, model := range tokenModels {
+		tokens := model.estimate(chars)
+		fmt.Fprintf(w, "%s\t~%s", model.name, formatTokens(tokens))
+		for _, size := range contextSizes {
+			fits := "no"
+			if tokens <= size.tokens {
+				fits = "yes"
+			}
+			fmt.Fprintf(w, "\t%s", fits)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Fprintln(out, "")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenModelEstimate(t *testing.T) {
+	assert.Equal(t, 25, tokenModel{charsPerToken: 4}.estimate(100))
+	assert.Equal(t, 29, tokenModel{charsPerToken: 3.5}.estimate(100))
+	assert.Equal(t, 0, tokenModel{charsPerToken: 3.5}.estimate(0))
+}
+
+func TestWriteModelEstimates(t *testing.T) {
+	var out strings.Builder
+	writeModelEstimates(&out, 120000) // 30k tokens for GPT-4o, ~34k for Claude
+
+	report := out.String()
+	assert.Contains(t, report, "Token estimates by model:")
+	assert.Regexp(t, `Model +Tokens +8k +32k +128k +200k\n`, report)
+	assert.Regexp(t, `GPT-4o \(o200k\) +~30k +no +yes +yes +yes\n`, report)
+	assert.Regexp(t, `Claude +~34k +no +no +yes +yes\n`, report)
+	assert.Regexp(t, `Llama 2 +~38k +no +no +yes +yes\n`, report)
+}
+
+func TestAnalyzeModels(t *testing.T) {
+	writeTestResult(t, map[string]int{"src/main.go": 40000})
+	defer os.Remove(resultName)
+
+	result, err := analyzeResultFileInternal(analyzeOptions{topCount: 5, models: true})
+	require.NoError(t, err)
+	assert.Regexp(t, `Claude +~11k +no +yes +yes +yes\n`, result)
+
+	result, err = analyzeResultFileInternal(analyzeOptions{topCount: 5})
+	require.NoError(t, err)
+	assert.NotContains(t, result, "Token estimates by model:", "Model estimates should be opt-in")
+
+	_, err = analyzeResultFileInternal(analyzeOptions{topCount: 5, models: true, format: "csv"})
+	assert.EqualError(t, err, "-format csv can't be combined with -models")
+}
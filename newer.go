@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date layouts accepted by -newer
+var newerDateLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+
+// parseNewer converts a -newer value into a cutoff time. The value is either
+// a duration before now (7d, 2w, 36h, 90m) or a date like 2024-06-01.
+func parseNewer(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty -newer value")
+	}
+
+	for _, layout := range newerDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+
+	// Days and weeks aren't understood by time.ParseDuration
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[value[len(value)-1]]; ok {
+		count, err := strconv.ParseFloat(value[:len(value)-1], 64)
+		if err != nil || count < 0 {
+			return time.Time{}, fmt.Errorf("invalid -newer value %q (use e.g. 7d, 12h or 2024-06-01)", value)
+		}
+		return now.Add(-time.Duration(count * float64(unit))), nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return time.Time{}, fmt.Errorf("invalid -newer value %q (use e.g. 7d, 12h or 2024-06-01)", value)
+	}
+	return now.Add(-duration), nil
+}
+
+// filterByModTime keeps the files modified after the cutoff
+func filterByModTime(root string, files []string, cutoff time.Time) []string {
+	var kept []string
+	for _, file := range files {
+		info, err := os.Stat(filepath.Join(root, file))
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNewer(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Time
+	}{
+		{"Days", "7d", now.AddDate(0, 0, -7)},
+		{"Weeks", "2w", now.AddDate(0, 0, -14)},
+		{"Hours", "36h", now.Add(-36 * time.Hour)},
+		{"Date", "2024-06-01", time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cutoff, err := parseNewer(tc.input, now)
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(cutoff), "expected %v, got %v", tc.expected, cutoff)
+		})
+	}
+
+	for _, input := range []string{"", "soon", "xd", "-3d", "2024-13-01"} {
+		_, err := parseNewer(input, now)
+		assert.Error(t, err, "input %q", input)
+	}
+}
+
+func TestFilterByModTime(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{"old.go": "old", "new.go": "new"})
+
+	old := time.Now().AddDate(0, 0, -30)
+	require.NoError(t, os.Chtimes(filepath.Join(testDir, "old.go"), old, old))
+
+	kept := filterByModTime(testDir, []string{"new.go", "old.go", "missing.go"}, time.Now().AddDate(0, 0, -7))
+	assert.Equal(t, []string{"new.go"}, kept)
+}
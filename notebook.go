@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// Extension of Jupyter notebooks, converted to source by gen
+const notebookExt = ".ipynb"
+
+// Kernel languages whose comments start with "//" rather than "#"
+var slashCommentLanguages = []string{"javascript", "typescript", "java", "scala", "kotlin", "c", "c++", "cpp", "csharp", "c#", "go", "rust", "swift"}
+
+// notebook holds the parts of an nbformat 4 notebook gen keeps
+type notebook struct {
+	Cells []struct {
+		CellType string          `json:"cell_type"`
+		Source   json.RawMessage `json:"source"` // a string or a list of lines
+	} `json:"cells"`
+	Metadata struct {
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+	} `json:"metadata"`
+}
+
+// notebookSource returns the source of a cell, joining it when it's stored
+// as a list of lines
+func notebookSource(raw json.RawMessage) string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return text
+	}
+	var lines []string
+	if json.Unmarshal(raw, &lines) == nil {
+		return strings.Join(lines, "")
+	}
+	return ""
+}
+
+// convertNotebook turns a Jupyter notebook into plain source in the
+// percent format of Jupytext and VS Code: code cells, each after a "# %%"
+// marker, and with markdown true the markdown cells as comments after
+// "# %% [markdown]". Outputs and metadata are dropped. It returns the
+// source and the kernel language, or false for files that aren't notebooks
+// or can't be parsed, which are kept as they are.
+func convertNotebook(file string, content []byte, markdown bool) ([]byte, string, bool) {
+	if !strings.EqualFold(filepath.Ext(file), notebookExt) {
+		return content, "", false
+	}
+	var nb notebook
+	if err := json.Unmarshal(content, &nb); err != nil || nb.Cells == nil {
+		return content, "", false
+	}
+
+	language := strings.ToLower(nb.Metadata.LanguageInfo.Name)
+	if language == "" {
+		language = strings.ToLower(nb.Metadata.KernelSpec.Language)
+	}
+	if language == "" {
+		language = "python"
+	}
+	comment := "#"
+	if contains(slashCommentLanguages, language) {
+		comment = "//"
+	}
+
+	var out strings.Builder
+	for _, cell := range nb.Cells {
+		source := strings.TrimRight(notebookSource(cell.Source), "\n")
+		switch {
+		case cell.CellType == "code":
+			out.WriteString(comment + " %%\n")
+			out.WriteString(source)
+		case cell.CellType == "markdown" && markdown:
+			out.WriteString(comment + " %% [markdown]\n")
+			for i, line := range strings.Split(source, "\n") {
+				if i > 0 {
+					out.WriteString("\n")
+				}
+				out.WriteString(strings.TrimRight(comment+" "+line, " "))
+			}
+		default:
+			continue
+		}
+		out.WriteString("\n\n")
+	}
+	return []byte(out.String()), language, true
+}
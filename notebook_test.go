@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNotebook = `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Analysis\n", "\n", "Load the data."]},
+  {"cell_type": "code", "execution_count": 1, "metadata": {}, "outputs": [{"output_type": "stream", "text": ["huge output\n"]}],
+   "source": ["import pandas as pd\n", "df = pd.read_csv(\"data.csv\")"]},
+  {"cell_type": "raw", "metadata": {}, "source": "raw text"},
+  {"cell_type": "code", "execution_count": 2, "metadata": {}, "outputs": [], "source": "df.head()\n"}
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}, "language_info": {"name": "python"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+
+func TestConvertNotebook(t *testing.T) {
+	source, language, ok := convertNotebook("analysis.ipynb", []byte(testNotebook), false)
+	require.True(t, ok)
+	assert.Equal(t, "python", language)
+	assert.Equal(t, "# %%\nimport pandas as pd\ndf = pd.read_csv(\"data.csv\")\n\n# %%\ndf.head()\n\n", string(source))
+
+	source, _, ok = convertNotebook("analysis.IPYNB", []byte(testNotebook), true)
+	require.True(t, ok)
+	assert.Equal(t, "# %% [markdown]\n# # Analysis\n#\n# Load the data.\n\n# %%\nimport pandas as pd\ndf = pd.read_csv(\"data.csv\")\n\n# %%\ndf.head()\n\n", string(source))
+
+	t.Run("Kernel language", func(t *testing.T) {
+		source, language, ok := convertNotebook("app.ipynb", []byte(`{"cells": [{"cell_type": "code", "source": "let x = 1"}], "metadata": {"kernelspec": {"language": "TypeScript"}}}`), false)
+		require.True(t, ok)
+		assert.Equal(t, "typescript", language)
+		assert.Equal(t, "// %%\nlet x = 1\n\n", string(source))
+	})
+
+	t.Run("Other files are kept", func(t *testing.T) {
+		for file, content := range map[string]string{
+			"data.json":    testNotebook,
+			"broken.ipynb": `{"cells": [`,
+			"other.ipynb":  `{"data": 1}`,
+		} {
+			converted, _, ok := convertNotebook(file, []byte(content), false)
+			assert.False(t, ok, file)
+			assert.Equal(t, content, string(converted), file)
+		}
+	})
+}
+
+func TestGenerateContentFileNotebook(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{"analysis.ipynb": testNotebook})
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("analysis.ipynb\nanalysis.ipynb:5-6"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, result, "#FILE analysis.ipynb\n#TYPE ipynb\n#START\n```python\n# %%\nimport pandas as pd\n")
+	assert.Contains(t, result, "#LINES 5-6 of 7\n#START\n```python\n# %%\ndf.head()\n```", "line ranges select from the source")
+	assert.NotContains(t, result, "huge output")
+	assert.NotContains(t, result, "Analysis")
+
+	result, err = generateContentFileInternal(testDir, genOptions{notebookMD: true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "# %% [markdown]\n# # Analysis\n")
+}
@@ -0,0 +1,29 @@
+package main
+
+// Patterns of conventional test code, fixtures and snapshots across
+// languages, excluded with -no-tests
+var testPatterns = []string{
+	// Test directories
+	"test/", "tests/", "spec/", "__tests__/", "__mocks__/", "e2e/",
+	// Fixtures and snapshots
+	"testdata/", "fixtures/", "__fixtures__/", "__snapshots__/", "*.snap",
+	// Go, Python, Ruby
+	"*_test.go", "test_*.py", "*_test.py", "conftest.py", "*_spec.rb", "*_test.rb",
+	// JavaScript and TypeScript
+	"*.test.js", "*.test.jsx", "*.test.mjs", "*.test.ts", "*.test.tsx",
+	"*.spec.js", "*.spec.jsx", "*.spec.mjs", "*.spec.ts", "*.spec.tsx",
+	// JVM, .NET, PHP, Rust, Swift
+	"*Test.java", "*Tests.java", "*Test.kt", "*Tests.kt", "*Tests.cs", "*Test.cs", "*Test.php", "*Tests.swift",
+}
+
+// testRules returns the -no-tests patterns as ignore rules
+func testRules() []gitignoreRule {
+	var rules []gitignoreRule
+	for _, pattern := range testPatterns {
+		if rule, ok := parseGitignoreLine(pattern); ok {
+			rule.source = "-no-tests"
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindNoTests(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":                              "package main\n",
+		"main_test.go":                         "package main\n",
+		"testdata/input.txt":                   "input\n",
+		"web/button.tsx":                       "export {}\n",
+		"web/button.spec.tsx":                  "test()\n",
+		"web/__tests__/app.js":                 "test()\n",
+		"web/__snapshots__/button.tsx.snap":    "snap\n",
+		"app/models/user.rb":                   "class User; end\n",
+		"spec/models/user_spec.rb":             "describe User\n",
+		"pkg/testing_utils.py":                 "def helper(): pass\n",
+		"pkg/test_parser.py":                   "def test_parse(): pass\n",
+		"src/main/java/LatestReport.java":      "class LatestReport {}\n",
+		"src/test/java/LatestReportTest.java":  "class LatestReportTest {}\n",
+		"src/main/java/ContestResultsTest.txt": "not code\n",
+	})
+
+	fs := flagSetWith(t, "-no-tests", "-case", "sensitive")
+	opts, err := findOptionsFromFlags(fs)
+	require.NoError(t, err)
+	skipped := make(map[string]string)
+	opts.onSkip = func(path, reason string) { skipped[path] = reason }
+
+	files, err := findFilesWithOptions(testDir, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"app/models/user.rb",
+		"main.go",
+		"pkg/testing_utils.py",
+		"src/main/java/ContestResultsTest.txt",
+		"src/main/java/LatestReport.java",
+		"web/button.tsx",
+	}, files)
+
+	assert.Equal(t, "path ignored by -no-tests (*_test.go)", skipped["main_test.go"])
+	assert.Equal(t, "path ignored by -no-tests (__tests__/)", skipped["web/__tests__"])
+	assert.Equal(t, "path ignored by -no-tests (test/)", skipped["src/test"])
+	assert.Equal(t, "tests", skipCategory(skipped["spec"]))
+
+	// Without the flag test code stays in
+	files, err = findFilesInternal(testDir, nil)
+	require.NoError(t, err)
+	assert.Contains(t, files, "main_test.go")
+	assert.Contains(t, files, "spec/models/user_spec.rb")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Supported -order strategies
+var orderStrategies = []string{"list", "alpha", "size", "depth", "deps", "priority"}
+
+// orderFiles arranges the files of the file list according to strategy.
+// The "priority" strategy puts files with a priority annotation first, by
+// their ranks, then files matching the priority patterns in pattern order,
+// followed by the remaining files alphabetically.
+func orderFiles(baseDir string, files []string, strategy string, priority []string, ranks map[string]int) ([]string, error) {
+	ordered := append([]string(nil), files...)
+
+	switch strategy {
+	case "", "list":
+		// Keep the order of the file list
+	case "alpha":
+		sort.Strings(ordered)
+	case "size":
+		sizes := make(map[string]int64, len(ordered))
+		for _, file := range ordered {
+			if info, err := os.Stat(filepath.Join(baseDir, entryPath(file))); err == nil {
+				sizes[file] = info.Size()
+			}
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			if sizes[ordered[i]] != sizes[ordered[j]] {
+				return sizes[ordered[i]] < sizes[ordered[j]]
+			}
+			return ordered[i] < ordered[j]
+		})
+	case "depth":
+		sort.SliceStable(ordered, func(i, j int) bool {
+			di, dj := strings.Count(ordered[i], "/"), strings.Count(ordered[j], "/")
+			if di != dj {
+				return di < dj
+			}
+			return ordered[i] < ordered[j]
+		})
+	case "deps":
+		ordered = dependencyOrder(baseDir, ordered)
+	case "priority":
+		// Annotated ranks start at 1, pattern ranks follow the largest one
+		offset := 0
+		for _, rank := range ranks {
+			offset = max(offset, rank)
+		}
+		rank := func(file string) int {
+			if rank, ok := ranks[file]; ok {
+				return rank - 1
+			}
+			for i, pattern := range priority {
+				if matchPriority(file, pattern) {
+					return offset + i
+				}
+			}
+			return offset + len(priority)
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			ri, rj := rank(ordered[i]), rank(ordered[j])
+			if ri != rj {
+				return ri < rj
+			}
+			return ordered[i] < ordered[j]
+		})
+	default:
+		return nil, fmt.Errorf("unknown order %q (use %s)", strategy, strings.Join(orderStrategies, ", "))
+	}
+
+	return ordered, nil
+}
+
+// matchPriority checks if a file matches a priority pattern. Patterns ending
+// with a slash match directories, other patterns are globs matched against
+// the whole path or the file name.
+func matchPriority(file, pattern string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(file, pattern)
+	}
+	if matched, _ := path.Match(pattern, file); matched {
+		return true
+	}
+	matched, _ := path.Match(pattern, path.Base(file))
+	return matched
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestFiles creates files with the given contents under dir
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		fullPath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestOrderFiles(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"b.txt":         "bbbbbbbbbb",
+		"a/deep/c.txt":  "c",
+		"a/d.txt":       "ddddd",
+		"README.md":     "readme",
+		"cmd/main.go":   "package main",
+		"internal/x.go": "package internal",
+	})
+	files := []string{"b.txt", "a/deep/c.txt", "a/d.txt", "README.md", "cmd/main.go", "internal/x.go"}
+
+	tests := []struct {
+		name     string
+		strategy string
+		priority []string
+		expected []string
+	}{
+		{"List order", "", nil, files},
+		{"Alphabetical", "alpha", nil, []string{"README.md", "a/d.txt", "a/deep/c.txt", "b.txt", "cmd/main.go", "internal/x.go"}},
+		{"Size ascending", "size", nil, []string{"a/deep/c.txt", "a/d.txt", "README.md", "b.txt", "cmd/main.go", "internal/x.go"}},
+		{"Depth", "depth", nil, []string{"README.md", "b.txt", "a/d.txt", "cmd/main.go", "internal/x.go", "a/deep/c.txt"}},
+		{"Priority", "priority", []string{"README.md", "cmd/", "*.go"}, []string{"README.md", "cmd/main.go", "internal/x.go", "a/d.txt", "a/deep/c.txt", "b.txt"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ordered, err := orderFiles(testDir, files, tc.strategy, tc.priority, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ordered)
+		})
+	}
+
+	t.Run("Priority annotations", func(t *testing.T) {
+		ranks := map[string]int{"b.txt": 2, "a/d.txt": 1}
+		ordered, err := orderFiles(testDir, files, "priority", []string{"README.md"}, ranks)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a/d.txt", "b.txt", "README.md", "a/deep/c.txt", "cmd/main.go", "internal/x.go"}, ordered)
+	})
+
+	t.Run("Unknown strategy", func(t *testing.T) {
+		_, err := orderFiles(testDir, files, "random", nil, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestDependencyOrder(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"go.mod":                "module example.com/app\n",
+		"main.go":               "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/internal/service\"\n)\n",
+		"internal/service/a.go": "package service\n\nimport \"example.com/app/internal/store\"\n",
+		"internal/store/db.go":  "package store\n",
+		"web/app.ts":            "import { api } from './api';\nimport React from 'react';\n",
+		"web/api/index.ts":      "export const api = require('../util.js');\n",
+		"web/util.js":           "module.exports = {};\n",
+	})
+
+	files := []string{"main.go", "internal/service/a.go", "internal/store/db.go", "web/app.ts", "web/api/index.ts", "web/util.js"}
+	ordered := dependencyOrder(testDir, files)
+
+	position := make(map[string]int)
+	for i, file := range ordered {
+		position[file] = i
+	}
+	assert.Len(t, ordered, len(files))
+	assert.Less(t, position["internal/store/db.go"], position["internal/service/a.go"])
+	assert.Less(t, position["internal/service/a.go"], position["main.go"])
+	assert.Less(t, position["web/util.js"], position["web/api/index.ts"])
+	assert.Less(t, position["web/api/index.ts"], position["web/app.ts"])
+}
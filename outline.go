@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// outlinePatterns lists, per fence language, the line patterns kept by
+// -outline for languages without a dedicated parser
+var outlinePatterns = map[string][]*regexp.Regexp{
+	"python": {
+		regexp.MustCompile(`^\s*(async\s+)?def\s+\w+`),
+		regexp.MustCompile(`^\s*class\s+\w+`),
+	},
+	"javascript": jsOutlinePatterns,
+	"typescript": jsOutlinePatterns,
+	"jsx":        jsOutlinePatterns,
+	"tsx":        jsOutlinePatterns,
+	"ruby": {
+		regexp.MustCompile(`^\s*(def|class|module)\s+`),
+	},
+	"php": {
+		regexp.MustCompile(`^\s*((abstract|final|public|private|protected|static)\s+)*(function|class|interface|trait|enum)\s+\w+`),
+	},
+	"rust": {
+		regexp.MustCompile(`^\s*(pub(\([^)]*\))?\s+)?((async|const|unsafe|extern)\s+)*(fn|struct|enum|trait|impl|type|mod)\b`),
+	},
+	"java":   jvmOutlinePatterns,
+	"kotlin": jvmOutlinePatterns,
+	"csharp": jvmOutlinePatterns,
+	"swift": {
+		regexp.MustCompile(`^\s*((public|private|internal|open|fileprivate|static|final)\s+)*(func|class|struct|enum|protocol|extension)\s+\w+`),
+	},
+}
+
+var jsOutlinePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?function\b`),
+	regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(abstract\s+)?class\s+\w+`),
+	regexp.MustCompile(`^\s*(export\s+)?(declare\s+)?(interface|type|enum)\s+\w+`),
+	regexp.MustCompile(`^\s*export\s+(const|let|var)\s+\w+`),
+}
+
+var jvmOutlinePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^\s*((public|private|protected|internal|static|final|abstract|sealed|data|open|override)\s+)*(class|interface|enum|record|object|struct)\s+\w+`),
+	regexp.MustCompile(`^\s*((public|private|protected|internal|static|final|abstract|override|synchronized|async|virtual)\s+)+[\w<>\[\],.? ]+\s+\w+\s*\(`),
+	regexp.MustCompile(`^\s*((public|private|protected|internal|override|suspend|inline)\s+)*fun\s+`),
+}
+
+// outlineContent reduces a source file to its declarations. The second
+// return value is false for languages without outline support.
+func outlineContent(file string, content []byte) ([]byte, bool) {
+	language := fenceLanguage(file, content)
+	if language == "go" {
+		return outlineGo(file, content)
+	}
+
+	patterns, ok := outlinePatterns[language]
+	if !ok {
+		return nil, false
+	}
+
+	var out bytes.Buffer
+	for _, line := range strings.Split(string(content), "\n") {
+		for _, pattern := range patterns {
+			if pattern.MatchString(line) {
+				line = strings.TrimRight(line, " \t\r")
+				if strings.HasSuffix(line, "{") {
+					line += " ... }"
+				}
+				out.WriteString(line + "\n")
+				break
+			}
+		}
+	}
+	return out.Bytes(), true
+}
+
+// outlineGo keeps the package clause, type declarations, exported constants
+// and variables, and function signatures without their bodies
+func outlineGo(file string, content []byte) ([]byte, bool) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, content, 0)
+	if err != nil {
+		return nil, false
+	}
+
+	var out bytes.Buffer
+	out.WriteString("package " + parsed.Name.Name + "\n")
+
+	for _, decl := range parsed.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			d.Body = nil
+		case *ast.GenDecl:
+			switch d.Tok {
+			case token.IMPORT:
+				continue
+			case token.CONST, token.VAR:
+				if !keepExportedSpecs(d) {
+					continue
+				}
+			}
+		}
+		out.WriteString("\n")
+		if err := printer.Fprint(&out, fset, decl); err != nil {
+			return nil, false
+		}
+		out.WriteString("\n")
+	}
+	return out.Bytes(), true
+}
+
+// keepExportedSpecs drops the unexported names of a const or var declaration
+// and reports whether anything is left
+func keepExportedSpecs(decl *ast.GenDecl) bool {
+	var specs []ast.Spec
+	for _, spec := range decl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for _, name := range valueSpec.Names {
+			if name.IsExported() {
+				specs = append(specs, spec)
+				break
+			}
+		}
+	}
+	decl.Specs = specs
+	if len(specs) == 1 {
+		decl.Lparen = token.NoPos
+	}
+	return len(specs) > 0
+}
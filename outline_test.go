@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutlineGo(t *testing.T) {
+	source := `package store
+
+import "fmt"
+
+const MaxItems = 10
+
+const internalLimit = 5
+
+// Store keeps items
+type Store struct {
+	items []string
+}
+
+func (s *Store) Add(item string) error {
+	if len(s.items) >= MaxItems {
+		return fmt.Errorf("full")
+	}
+	s.items = append(s.items, item)
+	return nil
+}
+
+func helper() int { return internalLimit }
+`
+	outline, ok := outlineContent("store.go", []byte(source))
+	require.True(t, ok)
+
+	result := string(outline)
+	assert.Contains(t, result, "package store")
+	assert.Contains(t, result, "const MaxItems = 10")
+	assert.Contains(t, result, "type Store struct")
+	assert.Contains(t, result, "func (s *Store) Add(item string) error")
+	assert.Contains(t, result, "func helper() int")
+	assert.NotContains(t, result, "import")
+	assert.NotContains(t, result, "internalLimit = 5")
+	assert.NotContains(t, result, "append(")
+}
+
+func TestOutlinePatterns(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		source      string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "Python",
+			file:        "app.py",
+			source:      "import os\n\nclass App:\n    def run(self):\n        return os.getcwd()\n\nasync def main():\n    pass\n",
+			contains:    []string{"class App:", "    def run(self):", "async def main():"},
+			notContains: []string{"import os", "getcwd"},
+		},
+		{
+			name:        "TypeScript",
+			file:        "api.ts",
+			source:      "import x from 'y';\nexport interface User {\n  id: number;\n}\nexport async function load(id: number) {\n  return fetch(id);\n}\n",
+			contains:    []string{"export interface User { ... }", "export async function load(id: number) { ... }"},
+			notContains: []string{"fetch", "import x"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			outline, ok := outlineContent(tc.file, []byte(tc.source))
+			require.True(t, ok)
+			for _, expected := range tc.contains {
+				assert.Contains(t, string(outline), expected)
+			}
+			for _, unexpected := range tc.notContains {
+				assert.NotContains(t, string(outline), unexpected)
+			}
+		})
+	}
+
+	t.Run("Unsupported language", func(t *testing.T) {
+		_, ok := outlineContent("notes.md", []byte("# Notes"))
+		assert.False(t, ok)
+	})
+
+	t.Run("Invalid Go falls back", func(t *testing.T) {
+		_, ok := outlineContent("broken.go", []byte("package"))
+		assert.False(t, ok)
+	})
+}
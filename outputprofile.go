@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// outputProfile is a named result of gen -all-profiles, configured under
+// output_profiles in the config file
+type outputProfile struct {
+	Flags  []string `json:"flags"`  // gen flags of this result, e.g. ["-only=go", "-go-api-only"]
+	Result string   `json:"result"` // result file, relative to the result's directory; skukozh_result.<name>.txt by default
+}
+
+// fileReads holds the contents of the files a gen run has read, so the
+// results of -all-profiles read each listed file once
+type fileReads map[string][]byte
+
+// read returns the content of the file name, reading it on first use. A nil
+// fileReads reads the file every time.
+func (r fileReads) read(name string) ([]byte, error) {
+	if content, ok := r[name]; ok {
+		return slices.Clone(content), nil
+	}
+	content, err := os.ReadFile(name)
+	if err == nil && r != nil {
+		r[name] = slices.Clone(content)
+	}
+	return content, err
+}
+
+// resultName returns the name of the profile's result file
+func (p outputProfile) resultName(name string) string {
+	if p.Result == "" {
+		ext := filepath.Ext(resultName)
+		return strings.TrimSuffix(resultName, ext) + "." + name + ext
+	}
+	if filepath.IsAbs(p.Result) {
+		return p.Result
+	}
+	return besideResult(p.Result)
+}
+
+// outputProfileNames returns the names of the output profiles of cfg, sorted
+func outputProfileNames(cfg config) []string {
+	names := make([]string, 0, len(cfg.OutputProfiles))
+	for name := range cfg.OutputProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// outputProfileFlags returns the gen flags of the profile name: the flags of
+// the command line, with the profile's own flags applied on top
+func outputProfileFlags(fs *flag.FlagSet, name string, p outputProfile) (*flag.FlagSet, error) {
+	profileFS := DefaultFlags()
+	var err error
+	// Flags given after the command aren't marked as set on fs, so copy all
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name != "all-profiles" && err == nil {
+			err = profileFS.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := parseCommandArgs(profileFS, "gen", p.Flags)
+	if err != nil {
+		return nil, fmt.Errorf("output profile %s: %w", name, err)
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("output profile %s: unexpected argument %q (only gen flags are allowed)", name, args[0])
+	}
+	if allProfiles, _ := strconv.ParseBool(profileFS.Lookup("all-profiles").Value.String()); allProfiles {
+		return nil, fmt.Errorf("output profile %s: -all-profiles can't be used in a profile", name)
+	}
+	return profileFS, nil
+}
+
+// generateAllProfiles writes the result of every output profile of the
+// config file for the files below directory and returns the exit code. The
+// profiles share the file list and the file contents, so each file is read
+// once however many results are written.
+func generateAllProfiles(fs *flag.FlagSet, directory string) int {
+	cfg, err := configFromFlags(fs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	names := outputProfileNames(cfg)
+	if len(names) == 0 {
+		fmt.Println("Error: -all-profiles needs output_profiles in the config file")
+		return 1
+	}
+
+	// Check every profile before writing any result
+	options := make([]genOptions, len(names))
+	results := make([]string, len(names))
+	for i, name := range names {
+		results[i] = cfg.OutputProfiles[name].resultName(name)
+		profileFS, err := outputProfileFlags(fs, name, cfg.OutputProfiles[name])
+		if err == nil {
+			options[i], err = checkedGenOptions(profileFS)
+		}
+		if err == nil && options[i].review {
+			err = fmt.Errorf("-review asks for input and can't be combined with -all-profiles")
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	origResultName := resultName
+	defer func() { resultName = origResultName }()
+
+	reads := make(fileReads)
+	for i, name := range names {
+		if err := os.MkdirAll(filepath.Dir(results[i]), 0755); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		resultName = results[i]
+		opts := options[i]
+		opts.reads = reads
+		statusf("Profile %s:\n", name)
+		if code := runWithHooks(fs, "gen", directory, genOutputName(opts), func() {
+			generateContentFile(directory, opts)
+		}); code != 0 {
+			return code
+		}
+	}
+	return 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAllProfiles(t *testing.T) {
+	dir, out := t.TempDir(), t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":      "package main\n\nfunc Run() {}\n\nfunc helper() {}\n",
+		"main_test.go": "package main\n",
+		"README.md":    "# App\n",
+	})
+	listFile := filepath.Join(out, "list.txt")
+	require.NoError(t, os.WriteFile(listFile, []byte("main.go\nmain_test.go\nREADME.md\n"), 0644))
+	configPath := filepath.Join(out, "config.json")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+	}
+	run := func(args ...string) (string, int) {
+		var exitCode int
+		output := CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, dir, append([]string{"-list-file", listFile, "-result-file", filepath.Join(out, "bundle.txt"), "-config", configPath}, args...)...)
+		})
+		return output, exitCode
+	}
+
+	t.Run("One result per profile", func(t *testing.T) {
+		writeConfig(`{"output_profiles": {
+			"full": {},
+			"api-only": {"flags": ["-only=go", "-go-api-only"]},
+			"docs": {"flags": ["-only", "md"], "result": "docs/context.txt"}
+		}}`)
+		output, exitCode := run("gen", "-annotate", "-all-profiles", ".")
+		require.Equal(t, 0, exitCode, output)
+		assert.Contains(t, output, "Profile api-only:\nContent file saved to "+filepath.Join(out, "bundle.api-only.txt"))
+
+		full := ReadTestFile(t, filepath.Join(out, "bundle.full.txt"))
+		assert.Contains(t, full, "#FILE main_test.go")
+		assert.Contains(t, full, "func helper")
+		assert.Contains(t, full, "#FILE README.md (1 line,", "command line flags apply to every profile")
+
+		api := ReadTestFile(t, filepath.Join(out, "bundle.api-only.txt"))
+		assert.Contains(t, api, "func Run")
+		assert.NotContains(t, api, "func helper")
+		assert.NotContains(t, api, "README.md")
+
+		docs := ReadTestFile(t, filepath.Join(out, "docs", "context.txt"))
+		assert.Contains(t, docs, "#FILE README.md")
+		assert.NotContains(t, docs, "main.go")
+
+		assert.NoFileExists(t, filepath.Join(out, "bundle.txt"))
+	})
+
+	t.Run("Invalid profiles write nothing", func(t *testing.T) {
+		writeConfig(`{"output_profiles": {"a": {}, "b": {"flags": ["-order=random"]}}}`)
+		output, exitCode := run("gen", "-all-profiles", ".")
+		assert.Equal(t, 1, exitCode)
+		assert.Contains(t, output, `Error: unknown order "random"`)
+		assert.NoFileExists(t, filepath.Join(out, "bundle.a.txt"))
+
+		writeConfig(`{"output_profiles": {"a": {"flags": ["-only=go", "src"]}}}`)
+		output, _ = run("gen", "-all-profiles", ".")
+		assert.Contains(t, output, `Error: output profile a: unexpected argument "src"`)
+
+		writeConfig(`{"output_profiles": {"a": {"flags": ["-review"]}}}`)
+		output, _ = run("gen", "-all-profiles", ".")
+		assert.Contains(t, output, "Error: -review asks for input and can't be combined with -all-profiles")
+
+		writeConfig(`{}`)
+		output, _ = run("gen", "-all-profiles", ".")
+		assert.Contains(t, output, "Error: -all-profiles needs output_profiles in the config file")
+	})
+}
+
+func TestFileReads(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	require.NoError(t, os.WriteFile(path, []byte("package a\n"), 0644))
+
+	reads := make(fileReads)
+	content, err := reads.read(path)
+	require.NoError(t, err)
+	content[0] = 'P'
+
+	require.NoError(t, os.WriteFile(path, []byte("package b\n"), 0644))
+	content, err = reads.read(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package a\n", string(content), "read once, and callers can't change the shared copy")
+
+	var none fileReads
+	content, err = none.read(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package b\n", string(content))
+	_, err = reads.read(filepath.Join(dir, "missing.go"))
+	assert.True(t, os.IsNotExist(err))
+}
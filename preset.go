@@ -0,0 +1,270 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Directory of the presets below a project's root, meant to be committed
+var presetDir = filepath.Join(".skukozh", "presets")
+
+// Extension of preset files
+const presetExt = ".txt"
+
+// presetName matches valid preset names, such as auth-service-context
+var presetName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// presetPath returns the path of the preset name below root
+func presetPath(root, name string) (string, error) {
+	if !presetName.MatchString(name) {
+		return "", fmt.Errorf("invalid preset name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return filepath.Join(root, presetDir, name+presetExt), nil
+}
+
+// savePreset stores the file list as the preset name below root. The preset
+// is a file list without the #ROOT header, as the root differs between
+// checkouts, and with included lists expanded. With filtersOnly only the
+// find flags are stored, so using the preset runs find again.
+func savePreset(root, name string, filtersOnly bool) (string, error) {
+	path, err := presetPath(root, name)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(fileListName)
+	if err != nil {
+		return "", err
+	}
+	_, filters := parseFileListHeader(content)
+
+	lines := []string{fileListVersion}
+	if filters != "" {
+		lines = append(lines, fileListFiltersPrefix+filters)
+	}
+	if filtersOnly {
+		if filters == "" {
+			return "", fmt.Errorf("%s records no find flags; run find again or save the files", fileListName)
+		}
+	} else {
+		entries, err := readFileList(fileListName)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			lines = append(lines, formatFileListEntry(entry))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return path, writeFileAtomic(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// loadPreset returns the entries and the find flags of the preset name
+// below root
+func loadPreset(root, name string) ([]fileListEntry, string, error) {
+	path, err := presetPath(root, name)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, "", fmt.Errorf("unknown preset %q (none in %s)", name, filepath.Join(root, presetDir))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	entries, err := readFileList(path)
+	if err != nil {
+		return nil, "", err
+	}
+	_, filters := parseFileListHeader(content)
+	return entries, filters, nil
+}
+
+// presetNames returns the names of the presets below root, sorted
+func presetNames(root string) ([]string, error) {
+	files, err := os.ReadDir(filepath.Join(root, presetDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, file := range files {
+		if name, ok := strings.CutSuffix(file.Name(), presetExt); ok && !file.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// splitFilters splits the find flags of a #FILTERS header into arguments,
+// unquoting the values quoted by commandFlagArgs
+func splitFilters(filters string) ([]string, error) {
+	var args []string
+	for rest := strings.TrimLeft(filters, " "); rest != ""; rest = strings.TrimLeft(rest, " ") {
+		name, value, ok := strings.Cut(rest, "=")
+		if ok && strings.HasPrefix(value, `"`) && !strings.Contains(name, " ") {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filters %q: %w", filters, err)
+			}
+			unquoted, _ := strconv.Unquote(quoted)
+			args = append(args, name+"="+unquoted)
+			rest = value[len(quoted):]
+			continue
+		}
+		var arg string
+		arg, rest, _ = strings.Cut(rest, " ")
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// runPreset handles "preset save", "preset use" and "preset list" and
+// returns the exit code. Presets live below the directory argument, which
+// defaults to the file list's root for save and to the current directory
+// otherwise.
+func runPreset(fs *flag.FlagSet, args []string) int {
+	if len(args) < 2 {
+		fmt.Print(usage)
+		return 1
+	}
+	action := args[1]
+	wanted := 3 // preset <action> <name>
+	if action == "list" {
+		wanted = 2
+	}
+	if len(args) < wanted || len(args) > wanted+1 {
+		fmt.Print(usage)
+		return 1
+	}
+
+	root := "."
+	var err error
+	switch {
+	case len(args) > wanted:
+		root = args[wanted]
+	case action == "save":
+		root, err = fileListRoot()
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	switch action {
+	case "save":
+		filtersOnly, _ := strconv.ParseBool(fs.Lookup("filters-only").Value.String())
+		path, err := savePreset(root, args[2], filtersOnly)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		statusf("Preset %s saved to %s\n", args[2], path)
+		return 0
+
+	case "use":
+		return usePreset(fs, root, args[2])
+
+	case "list":
+		names, err := presetNames(root)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			entries, filters, err := loadPreset(root, name)
+			switch {
+			case err != nil:
+				fmt.Fprintf(w, "%s\tinvalid: %v\n", name, err)
+			case len(entries) == 0:
+				fmt.Fprintf(w, "%s\tfind %s\n", name, filters)
+			default:
+				fmt.Fprintf(w, "%s\t%d files\n", name, len(entries))
+			}
+		}
+		w.Flush()
+		if len(names) == 0 {
+			statusf("No presets in %s; save one with 'skukozh preset save <name>'\n", filepath.Join(root, presetDir))
+		}
+		return 0
+
+	default:
+		fmt.Print(usage)
+		return 1
+	}
+}
+
+// usePreset writes the file list of the preset name for the files below
+// root. A preset holding only find flags runs find with them, or deps when
+// the list was made by deps.
+func usePreset(fs *flag.FlagSet, root, name string) int {
+	entries, filters, err := loadPreset(root, name)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if len(entries) == 0 {
+		if filters == "" {
+			fmt.Printf("Error: preset %s has neither files nor find flags\n", name)
+			return 1
+		}
+		args, err := splitFilters(filters)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		// The find paths follow the directory, and "--" ends flag parsing
+		flags, paths := args, []string(nil)
+		if i := slices.Index(args, "--"); i >= 0 {
+			flags, paths = args[:i], args[i+1:]
+		}
+		command := "find"
+		for _, arg := range flags {
+			if strings.HasPrefix(arg, "-seed=") || strings.HasPrefix(arg, "-around=") {
+				command = "deps"
+			}
+		}
+		findFS := DefaultFlags()
+		if err := findFS.Parse(append(append(append([]string{command}, flags...), "--", root), paths...)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		for _, global := range []string{"quiet", "hooks"} {
+			_ = findFS.Set(global, fs.Lookup(global).Value.String())
+		}
+		return runWithFlags(findFS)
+	}
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = formatFileListEntry(entry)
+	}
+	unlock, err := lockOutput(fileListName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	defer unlock()
+	if err := writeFileListLines(root, lines, filters); err != nil {
+		fmt.Printf("Error writing file list: %v\n", err)
+		return 1
+	}
+	statusf("Using preset %s: %d files. File list saved to %s\n", name, len(entries), fileListName)
+	return 0
+}
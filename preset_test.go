@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresets(t *testing.T) {
+	files := map[string]string{
+		"auth/login.go":  "package auth\n",
+		"auth/token.go":  "package auth\n",
+		"auth/x_test.go": "package auth\n",
+		"billing/pay.go": "package billing\n",
+		"README.md":      "# App\n",
+	}
+	mine, theirs := t.TempDir(), t.TempDir()
+	writeTestFiles(t, mine, files)
+	writeTestFiles(t, theirs, files)
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+
+	t.Run("Save and use", func(t *testing.T) {
+		var exitCode int
+		CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, mine, "-list-file", listFile, "find", "-ext", "go", "-not-ext", "_test.go", ".", "--", "auth")
+		})
+		require.Equal(t, 0, exitCode)
+		list := ReadTestFile(t, listFile) + "\n# curated\nREADME.md priority=1\n"
+		require.NoError(t, os.WriteFile(listFile, []byte(list), 0644))
+
+		// Saved below the list's root, wherever skukozh runs
+		output := CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, t.TempDir(), "-list-file", listFile, "preset", "save", "auth-context")
+		})
+		require.Equal(t, 0, exitCode)
+		preset := filepath.Join(mine, ".skukozh", "presets", "auth-context.txt")
+		assert.Contains(t, output, "Preset auth-context saved to "+preset)
+		assert.Equal(t, "#SKUKOZH-LIST v2\n#FILTERS -ext=go -not-ext=_test.go -- auth\nauth/login.go\nauth/token.go\nREADME.md priority=1\n", ReadTestFile(t, preset))
+
+		// A teammate's checkout gets a list rooted in it
+		require.NoError(t, os.MkdirAll(filepath.Join(theirs, ".skukozh", "presets"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(theirs, ".skukozh", "presets", "auth-context.txt"), []byte(ReadTestFile(t, preset)), 0644))
+		output = CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, theirs, "-list-file", listFile, "preset", "use", "auth-context")
+		})
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "Using preset auth-context: 3 files.")
+		assert.Equal(t, fileListText(t, theirs, "-ext=go -not-ext=_test.go -- auth", "auth/login.go", "auth/token.go", "README.md priority=1"), ReadTestFile(t, listFile))
+	})
+
+	t.Run("Filters only", func(t *testing.T) {
+		var exitCode int
+		CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, mine, "-list-file", listFile, "find", "-ext", "go,!_test.go", "-max-depth", "3", ".")
+		})
+		require.Equal(t, 0, exitCode)
+		CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, mine, "-list-file", listFile, "preset", "save", "-filters-only", "go-code")
+		})
+		require.Equal(t, 0, exitCode)
+		assert.Equal(t, "#SKUKOZH-LIST v2\n#FILTERS -ext=go,!_test.go -max-depth=3\n", ReadTestFile(t, filepath.Join(mine, ".skukozh", "presets", "go-code.txt")))
+
+		writeTestFiles(t, mine, map[string]string{"billing/refund.go": "package billing\n"})
+		output := CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, mine, "-list-file", listFile, "preset", "use", "go-code")
+		})
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "Found 4 files.")
+		assert.Equal(t, fileListText(t, mine, "-ext=go,!_test.go -max-depth=3", "auth/login.go", "auth/token.go", "billing/pay.go", "billing/refund.go"), ReadTestFile(t, listFile))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		var exitCode int
+		output := CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, mine, "preset", "list")
+		})
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "auth-context  3 files")
+		assert.Contains(t, output, "go-code       find -ext=go,!_test.go -max-depth=3")
+
+		output = CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, theirs, "preset", "list", t.TempDir())
+		})
+		require.Equal(t, 0, exitCode)
+		assert.Contains(t, output, "No presets in")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		var exitCode int
+		output := CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, mine, "preset", "use", "missing")
+		})
+		assert.Equal(t, 1, exitCode)
+		assert.Contains(t, output, `unknown preset "missing"`)
+
+		output = CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, mine, "-list-file", listFile, "preset", "save", "../escape")
+		})
+		assert.Equal(t, 1, exitCode)
+		assert.Contains(t, output, `invalid preset name "../escape"`)
+
+		output = CaptureOutput(t, func() {
+			exitCode = runCommandIn(t, mine, "preset", "remove", "auth-context")
+		})
+		assert.Equal(t, 1, exitCode)
+		assert.Contains(t, output, "Usage:")
+	})
+}
+
+func TestSplitFilters(t *testing.T) {
+	args, err := splitFilters(`-ext=go,md -grep="func  main" -hidden -- src docs`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-ext=go,md", "-grep=func  main", "-hidden", "--", "src", "docs"}, args)
+
+	_, err = splitFilters(`-grep="unterminated`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A processor command taking longer than this on one file fails gen
+const processorTimeout = time.Minute
+
+// processor transforms the content of a file section in gen. Processors run
+// in the order given with -processors, after the built-in transforms and
+// before blank lines are removed.
+type processor interface {
+	// applies reports whether the processor handles the file
+	applies(file string) bool
+	// process returns the transformed content of the file
+	process(file, language string, content []byte) ([]byte, error)
+}
+
+// processorConfig registers an external processor in the config file
+type processorConfig struct {
+	Command []string `json:"command"` // program and arguments, run without a shell
+	Files   []string `json:"files"`   // path patterns as for -priority; empty matches every file
+}
+
+// commandProcessor runs an external command for each file. The command
+// reads the content on stdin and writes the transformed content to stdout;
+// SKUKOZH_FILE and SKUKOZH_LANGUAGE hold the path and fence language of the
+// file. A non-zero exit status fails gen.
+type commandProcessor struct {
+	Name string
+	processorConfig
+}
+
+func (p commandProcessor) applies(file string) bool {
+	if len(p.Files) == 0 {
+		return true
+	}
+	for _, pattern := range p.Files {
+		if matchPriority(file, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p commandProcessor) process(file, language string, content []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), processorTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Env = append(os.Environ(), "SKUKOZH_FILE="+file, "SKUKOZH_LANGUAGE="+language)
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", processorTimeout)
+		}
+		return nil, fmt.Errorf("processor %s failed on %s: %v: %s", p.Name, file, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// resolveProcessors looks up the processors named by -processors in the
+// config file
+func resolveProcessors(names []string, cfg config) ([]processor, error) {
+	var processors []processor
+	for _, name := range names {
+		p, ok := cfg.Processors[name]
+		if !ok {
+			if len(cfg.Processors) == 0 {
+				return nil, fmt.Errorf("unknown processor %q (register it under \"processors\" in the config file)", name)
+			}
+			return nil, fmt.Errorf("unknown processor %q (available: %s)", name, processorNames(cfg))
+		}
+		if len(p.Command) == 0 {
+			return nil, fmt.Errorf("processor %q has no command", name)
+		}
+		processors = append(processors, commandProcessor{Name: name, processorConfig: p})
+	}
+	return processors, nil
+}
+
+// processorsFromFlags returns the processors selected with -processors
+func processorsFromFlags(fs *flag.FlagSet) ([]processor, error) {
+	names := splitList(fs.Lookup("processors").Value.String())
+	if len(names) == 0 {
+		return nil, nil
+	}
+	cfg, err := configFromFlags(fs)
+	if err != nil {
+		return nil, err
+	}
+	return resolveProcessors(names, cfg)
+}
+
+// runProcessors passes the content of a file through the processors that
+// apply to it
+func runProcessors(processors []processor, file string, content []byte) ([]byte, error) {
+	for _, p := range processors {
+		if !p.applies(file) {
+			continue
+		}
+		processed, err := p.process(file, fenceLanguage(file, content), content)
+		if err != nil {
+			return nil, err
+		}
+		content = processed
+	}
+	return content, nil
+}
+
+// processorNames lists the processors registered in the config file
+func processorNames(cfg config) string {
+	var names []string
+	for name := range cfg.Processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
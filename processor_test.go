@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upperProcessor is an in-process processor for tests
+type upperProcessor struct{}
+
+func (upperProcessor) applies(file string) bool { return filepath.Ext(file) == ".txt" }
+
+func (upperProcessor) process(file, language string, content []byte) ([]byte, error) {
+	return bytes.ToUpper(content), nil
+}
+
+func requireShell(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+}
+
+func TestResolveProcessors(t *testing.T) {
+	cfg := config{Processors: map[string]processorConfig{
+		"upper": {Command: []string{"tr", "a-z", "A-Z"}},
+		"empty": {},
+	}}
+
+	processors, err := resolveProcessors([]string{"upper"}, cfg)
+	require.NoError(t, err)
+	require.Len(t, processors, 1)
+
+	_, err = resolveProcessors([]string{"missing"}, cfg)
+	assert.EqualError(t, err, `unknown processor "missing" (available: empty, upper)`)
+	_, err = resolveProcessors([]string{"empty"}, cfg)
+	assert.EqualError(t, err, `processor "empty" has no command`)
+	_, err = resolveProcessors([]string{"upper"}, config{})
+	assert.EqualError(t, err, `unknown processor "upper" (register it under "processors" in the config file)`)
+}
+
+func TestRunProcessors(t *testing.T) {
+	requireShell(t)
+	processors := []processor{
+		upperProcessor{},
+		commandProcessor{Name: "tag", processorConfig: processorConfig{
+			Command: []string{"sh", "-c", `cat; echo "# $SKUKOZH_FILE $SKUKOZH_LANGUAGE"`},
+			Files:   []string{"*.go"},
+		}},
+	}
+
+	content, err := runProcessors(processors, "notes.txt", []byte("hello\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO\n", string(content))
+
+	content, err = runProcessors(processors, "cmd/main.go", []byte("package main\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n# cmd/main.go go\n", string(content))
+
+	failing := []processor{commandProcessor{Name: "broken", processorConfig: processorConfig{
+		Command: []string{"sh", "-c", "echo oops >&2; exit 3"},
+	}}}
+	_, err = runProcessors(failing, "main.go", []byte("package main\n"))
+	assert.EqualError(t, err, "processor broken failed on main.go: exit status 3: oops")
+}
+
+func TestGenCommandProcessors(t *testing.T) {
+	requireShell(t)
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(testDir, "skukozh.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{
+  "processors": {
+    "shout": {"command": ["tr", "a-z", "A-Z"], "files": ["*.txt"]}
+  }
+}`), 0644))
+	require.NoError(t, os.WriteFile(fileListName, []byte("file1.go\nfile5.txt"), 0644))
+	defer os.Remove(fileListName)
+	defer os.Remove(resultName)
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"gen", "-config", configPath, "-processors", "shout", testDir}))
+	var exitCode int
+	CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 0, exitCode)
+	result := ReadTestFile(t, resultName)
+	assert.Contains(t, result, "SOME TEXT CONTENT\nWITH BLANK LINES")
+	assert.Contains(t, result, "package main\nfunc main() {", "Files not matching the processor stay untouched")
+
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"gen", "-config", configPath, "-processors", "whisper", testDir}))
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "Error: unknown processor \"whisper\" (available: shout)\n", output)
+}
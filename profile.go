@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// profile is a named set of find filters selected with -profile
+type profile struct {
+	Ext    string   `json:"ext"`     // like -ext, used when -ext isn't given
+	NotExt string   `json:"not_ext"` // like -not-ext, added to -not-ext
+	Ignore []string `json:"ignore"`  // .gitignore-style patterns, relative to the directory
+}
+
+// builtinProfiles are available without a config file. Profiles of the
+// same name in the config file replace them.
+var builtinProfiles = map[string]profile{
+	"frontend": {
+		Ext:    "js,jsx,mjs,cjs,ts,tsx,vue,svelte,html,htm,css,scss,sass,less,json",
+		NotExt: ".min.js,.min.css,.map",
+		Ignore: []string{"coverage/", ".next/", ".nuxt/", ".svelte-kit/", "storybook-static/", "package-lock.json", "yarn.lock", "pnpm-lock.yaml"},
+	},
+	"backend": {
+		Ext:    "go,py,rb,php,java,kt,kts,cs,rs,scala,c,h,cpp,hpp,sql,proto",
+		Ignore: []string{"testdata/", "fixtures/", "__pycache__/", "*.pb.go", "*_pb2.py"},
+	},
+	"docs-only": {
+		Ext: "md,rst,adoc,txt",
+	},
+	"minimal": {
+		NotExt: "_test.go,.test.js,.test.ts,.spec.js,.spec.ts,.min.js,.min.css,.snap,.lock",
+		Ignore: []string{
+			"test/", "tests/", "__tests__/", "spec/", "testdata/", "fixtures/", "examples/", "docs/",
+			"package-lock.json", "pnpm-lock.yaml", "go.sum",
+		},
+	},
+}
+
+// resolveProfile looks up a profile in the config file, then among the
+// built-in profiles
+func resolveProfile(name string, cfg config) (profile, error) {
+	if p, ok := cfg.Profiles[name]; ok {
+		return p, nil
+	}
+	if p, ok := builtinProfiles[name]; ok {
+		return p, nil
+	}
+	return profile{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(profileNames(cfg), ", "))
+}
+
+// profileNames returns the names of the built-in and configured profiles
+func profileNames(cfg config) []string {
+	var names []string
+	for name := range builtinProfiles {
+		names = append(names, name)
+	}
+	for name := range cfg.Profiles {
+		if _, ok := builtinProfiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rules parses the ignore patterns of the profile
+func (p profile) rules(name string) []gitignoreRule {
+	var rules []gitignoreRule
+	for i, pattern := range p.Ignore {
+		if rule, ok := parseGitignoreLine(pattern); ok {
+			rule.line = i + 1
+			rule.source = "profile " + name
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// profileFromFlags returns the profile selected with -profile, if any
+func profileFromFlags(fs *flag.FlagSet) (string, profile, error) {
+	name := fs.Lookup("profile").Value.String()
+	if name == "" {
+		return "", profile{}, nil
+	}
+	cfg, err := configFromFlags(fs)
+	if err != nil {
+		return "", profile{}, err
+	}
+	p, err := resolveProfile(name, cfg)
+	return name, p, err
+}
+
+// applyProfileFlags merges the extension filters of the -profile into the
+// -ext and -not-ext flags. An explicit -ext takes precedence over the
+// profile's extensions.
+func applyProfileFlags(fs *flag.FlagSet) error {
+	_, p, err := profileFromFlags(fs)
+	if err != nil {
+		return err
+	}
+	if p.Ext != "" && fs.Lookup("ext").Value.String() == "" {
+		if err := fs.Set("ext", p.Ext); err != nil {
+			return err
+		}
+	}
+	if p.NotExt != "" {
+		notExt := p.NotExt
+		if current := fs.Lookup("not-ext").Value.String(); current != "" {
+			notExt = current + "," + notExt
+		}
+		if err := fs.Set("not-ext", notExt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
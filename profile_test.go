@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "team.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"profiles": {"api": {"ext": "go", "not_ext": "_test.go", "ignore": ["internal/"]}}}`), 0644))
+
+	cfg, err := loadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, profile{Ext: "go", NotExt: "_test.go", Ignore: []string{"internal/"}}, cfg.Profiles["api"])
+
+	// The default config file is optional, an explicit one isn't
+	cfg, err = loadConfig("")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Profiles)
+	_, err = loadConfig(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"profiles": [}`), 0644))
+	_, err = loadConfig(configPath)
+	assert.ErrorContains(t, err, "invalid config file")
+}
+
+func TestResolveProfile(t *testing.T) {
+	cfg := config{Profiles: map[string]profile{
+		"docs-only": {Ext: "md"},
+		"api":       {Ext: "go"},
+	}}
+
+	p, err := resolveProfile("docs-only", cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "md", p.Ext, "config profiles replace built-in ones")
+
+	p, err = resolveProfile("frontend", cfg)
+	require.NoError(t, err)
+	assert.Equal(t, builtinProfiles["frontend"], p)
+
+	_, err = resolveProfile("nope", cfg)
+	assert.EqualError(t, err, `unknown profile "nope" (available: api, backend, docs-only, frontend, minimal)`)
+}
+
+func TestApplyProfileFlags(t *testing.T) {
+	fs := DefaultFlags()
+	require.NoError(t, fs.Parse([]string{"-profile", "frontend", "-not-ext", ".d.ts"}))
+	require.NoError(t, applyProfileFlags(fs))
+	assert.Equal(t, builtinProfiles["frontend"].Ext, fs.Lookup("ext").Value.String())
+	assert.Equal(t, ".d.ts,"+builtinProfiles["frontend"].NotExt, fs.Lookup("not-ext").Value.String())
+
+	// An explicit -ext wins over the profile
+	fs = DefaultFlags()
+	require.NoError(t, fs.Parse([]string{"-profile", "frontend", "-ext", "vue"}))
+	require.NoError(t, applyProfileFlags(fs))
+	assert.Equal(t, "vue", fs.Lookup("ext").Value.String())
+
+	fs = DefaultFlags()
+	require.NoError(t, fs.Parse([]string{"-profile", "nope"}))
+	assert.ErrorContains(t, applyProfileFlags(fs), `unknown profile "nope"`)
+}
+
+func TestFindWithProfile(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":           "package main\n",
+		"main_test.go":      "package main\n",
+		"internal/x.go":     "package internal\n",
+		"internal/Gen/y.go": "package gen\n",
+		"README.md":         "# Readme\n",
+		"app.js":            "console.log(1)\n",
+	})
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"profiles": {"api": {"ext": "go", "not_ext": "_test.go", "ignore": ["internal/gen/"]}}}`), 0644))
+
+	fs := DefaultFlags()
+	require.NoError(t, fs.Parse([]string{"-profile", "api", "-config", configPath, "-case", "insensitive"}))
+	require.NoError(t, applyProfileFlags(fs))
+	opts, err := findOptionsFromFlags(fs)
+	require.NoError(t, err)
+	include, exclude := extFiltersFromFlags(fs)
+	opts.excludedExts = exclude
+
+	skipped := make(map[string]string)
+	opts.onSkip = func(path, reason string) { skipped[path] = reason }
+	files, err := findFilesWithOptions(testDir, include, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"internal/x.go", "main.go"}, files)
+	assert.Equal(t, "path ignored by profile api (internal/gen/)", skipped["internal/Gen"])
+	assert.Equal(t, "profile", skipCategory(skipped["internal/Gen"]))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// promptText returns the instruction given inline or read from a file, with
+// trailing newlines removed. Giving both is an error.
+func promptText(text, file, name string) (string, error) {
+	if text != "" && file != "" {
+		return "", fmt.Errorf("use either -%s or -%s-file, not both", name, name)
+	}
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("reading -%s-file: %w", name, err)
+		}
+		text = string(content)
+	}
+	return strings.TrimRight(text, "\r\n"), nil
+}
+
+// promptPrefix returns the instruction block placed before the bundle body,
+// separated from it by a blank line
+func promptPrefix(opts genOptions) (string, error) {
+	prefix, err := promptText(opts.prompt, opts.promptFile, "prompt")
+	if err != nil || prefix == "" {
+		return "", err
+	}
+	return prefix + "\n\n", nil
+}
+
+// addPrompts puts the instruction block before the bundle body and the
+// closing instruction after it
+func addPrompts(body string, opts genOptions) (string, error) {
+	prefix, err := promptPrefix(opts)
+	if err != nil {
+		return "", err
+	}
+	suffix, err := promptText(opts.promptSuffix, opts.promptSuffixFile, "prompt-suffix")
+	if err != nil {
+		return "", err
+	}
+
+	body = prefix + body
+	if suffix != "" {
+		body += suffix + "\n"
+	}
+	return body, nil
+}
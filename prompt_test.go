@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateContentFilePrompts(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{"main.go": "package main\n"})
+	require.NoError(t, os.WriteFile(fileListName, []byte("main.go"), 0644))
+	defer os.Remove(fileListName)
+
+	promptFile := filepath.Join(t.TempDir(), "prompt.md")
+	require.NoError(t, os.WriteFile(promptFile, []byte("Review this code for bugs.\n\n"), 0644))
+
+	content, err := generateContentFileInternal(testDir, genOptions{
+		promptFile:   promptFile,
+		promptSuffix: "Answer in English.",
+		checksum:     true,
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(content, "Review this code for bugs.\n\n#FILE main.go\n"), content)
+	assert.Contains(t, content, "#END\n\nAnswer in English.\n#CHECKSUMS\n")
+
+	// The prompts are part of the checksummed body
+	report, problems, err := verifyBundle(content, testDir)
+	require.NoError(t, err, report)
+	assert.Zero(t, problems, report)
+
+	t.Run("Inline and file prompt together", func(t *testing.T) {
+		_, err := generateContentFileInternal(testDir, genOptions{prompt: "a", promptFile: promptFile})
+		assert.ErrorContains(t, err, "use either -prompt or -prompt-file")
+	})
+
+	t.Run("Missing prompt file", func(t *testing.T) {
+		_, err := generateContentFileInternal(testDir, genOptions{promptSuffixFile: filepath.Join(testDir, "missing.md")})
+		assert.ErrorContains(t, err, "reading -prompt-suffix-file")
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Built-in prompt templates, one file per prompt named after it
+//
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// Extension of prompt template files
+const promptExt = ".tmpl"
+
+// promptDescriptionComment matches the template comment starting a prompt
+// template, which describes it in the prompts list
+var promptDescriptionComment = regexp.MustCompile(`^\{\{/\*\s*(.*?)\s*\*/\}\}`)
+
+// promptTemplate is a prompt of the library
+type promptTemplate struct {
+	name   string
+	source string // "built-in" or the path of the user's file
+	text   string
+}
+
+// promptTemplateData holds the fields available to prompt templates
+type promptTemplateData struct {
+	Question string // text given after the prompt name, empty if none
+}
+
+// description returns the text of the comment starting the template
+func (p promptTemplate) description() string {
+	if match := promptDescriptionComment.FindStringSubmatch(p.text); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// userPromptDir returns the directory holding the user's prompt templates,
+// e.g. ~/.config/skukozh/prompts on Linux
+func userPromptDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "skukozh", "prompts"), nil
+}
+
+// loadPrompts returns the built-in prompts and the user's, which replace
+// built-in prompts of the same name
+func loadPrompts() (map[string]promptTemplate, error) {
+	prompts := make(map[string]promptTemplate)
+	entries, err := builtinPrompts.ReadDir("prompts")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		content, err := builtinPrompts.ReadFile("prompts/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(entry.Name(), promptExt)
+		prompts[name] = promptTemplate{name: name, source: "built-in", text: string(content)}
+	}
+
+	dir, err := userPromptDir()
+	if err != nil {
+		return prompts, nil // No config directory, so no user prompts
+	}
+	entries, err = os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return prompts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != promptExt {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(entry.Name(), promptExt)
+		prompts[name] = promptTemplate{name: name, source: path, text: string(content)}
+	}
+	return prompts, nil
+}
+
+// promptNames lists the names of the prompts
+func promptNames(prompts map[string]promptTemplate) []string {
+	names := make([]string, 0, len(prompts))
+	for name := range prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findPrompt looks up a prompt of the library by name
+func findPrompt(name string) (promptTemplate, error) {
+	prompts, err := loadPrompts()
+	if err != nil {
+		return promptTemplate{}, err
+	}
+	prompt, ok := prompts[name]
+	if !ok {
+		return prompt, fmt.Errorf("unknown prompt %q (available: %s)", name, strings.Join(promptNames(prompts), ", "))
+	}
+	return prompt, nil
+}
+
+// renderPrompt renders the named prompt with the question given with it
+func renderPrompt(name, question string) (string, error) {
+	prompt, err := findPrompt(name)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(prompt.text)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt %s (%s): %w", name, prompt.source, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, promptTemplateData{Question: question}); err != nil {
+		return "", fmt.Errorf("rendering prompt %s: %w", name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// showPrompts lists the prompts of the library, or prints the template of
+// one so it can be copied and adapted, and returns the exit code
+func showPrompts(name string) int {
+	if name != "" {
+		prompt, err := findPrompt(name)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Print(prompt.text)
+		return 0
+	}
+
+	prompts, err := loadPrompts()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range promptNames(prompts) {
+		prompt := prompts[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, prompt.description(), prompt.source)
+	}
+	w.Flush()
+	if dir, err := userPromptDir(); err == nil {
+		statusf("\nAdd your own as <name>%s files in %s\n", promptExt, dir)
+	}
+	return 0
+}
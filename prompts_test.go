@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setUserPromptDir points the user's config directory to a temporary one
+// and returns its prompt directory
+func setUserPromptDir(t *testing.T) string {
+	t.Helper()
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir) // macOS derives the config directory from HOME
+	dir, err := userPromptDir()
+	require.NoError(t, err)
+	return dir
+}
+
+func TestBuiltinPrompts(t *testing.T) {
+	setUserPromptDir(t)
+	prompts, err := loadPrompts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"architecture-summary", "bug-hunt", "code-review", "test-generation"}, promptNames(prompts))
+
+	for name, prompt := range prompts {
+		assert.Equal(t, "built-in", prompt.source)
+		assert.NotEmpty(t, prompt.description(), name)
+
+		// Every template renders with and without a question
+		plain, err := renderPrompt(name, "")
+		require.NoError(t, err, name)
+		assert.NotContains(t, plain, "{{", name)
+		focused, err := renderPrompt(name, "the cache layer")
+		require.NoError(t, err, name)
+		assert.True(t, strings.HasSuffix(focused, "the cache layer"), name)
+		assert.True(t, strings.HasPrefix(focused, plain), name)
+	}
+}
+
+func TestUserPrompts(t *testing.T) {
+	dir := setUserPromptDir(t)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	writeTestFiles(t, dir, map[string]string{
+		"code-review.tmpl": "{{/* Our team's review checklist */}}\nCheck our conventions.{{if .Question}} {{.Question}}{{end}}\n",
+		"migration.tmpl":   "Plan the migration to {{.Question}}.",
+		"notes.txt":        "not a prompt",
+	})
+
+	prompts, err := loadPrompts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"architecture-summary", "bug-hunt", "code-review", "migration", "test-generation"}, promptNames(prompts))
+	assert.Equal(t, filepath.Join(dir, "code-review.tmpl"), prompts["code-review"].source)
+	assert.Equal(t, "Our team's review checklist", prompts["code-review"].description())
+	assert.Empty(t, prompts["migration"].description())
+
+	rendered, err := renderPrompt("code-review", "Mind the SQL.")
+	require.NoError(t, err)
+	assert.Equal(t, "Check our conventions. Mind the SQL.", rendered)
+
+	_, err = renderPrompt("security-audit", "")
+	assert.EqualError(t, err, "unknown prompt \"security-audit\" (available: architecture-summary, bug-hunt, code-review, migration, test-generation)")
+
+	writeTestFiles(t, dir, map[string]string{"broken.tmpl": "{{.Questoin}}"})
+	_, err = renderPrompt("broken", "")
+	assert.ErrorContains(t, err, "rendering prompt broken:")
+}
+
+func TestPromptsCommand(t *testing.T) {
+	dir := setUserPromptDir(t)
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, t.TempDir(), "prompts")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Contains(t, output, "bug-hunt              Hunt for bugs, race conditions and edge cases that break at runtime    built-in\n")
+	assert.Contains(t, output, "Add your own as <name>.tmpl files in "+dir+"\n")
+
+	output = CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, t.TempDir(), "prompts", "code-review")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.True(t, strings.HasPrefix(output, "{{/* Review the code"), "the template is printed as is")
+}
+
+func TestAskWithPrompt(t *testing.T) {
+	setUserPromptDir(t)
+	testDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, resultName), []byte(testBundle("main.go", "package main")), 0644))
+
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"choices": [{"message": {"content": "No bugs found."}}]}`))
+	}))
+	defer server.Close()
+
+	// The question is optional with a prompt
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "ask", "-base-url", server.URL, "-prompt", "bug-hunt")
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Equal(t, "No bugs found.\n", output)
+
+	expected, err := renderPrompt("bug-hunt", "")
+	require.NoError(t, err)
+	content := request["messages"].([]any)[0].(map[string]any)["content"].(string)
+	assert.True(t, strings.HasSuffix(content, "#END\n\n"+expected))
+
+	output = CaptureOutput(t, func() {
+		exitCode = runCommandIn(t, testDir, "ask", "-base-url", server.URL, "-prompt", "nope")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, `Error: unknown prompt "nope"`)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Input read by gen -review, replaced in tests
+var reviewInput io.Reader = os.Stdin
+
+// Number of lines shown when previewing a file during review
+const reviewPreviewLines = 20
+
+// reviewFileList asks for every file of the file list whether to keep it.
+// The file list is rewritten after each answer, so an interrupted review
+// keeps the decisions made so far. Files that weren't reviewed yet stay in
+// the list, and the entries keep their annotations. Included lists aren't
+// reviewed and stay in the list.
+func reviewFileList(baseDir string, in io.Reader, out io.Writer) ([]string, error) {
+	content, err := os.ReadFile(fileListName)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseFileListEntries(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file list %s: %w", fileListName, err)
+	}
+	_, filters := parseFileListHeader(content)
+	var reviewed []int
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		if entry.Include == "" {
+			reviewed = append(reviewed, i)
+		}
+		lines[i] = formatFileListEntry(entry)
+	}
+
+	dropped := make([]bool, len(entries))
+	reader := bufio.NewReader(in)
+	var kept []string
+	for n := 0; n < len(reviewed); n++ {
+		i := reviewed[n]
+		file := entries[i].Path
+		fileContent, err := os.ReadFile(filepath.Join(baseDir, entryPath(file)))
+		if err != nil {
+			fmt.Fprintf(out, "[%d/%d] %s (unreadable: %v)\n", n+1, len(reviewed), file, err)
+		} else {
+			fmt.Fprintf(out, "[%d/%d] %s (%d bytes, ~%s tokens)\n", n+1, len(reviewed), file, len(fileContent), formatTokens(estimateTokens(string(fileContent))))
+		}
+
+		answer := reviewAnswer(reader, out, fileContent)
+		switch answer {
+		case "y":
+			kept = append(kept, file)
+		case "a":
+			for _, j := range reviewed[n:] {
+				kept = append(kept, entries[j].Path)
+			}
+			n = len(reviewed)
+		case "q":
+			for _, j := range reviewed[n:] {
+				dropped[j] = true
+			}
+			n = len(reviewed)
+		default:
+			dropped[i] = true
+		}
+
+		var listed []string
+		for j, line := range lines {
+			if !dropped[j] {
+				listed = append(listed, line)
+			}
+		}
+		if err := writeFileListLines(baseDir, listed, filters); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Fprintf(out, "Kept %d of %d files. File list saved to %s\n", len(kept), len(reviewed), fileListName)
+	return kept, nil
+}
+
+// reviewAnswer prompts until it reads a decision: y (include), n (skip),
+// a (include this and all remaining files) or q (skip this and all remaining
+// files). p previews the file and asks again. The end of the input accepts
+// the remaining files.
+func reviewAnswer(reader *bufio.Reader, out io.Writer, content []byte) string {
+	for {
+		fmt.Fprint(out, "Include? [Y]es/[n]o/[p]review/[a]ll remaining/[q]uit: ")
+		line, err := reader.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if err != nil && answer == "" {
+			fmt.Fprintln(out)
+			return "a"
+		}
+
+		switch answer {
+		case "", "y", "yes":
+			return "y"
+		case "n", "no":
+			return "n"
+		case "a", "all":
+			return "a"
+		case "q", "quit":
+			return "q"
+		case "p", "preview":
+			lines := strings.Split(string(content), "\n")
+			if len(lines) > reviewPreviewLines {
+				lines = append(lines[:reviewPreviewLines], fmt.Sprintf("... (%d more lines)", len(lines)-reviewPreviewLines))
+			}
+			fmt.Fprintln(out, strings.Join(lines, "\n"))
+		default:
+			fmt.Fprintf(out, "Unknown answer %q\n", answer)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewFileList(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"a.go": "package a\n",
+		"b.go": "package b\n",
+		"c.go": "package c\n",
+		"d.go": "package d\n",
+	})
+	defer os.Remove(fileListName)
+
+	writeList := func() {
+		require.NoError(t, os.WriteFile(fileListName, []byte("a.go\nb.go\nc.go\nd.go"), 0644))
+	}
+
+	t.Run("Answers per file", func(t *testing.T) {
+		writeList()
+		var out bytes.Buffer
+		kept, err := reviewFileList(testDir, strings.NewReader("\nn\np\ny\nq\n"), &out)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.go", "c.go"}, kept)
+		assert.Equal(t, fileListText(t, testDir, "", "a.go", "c.go"), ReadTestFile(t, fileListName))
+		assert.Contains(t, out.String(), "[1/4] a.go (10 bytes, ~3 tokens)")
+		assert.Contains(t, out.String(), "package c\n")
+		assert.Contains(t, out.String(), "Kept 2 of 4 files.")
+	})
+
+	t.Run("Annotations and filters are kept", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(fileListName, []byte("#FILTERS -ext=go\na.go priority=2\n# skip?\nb.go lines=1-1\nc.go\n"), 0644))
+		kept, err := reviewFileList(testDir, strings.NewReader("y\nn\n"), &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.go", "c.go"}, kept)
+		assert.Equal(t, fileListText(t, testDir, "-ext=go", "a.go priority=2", "c.go"), ReadTestFile(t, fileListName))
+	})
+
+	t.Run("Included lists stay", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(fileListName, []byte("a.go\n@core.txt priority=1\nb.go\n"), 0644))
+		var out bytes.Buffer
+		kept, err := reviewFileList(testDir, strings.NewReader("n\ny\n"), &out)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b.go"}, kept)
+		assert.Equal(t, fileListText(t, testDir, "", "@core.txt priority=1", "b.go"), ReadTestFile(t, fileListName))
+		assert.Contains(t, out.String(), "[2/2] b.go")
+	})
+
+	t.Run("All remaining", func(t *testing.T) {
+		writeList()
+		kept, err := reviewFileList(testDir, strings.NewReader("n\na\n"), &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b.go", "c.go", "d.go"}, kept)
+	})
+
+	t.Run("End of input keeps the rest", func(t *testing.T) {
+		writeList()
+		kept, err := reviewFileList(testDir, strings.NewReader("n\n"), &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b.go", "c.go", "d.go"}, kept)
+		assert.Equal(t, fileListText(t, testDir, "", "b.go", "c.go", "d.go"), ReadTestFile(t, fileListName))
+	})
+
+	t.Run("Unknown answers ask again", func(t *testing.T) {
+		writeList()
+		var out bytes.Buffer
+		kept, err := reviewFileList(testDir, strings.NewReader("maybe\nq\n"), &out)
+		require.NoError(t, err)
+		assert.Empty(t, kept)
+		assert.Contains(t, out.String(), `Unknown answer "maybe"`)
+	})
+}
+
+func TestGenerateContentFileReview(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	require.NoError(t, os.WriteFile(fileListName, []byte("a.go\nb.go"), 0644))
+	defer os.Remove(fileListName)
+	defer os.Remove(resultName)
+
+	origInput := reviewInput
+	reviewInput = strings.NewReader("n\ny\n")
+	defer func() { reviewInput = origInput }()
+
+	CaptureOutput(t, func() {
+		generateContentFile(testDir, genOptions{review: true})
+	})
+
+	content := ReadTestFile(t, resultName)
+	assert.NotContains(t, content, "#FILE a.go")
+	assert.Contains(t, content, "#FILE b.go")
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// isRootManifest reports whether a file is included by -root-files: a
+// manifest of one of the stacks recognized by -detect, such as go.mod,
+// package.json or Cargo.toml, at the top of the directory
+func isRootManifest(relPath string) bool {
+	if strings.Contains(relPath, "/") {
+		return false
+	}
+	name := strings.ToLower(relPath)
+	for _, s := range stacks {
+		for _, pattern := range s.manifests {
+			if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
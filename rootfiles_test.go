@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRootManifest(t *testing.T) {
+	for _, file := range []string{"go.mod", "package.json", "Cargo.toml", "composer.json", "requirements.txt", "Gemfile", "app.gemspec", "App.csproj", "pom.xml"} {
+		assert.True(t, isRootManifest(file), file)
+	}
+	for _, file := range []string{"web/package.json", "main.go", "package-lock.json", "config/application.rb"} {
+		assert.False(t, isRootManifest(file), file)
+	}
+}
+
+func TestFindRootFiles(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":          "package main\n",
+		"go.mod":           "module app\n",
+		"package.json":     "{}\n",
+		"requirements.txt": "requests\n",
+		"web/package.json": "{}\n",
+		"notes.txt":        "Notes\n",
+	})
+
+	fs := flagSetWith(t, "-root-files", "-ext", "go")
+	opts, err := findOptionsFromFlags(fs)
+	require.NoError(t, err)
+
+	files, err := findFilesWithOptions(testDir, []string{".go"}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go.mod", "main.go", "package.json", "requirements.txt"}, files)
+
+	included, reason, err := explainPath(testDir, "package.json", []string{".go"}, opts)
+	require.NoError(t, err)
+	assert.True(t, included)
+	assert.Equal(t, "manifest at the top included by -root-files", reason)
+
+	// Without the flag the extension filter applies to manifests as well
+	files, err = findFilesInternal(testDir, []string{".go"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, files)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Extensions of the data files sampled by -sample-rows, mapped to whether
+// their first row is a header
+var dataFileHeaders = map[string]bool{
+	".csv":    true,
+	".tsv":    true,
+	".jsonl":  false,
+	".ndjson": false,
+}
+
+// splitRecords splits data file content into records, one per line except
+// for CSV fields quoted across lines, which stay in one record
+func splitRecords(content string, quoted bool) []string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if !quoted {
+		return lines
+	}
+	var records []string
+	var record []string
+	open := false
+	for _, line := range lines {
+		record = append(record, line)
+		if strings.Count(line, `"`)%2 == 1 {
+			open = !open
+		}
+		if !open {
+			records = append(records, strings.Join(record, "\n"))
+			record = nil
+		}
+	}
+	if len(record) > 0 {
+		records = append(records, strings.Join(record, "\n"))
+	}
+	return records
+}
+
+// sampleDataFile keeps the header row and the first and last rows rows of a
+// CSV, TSV or JSON Lines file, replacing the rows in between with a note
+// giving the row count. The second return value is false for other files
+// and for files with at most 2*rows rows.
+func sampleDataFile(file string, content []byte, rows int) ([]byte, bool) {
+	ext := strings.ToLower(path.Ext(file))
+	header, ok := dataFileHeaders[ext]
+	if !ok || rows <= 0 {
+		return content, false
+	}
+	records := splitRecords(string(content), ext == ".csv")
+	var kept []string
+	if header && len(records) > 0 {
+		kept, records = records[:1], records[1:]
+	}
+	if len(records) <= rows*2 {
+		return content, false
+	}
+
+	kept = append(kept, records[:rows]...)
+	kept = append(kept, fmt.Sprintf("... [sampled %d of %d rows, %d omitted] ...", rows*2, len(records), len(records)-rows*2))
+	kept = append(kept, records[len(records)-rows:]...)
+	return []byte(strings.Join(kept, "\n") + "\n"), true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleDataFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		rows    int
+		want    string
+		sampled bool
+	}{
+		{
+			name:    "csv keeps the header",
+			file:    "data/users.csv",
+			content: "id,name\n1,a\n2,b\n3,c\n4,d\n5,e\n",
+			rows:    1,
+			want:    "id,name\n1,a\n... [sampled 2 of 5 rows, 3 omitted] ...\n5,e\n",
+			sampled: true,
+		},
+		{
+			name:    "csv quoted newlines",
+			file:    "notes.CSV",
+			content: "id,note\n1,\"first\nline\"\n2,b\n3,c\n4,\"last\nline\"\n",
+			rows:    1,
+			want:    "id,note\n1,\"first\nline\"\n... [sampled 2 of 4 rows, 2 omitted] ...\n4,\"last\nline\"\n",
+			sampled: true,
+		},
+		{
+			name:    "tsv",
+			file:    "data.tsv",
+			content: "a\tb\n1\t2\n3\t4\n5\t6\n",
+			rows:    1,
+			want:    "a\tb\n1\t2\n... [sampled 2 of 3 rows, 1 omitted] ...\n5\t6\n",
+			sampled: true,
+		},
+		{
+			name:    "jsonl has no header",
+			file:    "events.jsonl",
+			content: "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n",
+			rows:    1,
+			want:    "{\"n\":1}\n... [sampled 2 of 3 rows, 1 omitted] ...\n{\"n\":3}\n",
+			sampled: true,
+		},
+		{
+			name:    "short file",
+			file:    "small.csv",
+			content: "id\n1\n2\n",
+			rows:    1,
+			want:    "id\n1\n2\n",
+		},
+		{
+			name:    "other file",
+			file:    "main.go",
+			content: "package main\n\nfunc main() {}\n",
+			rows:    1,
+			want:    "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:    "disabled",
+			file:    "events.jsonl",
+			content: "1\n2\n3\n",
+			rows:    0,
+			want:    "1\n2\n3\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, sampled := sampleDataFile(tc.file, []byte(tc.content), tc.rows)
+			assert.Equal(t, tc.want, string(got))
+			assert.Equal(t, tc.sampled, sampled)
+		})
+	}
+}
+
+func TestGenerateContentFileSampleRows(t *testing.T) {
+	testDir := t.TempDir()
+	var rows strings.Builder
+	rows.WriteString("id,value\n")
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&rows, "%d,%d\n", i, i*i)
+	}
+	writeTestFiles(t, testDir, map[string]string{"data.csv": rows.String()})
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("data.csv\ndata.csv:1-3"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{sampleRows: 2})
+	require.NoError(t, err)
+	assert.Contains(t, result, "id,value\n1,1\n2,4\n... [sampled 4 of 1000 rows, 996 omitted] ...\n999,998001\n1000,1000000\n")
+	// Line ranges select rows themselves
+	assert.Contains(t, result, "id,value\n1,1\n2,4\n```")
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Schema types accepted by -condense-schemas
+var condenseSchemaTypes = []string{"openapi", "proto"}
+
+// HTTP methods of the operations of an OpenAPI path item
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// parseCondenseSchemas validates a -condense-schemas value: a
+// comma-separated list of openapi and proto, or all
+func parseCondenseSchemas(value string) ([]string, error) {
+	var types []string
+	for _, item := range splitList(strings.ToLower(value)) {
+		switch {
+		case item == "all":
+			types = append(types, condenseSchemaTypes...)
+		case contains(condenseSchemaTypes, item):
+			types = append(types, item)
+		default:
+			return nil, fmt.Errorf("unknown -condense-schemas type %q (use %s or all)", item, strings.Join(condenseSchemaTypes, ", "))
+		}
+	}
+	return types, nil
+}
+
+// condenseSchema replaces an OpenAPI or Swagger document, or a .proto file,
+// with a summary of what it declares, when its type is among types. The
+// second return value is false for other files, and for documents without
+// operations, schemas or declarations.
+func condenseSchema(file string, content []byte, types []string) ([]byte, bool) {
+	switch ext := strings.ToLower(path.Ext(file)); {
+	case ext == ".proto" && contains(types, "proto"):
+		return condenseProto(content)
+	case (ext == ".yaml" || ext == ".yml" || ext == ".json") && contains(types, "openapi"):
+		return condenseOpenAPI(ext, content)
+	}
+	return content, false
+}
+
+// schemaEvent is a key of a YAML or JSON document, with its path from the
+// top and its scalar value, empty for keys holding a mapping or a list.
+// List items appear as "-" in the path.
+type schemaEvent struct {
+	path  []string
+	value string
+}
+
+// yamlKey matches a "key: value" line of a YAML mapping, with an optional
+// list item dash and a quoted or plain key
+var yamlKey = regexp.MustCompile(`^(- +)?("[^"]*"|'[^']*'|[^\s:'"#-][^:#]*?|-[^\s:#][^:#]*?)\s*:(?:\s+(.*))?$`)
+
+// yamlEvents walks the keys of a block-style YAML document by indentation,
+// which is enough for the nested mappings of an OpenAPI document. Flow
+// collections, anchors and multi-document files aren't followed; block
+// scalars are skipped.
+func yamlEvents(content []byte) []schemaEvent {
+	type level struct {
+		indent int
+		key    string
+	}
+	var stack []level
+	var events []schemaEvent
+	scalarIndent := -1 // indent of the key of the block scalar being skipped
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if scalarIndent >= 0 {
+			if trimmed == "" || indent > scalarIndent {
+				continue
+			}
+			scalarIndent = -1
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		match := yamlKey.FindStringSubmatch(trimmed)
+		if match == nil {
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if match[1] != "" {
+			stack = append(stack, level{indent, "-"})
+			indent += len(match[1])
+		}
+		key := strings.Trim(match[2], `"'`)
+		stack = append(stack, level{indent, key})
+
+		value := strings.TrimSpace(match[3])
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			scalarIndent, value = indent, ""
+		}
+		if hash := strings.Index(value, " #"); hash >= 0 {
+			value = strings.TrimSpace(value[:hash])
+		}
+		keys := make([]string, len(stack))
+		for i, l := range stack {
+			keys[i] = l.key
+		}
+		events = append(events, schemaEvent{path: keys, value: strings.Trim(value, `"'`)})
+	}
+	return events
+}
+
+// jsonEvents walks the keys of a JSON document in order
+func jsonEvents(content []byte) ([]schemaEvent, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var events []schemaEvent
+	// walk reads the value at keys, returning it if it is a scalar
+	var walk func(keys []string) (string, error)
+	walk = func(keys []string) (string, error) {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return "", err
+				}
+				child := append(append([]string{}, keys...), fmt.Sprint(key))
+				index := len(events)
+				events = append(events, schemaEvent{path: child})
+				if events[index].value, err = walk(child); err != nil {
+					return "", err
+				}
+			}
+		case json.Delim('['):
+			for decoder.More() {
+				if _, err := walk(append(append([]string{}, keys...), "-")); err != nil {
+					return "", err
+				}
+			}
+		default:
+			return fmt.Sprint(token), nil
+		}
+		_, err = decoder.Token()
+		return "", err
+	}
+	_, err := walk(nil)
+	return events, err
+}
+
+// openAPIOperation is an operation of an OpenAPI path
+type openAPIOperation struct {
+	method, path, summary, operationID string
+}
+
+// openAPISchema is a named schema of an OpenAPI document with its
+// properties and their types
+type openAPISchema struct {
+	name       string
+	properties []string
+	types      map[string]string
+}
+
+// condenseOpenAPI summarizes an OpenAPI 3 or Swagger 2 document: its title,
+// one line per operation with its summary and operation ID, and the
+// properties of its schemas or definitions
+func condenseOpenAPI(ext string, content []byte) ([]byte, bool) {
+	var events []schemaEvent
+	if ext == ".json" {
+		var err error
+		if events, err = jsonEvents(content); err != nil {
+			return content, false
+		}
+	} else {
+		events = yamlEvents(content)
+	}
+
+	var spec, version, title, apiVersion string
+	var operations []*openAPIOperation
+	byKey := make(map[string]*openAPIOperation)
+	var schemas []*openAPISchema
+	byName := make(map[string]*openAPISchema)
+	for _, event := range events {
+		p, value := event.path, event.value
+		switch {
+		case len(p) == 1 && (p[0] == "openapi" || p[0] == "swagger"):
+			spec, version = map[string]string{"openapi": "OpenAPI", "swagger": "Swagger"}[p[0]], value
+		case len(p) == 2 && p[0] == "info" && p[1] == "title":
+			title = value
+		case len(p) == 2 && p[0] == "info" && p[1] == "version":
+			apiVersion = value
+		case len(p) >= 3 && p[0] == "paths" && contains(openAPIMethods, p[2]):
+			key := p[1] + " " + p[2]
+			op, ok := byKey[key]
+			if !ok {
+				op = &openAPIOperation{method: strings.ToUpper(p[2]), path: p[1]}
+				byKey[key] = op
+				operations = append(operations, op)
+			}
+			if len(p) == 4 && p[3] == "summary" {
+				op.summary = value
+			} else if len(p) == 4 && p[3] == "operationId" {
+				op.operationID = value
+			}
+		default:
+			// Schemas live in components.schemas (OpenAPI 3) or definitions (Swagger 2)
+			var rest []string
+			if len(p) >= 3 && p[0] == "components" && p[1] == "schemas" {
+				rest = p[2:]
+			} else if len(p) >= 2 && p[0] == "definitions" {
+				rest = p[1:]
+			} else {
+				continue
+			}
+			schema, ok := byName[rest[0]]
+			if !ok {
+				schema = &openAPISchema{name: rest[0], types: make(map[string]string)}
+				byName[rest[0]] = schema
+				schemas = append(schemas, schema)
+			}
+			if len(rest) < 3 || rest[1] != "properties" {
+				continue
+			}
+			property := rest[2]
+			if _, ok := schema.types[property]; !ok {
+				schema.properties = append(schema.properties, property)
+				schema.types[property] = ""
+			}
+			switch {
+			case len(rest) == 4 && rest[3] == "type" && schema.types[property] == "":
+				schema.types[property] = value
+			case len(rest) == 4 && rest[3] == "$ref":
+				schema.types[property] = path.Base(value)
+			case len(rest) == 5 && rest[3] == "items" && (rest[4] == "type" || rest[4] == "$ref"):
+				schema.types[property] = "[]" + path.Base(value)
+			}
+		}
+	}
+	if spec == "" || len(operations) == 0 && len(schemas) == 0 {
+		return content, false
+	}
+
+	var out strings.Builder
+	comment := "#"
+	if ext == ".json" {
+		comment = "//"
+	}
+	fmt.Fprintf(&out, "%s %s %s summary, condensed by -condense-schemas", comment, spec, version)
+	if title != "" {
+		fmt.Fprintf(&out, ": %s", strings.TrimSpace(title+" "+apiVersion))
+	}
+	out.WriteString("\n")
+	for _, op := range operations {
+		line := op.method + " " + op.path
+		if op.summary != "" {
+			line += " - " + op.summary
+		}
+		if op.operationID != "" {
+			line += " [" + op.operationID + "]"
+		}
+		out.WriteString(line + "\n")
+	}
+	if len(schemas) > 0 {
+		out.WriteString("schemas:\n")
+	}
+	for _, schema := range schemas {
+		properties := make([]string, len(schema.properties))
+		for i, name := range schema.properties {
+			properties[i] = strings.TrimSpace(name + ": " + schema.types[name])
+			properties[i] = strings.TrimSuffix(properties[i], ":")
+		}
+		out.WriteString(strings.TrimSpace(schema.name+" {"+strings.Join(properties, ", ")+"}") + "\n")
+	}
+	return []byte(out.String()), true
+}
+
+// protoNode is a statement of a .proto file, or a block with the
+// statements inside its braces
+type protoNode struct {
+	header   string
+	block    bool
+	children []protoNode
+}
+
+// protoFieldNoise matches the field number and the options of a field or
+// enum value
+var protoFieldNoise = regexp.MustCompile(`\s*=\s*-?(?:0x[0-9a-fA-F]+|\d+)\s*(?:\[.*\])?\s*$`)
+
+// Statements left out of a condensed .proto file
+var protoDroppedStatements = []string{"import", "option", "reserved", "extensions"}
+
+// stripProtoComments removes the // and /* */ comments of a .proto file,
+// leaving strings alone
+func stripProtoComments(content []byte) string {
+	var out strings.Builder
+	s := string(content)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"' || s[i] == '\'':
+			end := i + 1
+			for end < len(s) && s[end] != s[i] && s[end] != '\n' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end, len(s)-1)
+			out.WriteString(s[i : end+1])
+			i = end
+		case strings.HasPrefix(s[i:], "//"):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			out.WriteString("\n")
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return out.String()
+			}
+			i += end + 3
+			out.WriteString(" ")
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String()
+}
+
+// parseProto splits .proto source without comments into statements and
+// blocks, returning the nodes and the rest after a closing brace
+func parseProto(s string) ([]protoNode, string) {
+	var nodes []protoNode
+	var text strings.Builder
+	for len(s) > 0 {
+		c := s[0]
+		switch c {
+		case '"', '\'':
+			end := strings.IndexByte(s[1:], c)
+			if end < 0 {
+				end = len(s) - 2
+			}
+			text.WriteString(s[:end+2])
+			s = s[end+2:]
+			continue
+		case ';':
+			if header := strings.Join(strings.Fields(text.String()), " "); header != "" {
+				nodes = append(nodes, protoNode{header: header})
+			}
+			text.Reset()
+		case '{':
+			var children []protoNode
+			children, s = parseProto(s[1:])
+			nodes = append(nodes, protoNode{header: strings.Join(strings.Fields(text.String()), " "), block: true, children: children})
+			text.Reset()
+			continue
+		case '}':
+			if header := strings.Join(strings.Fields(text.String()), " "); header != "" {
+				nodes = append(nodes, protoNode{header: header})
+			}
+			return nodes, s[1:]
+		default:
+			text.WriteByte(c)
+		}
+		s = s[1:]
+	}
+	if header := strings.Join(strings.Fields(text.String()), " "); header != "" {
+		nodes = append(nodes, protoNode{header: header})
+	}
+	return nodes, ""
+}
+
+// renderProtoNode renders a statement or block on one line, without field
+// numbers and options; rpc blocks, which only hold options, become plain
+// statements. It returns "" for the statements left out.
+func renderProtoNode(node protoNode) string {
+	keyword, _, _ := strings.Cut(node.header, " ")
+	if contains(protoDroppedStatements, keyword) || strings.HasPrefix(node.header, "option(") {
+		return ""
+	}
+	if !node.block || keyword == "rpc" {
+		return protoFieldNoise.ReplaceAllString(node.header, "") + ";"
+	}
+	var children []string
+	for _, child := range node.children {
+		if text := renderProtoNode(child); text != "" {
+			children = append(children, text)
+		}
+	}
+	if len(children) == 0 {
+		return node.header + " {}"
+	}
+	return node.header + " { " + strings.Join(children, " ") + " }"
+}
+
+// condenseProto summarizes a .proto file: the syntax and package
+// statements, then every message, enum and extension on one line and every
+// service with one line per rpc, without comments, imports, options and
+// field numbers
+func condenseProto(content []byte) ([]byte, bool) {
+	nodes, _ := parseProto(stripProtoComments(content))
+	var out strings.Builder
+	out.WriteString("// Condensed by -condense-schemas: comments, imports, options and field numbers left out\n")
+	declarations := 0
+	for _, node := range nodes {
+		keyword, _, _ := strings.Cut(node.header, " ")
+		switch {
+		case keyword == "service" && node.block:
+			out.WriteString(node.header + " {\n")
+			for _, child := range node.children {
+				if text := renderProtoNode(child); text != "" {
+					out.WriteString("  " + text + "\n")
+				}
+			}
+			out.WriteString("}\n")
+			declarations++
+		default:
+			if text := renderProtoNode(node); text != "" {
+				out.WriteString(text + "\n")
+				if node.block {
+					declarations++
+				}
+			}
+		}
+	}
+	if declarations == 0 {
+		return content, false
+	}
+	return []byte(out.String()), true
+}
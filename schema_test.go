@@ -0,0 +1,205 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const petStoreYAML = `openapi: 3.0.3
+info:
+  title: "Pet Store"
+  description: |
+    A sample API.
+    paths: not a key
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      summary: List all pets
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+    post:
+      summary: Create a pet # creates
+      operationId: createPet
+  "/pets/{petId}":
+    parameters:
+      - name: petId
+    delete:
+      operationId: deletePet
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        id:
+          type: integer
+        owner:
+          $ref: '#/components/schemas/User'
+        tags:
+          type: array
+          items:
+            type: string
+    User:
+      type: object
+`
+
+func TestParseCondenseSchemas(t *testing.T) {
+	types, err := parseCondenseSchemas("Proto")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"proto"}, types)
+
+	types, err = parseCondenseSchemas("all")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openapi", "proto"}, types)
+
+	_, err = parseCondenseSchemas("openapi,graphql")
+	assert.EqualError(t, err, `unknown -condense-schemas type "graphql" (use openapi, proto or all)`)
+}
+
+func TestCondenseOpenAPIYAML(t *testing.T) {
+	got, ok := condenseSchema("api/openapi.yaml", []byte(petStoreYAML), condenseSchemaTypes)
+	require.True(t, ok)
+	assert.Equal(t, "# OpenAPI 3.0.3 summary, condensed by -condense-schemas: Pet Store 1.0.0\n"+
+		"GET /pets - List all pets [listPets]\n"+
+		"POST /pets - Create a pet [createPet]\n"+
+		"DELETE /pets/{petId} [deletePet]\n"+
+		"schemas:\n"+
+		"Pet {id: integer, owner: User, tags: []string}\n"+
+		"User {}\n", string(got))
+}
+
+func TestCondenseSwaggerJSON(t *testing.T) {
+	content := `{
+  "swagger": "2.0",
+  "info": {"title": "Shop", "version": "2"},
+  "paths": {
+    "/orders": {
+      "post": {"summary": "Place an order", "operationId": "placeOrder", "tags": ["orders"]},
+      "get": {"operationId": "listOrders"}
+    }
+  },
+  "definitions": {
+    "Order": {"properties": {"id": {"type": "string"}, "items": {"type": "array", "items": {"$ref": "#/definitions/Item"}}}}
+  }
+}`
+	got, ok := condenseSchema("swagger.json", []byte(content), []string{"openapi"})
+	require.True(t, ok)
+	assert.Equal(t, "// Swagger 2.0 summary, condensed by -condense-schemas: Shop 2\n"+
+		"POST /orders - Place an order [placeOrder]\n"+
+		"GET /orders [listOrders]\n"+
+		"schemas:\n"+
+		"Order {id: string, items: []Item}\n", string(got))
+}
+
+func TestCondenseSchemaKeepsOtherFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		types   []string
+	}{
+		{"plain yaml", "config.yaml", "server:\n  port: 8080\n", condenseSchemaTypes},
+		{"invalid json", "openapi.json", `{"openapi": "3.0.0", `, condenseSchemaTypes},
+		{"spec without paths", "openapi.yml", "openapi: 3.1.0\ninfo:\n  title: Empty\n", condenseSchemaTypes},
+		{"unselected type", "openapi.yaml", petStoreYAML, []string{"proto"}},
+		{"proto without declarations", "empty.proto", "syntax = \"proto3\";\n", condenseSchemaTypes},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := condenseSchema(tc.file, []byte(tc.content), tc.types)
+			assert.False(t, ok)
+			assert.Equal(t, tc.content, string(got))
+		})
+	}
+}
+
+func TestCondenseProto(t *testing.T) {
+	content := `// Shop service definitions
+syntax = "proto3";
+
+package shop.v1;
+
+import "google/api/annotations.proto";
+option go_package = "example.com/shop/v1;shopv1"; // see https://example.com
+
+/* An order
+   placed by a customer */
+message Order {
+  reserved 4, 5;
+  string id = 1 [json_name = "orderId"];
+  repeated Item items = 2;
+  map<string, string> labels = 3;
+  oneof payment {
+    string card = 6;
+    string voucher = 7;
+  }
+  message Item {
+    string sku = 1;
+  }
+}
+
+enum Status {
+  option allow_alias = true;
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OPEN = 1;
+}
+
+service Shop {
+  option (google.api.default_host) = "shop.example.com";
+  rpc GetOrder(GetOrderRequest) returns (Order) {
+    option (google.api.http) = { get: "/v1/orders/{id}" };
+  }
+  rpc Watch(stream WatchRequest) returns (stream Order);
+}
+`
+	got, ok := condenseSchema("shop/v1/shop.proto", []byte(content), []string{"proto"})
+	require.True(t, ok)
+	assert.Equal(t, "// Condensed by -condense-schemas: comments, imports, options and field numbers left out\n"+
+		"syntax = \"proto3\";\n"+
+		"package shop.v1;\n"+
+		"message Order { string id; repeated Item items; map<string, string> labels; oneof payment { string card; string voucher; } message Item { string sku; } }\n"+
+		"enum Status { STATUS_UNSPECIFIED; STATUS_OPEN; }\n"+
+		"service Shop {\n"+
+		"  rpc GetOrder(GetOrderRequest) returns (Order);\n"+
+		"  rpc Watch(stream WatchRequest) returns (stream Order);\n"+
+		"}\n", string(got))
+}
+
+func TestGenerateContentFileCondenseSchemas(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"openapi.yaml": petStoreYAML,
+		"api.proto":    "syntax = \"proto3\";\nmessage Ping { string id = 1; }\n",
+	})
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("openapi.yaml\napi.proto"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{condense: []string{"proto"}})
+	require.NoError(t, err)
+	assert.Contains(t, result, "message Ping { string id; }")
+	assert.Contains(t, result, "operationId: listPets")
+
+	result, err = generateContentFileInternal(testDir, genOptions{condense: condenseSchemaTypes})
+	require.NoError(t, err)
+	assert.Contains(t, result, "GET /pets - List all pets [listPets]")
+	assert.NotContains(t, result, "operationId")
+}
+
+func TestGenCommandRejectsUnknownSchemaType(t *testing.T) {
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"gen", "-condense-schemas", "wsdl", "."}))
+
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, `Error: unknown -condense-schemas type "wsdl"`)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File names that usually mark an application entry point
+var entryPointNames = []string{
+	"main.go", "main.py", "__main__.py", "app.py", "manage.py", "wsgi.py",
+	"main.rs", "lib.rs", "index.js", "index.ts", "server.js", "server.ts", "app.js", "app.ts",
+	"main.js", "main.ts", "main.c", "main.cpp", "Program.cs", "Main.java", "index.php", "config.ru",
+}
+
+// projectSummary collects the statistics shown in the -summary preamble
+type projectSummary struct {
+	files       int
+	lines       int // non-blank lines, comments included
+	languages   map[string]int
+	entryPoints []string
+}
+
+// newProjectSummary returns an empty summary ready to collect files
+func newProjectSummary() *projectSummary {
+	return &projectSummary{languages: make(map[string]int)}
+}
+
+// add records a file included in the bundle
+func (s *projectSummary) add(file, language string, lines int) {
+	s.files++
+	s.lines += lines
+	if language == "" {
+		language = "other"
+	}
+	s.languages[language]++
+	if containsIgnoreCase(entryPointNames, filepath.Base(file)) {
+		s.entryPoints = append(s.entryPoints, file)
+	}
+}
+
+// render formats the summary preamble for the project rooted at baseDir
+func (s *projectSummary) render(baseDir string) string {
+	name := filepath.Base(baseDir)
+	if absDir, err := filepath.Abs(baseDir); err == nil {
+		name = filepath.Base(absDir)
+	}
+
+	var out strings.Builder
+	out.WriteString("#SUMMARY\n")
+	fmt.Fprintf(&out, "Project: %s\n", name)
+	fmt.Fprintf(&out, "Files: %d\n", s.files)
+	fmt.Fprintf(&out, "Non-blank lines: %d\n", s.lines)
+
+	// Languages ordered by file count, then by name
+	languages := make([]string, 0, len(s.languages))
+	for lang := range s.languages {
+		languages = append(languages, lang)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if s.languages[languages[i]] != s.languages[languages[j]] {
+			return s.languages[languages[i]] > s.languages[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
+	var langParts []string
+	for _, lang := range languages {
+		langParts = append(langParts, fmt.Sprintf("%s (%d)", lang, s.languages[lang]))
+	}
+	if len(langParts) > 0 {
+		fmt.Fprintf(&out, "Languages: %s\n", strings.Join(langParts, ", "))
+	}
+
+	if len(s.entryPoints) > 0 {
+		fmt.Fprintf(&out, "Entry points: %s\n", strings.Join(s.entryPoints, ", "))
+	}
+	if module := goModulePath(filepath.Join(baseDir, "go.mod")); module != "" {
+		fmt.Fprintf(&out, "Go module: %s\n", module)
+	}
+	if scripts := packageJSONScripts(filepath.Join(baseDir, "package.json")); len(scripts) > 0 {
+		fmt.Fprintf(&out, "package.json scripts: %s\n", strings.Join(scripts, ", "))
+	}
+
+	out.WriteString("#END SUMMARY\n\n")
+	return out.String()
+}
+
+// goModulePath returns the module path declared in a go.mod file
+func goModulePath(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		}
+	}
+	return ""
+}
+
+// packageJSONScripts returns the sorted script names declared in a package.json file
+func packageJSONScripts(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil
+	}
+
+	scripts := make([]string, 0, len(pkg.Scripts))
+	for name := range pkg.Scripts {
+		scripts = append(scripts, name)
+	}
+	sort.Strings(scripts)
+	return scripts
+}
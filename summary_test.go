@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectSummary(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"go.mod":       "module example.com/demo\n\ngo 1.23\n",
+		"package.json": `{"name": "demo", "scripts": {"test": "jest", "build": "tsc"}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(testDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	if err := os.WriteFile("skukozh_file_list.txt", []byte("file1.go\nsubdir/file3.go\nfile2.js"), 0644); err != nil {
+		t.Fatalf("Failed to create file list: %v", err)
+	}
+	defer os.Remove("skukozh_file_list.txt")
+
+	result, err := generateContentFileInternal(testDir, genOptions{summary: true})
+	require.NoError(t, err)
+
+	assert.Contains(t, result, "#SUMMARY\n")
+	assert.Contains(t, result, "Project: "+filepath.Base(testDir))
+	assert.Contains(t, result, "Files: 3")
+	assert.Contains(t, result, "Non-blank lines: 8")
+	assert.Contains(t, result, "Languages: go (2), javascript (1)")
+	assert.Contains(t, result, "Go module: example.com/demo")
+	assert.Contains(t, result, "package.json scripts: build, test")
+	assert.True(t, strings.HasPrefix(result, "#SUMMARY\n"), "Summary should come first")
+}
+
+func TestEntryPointDetection(t *testing.T) {
+	summary := newProjectSummary()
+	summary.add("cmd/api/main.go", "go", 10)
+	summary.add("internal/service.go", "go", 20)
+	summary.add("web/index.ts", "typescript", 5)
+
+	result := summary.render(t.TempDir())
+	assert.Contains(t, result, "Entry points: cmd/api/main.go, web/index.ts")
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// symbolSuffix matches a symbol name appended to a file list entry, such as
+// src/foo.go#FuncName or src/server.go#Server.Run
+var symbolSuffix = regexp.MustCompile(`^(.+)#([A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*)?)$`)
+
+// parseSymbolEntry splits a file list entry into the file path and the
+// symbol it selects, if any
+func parseSymbolEntry(entry string) (string, string, bool) {
+	match := symbolSuffix.FindStringSubmatch(entry)
+	if match == nil {
+		return entry, "", false
+	}
+	return match[1], match[2], true
+}
+
+// findSymbol returns the lines declaring name in a source file, including
+// its doc comment. Go files are parsed, where methods can be given as
+// Type.Method or by their bare name; other languages with -outline support find the declaration
+// line by pattern and its end by indentation, end keyword or braces.
+func findSymbol(file string, content []byte, name string) (lineSelection, error) {
+	language := fenceLanguage(file, content)
+	var r lineSelection
+	var ok bool
+	switch {
+	case language == "go":
+		var err error
+		if r, ok, err = findGoSymbol(file, content, name); err != nil {
+			return r, err
+		}
+	case outlinePatterns[language] != nil:
+		r, ok = findPatternSymbol(language, content, name)
+	default:
+		return r, fmt.Errorf("symbol extraction isn't supported for %s", file)
+	}
+	if !ok {
+		return r, fmt.Errorf("symbol %s not found in %s", name, file)
+	}
+	return r, nil
+}
+
+// findGoSymbol finds a function, method, type, constant or variable in a
+// Go file. Declarations in a group only span their own spec.
+func findGoSymbol(file string, content []byte, name string) (lineSelection, bool, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, content, parser.ParseComments)
+	if err != nil {
+		return lineSelection{}, false, err
+	}
+
+	span := func(doc *ast.CommentGroup, node ast.Node) lineSelection {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		return lineSelection{start: fset.Position(start).Line, end: fset.Position(node.End()).Line}
+	}
+
+	// A method matches its bare name unless a function has that name
+	var method *ast.FuncDecl
+	for _, decl := range parsed.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if goFuncName(d) == name {
+				return span(d.Doc, d), true, nil
+			}
+			if method == nil && d.Recv != nil && d.Name.Name == name {
+				method = d
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				doc, declared := goSpecDeclares(spec, name)
+				if !declared {
+					continue
+				}
+				if d.Lparen == token.NoPos {
+					return span(d.Doc, d), true, nil
+				}
+				return span(doc, spec), true, nil
+			}
+		}
+	}
+	if method != nil {
+		return span(method.Doc, method), true, nil
+	}
+	return lineSelection{}, false, nil
+}
+
+// goSpecDeclares reports whether a type or value spec declares name, along
+// with the spec's doc comment
+func goSpecDeclares(spec ast.Spec, name string) (*ast.CommentGroup, bool) {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc, s.Name.Name == name
+	case *ast.ValueSpec:
+		for _, ident := range s.Names {
+			if ident.Name == name {
+				return s.Doc, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// findPatternSymbol finds the first declaration line matching the -outline
+// patterns of the language that names the symbol before any parameter list
+func findPatternSymbol(language string, content []byte, name string) (lineSelection, bool) {
+	if idx := strings.LastIndex(name, "."); idx != -1 {
+		name = name[idx+1:] // Class.method is matched by the method name
+	}
+	nameRe := regexp.MustCompile(`(^|[^\w$])` + regexp.QuoteMeta(name) + `($|[^\w$])`)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+
+	for i, line := range lines {
+		loc := nameRe.FindStringIndex(line)
+		if loc == nil || strings.Contains(line[:loc[0]], "(") {
+			continue
+		}
+		for _, pattern := range outlinePatterns[language] {
+			if pattern.MatchString(line) {
+				return lineSelection{start: docStart(lines, i) + 1, end: blockEnd(language, lines, i) + 1}, true
+			}
+		}
+	}
+	return lineSelection{}, false
+}
+
+// Line prefixes of comments, decorators and annotations kept above a
+// declaration
+var docPrefixes = []string{"//", "/*", "*", "#", "@", "--"}
+
+// docStart returns the index of the first comment, decorator or annotation
+// line directly above the declaration at line i
+func docStart(lines []string, i int) int {
+	for i > 0 && hasAnyPrefix(strings.TrimSpace(lines[i-1]), docPrefixes) {
+		i--
+	}
+	return i
+}
+
+// blockEnd returns the index of the last line of the declaration starting
+// at line i
+func blockEnd(language string, lines []string, i int) int {
+	switch language {
+	case "python":
+		return indentedBlockEnd(lines, i)
+	case "ruby":
+		return endKeywordBlockEnd(lines, i)
+	default:
+		return braceBlockEnd(lines, i)
+	}
+}
+
+// indentation returns the width of the leading whitespace of a line
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// indentedBlockEnd ends a Python block before the first non-blank line
+// indented no deeper than its signature
+func indentedBlockEnd(lines []string, i int) int {
+	indent := indentation(lines[i])
+
+	// Skip the rest of a signature spanning several lines
+	body := i
+	for body < len(lines)-1 && !strings.HasSuffix(strings.TrimSpace(lines[body]), ":") {
+		body++
+	}
+
+	end := body
+	for j := body + 1; j < len(lines); j++ {
+		if strings.TrimSpace(lines[j]) == "" {
+			continue
+		}
+		if indentation(lines[j]) <= indent {
+			break
+		}
+		end = j
+	}
+	return end
+}
+
+// endKeywordBlockEnd ends a Ruby block at the first end keyword indented
+// like its declaration
+func endKeywordBlockEnd(lines []string, i int) int {
+	indent := indentation(lines[i])
+	for j := i + 1; j < len(lines); j++ {
+		trimmed := strings.TrimSpace(lines[j])
+		if indentation(lines[j]) == indent && (trimmed == "end" || strings.HasPrefix(trimmed, "end ")) {
+			return j
+		}
+	}
+	return i
+}
+
+// braceBlockEnd ends a block where its braces balance again. Braces in
+// quoted strings and line comments are ignored. Declarations without a
+// body end at a semicolon or before the next blank line.
+func braceBlockEnd(lines []string, i int) int {
+	depth := 0
+	opened := false
+	for j := i; j < len(lines); j++ {
+		if !opened && j > i && strings.TrimSpace(lines[j]) == "" {
+			return j - 1
+		}
+
+		var quote rune
+		escaped := false
+		prev := rune(0)
+	chars:
+		for _, c := range lines[j] {
+			switch {
+			case escaped:
+				escaped = false
+			case quote != 0:
+				if c == '\\' {
+					escaped = true
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '/' && prev == '/':
+				break chars
+			case c == '"' || c == '\'' || c == '`':
+				quote = c
+			case c == '{':
+				depth++
+				opened = true
+			case c == '}':
+				depth--
+			}
+			prev = c
+		}
+		if opened && depth <= 0 {
+			return j
+		}
+		if !opened && strings.HasSuffix(strings.TrimSpace(lines[j]), ";") {
+			return j
+		}
+	}
+	return len(lines) - 1
+}
+
+// extractSymbol prints the declaration of a symbol in a file and returns
+// the exit code
+func extractSymbol(file, name string) int {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	r, err := findSymbol(file, content, name)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	symbol, _, err := r.slice(content, file)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	fmt.Print(string(symbol))
+	return 0
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const symbolGoSource = `package server
+
+import "net/http"
+
+// Version of the server
+const (
+	// Major version
+	Major = 1
+	Minor = 2
+)
+
+// Server handles requests
+type Server struct {
+	mux *http.ServeMux
+}
+
+// Run starts the server
+// on the given address.
+func (s *Server) Run(addr string) error {
+	return http.ListenAndServe(addr, s.mux)
+}
+
+func Run() {}
+
+func (s *Server) Close() {}
+`
+
+// symbolLines returns the lines of content selected by r
+func symbolLines(t *testing.T, content string, r lineSelection) string {
+	t.Helper()
+	selected, _, err := r.slice([]byte(content), "test")
+	require.NoError(t, err)
+	return string(selected)
+}
+
+func TestFindGoSymbol(t *testing.T) {
+	tests := []struct {
+		name     string
+		symbol   string
+		expected string
+	}{
+		{"Method by type", "Server.Run", "// Run starts the server\n// on the given address.\nfunc (s *Server) Run(addr string) error {\n\treturn http.ListenAndServe(addr, s.mux)\n}\n"},
+		{"Function before method", "Run", "func Run() {}\n"},
+		{"Method by bare name", "Close", "func (s *Server) Close() {}\n"},
+		{"Type with doc", "Server", "// Server handles requests\ntype Server struct {\n\tmux *http.ServeMux\n}\n"},
+		{"Grouped constant", "Major", "\t// Major version\n\tMajor = 1\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := findSymbol("server.go", []byte(symbolGoSource), tc.symbol)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, symbolLines(t, symbolGoSource, r))
+		})
+	}
+
+	_, err := findSymbol("server.go", []byte(symbolGoSource), "Missing")
+	assert.EqualError(t, err, "symbol Missing not found in server.go")
+}
+
+func TestFindPatternSymbol(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		symbol   string
+		expected string
+	}{
+		{
+			"Python decorated function",
+			"app.py",
+			"import x\n\n# Handles the index\n@app.route('/')\ndef index(\n    request,\n):\n    if request:\n\n        return 1\n    return 2\n\ndef other():\n    pass\n",
+			"index",
+			"# Handles the index\n@app.route('/')\ndef index(\n    request,\n):\n    if request:\n\n        return 1\n    return 2\n",
+		},
+		{
+			"Python method by class",
+			"app.py",
+			"class A:\n    def run(self):\n        return 1\n\n    def stop(self):\n        pass\n",
+			"A.run",
+			"    def run(self):\n        return 1\n",
+		},
+		{
+			"JavaScript function with braces in strings",
+			"app.js",
+			"/**\n * Greets\n */\nexport function greet(name) {\n  const s = \"}\";\n  if (name) { return `{${name}`; } // }\n  return s;\n}\nfunction other() {}\n",
+			"greet",
+			"/**\n * Greets\n */\nexport function greet(name) {\n  const s = \"}\";\n  if (name) { return `{${name}`; } // }\n  return s;\n}\n",
+		},
+		{
+			"TypeScript type without a body",
+			"types.ts",
+			"export type ID = string;\nexport interface User {\n  id: ID;\n}\n",
+			"ID",
+			"export type ID = string;\n",
+		},
+		{
+			"Ruby method",
+			"user.rb",
+			"class User\n  # Full name\n  def name\n    if x\n      y\n    end\n  end\n\n  def email; end\nend\n",
+			"name",
+			"  # Full name\n  def name\n    if x\n      y\n    end\n  end\n",
+		},
+		{
+			"Java method skips parameters",
+			"Report.java",
+			"class Report {\n    @Override\n    public String render(Format format) {\n        return format.apply(this);\n    }\n\n    public void Format() {}\n}\n",
+			"render",
+			"    @Override\n    public String render(Format format) {\n        return format.apply(this);\n    }\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := findSymbol(tc.file, []byte(tc.content), tc.symbol)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, symbolLines(t, tc.content, r))
+		})
+	}
+
+	_, err := findSymbol("notes.txt", []byte("hello"), "hello")
+	assert.EqualError(t, err, "symbol extraction isn't supported for notes.txt")
+}
+
+func TestGenerateSymbolEntries(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{"server.go": symbolGoSource})
+
+	tmpDir := t.TempDir()
+	oldWd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(tmpDir))
+	defer os.Chdir(oldWd)
+
+	assert.Equal(t, "server.go", entryPath("server.go#Server.Run"))
+
+	require.NoError(t, os.WriteFile(fileListName, []byte("server.go#Server.Run\nserver.go#Missing"), 0644))
+	var result string
+	output := CaptureOutput(t, func() {
+		var err error
+		result, err = generateContentFileInternal(testDir, genOptions{})
+		require.NoError(t, err)
+	})
+	assert.Equal(t, "#FILE server.go\n#TYPE go\n#SYMBOL Server.Run\n#LINES 17-21 of 25\n#START\n```go\n// Run starts the server\n// on the given address.\nfunc (s *Server) Run(addr string) error {\n\treturn http.ListenAndServe(addr, s.mux)\n}\n```\n#END\n\n", result)
+	assert.Contains(t, output, "symbol Missing not found in server.go")
+}
+
+func TestExtractSymbolCommand(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{"server.go": symbolGoSource})
+	file := filepath.Join(testDir, "server.go")
+
+	var code int
+	output := CaptureOutput(t, func() {
+		fs := DefaultFlags()
+		require.NoError(t, fs.Parse([]string{"extract-symbol", file, "Server"}))
+		code = runWithFlags(fs)
+	})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "// Server handles requests\ntype Server struct {\n\tmux *http.ServeMux\n}\n", output)
+
+	output = CaptureOutput(t, func() {
+		fs := DefaultFlags()
+		require.NoError(t, fs.Parse([]string{"extract-symbol", file, "Nope"}))
+		code = runWithFlags(fs)
+	})
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.HasPrefix(output, "Error: symbol Nope not found in "))
+}
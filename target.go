@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// budgetTarget is a context budget analyze -target compares the bundle to
+type budgetTarget struct {
+	name   string
+	tokens int
+}
+
+// Named budgets of analyze -target, by the context size of common models
+var budgetTargets = []budgetTarget{
+	{"llama3-8k", 8192},
+	{"gpt-4-32k", 32768},
+	{"gpt-4o-128k", 128000},
+	{"claude-200k", 200000},
+	{"gemini-1m", 1000000},
+}
+
+// Number of files to remove named in the budget report
+const targetListedFiles = 5
+
+// parseTarget resolves a -target value: the name of a budget or a token
+// count such as 150000, 150k or 1.5M
+func parseTarget(value string) (budgetTarget, error) {
+	names := make([]string, len(budgetTargets))
+	for i, target := range budgetTargets {
+		if strings.EqualFold(value, target.name) {
+			return target, nil
+		}
+		names[i] = target.name
+	}
+
+	number, multiplier := strings.TrimSpace(value), 1.0
+	switch {
+	case strings.HasSuffix(strings.ToLower(number), "k"):
+		number, multiplier = number[:len(number)-1], 1000
+	case strings.HasSuffix(strings.ToUpper(number), "M"):
+		number, multiplier = number[:len(number)-1], 1000000
+	}
+	tokens, err := strconv.ParseFloat(number, 64)
+	if err != nil || tokens*multiplier < 1 {
+		return budgetTarget{}, fmt.Errorf("unknown target %q (use a token count such as 150k or %s)", value, strings.Join(names, ", "))
+	}
+	return budgetTarget{name: value, tokens: int(tokens * multiplier)}, nil
+}
+
+// targetFromOptions parses the -target of the analyze options, if any
+func targetFromOptions(opts analyzeOptions) (budgetTarget, error) {
+	if opts.target == "" {
+		return budgetTarget{}, nil
+	}
+	target, err := parseTarget(opts.target)
+	if err != nil {
+		return target, fmt.Errorf("-target: %w", err)
+	}
+	return target, nil
+}
+
+// writeTargetReport prints how much of the target budget a bundle of
+// totalTokens uses, the headroom left, and how many of the files with the
+// most tokens would have to go for it to fit
+func writeTargetReport(out io.Writer, target budgetTarget, totalTokens int, files []FileInfo) {
+	fmt.Fprintf(out, "Budget %s (%s tokens):\n", target.name, formatTokens(target.tokens))
+	fmt.Fprintf(out, "  Used: ~%s tokens (%.1f%%)\n", formatTokens(totalTokens), float64(totalTokens)*100/float64(target.tokens))
+	headroom := target.tokens - totalTokens
+	if headroom >= 0 {
+		fmt.Fprintf(out, "  Headroom: ~%s tokens (%.1f%%)\n\n", formatTokens(headroom), float64(headroom)*100/float64(target.tokens))
+		return
+	}
+	fmt.Fprintf(out, "  Over by: ~%s tokens\n", formatTokens(-headroom))
+
+	largest := append([]FileInfo(nil), files...)
+	sort.SliceStable(largest, func(i, j int) bool {
+		return largest[i].tokens > largest[j].tokens
+	})
+	removed, count := 0, 0
+	for _, file := range largest {
+		if removed >= -headroom {
+			break
+		}
+		removed += file.tokens
+		count++
+	}
+	if removed < -headroom {
+		fmt.Fprintln(out, "  Doesn't fit even without its files; the bundle's own text is over the budget")
+		fmt.Fprintln(out, "")
+		return
+	}
+
+	if count == 1 {
+		fmt.Fprintf(out, "  To fit, remove the largest file (~%s tokens):\n", formatTokens(removed))
+	} else {
+		fmt.Fprintf(out, "  To fit, remove the %d largest files (~%s tokens):\n", count, formatTokens(removed))
+	}
+	for _, file := range largest[:min(count, targetListedFiles)] {
+		fmt.Fprintf(out, "    %s (~%s)\n", file.path, formatTokens(file.tokens))
+	}
+	if count > targetListedFiles {
+		fmt.Fprintf(out, "    and %d more\n", count-targetListedFiles)
+	}
+	fmt.Fprintln(out, "")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		value  string
+		tokens int
+	}{
+		{"claude-200k", 200000},
+		{"GPT-4o-128k", 128000},
+		{"150000", 150000},
+		{"150k", 150000},
+		{"1.5M", 1500000},
+	}
+	for _, tc := range tests {
+		target, err := parseTarget(tc.value)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.tokens, target.tokens, tc.value)
+	}
+
+	for _, value := range []string{"", "claude", "-5k", "0"} {
+		_, err := parseTarget(value)
+		assert.ErrorContains(t, err, "use a token count such as 150k or llama3-8k, gpt-4-32k", value)
+	}
+}
+
+func TestWriteTargetReport(t *testing.T) {
+	files := []FileInfo{
+		{path: "a.go", tokens: 300},
+		{path: "big.json", tokens: 5000},
+		{path: "b.go", tokens: 2000},
+		{path: "c.go", tokens: 2000},
+	}
+
+	t.Run("Fits", func(t *testing.T) {
+		var out bytes.Buffer
+		writeTargetReport(&out, budgetTarget{name: "20k", tokens: 20000}, 9500, files)
+		assert.Equal(t, "Budget 20k (20k tokens):\n  Used: ~9.5k tokens (47.5%)\n  Headroom: ~11k tokens (52.5%)\n\n", out.String())
+	})
+
+	t.Run("Over", func(t *testing.T) {
+		var out bytes.Buffer
+		writeTargetReport(&out, budgetTarget{name: "5k", tokens: 5000}, 9500, files)
+		assert.Equal(t, "Budget 5k (5.0k tokens):\n  Used: ~9.5k tokens (190.0%)\n  Over by: ~4.5k tokens\n"+
+			"  To fit, remove the largest file (~5.0k tokens):\n    big.json (~5.0k)\n\n", out.String())
+
+		out.Reset()
+		writeTargetReport(&out, budgetTarget{name: "2k", tokens: 2000}, 9500, files)
+		assert.Contains(t, out.String(), "To fit, remove the 3 largest files (~9.0k tokens):\n    big.json (~5.0k)\n    b.go (~2.0k)\n    c.go (~2.0k)\n")
+	})
+
+	t.Run("Doesn't fit without files", func(t *testing.T) {
+		var out bytes.Buffer
+		writeTargetReport(&out, budgetTarget{name: "100", tokens: 100}, 9500, files)
+		assert.Contains(t, out.String(), "Doesn't fit even without its files")
+	})
+}
+
+func TestAnalyzeTarget(t *testing.T) {
+	content := testBundle("big.go", strings.Repeat("x", 4000), "small.go", "package main")
+	require.NoError(t, os.WriteFile(resultName, []byte(content), 0644))
+	defer os.Remove(resultName)
+
+	result, err := analyzeResultFileInternal(analyzeOptions{topCount: 5, target: "1k"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "Budget 1k (1.0k tokens):\n  Used: ~1.0k tokens (103.1%)\n  Over by: ~31 tokens\n  To fit, remove the largest file (~1.0k tokens):\n    big.go (~1.0k)\n")
+
+	_, err = analyzeResultFileInternal(analyzeOptions{topCount: 5, target: "huge"})
+	assert.ErrorContains(t, err, `-target: unknown target "huge"`)
+	_, err = analyzeResultFileInternal(analyzeOptions{topCount: 5, target: "1k", format: "csv"})
+	assert.EqualError(t, err, "-format csv can't be combined with -target")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"skukozh/bundle"
+)
+
+// sectionTemplateData holds the fields available to -template files
+type sectionTemplateData struct {
+	Index    int    // position of the file in the result, starting at 1
+	Path     string // path relative to the scanned directory
+	Type     string // file extension without the dot, or the language
+	Language string // fence language
+	Lines    int    // non-blank lines before truncation
+	Range    string // selected lines for entries with a line range or symbol, e.g. "120-240 of 812"
+	Symbol   string // symbol selected by the entry, if any
+	SHA256   string // checksum of the file on disk
+	Git      string // #GIT header line with -git-meta, empty otherwise
+	Blame    string // #BLAME header line with -blame, empty otherwise
+	Content  string // processed content, without a trailing newline
+	Fence    string // code fence longer than any run of backticks in Content
+}
+
+// loadSectionTemplate parses a -template file
+func loadSectionTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// renderSectionTemplate renders a file section with a -template
+func renderSectionTemplate(tmpl *template.Template, index int, section fileSection) (string, error) {
+	var out strings.Builder
+	err := tmpl.Execute(&out, sectionTemplateData{
+		Index:    index,
+		Path:     section.Path,
+		Type:     section.Type,
+		Language: section.Language,
+		Lines:    section.Lines,
+		Range:    section.Range,
+		Symbol:   section.Symbol,
+		SHA256:   section.SHA256,
+		Git:      strings.TrimSuffix(section.Git, "\n"),
+		Blame:    strings.TrimSuffix(section.Blame, "\n"),
+		Content:  strings.TrimSuffix(section.Content, "\n"),
+		Fence:    bundle.Fence(section.Content),
+	})
+	if err != nil {
+		return "", fmt.Errorf("rendering template for %s: %w", section.Path, err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateContentFileTemplate(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"README.md": "# Title\n",
+	})
+	require.NoError(t, os.WriteFile(fileListName, []byte("main.go\nREADME.md"), 0644))
+	defer os.Remove(fileListName)
+
+	tmplPath := filepath.Join(t.TempDir(), "section.tmpl")
+	tmpl := `<file index="{{.Index}}" path="{{.Path}}" lang="{{.Language}}" lines="{{.Lines}}">
+{{.Content}}
+</file>
+`
+	require.NoError(t, os.WriteFile(tmplPath, []byte(tmpl), 0644))
+
+	content, err := generateContentFileInternal(testDir, genOptions{template: tmplPath})
+	require.NoError(t, err)
+	assert.Equal(t, `<file index="1" path="main.go" lang="go" lines="2">
+package main
+func main() {}
+</file>
+<file index="2" path="README.md" lang="markdown" lines="1">
+# Title
+</file>
+`, content)
+
+	t.Run("Incremental runs render cached sections", func(t *testing.T) {
+		defer os.Remove(cacheName)
+		var first, second string
+		CaptureOutput(t, func() {
+			first, err = generateContentFileInternal(testDir, genOptions{incremental: true})
+		})
+		require.NoError(t, err)
+		assert.Contains(t, first, "#FILE main.go")
+
+		CaptureOutput(t, func() {
+			second, err = generateContentFileInternal(testDir, genOptions{incremental: true, template: tmplPath})
+		})
+		require.NoError(t, err)
+		assert.Equal(t, content, second)
+	})
+
+	t.Run("Fence", func(t *testing.T) {
+		writeTestFiles(t, testDir, map[string]string{"README.md": "# Title\n```sh\nmake\n```\n"})
+		fencePath := filepath.Join(t.TempDir(), "fence.tmpl")
+		require.NoError(t, os.WriteFile(fencePath, []byte("{{.Fence}}{{.Language}}\n{{.Content}}\n{{.Fence}}\n"), 0644))
+
+		content, err := generateContentFileInternal(testDir, genOptions{template: fencePath})
+		require.NoError(t, err)
+		assert.Equal(t, "```go\npackage main\nfunc main() {}\n```\n````markdown\n# Title\n```sh\nmake\n```\n````\n", content)
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		badPath := filepath.Join(t.TempDir(), "bad.tmpl")
+		require.NoError(t, os.WriteFile(badPath, []byte("{{.Path"), 0644))
+		_, err := generateContentFileInternal(testDir, genOptions{template: badPath})
+		assert.ErrorContains(t, err, "invalid template")
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		badPath := filepath.Join(t.TempDir(), "field.tmpl")
+		require.NoError(t, os.WriteFile(badPath, []byte("{{.Missing}}"), 0644))
+		_, err := generateContentFileInternal(testDir, genOptions{template: badPath})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"path"
+	"strings"
+)
+
+// Markers test file names add to the name of the file they test, as in
+// parser_test.go, user_spec.rb, button.test.tsx or ReportTests.java
+var testNameMarkers = []string{"_test", "_spec", ".test", ".spec", "Tests", "Test"}
+
+// The -no-tests patterns, matched by isTestPath
+var testPathRules = testRules()
+
+// isTestPath reports whether a file is test code, a fixture or a snapshot:
+// it or one of its parent directories matches the -no-tests patterns
+func isTestPath(relPath string) bool {
+	return isIgnoredByGitignore(relPath, testPathRules, false)
+}
+
+// testSubjectName returns the name of the file a test file tests, such as
+// parser.go for parser_test.go or parser.py for test_parser.py, and false
+// for names without a test marker
+func testSubjectName(name string) (string, bool) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if ext == ".py" && strings.HasPrefix(stem, "test_") && len(stem) > len("test_") {
+		return stem[len("test_"):] + ext, true
+	}
+	for _, marker := range testNameMarkers {
+		if strings.HasSuffix(stem, marker) && len(stem) > len(marker) {
+			return strings.TrimSuffix(stem, marker) + ext, true
+		}
+	}
+	return "", false
+}
+
+// sharedDirSuffix returns how many trailing directory names two directories
+// share, so spec/models and app/models share one; the same directory shares
+// all of them
+func sharedDirSuffix(a, b string) int {
+	if a == b {
+		return math.MaxInt
+	}
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	n := 0
+	for n < len(as) && n < len(bs) && as[len(as)-1-n] == bs[len(bs)-1-n] {
+		n++
+	}
+	return n
+}
+
+// testSubject picks the file a test file tests among candidates: the file
+// with the subject's name in the test's directory, or else the one whose
+// directory ends like the test's. It returns false when no file, or more
+// than one equally close file, has the subject's name.
+func testSubject(test string, candidates []string) (string, bool) {
+	name, ok := testSubjectName(path.Base(test))
+	if !ok {
+		return "", false
+	}
+	subject, best, tied := "", -1, false
+	for _, candidate := range candidates {
+		if path.Base(candidate) != name {
+			continue
+		}
+		switch score := sharedDirSuffix(path.Dir(test), path.Dir(candidate)); {
+		case score > best:
+			subject, best, tied = candidate, score, false
+		case score == best:
+			tied = true
+		}
+	}
+	return subject, best >= 0 && !tied
+}
+
+// filterTests keeps the test code, fixtures and snapshots of files for
+// -tests-only, and with withSubjects the files they test, reporting the
+// others to skip
+func filterTests(files []string, withSubjects bool, skip func(path, reason string)) []string {
+	var tests, others []string
+	for _, file := range files {
+		if isTestPath(file) {
+			tests = append(tests, file)
+		} else {
+			others = append(others, file)
+		}
+	}
+
+	subjects := make(map[string]bool)
+	if withSubjects {
+		for _, test := range tests {
+			if subject, ok := testSubject(test, others); ok {
+				subjects[subject] = true
+			}
+		}
+	}
+
+	kept := tests
+	for _, file := range others {
+		if subjects[file] {
+			kept = append(kept, file)
+		} else {
+			skip(file, "not test code (-tests-only)")
+		}
+	}
+	return kept
+}
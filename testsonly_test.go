@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestSubjectName(t *testing.T) {
+	for name, subject := range map[string]string{
+		"parser_test.go":      "parser.go",
+		"test_parser.py":      "parser.py",
+		"parser_test.py":      "parser.py",
+		"user_spec.rb":        "user.rb",
+		"button.test.tsx":     "button.tsx",
+		"api.spec.js":         "api.js",
+		"ReportTest.java":     "Report.java",
+		"ReportTests.cs":      "Report.cs",
+		"InvoiceTest.php":     "Invoice.php",
+		"ParserTests.swift":   "Parser.swift",
+		"helpers_test.go.bak": "",
+		"conftest.py":         "",
+		"Test.java":           "",
+	} {
+		got, ok := testSubjectName(name)
+		assert.Equal(t, subject != "", ok, name)
+		assert.Equal(t, subject, got, name)
+	}
+}
+
+func TestFindTestsOnly(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		"main.go":                       "package main\n",
+		"parser.go":                     "package main\n",
+		"parser_test.go":                "package main\n",
+		"testdata/input.txt":            "input\n",
+		"web/button.tsx":                "export {}\n",
+		"web/button.test.tsx":           "test()\n",
+		"app/models/user.rb":            "class User; end\n",
+		"app/models/admin/user.rb":      "class Admin::User; end\n",
+		"spec/models/user_spec.rb":      "describe User\n",
+		"src/main/java/Report.java":     "class Report {}\n",
+		"src/test/java/ReportTest.java": "class ReportTest {}\n",
+		"pkg/a/util.py":                 "def a(): pass\n",
+		"pkg/b/util.py":                 "def b(): pass\n",
+		"tests/test_util.py":            "def test_util(): pass\n",
+	})
+
+	fs := flagSetWith(t, "-tests-only", "-case", "sensitive")
+	opts, err := findOptionsFromFlags(fs)
+	require.NoError(t, err)
+	skipped := make(map[string]string)
+	opts.onSkip = func(path, reason string) { skipped[path] = reason }
+
+	files, err := findFilesWithOptions(testDir, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"parser_test.go",
+		"spec/models/user_spec.rb",
+		"src/test/java/ReportTest.java",
+		"testdata/input.txt",
+		"tests/test_util.py",
+		"web/button.test.tsx",
+	}, files)
+	assert.Equal(t, "not test code (-tests-only)", skipped["main.go"])
+	assert.Equal(t, "not tests", skipCategory(skipped["main.go"]))
+
+	// Subjects are found next to the test or in the directory ending the same
+	// way; ambiguous ones are left out
+	fs = flagSetWith(t, "-tests-only", "-with-subjects", "-case", "sensitive")
+	opts, err = findOptionsFromFlags(fs)
+	require.NoError(t, err)
+	files, err = findFilesWithOptions(testDir, nil, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"app/models/user.rb",
+		"parser.go",
+		"parser_test.go",
+		"spec/models/user_spec.rb",
+		"src/main/java/Report.java",
+		"src/test/java/ReportTest.java",
+		"testdata/input.txt",
+		"tests/test_util.py",
+		"web/button.test.tsx",
+		"web/button.tsx",
+	}, files)
+
+	included, reason, err := explainPath(testDir, "parser.go", nil, opts)
+	require.NoError(t, err)
+	assert.True(t, included)
+	assert.Equal(t, "tested by a listed test file, included by -with-subjects", reason)
+
+	_, err = findOptionsFromFlags(flagSetWith(t, "-tests-only", "-no-tests"))
+	assert.EqualError(t, err, "-tests-only can't be combined with -no-tests")
+	_, err = findOptionsFromFlags(flagSetWith(t, "-with-subjects"))
+	assert.EqualError(t, err, "-with-subjects only applies with -tests-only")
+}
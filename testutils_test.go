@@ -3,6 +3,8 @@ package main
 import (
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -69,3 +71,19 @@ func ReadTestFile(t *testing.T, path string) string {
 
 	return string(data)
 }
+
+// fileListText renders a text file list of files found in root with the
+// given filters, as written by find
+func fileListText(t *testing.T, root, filters string, files ...string) string {
+	t.Helper()
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", root, err)
+	}
+	lines := []string{fileListVersion, fileListRootPrefix + filepath.ToSlash(absRoot)}
+	if filters != "" {
+		lines = append(lines, fileListFiltersPrefix+filters)
+	}
+	return strings.Join(append(lines, files...), "\n")
+}
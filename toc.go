@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tocEntry locates a file section in the bundle body
+type tocEntry struct {
+	path   string
+	offset int // byte offset of the section in the body
+	line   int // number of lines in the body before the section
+}
+
+// renderTOC formats the table of contents for a body starting at byte
+// offset start, after lines lines of the result
+func renderTOC(entries []tocEntry, start, lines int) string {
+	var out strings.Builder
+	out.WriteString("#TOC\n")
+	lines += len(entries) + 3 // the TOC itself, with its blank line
+	for i, entry := range entries {
+		fmt.Fprintf(&out, "%d. %s (line %d, byte %d)\n", i+1, entry.path, lines+entry.line+1, start+entry.offset)
+	}
+	out.WriteString("#END TOC\n\n")
+	return out.String()
+}
+
+// addTOC inserts the table of contents in front of the body starting at
+// bodyStart in result. Entries point at the line and byte offset of their
+// sections in the final result, so the byte offsets include the TOC itself.
+func addTOC(result string, bodyStart int, entries []tocEntry) string {
+	lines := strings.Count(result[:bodyStart], "\n")
+	toc := renderTOC(entries, bodyStart, lines)
+	for {
+		// Offsets grow with the TOC, repeat until its length is stable
+		next := renderTOC(entries, bodyStart+len(toc), lines)
+		if len(next) == len(toc) {
+			toc = next
+			break
+		}
+		toc = next
+	}
+	return result[:bodyStart] + toc + result[bodyStart:]
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTOC(t *testing.T) {
+	entries := []tocEntry{{path: "a.go", offset: 0, line: 0}, {path: "b.go", offset: 40, line: 6}}
+	assert.Equal(t, "#TOC\n1. a.go (line 6, byte 100)\n2. b.go (line 12, byte 140)\n#END TOC\n\n", renderTOC(entries, 100, 0))
+}
+
+func TestGenerateWithTOC(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	require.NoError(t, os.WriteFile(fileListName, []byte("file1.go\nfile2.js\nsubdir/file3.go\nfile5.txt"), 0644))
+	defer os.Remove(fileListName)
+
+	tocLine := regexp.MustCompile(`(?m)^\d+\. (\S+) \(line (\d+), byte (\d+)\)$`)
+	for _, opts := range []genOptions{
+		{toc: true},
+		{toc: true, summary: true, prompt: "Review this code.\nBe brief.", promptSuffix: "Thanks."},
+		{toc: true, annotate: true, checksum: true},
+		{toc: true, meta: true, prompt: "Review"},
+	} {
+		t.Run(fmt.Sprintf("%+v", opts), func(t *testing.T) {
+			result, err := generateContentFileInternal(testDir, opts)
+			require.NoError(t, err)
+
+			matches := tocLine.FindAllStringSubmatch(result, -1)
+			require.Len(t, matches, 4)
+			lines := strings.Split(result, "\n")
+			for _, match := range matches {
+				line, _ := strconv.Atoi(match[2])
+				offset, _ := strconv.Atoi(match[3])
+				assert.True(t, strings.HasPrefix(lines[line-1], "#FILE "+match[1]), "line %d: %s", line, lines[line-1])
+				assert.True(t, strings.HasPrefix(result[offset:], "#FILE "+match[1]), "byte %d", offset)
+			}
+		})
+	}
+
+	t.Run("TOC comes after the prompt and summary", func(t *testing.T) {
+		result, err := generateContentFileInternal(testDir, genOptions{toc: true, summary: true, prompt: "Review"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result, "Review\n\n#SUMMARY\n"))
+		assert.Contains(t, result, "#END SUMMARY\n\n#TOC\n1. file1.go")
+	})
+
+	t.Run("Rejected for other formats", func(t *testing.T) {
+		_, err := generateContentFileInternal(testDir, genOptions{toc: true, format: "jsonl"})
+		assert.EqualError(t, err, "-format jsonl can't be combined with -toc")
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// parseTypeList parses a -only or -skip value such as 'go,md' or
+// '*.min.js,_test.go' into lowercase patterns. Items without wildcards are
+// extensions or suffixes as for -ext; items with *, ? or [ are globs
+// matched against the file name.
+func parseTypeList(value string) []string {
+	var patterns []string
+	for _, item := range splitList(value) {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if !isTypeGlob(item) && !strings.HasPrefix(item, ".") && !strings.HasPrefix(item, "_") {
+			item = "." + item
+		}
+		patterns = append(patterns, item)
+	}
+	return patterns
+}
+
+// isTypeGlob checks if a type pattern has wildcards
+func isTypeGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// matchType checks if the file of a file list entry matches one of the
+// type patterns, ignoring case
+func matchType(entry string, patterns []string) bool {
+	name := strings.ToLower(path.Base(entryPath(entry)))
+	for _, pattern := range patterns {
+		if isTypeGlob(pattern) {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		} else if strings.HasSuffix(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepType checks if gen includes the file of a file list entry under the
+// -only and -skip patterns; -skip wins over -only
+func keepType(entry string, only, skip []string) bool {
+	if len(only) > 0 && !matchType(entry, only) {
+		return false
+	}
+	return !matchType(entry, skip)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTypeList(t *testing.T) {
+	assert.Equal(t, []string{".go", ".md", "_test.go", "*.min.js"}, parseTypeList("go, .MD,_test.go,*.min.js,"))
+	assert.Nil(t, parseTypeList(""))
+}
+
+func TestKeepType(t *testing.T) {
+	only, skip := parseTypeList("go,md,*.proto"), parseTypeList("_test.go,*.PB.go")
+	tests := []struct {
+		entry string
+		keep  bool
+	}{
+		{"main.go", true},
+		{"docs/README.MD", true},
+		{"api/v1/user.proto", true},
+		{"main_test.go", false},
+		{"api/v1/user.pb.go", false},
+		{"web/app.js", false},
+		{"src/server.go:120-240", true},
+		{"src/server.go#Server.Run", true},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.keep, keepType(tc.entry, only, skip), tc.entry)
+	}
+	assert.True(t, keepType("web/app.js", nil, nil))
+	assert.False(t, keepType("data.json", nil, parseTypeList("json")))
+}
+
+func TestGenerateContentFileTypeFilters(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(fileListName, []byte("file1.go\nfile2.js\nsubdir/file3.go\nsubdir/file4.php\nfile5.txt"), 0644))
+	defer os.Remove(fileListName)
+
+	paths := func(opts genOptions) []string {
+		t.Helper()
+		content, err := generateContentFileInternal(testDir, opts)
+		require.NoError(t, err)
+		var paths []string
+		for _, section := range parseBundleSections(content) {
+			paths = append(paths, section.path)
+		}
+		return paths
+	}
+	assert.Equal(t, []string{"file1.go", "subdir/file3.go", "file5.txt"}, paths(genOptions{only: parseTypeList("go,txt")}))
+	assert.Equal(t, []string{"file2.js", "subdir/file4.php", "file5.txt"}, paths(genOptions{skip: parseTypeList("go")}))
+	assert.Equal(t, []string{"file1.go"}, paths(genOptions{only: parseTypeList("go"), skip: parseTypeList("file3*")}))
+}
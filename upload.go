@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// Environment variable with a bearer token sent with HTTP uploads
+const uploadTokenEnv = "SKUKOZH_UPLOAD_TOKEN"
+
+// An HTTP upload taking longer than this fails gen
+const uploadTimeout = 5 * time.Minute
+
+// Command line tools storing files in cloud buckets, by URL scheme. They
+// pick up credentials the way they always do (profiles, environment
+// variables, instance metadata).
+var uploadTools = map[string][]string{
+	"s3": {"aws", "s3", "cp", "--only-show-errors"},
+	"gs": {"gcloud", "storage", "cp"},
+}
+
+// parseUploadTarget validates a -upload target: an s3:// or gs:// URL, or
+// an http(s):// URL the file is PUT to
+func parseUploadTarget(target string) (*url.URL, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload target %q: %w", target, err)
+	}
+	switch u.Scheme {
+	case "s3", "gs", "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported upload target %q (use s3://bucket/key, gs://bucket/key or an http(s):// URL)", target)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("upload target %q has no bucket or host", target)
+	}
+	return u, nil
+}
+
+// uploadDestination returns the URL a file is uploaded to: the target, or
+// the file name appended to it when the target ends in a slash or names a
+// bare bucket
+func uploadDestination(target *url.URL, file string) *url.URL {
+	dest := *target
+	if dest.Path == "" || strings.HasSuffix(dest.Path, "/") {
+		dest.Path = path.Join("/", dest.Path, path.Base(file))
+	}
+	return &dest
+}
+
+// uploadResultFile uploads a written result file and returns the URL it
+// was stored at
+func uploadResultFile(file, target string) (string, error) {
+	u, err := parseUploadTarget(target)
+	if err != nil {
+		return "", err
+	}
+	dest := uploadDestination(u, file)
+
+	if tool, ok := uploadTools[dest.Scheme]; ok {
+		return dest.String(), runUploadTool(tool, file, dest.String())
+	}
+	return httpUpload(file, dest)
+}
+
+// runUploadTool copies a file to a bucket with a cloud command line tool
+func runUploadTool(tool []string, file, dest string) error {
+	if _, err := exec.LookPath(tool[0]); err != nil {
+		return fmt.Errorf("uploading to %s requires the %s command: %w", dest, tool[0], err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool[0], append(tool[1:], file, dest)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %v: %s", tool[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+// httpUpload PUTs a file to an HTTP(S) URL, with the token from
+// SKUKOZH_UPLOAD_TOKEN or the credentials in the URL. A Location header in
+// the response is taken as the URL of the stored file.
+func httpUpload(file string, dest *url.URL) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPut, dest.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if token := os.Getenv(uploadTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: uploadTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("PUT %s: %s: %s", dest.Redacted(), resp.Status, bytes.TrimSpace(body))
+	}
+
+	if location, err := resp.Location(); err == nil {
+		return location.Redacted(), nil
+	}
+	return dest.Redacted(), nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUploadTarget(t *testing.T) {
+	for _, target := range []string{"s3://bundles/app/", "gs://bundles", "https://prompts.example.com/bundles/app.txt"} {
+		_, err := parseUploadTarget(target)
+		assert.NoError(t, err, target)
+	}
+
+	_, err := parseUploadTarget("ftp://example.com/bundle")
+	assert.EqualError(t, err, `unsupported upload target "ftp://example.com/bundle" (use s3://bucket/key, gs://bucket/key or an http(s):// URL)`)
+	_, err = parseUploadTarget("bundles/app.txt")
+	assert.Error(t, err)
+	_, err = parseUploadTarget("s3:///app.txt")
+	assert.EqualError(t, err, `upload target "s3:///app.txt" has no bucket or host`)
+}
+
+func TestUploadDestination(t *testing.T) {
+	tests := map[string]string{
+		"s3://bundles":              "s3://bundles/skukozh_result.txt.gz",
+		"s3://bundles/app/":         "s3://bundles/app/skukozh_result.txt.gz",
+		"gs://bundles/app/2024.gz":  "gs://bundles/app/2024.gz",
+		"https://example.com/put/":  "https://example.com/put/skukozh_result.txt.gz",
+		"https://example.com/a?x=1": "https://example.com/a?x=1",
+	}
+	for target, expected := range tests {
+		u, err := parseUploadTarget(target)
+		require.NoError(t, err)
+		assert.Equal(t, expected, uploadDestination(u, resultName+".gz").String(), target)
+	}
+}
+
+func TestHTTPUpload(t *testing.T) {
+	var body []byte
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		auth = r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+		if r.URL.Path == "/redirecting/skukozh_result.txt" {
+			w.Header().Set("Location", "/bundles/42")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if r.URL.Path == "/full/skukozh_result.txt" {
+			http.Error(w, "quota exceeded", http.StatusInsufficientStorage)
+		}
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), resultName)
+	require.NoError(t, os.WriteFile(file, []byte("bundle"), 0644))
+	t.Setenv(uploadTokenEnv, "s3cr3t")
+
+	location, err := uploadResultFile(file, server.URL+"/bundles/")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/bundles/skukozh_result.txt", location)
+	assert.Equal(t, "bundle", string(body))
+	assert.Equal(t, "Bearer s3cr3t", auth)
+
+	// A Location header names the stored file
+	location, err = uploadResultFile(file, server.URL+"/redirecting/")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/bundles/42", location)
+
+	// Credentials in the URL are not echoed back
+	_, err = uploadResultFile(file, "http://user:pass@"+server.Listener.Addr().String()+"/full/")
+	assert.EqualError(t, err, "PUT http://user:xxxxx@"+server.Listener.Addr().String()+"/full/skukozh_result.txt: 507 Insufficient Storage: quota exceeded")
+}
+
+func TestGenUploadToBucket(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	// A stand-in aws command recording its arguments
+	binDir := t.TempDir()
+	logFile := filepath.Join(binDir, "aws.log")
+	script := "#!/bin/sh\necho \"$@\" > " + logFile + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "aws"), []byte(script), 0755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "find", "."}))
+	var exitCode int
+	CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+
+	flagSet = DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "-quiet", "gen", "-upload", "s3://bundles/app/", "."}))
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	require.Equal(t, 0, exitCode)
+	assert.Equal(t, "Uploaded to s3://bundles/app/skukozh_result.txt\n", output, "the URL is printed even with -quiet")
+	assert.Equal(t, "s3 cp --only-show-errors skukozh_result.txt s3://bundles/app/skukozh_result.txt\n", ReadTestFile(t, logFile))
+}
+
+func TestGenUploadInvalidTarget(t *testing.T) {
+	testDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	flagSet := DefaultFlags()
+	require.NoError(t, flagSet.Parse([]string{"-chdir", testDir, "gen", "-upload", "scp://host/bundles", "."}))
+	var exitCode int
+	output := CaptureOutput(t, func() {
+		exitCode = runWithFlags(flagSet)
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output, `Error: unsupported upload target "scp://host/bundles"`)
+	assert.NoFileExists(t, filepath.Join(testDir, resultName))
+}
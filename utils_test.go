@@ -178,3 +178,110 @@ func TestIsIgnoredByGitignore(t *testing.T) {
 		})
 	}
 }
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{"Empty text", "", 0},
+		{"Short text", "abc", 1},
+		{"Exact multiple", "abcdefgh", 2},
+		{"Multibyte runes", "привет", 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, estimateTokens(tc.text), "estimateTokens(%q) returned unexpected result", tc.text)
+		})
+	}
+}
+
+func TestTruncateLines(t *testing.T) {
+	lines := []string{"1", "2", "3", "4", "5", "6", "7"}
+
+	t.Run("Short input is kept", func(t *testing.T) {
+		assert.Equal(t, lines, truncateLines(lines, 4))
+	})
+
+	t.Run("Long input keeps head and tail", func(t *testing.T) {
+		result := truncateLines(lines, 2)
+		assert.Equal(t, []string{"1", "2", "... [truncated 3 lines] ...", "6", "7"}, result)
+	})
+
+	t.Run("Zero preview lines keeps only marker", func(t *testing.T) {
+		result := truncateLines(lines, 0)
+		assert.Equal(t, []string{"... [truncated 7 lines] ..."}, result)
+	})
+}
+
+func TestFenceLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		expected string
+	}{
+		{"Go file", "main.go", "package main", "go"},
+		{"YAML short extension", "config.yml", "key: value", "yaml"},
+		{"TSX file", "src/App.tsx", "", "tsx"},
+		{"C header", "include/lib.h", "", "c"},
+		{"Uppercase extension", "README.MD", "", "markdown"},
+		{"Dockerfile", "Dockerfile", "FROM alpine", "dockerfile"},
+		{"Dockerfile variant", "build/Dockerfile.prod", "FROM alpine", "dockerfile"},
+		{"Makefile", "Makefile", "all:", "makefile"},
+		{"Env shebang", "scripts/run", "#!/usr/bin/env python3\nprint(1)", "python"},
+		{"Direct shebang", "deploy", "#!/bin/bash\necho hi", "bash"},
+		{"Unknown extension", "data.foo", "", "foo"},
+		{"No extension and no shebang", "NOTES", "plain text", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := fenceLanguage(tc.path, []byte(tc.content))
+			assert.Equal(t, tc.expected, result, "fenceLanguage(%s) returned unexpected result", tc.path)
+		})
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Already LF", "a\nb\n", "a\nb\n"},
+		{"CRLF", "a\r\nb\r\n", "a\nb\n"},
+		{"Lone CR", "a\rb", "a\nb"},
+		{"BOM stripped", "\xef\xbb\xbfa\r\nb", "a\nb"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, string(normalizeLineEndings([]byte(tc.input))))
+		})
+	}
+}
+
+func TestParseExtFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		include []string
+		exclude []string
+	}{
+		{"Plain extensions", "go, .JS", []string{".go", ".js"}, nil},
+		{"Negated suffix", "go,!_test.go", []string{".go"}, []string{"_test.go"}},
+		{"Only negations", "!min.js,!.map", nil, []string{".min.js", ".map"}},
+		{"Empty", "", nil, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			include, exclude := parseExtFilter(tc.input)
+			assert.Equal(t, tc.include, include)
+			assert.Equal(t, tc.exclude, exclude)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// explainPath reports whether find would include target, a path relative to
+// root or absolute, and the rule that decided it
+func explainPath(root, target string, supportedExts []string, opts findOptions) (bool, string, error) {
+	relPath, err := relativeToRoot(root, target)
+	if err != nil {
+		return false, "", err
+	}
+	info, err := os.Stat(filepath.Join(root, relPath))
+	if err != nil {
+		return false, "", err
+	}
+
+	skipped := make(map[string]string)
+	opts.onSkip = func(path, reason string) {
+		skipped[path] = reason
+	}
+	files, err := findFilesWithOptions(root, supportedExts, opts)
+	if err != nil {
+		return false, "", err
+	}
+
+	if contains(files, relPath) {
+		if opts.coverage != nil {
+			percent, _ := coveragePercent(opts.coverage.byFile(files)[relPath])
+			return true, fmt.Sprintf("coverage %.1f%% is below -coverage-below (%g%%)", percent, opts.coverageBelow), nil
+		}
+		switch {
+		case opts.withSubjects && !isTestPath(relPath):
+			return true, "tested by a listed test file, included by -with-subjects", nil
+		case opts.withDocs && isKeyDoc(relPath):
+			return true, "key documentation included by -with-docs", nil
+		case opts.rootFiles && isRootManifest(relPath):
+			return true, "manifest at the top included by -root-files", nil
+		case len(supportedExts) == 0 && isWellKnownFile(path.Base(relPath), opts.knownFiles):
+			return true, "well-known project file", nil
+		case len(supportedExts) == 0 && hasAnySuffix(path.Base(relPath), opts.textExts):
+			return true, "extension treated as text (-treat-as-text or text_exts)", nil
+		case len(supportedExts) == 0 && opts.detect && len(detectStacks(root)) > 0:
+			return true, "extension is among the extensions of the detected stacks", nil
+		case len(supportedExts) == 0:
+			return true, "extension is among the default text extensions", nil
+		default:
+			return true, "matches the -ext filter", nil
+		}
+	}
+
+	// The path itself or the closest skipped parent directory decides
+	for dir := relPath; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if reason, ok := skipped[dir]; ok {
+			if dir != relPath {
+				reason += fmt.Sprintf(", via parent directory %s", dir)
+			}
+			return false, reason, nil
+		}
+	}
+
+	if info.IsDir() {
+		return false, "directories are not part of the file list", nil
+	}
+	return false, "not reached by find", nil
+}
+
+// relativeToRoot converts target into a slash-separated path relative to root
+func relativeToRoot(root, target string) (string, error) {
+	if filepath.IsAbs(target) {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return "", err
+		}
+		target, err = filepath.Rel(absRoot, target)
+		if err != nil {
+			return "", err
+		}
+	}
+	relPath := filepath.ToSlash(filepath.Clean(target))
+	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", fmt.Errorf("%s is not inside %s", target, root)
+	}
+	return relPath, nil
+}
+
+// explainFile prints why find includes or excludes a path
+func explainFile(root, target string, supportedExts, excludedExts []string, fs *flag.FlagSet) {
+	defer applyFindFlags(fs)()
+
+	opts, err := findOptionsFromFlags(fs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+	opts.excludedExts = excludedExts
+
+	included, reason, err := explainPath(root, target, supportedExts, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+		return // This ensures the function stops here in tests
+	}
+
+	if included {
+		fmt.Printf("%s is included: %s\n", target, reason)
+	} else {
+		fmt.Printf("%s is excluded: %s\n", target, reason)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainPath(t *testing.T) {
+	testDir := t.TempDir()
+	writeTestFiles(t, testDir, map[string]string{
+		".gitignore":                "# build output\n\n*.log\n",
+		"main.go":                   "package main\n",
+		"Dockerfile":                "FROM scratch\n",
+		"debug.log":                 "log\n",
+		"image.bin":                 "\x00\x01",
+		"empty.txt":                 "",
+		"node_modules/lib/index.js": "module.exports = {}\n",
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		exts     []string
+		included bool
+		reason   string
+	}{
+		{"Default text extension", "main.go", nil, true, "extension is among the default text extensions"},
+		{"Empty file", "empty.txt", nil, true, "extension is among the default text extensions"},
+		{"Well-known file", "Dockerfile", nil, true, "well-known project file"},
+		{"Explicit extension", "main.go", []string{".go"}, true, "matches the -ext filter"},
+		{"Gitignore rule with line", "debug.log", nil, false, "path ignored by .gitignore:3 (*.log)"},
+		{"Package directory parent", "node_modules/lib/index.js", nil, false, "package directory, via parent directory node_modules"},
+		{"Hidden file", ".gitignore", nil, false, "hidden file"},
+		{"Unknown file type", "image.bin", nil, false, "extension not among the default text extensions (binary or unknown file type)"},
+		{"Extension filter", "Dockerfile", []string{".go"}, false, "extension not in -ext filter"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			included, reason, err := explainPath(testDir, tc.path, tc.exts, findOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.included, included)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+
+	t.Run("Hidden flag", func(t *testing.T) {
+		flagMutex.Lock()
+		*hidden = true
+		flagMutex.Unlock()
+		defer func() {
+			flagMutex.Lock()
+			*hidden = false
+			flagMutex.Unlock()
+		}()
+
+		included, reason, err := explainPath(testDir, "main.go", nil, findOptions{})
+		require.NoError(t, err)
+		assert.True(t, included)
+		assert.Equal(t, "extension is among the default text extensions", reason)
+
+		included, reason, err = explainPath(testDir, "debug.log", nil, findOptions{})
+		require.NoError(t, err)
+		assert.False(t, included)
+		assert.Equal(t, "extension not among the default text extensions (binary or unknown file type)", reason, "-hidden drops .gitignore, not the extension check")
+	})
+
+	t.Run("Missing path", func(t *testing.T) {
+		_, _, err := explainPath(testDir, "missing.go", nil, findOptions{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Path outside the directory", func(t *testing.T) {
+		_, _, err := explainPath(testDir, "../main.go", nil, findOptions{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Extensions of the XML-family files checked by the XML guards
+var xmlFamilyExts = []string{
+	".xml", ".svg", ".xsd", ".xsl", ".xslt", ".wsdl", ".plist", ".xaml", ".resx", ".xlf", ".xliff",
+	".iml", ".csproj", ".vbproj", ".fsproj", ".props", ".targets", ".kml", ".gpx", ".rss", ".atom",
+}
+
+const (
+	// XML-family files larger than this are left out
+	xmlMaxSize = 100 << 10
+	// Bytes at the start of an XML-family file searched for generator marks
+	xmlHeadSize = 4 << 10
+)
+
+// generatedXML matches the start of XML written by tools rather than people:
+// generator comments, coverage and test reports, linter results and
+// IntelliJ project files
+var generatedXML = regexp.MustCompile(`(?i)<!--[^>]*(generated by|auto-?generated|do not edit)|` +
+	`<(coverage|CoverageSession|testsuites|testsuite|checkstyle|pmd|BugCollection)[\s>]|` +
+	`<!DOCTYPE report PUBLIC "-//JACOCO|<project version="4">`)
+
+// isXMLFamily reports whether file is XML or a format built on it
+func isXMLFamily(file string) bool {
+	return contains(xmlFamilyExts, strings.ToLower(filepath.Ext(file)))
+}
+
+// xmlReason returns why an XML-family file of the given size and head is
+// left out, or "" if it looks like one people maintain
+func xmlReason(size int64, head []byte) string {
+	if size > xmlMaxSize {
+		return fmt.Sprintf("large XML (%d KB, use -include-xml to keep it)", size>>10)
+	}
+	if match := generatedXML.Find(head); match != nil {
+		return fmt.Sprintf("generated XML (%s, use -include-xml to keep it)", strings.Join(strings.Fields(string(match)), " "))
+	}
+	return ""
+}
+
+// filterXML drops the XML-family files that are large or generated, such
+// as coverage reports and IDE configs. Files that can't be read are kept
+// for gen to report.
+func filterXML(root string, files []string, skip func(path, reason string)) []string {
+	var kept []string
+	for _, file := range files {
+		if !isXMLFamily(file) {
+			kept = append(kept, file)
+			continue
+		}
+		f, err := os.Open(filepath.Join(root, file))
+		if err != nil {
+			kept = append(kept, file)
+			continue
+		}
+		info, statErr := f.Stat()
+		head, _ := io.ReadAll(io.LimitReader(f, xmlHeadSize))
+		f.Close()
+		if statErr == nil {
+			if reason := xmlReason(info.Size(), head); reason != "" {
+				skip(file, reason)
+				continue
+			}
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXMLReason(t *testing.T) {
+	tests := []struct {
+		name string
+		size int64
+		head string
+		want string
+	}{
+		{"hand-written", 2000, `<?xml version="1.0"?>` + "\n<project>\n  <modelVersion>4.0.0</modelVersion>", ""},
+		{"large", 300 << 10, `<?xml version="1.0"?>`, "large XML (300 KB, use -include-xml to keep it)"},
+		{"generator comment", 500, "<?xml version=\"1.0\"?>\n<!-- This file was\n     generated by xsdgen -->", "generated XML (<!-- This file was generated by, use -include-xml to keep it)"},
+		{"cobertura", 900, `<?xml version="1.0" ?>` + "\n<coverage line-rate=\"0.8\">", "generated XML (<coverage, use -include-xml to keep it)"},
+		{"junit", 900, "<testsuites>\n<testsuite name=\"a\">", "generated XML (<testsuites>, use -include-xml to keep it)"},
+		{"jacoco", 900, `<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">`, `generated XML (<!DOCTYPE report PUBLIC "-//JACOCO, use -include-xml to keep it)`},
+		{"intellij", 900, `<?xml version="1.0" encoding="UTF-8"?>` + "\n<project version=\"4\">\n  <component name=\"ChangeListManager\">", `generated XML (<project version="4">, use -include-xml to keep it)`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, xmlReason(tc.size, []byte(tc.head)))
+		})
+	}
+}
+
+func TestFindXML(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"pom.xml":               "<project>\n  <modelVersion>4.0.0</modelVersion>\n</project>\n",
+		"target/site/cov.xml":   "<?xml version=\"1.0\"?>\n<coverage line-rate=\"1\">\n</coverage>\n",
+		".idea/workspace.xml":   "<?xml version=\"1.0\"?>\n<project version=\"4\">\n</project>\n",
+		"assets/logo.svg":       "<svg>\n" + strings.Repeat("  <path d=\"M0 0L1 1\"/>\n", 6000) + "</svg>\n",
+		"docs/coverage.xml.txt": "<coverage>\n",
+	})
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	find := func(args ...string) ([]string, string) {
+		output := CaptureOutput(t, func() {
+			require.Equal(t, 0, runCommandIn(t, dir, append([]string{"-list-file", listFile, "find", "-ext", "xml,svg,txt", "-hidden", "-no-ignore"}, args...)...))
+		})
+		entries, err := readFileList(listFile)
+		require.NoError(t, err)
+		var paths []string
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+		return paths, output
+	}
+
+	paths, output := find(".")
+	assert.Equal(t, []string{"docs/coverage.xml.txt", "pom.xml"}, paths)
+	assert.Contains(t, output, "3 large or generated XML")
+
+	paths, _ = find("-include-xml", ".")
+	assert.Equal(t, []string{".idea/workspace.xml", "assets/logo.svg", "docs/coverage.xml.txt", "pom.xml", "target/site/cov.xml"}, paths)
+}